@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
@@ -165,12 +166,13 @@ func BART(deps Container) oscar.BOSServer {
 	logger := deps.logger.With("svc", "BART")
 
 	sessionManager := state.NewInMemorySessionManager(logger)
+	// buddy icon updates are broadcast to buddies signed on to BOS
 	bartService := foodgroup.NewBARTService(
 		logger,
 		deps.sqLiteUserStore,
-		sessionManager,
+		deps.inMemorySessionManager,
 		deps.sqLiteUserStore,
-		sessionManager,
+		deps.inMemorySessionManager,
 	)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
@@ -230,7 +232,7 @@ func BOS(deps Container) oscar.BOSServer {
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
 	)
-	chatNavService := foodgroup.NewChatNavService(logger, deps.sqLiteUserStore)
+	chatNavService := foodgroup.NewChatNavService(deps.cfg, logger, deps.sqLiteUserStore, deps.chatSessionManager)
 	feedbagService := foodgroup.NewFeedbagService(
 		logger,
 		deps.inMemorySessionManager,
@@ -267,6 +269,7 @@ func BOS(deps Container) oscar.BOSServer {
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.sqLiteUserStore,
 	)
 	userLookupService := foodgroup.NewUserLookupService(deps.sqLiteUserStore)
 
@@ -310,7 +313,7 @@ func Chat(deps Container) oscar.ChatServer {
 		deps.sqLiteUserStore,
 		nil,
 	)
-	chatService := foodgroup.NewChatService(deps.chatSessionManager)
+	chatService := foodgroup.NewChatService(deps.cfg, logger, deps.chatSessionManager, deps.inMemorySessionManager, deps.sqLiteUserStore)
 	oServiceService := foodgroup.NewOServiceServiceForChat(
 		deps.cfg,
 		logger,
@@ -348,7 +351,7 @@ func ChatNav(deps Container) oscar.BOSServer {
 		deps.sqLiteUserStore,
 		nil,
 	)
-	chatNavService := foodgroup.NewChatNavService(logger, deps.sqLiteUserStore)
+	chatNavService := foodgroup.NewChatNavService(deps.cfg, logger, deps.sqLiteUserStore, deps.chatSessionManager)
 	oServiceService := foodgroup.NewOServiceServiceForChatNav(
 		deps.cfg,
 		logger,
@@ -379,7 +382,7 @@ func MgmtAPI(deps Container) *http.Server {
 	}
 	return http.NewManagementAPI(bld, deps.cfg, deps.sqLiteUserStore, deps.inMemorySessionManager, deps.sqLiteUserStore,
 		deps.sqLiteUserStore, deps.chatSessionManager, deps.sqLiteUserStore, deps.inMemorySessionManager,
-		deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.logger)
+		deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.logger)
 }
 
 // ODir creates an OSCAR server for the ODir food group.
@@ -417,10 +420,23 @@ func ODir(deps Container) oscar.BOSServer {
 func TOC(deps Container) toc.Server {
 	logger := deps.logger.With("svc", "TOC")
 	sessionManager := state.NewInMemorySessionManager(logger)
+
+	var imHistory *toc.IMHistory
+	if deps.cfg.TOCAbuseReports {
+		// message history is only kept for attaching to abuse reports
+		imHistory = toc.NewIMHistory()
+	}
+
+	var violationCounter *toc.ViolationCounter
+	if deps.cfg.TOCMaxProtocolViolations > 0 {
+		violationCounter = toc.NewViolationCounter()
+	}
+
 	return toc.Server{
 		Logger:     logger,
 		ListenAddr: net.JoinHostPort(deps.cfg.TOCHost, deps.cfg.TOCPort),
 		BOSProxy: toc.OSCARProxy{
+			AbuseReportStore: deps.sqLiteUserStore,
 			AdminService: foodgroup.NewAdminService(
 				deps.sqLiteUserStore,
 				deps.sqLiteUserStore,
@@ -438,6 +454,16 @@ func TOC(deps Container) toc.Server {
 				deps.sqLiteUserStore,
 				nil,
 			),
+			BARTService: foodgroup.NewBARTService(
+				logger,
+				deps.sqLiteUserStore,
+				deps.inMemorySessionManager,
+				deps.sqLiteUserStore,
+				deps.inMemorySessionManager,
+			),
+			BuddyCommentStore: deps.sqLiteUserStore,
+			BuddyCounter:      deps.sqLiteUserStore,
+			BuddyLister:       deps.sqLiteUserStore,
 			BuddyListRegistry: deps.sqLiteUserStore,
 			BuddyService: foodgroup.NewBuddyService(
 				deps.inMemorySessionManager,
@@ -445,14 +471,30 @@ func TOC(deps Container) toc.Server {
 				deps.sqLiteUserStore,
 				deps.inMemorySessionManager,
 			),
+			Config:           deps.cfg,
+			ConfigLocker:     toc.NewConfigLocker(),
 			CookieBaker:      deps.hmacCookieBaker,
 			DirSearchService: foodgroup.NewODirService(logger, deps.sqLiteUserStore),
+			ErrorLogLimiter:  toc.NewErrorLogLimiter(),
+			FeedbagService: foodgroup.NewFeedbagService(
+				logger,
+				deps.inMemorySessionManager,
+				deps.sqLiteUserStore,
+				deps.sqLiteUserStore,
+				deps.sqLiteUserStore,
+				deps.inMemorySessionManager,
+			),
 			ICBMService: foodgroup.NewICBMService(
 				deps.inMemorySessionManager,
 				deps.sqLiteUserStore,
 				deps.sqLiteUserStore,
 				deps.inMemorySessionManager,
 			),
+			IMHistory: imHistory,
+			IMThrottle: toc.NewIMThrottle(
+				deps.cfg.TOCIMRecipientLimit,
+				time.Duration(deps.cfg.TOCIMRecipientWindowSecs)*time.Second,
+			),
 			LocateService: foodgroup.NewLocateService(
 				deps.inMemorySessionManager,
 				deps.sqLiteUserStore,
@@ -460,6 +502,11 @@ func TOC(deps Container) toc.Server {
 				deps.inMemorySessionManager,
 			),
 			Logger: logger,
+			LookupThrottle: toc.NewIMThrottle(
+				deps.cfg.TOCInfoLookupsPerMin,
+				time.Minute,
+			),
+			OfflineMessageManager: deps.sqLiteUserStore,
 			OServiceServiceBOS: foodgroup.NewOServiceServiceForBOS(
 				deps.cfg,
 				deps.inMemorySessionManager,
@@ -468,6 +515,7 @@ func TOC(deps Container) toc.Server {
 				deps.sqLiteUserStore,
 				deps.sqLiteUserStore,
 				deps.inMemorySessionManager,
+				nil, // OSCARProxy delivers offline messages to TOC users
 			),
 			PermitDenyService: foodgroup.NewPermitDenyService(
 				deps.sqLiteUserStore,
@@ -475,8 +523,22 @@ func TOC(deps Container) toc.Server {
 				deps.inMemorySessionManager,
 				deps.inMemorySessionManager,
 			),
+			RecentIMSenders: toc.NewRecentIMSenders(
+				time.Duration(deps.cfg.TOCEvilSenderTTLSecs) * time.Second,
+			),
+			ReportThrottle: toc.NewIMThrottle(
+				deps.cfg.TOCAbuseReportsPerHour,
+				time.Hour,
+			),
 			TOCConfigStore: deps.sqLiteUserStore,
-			ChatService:    foodgroup.NewChatService(deps.chatSessionManager),
+			UnconfirmedIMThrottle: toc.NewIMThrottle(
+				deps.cfg.TOCUnconfirmedIMsPerMin,
+				time.Minute,
+			),
+			UserManager:      deps.sqLiteUserStore,
+			ViolationCounter: violationCounter,
+			WarnThrottle:     toc.NewWarnThrottle(deps.cfg.TOCWarnRateLimits),
+			ChatService:      foodgroup.NewChatService(deps.cfg, logger, deps.chatSessionManager, deps.inMemorySessionManager, deps.sqLiteUserStore),
 			OServiceServiceChat: foodgroup.NewOServiceServiceForChat(
 				deps.cfg,
 				logger,
@@ -486,7 +548,14 @@ func TOC(deps Container) toc.Server {
 				deps.sqLiteUserStore,
 				sessionManager,
 			),
-			ChatNavService: foodgroup.NewChatNavService(logger, deps.sqLiteUserStore),
+			ChatNavService:      foodgroup.NewChatNavService(deps.cfg, logger, deps.sqLiteUserStore, deps.chatSessionManager),
+			ChatMessageRelayer:  deps.chatSessionManager,
+			ChatOccupantCounter: deps.chatSessionManager,
+			ChatRoomManager:     deps.sqLiteUserStore,
 		},
+		ReadTimeout:     time.Duration(deps.cfg.TOCReadTimeoutSecs) * time.Second,
+		WriteTimeout:    time.Duration(deps.cfg.TOCWriteTimeoutSecs) * time.Second,
+		Compression:     deps.cfg.TOCCompression,
+		InitDoneTimeout: time.Duration(deps.cfg.TOCInitDoneTimeoutSecs) * time.Second,
 	}
 }
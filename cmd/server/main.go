@@ -75,7 +75,14 @@ func main() {
 	start(ODir(deps))
 	start(TOC(deps))
 
-	if err := g.Wait(); err != nil {
+	err = g.Wait()
+
+	// close the database once all connections have drained
+	if closeErr := deps.sqLiteUserStore.Close(); closeErr != nil {
+		fmt.Printf("error closing feedbag store: %v\n", closeErr)
+	}
+
+	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
@@ -2,22 +2,70 @@ package config
 
 //go:generate go run github.com/mk6i/retro-aim-server/cmd/config_generator unix settings.env
 type Config struct {
-	ApiHost     string `envconfig:"API_HOST" require:"true" val:"127.0.0.1" description:"Specifies the IP address or hostname that the management API binds to for incoming connections (127.0.0.1 restricts to same machine only)."`
-	ApiPort     string `envconfig:"API_PORT" required:"true" val:"8080" description:"The port that the management API service binds to."`
-	AlertPort   string `envconfig:"ALERT_PORT" required:"true" val:"5194" description:"The port that the Alert service binds to."`
-	AuthPort    string `envconfig:"AUTH_PORT" required:"true" val:"5190" description:"The port that the auth service binds to."`
-	BARTPort    string `envconfig:"BART_PORT" required:"true" val:"5195" description:"The port that the BART service binds to."`
-	BOSPort     string `envconfig:"BOS_PORT" required:"true" val:"5191" description:"The port that the BOS service binds to."`
-	ChatNavPort string `envconfig:"CHAT_NAV_PORT" required:"true" val:"5193" description:"The port that the chat nav service binds to."`
-	ChatPort    string `envconfig:"CHAT_PORT" required:"true" val:"5192" description:"The port that the chat service binds to."`
-	AdminPort   string `envconfig:"ADMIN_PORT" required:"true" val:"5196" description:"The port that the admin service binds to."`
-	ODirPort    string `envconfig:"ODIR_PORT" required:"true" val:"5197" description:"The port that the ODir service binds to."`
-	DBPath      string `envconfig:"DB_PATH" required:"true" val:"oscar.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist."`
-	DisableAuth bool   `envconfig:"DISABLE_AUTH" required:"true" val:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
-	LogLevel    string `envconfig:"LOG_LEVEL" required:"true" val:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
-	OSCARHost   string `envconfig:"OSCAR_HOST" required:"true" val:"127.0.0.1" description:"The hostname that AIM clients connect to in order to reach OSCAR services (auth, BOS, BUCP, etc). Make sure the hostname is reachable by all clients. For local development, the default loopback address should work provided the server and AIM client(s) are running on the same machine. For LAN-only clients, a private IP address (e.g. 192.168..) or hostname should suffice. For clients connecting over the Internet, specify your public IP address and ensure that TCP ports 5190-5197 are open on your firewall."`
-	TOCHost     string `envconfig:"TOC_HOST" require:"true" val:"0.0.0.0" description:"Specifies the IP address or hostname that the TOC service binds to for incoming connections (0.0.0.0 listens on all interfaces)."`
-	TOCPort     string `envconfig:"TOC_PORT" required:"true" val:"9898" description:"The port that the TOC service binds to."`
+	ApiHost                  string   `envconfig:"API_HOST" require:"true" val:"127.0.0.1" description:"Specifies the IP address or hostname that the management API binds to for incoming connections (127.0.0.1 restricts to same machine only)."`
+	ApiPort                  string   `envconfig:"API_PORT" required:"true" val:"8080" description:"The port that the management API service binds to."`
+	AlertPort                string   `envconfig:"ALERT_PORT" required:"true" val:"5194" description:"The port that the Alert service binds to."`
+	AuthPort                 string   `envconfig:"AUTH_PORT" required:"true" val:"5190" description:"The port that the auth service binds to."`
+	BARTPort                 string   `envconfig:"BART_PORT" required:"true" val:"5195" description:"The port that the BART service binds to."`
+	BOSPort                  string   `envconfig:"BOS_PORT" required:"true" val:"5191" description:"The port that the BOS service binds to."`
+	ChatMaxCreatedRooms      int      `envconfig:"CHAT_MAX_CREATED_ROOMS" required:"false" val:"0" description:"The maximum number of chat rooms a user can have created at once. Only rooms that currently have people in them count against the limit. Joining rooms that already exist is unaffected. Set to 0 to disable."`
+	ChatNavPort              string   `envconfig:"CHAT_NAV_PORT" required:"true" val:"5193" description:"The port that the chat nav service binds to."`
+	ChatPort                 string   `envconfig:"CHAT_PORT" required:"true" val:"5192" description:"The port that the chat service binds to."`
+	ChatPrivateCharset       string   `envconfig:"CHAT_PRIVATE_CHARSET" required:"false" val:"us-ascii" description:"The character set of messages in chat rooms on the private exchange (exchange 4), which hosts the rooms created by users. One of us-ascii, iso-8859-1 or unicode-2-0."`
+	ChatPrivateLang          string   `envconfig:"CHAT_PRIVATE_LANG" required:"false" val:"en" description:"The language code of chat rooms on the private exchange (exchange 4)."`
+	ChatPrivateMaxMsgLen     uint16   `envconfig:"CHAT_PRIVATE_MAX_MSG_LEN" required:"false" val:"1024" description:"The maximum length of chat messages in rooms on the private exchange (exchange 4)."`
+	ChatPrivateMaxOccupancy  uint16   `envconfig:"CHAT_PRIVATE_MAX_OCCUPANCY" required:"false" val:"100" description:"The maximum number of people in a chat room on the private exchange (exchange 4)."`
+	ChatPublicCharset        string   `envconfig:"CHAT_PUBLIC_CHARSET" required:"false" val:"us-ascii" description:"The character set of messages in chat rooms on the public exchange (exchange 5), which hosts the rooms created by the server operator. One of us-ascii, iso-8859-1 or unicode-2-0."`
+	ChatPublicLang           string   `envconfig:"CHAT_PUBLIC_LANG" required:"false" val:"en" description:"The language code of chat rooms on the public exchange (exchange 5)."`
+	ChatPublicMaxMsgLen      uint16   `envconfig:"CHAT_PUBLIC_MAX_MSG_LEN" required:"false" val:"1024" description:"The maximum length of chat messages in rooms on the public exchange (exchange 5)."`
+	ChatPublicMaxOccupancy   uint16   `envconfig:"CHAT_PUBLIC_MAX_OCCUPANCY" required:"false" val:"250" description:"The maximum number of people in a chat room on the public exchange (exchange 5)."`
+	ChatTLVOrderQuirks       []string `envconfig:"CHAT_TLV_ORDER_QUIRKS" required:"false" val:"" description:"Comma-separated list of client ID:TLV order pairs that set the order of the TLVs in chat messages sent to clients whose client ID contains the given text (e.g. 'ICQ 2000:message+sender'). The TLV order is a '+'-separated list of 'sender', 'whisper', and 'message'. TLVs left out of the list are omitted. Client ID text is case-insensitive and the first matching entry applies. Clients that match no entry receive the order sender+whisper+message, which AIM 2.x requires to show the sender's screen name with each message."`
+	ChatTranscriptMaxAgeDays int      `envconfig:"CHAT_TRANSCRIPT_MAX_AGE_DAYS" required:"false" val:"30" description:"The number of days chat transcript entries are kept before they are deleted. Set to 0 to keep transcripts forever."`
+	ChatTranscriptRooms      []string `envconfig:"CHAT_TRANSCRIPT_ROOMS" required:"false" val:"" description:"Comma-separated list of chat room names whose messages are saved to a transcript in the database along with the sender's screen name and the time sent (e.g. 'Lobby,Help Desk'). Room names are case-insensitive and every instance of a listed room is transcribed. Chat participants are not told that the room is transcribed, so let your users know, for example in the room topic or your community rules. Leave empty to disable."`
+	AdminPort                string   `envconfig:"ADMIN_PORT" required:"true" val:"5196" description:"The port that the admin service binds to."`
+	ODirPort                 string   `envconfig:"ODIR_PORT" required:"true" val:"5197" description:"The port that the ODir service binds to."`
+	DBPath                   string   `envconfig:"DB_PATH" required:"true" val:"oscar.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist."`
+	DisableAuth              bool     `envconfig:"DISABLE_AUTH" required:"true" val:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
+	LogLevel                 string   `envconfig:"LOG_LEVEL" required:"true" val:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
+	OSCARHost                string   `envconfig:"OSCAR_HOST" required:"true" val:"127.0.0.1" description:"The hostname that AIM clients connect to in order to reach OSCAR services (auth, BOS, BUCP, etc). Make sure the hostname is reachable by all clients. For local development, the default loopback address should work provided the server and AIM client(s) are running on the same machine. For LAN-only clients, a private IP address (e.g. 192.168..) or hostname should suffice. For clients connecting over the Internet, specify your public IP address and ensure that TCP ports 5190-5197 are open on your firewall."`
+	OSCARIdleTimeoutSecs     int      `envconfig:"OSCAR_IDLE_TIMEOUT_SECS" required:"false" val:"0" description:"The number of seconds the OSCAR services wait to receive any frame from a client, including a keepalive, before considering the connection dead and closing it. Set to 0 to disable. When enabled, set it well above the client keepalive interval so that idle users aren't disconnected."`
+	OSCARKeepAliveSecs       int      `envconfig:"OSCAR_KEEPALIVE_SECS" required:"false" val:"0" description:"The number of seconds an OSCAR connection can go without traffic before the server sends the client a keepalive frame, which stops NAT gateways and firewalls from dropping idle connections. Set to 0 to disable."`
+	TOCHost                  string   `envconfig:"TOC_HOST" require:"true" val:"0.0.0.0" description:"Specifies the IP address or hostname that the TOC service binds to for incoming connections (0.0.0.0 listens on all interfaces)."`
+	TOCPort                  string   `envconfig:"TOC_PORT" required:"true" val:"9898" description:"The port that the TOC service binds to."`
+	TOCAllowedClients        []string `envconfig:"TOC_ALLOWED_CLIENTS" required:"false" val:"" description:"Comma-separated list of client version patterns that are allowed to sign on to the TOC service. The client version is the last argument of the toc_signon command. Patterns may contain '*' wildcards (e.g. 'TIC:TiK*'). If empty, all client versions are allowed."`
+	TOCBlockedClients        []string `envconfig:"TOC_BLOCKED_CLIENTS" required:"false" val:"" description:"Comma-separated list of client version patterns that are not allowed to sign on to the TOC service. Patterns may contain '*' wildcards. Blocked patterns take precedence over allowed patterns."`
+	TOCAbuseReports          bool     `envconfig:"TOC_ABUSE_REPORTS" required:"false" val:"false" description:"Allow TOC users to report abusive users with the non-standard toc_report_user command. Reports are stored for operator review along with the last few instant messages the reporter received from the reported user."`
+	TOCAbuseReportsPerHour   int      `envconfig:"TOC_ABUSE_REPORTS_PER_HOUR" required:"false" val:"5" description:"The maximum number of abuse reports per hour a TOC user can file. Reports that exceed the limit are rejected. Set to 0 to disable."`
+	TOCChatStrictCharset     bool     `envconfig:"TOC_CHAT_STRICT_CHARSET" required:"false" val:"false" description:"Convert chat messages sent by TOC users to the charset of the chat room, rejecting messages that contain characters the charset can't represent. When disabled, messages with characters outside the room charset are sent as UTF-8, which some older clients misrender."`
+	TOCChatJoinExtended      bool     `envconfig:"TOC_CHAT_JOIN_EXTENDED" required:"false" val:"false" description:"Append the exchange and instance number of the joined room to CHAT_JOIN messages sent to TOC clients (CHAT_JOIN:<id>:<name>:<exchange>:<instance>), which allows clients to tell apart instances of rooms with the same name. Leave disabled for clients that expect the standard CHAT_JOIN format."`
+	TOCChatRoomBlocks        []string `envconfig:"TOC_CHAT_ROOM_BLOCKS" required:"false" val:"" description:"Comma-separated list of room:screen name pairs that prevent TOC users from joining specific chat rooms (e.g. 'lobby:spammer1,lobby:spammer2'). Room names are case-insensitive."`
+	TOCChatRoomReplication   bool     `envconfig:"TOC_CHAT_ROOM_REPLICATION" required:"false" val:"false" description:"Route TOC users who join a full chat room to the next instance of the room that has space. Instances are replicas of a room that share its name. Up to 16 instances of a room are used."`
+	TOCAutoAwayMins          int      `envconfig:"TOC_AUTO_AWAY_MINS" required:"false" val:"0" description:"Automatically set an away message on behalf of TOC users who have been idle for this many minutes and have not set an away message themselves. The away message is cleared when the user becomes active again. Set to 0 to disable."`
+	TOCAutoJoinRooms         []string `envconfig:"TOC_AUTO_JOIN_ROOMS" required:"false" val:"" description:"Comma-separated list of chat room names that TOC users automatically join after signing on (e.g. 'Lobby,Welcome'). Rooms are created on exchange 4 if they don't exist. Leave empty to disable."`
+	TOCCompression           bool     `envconfig:"TOC_COMPRESSION" required:"false" val:"false" description:"Allow TOC clients to compress their connection with DEFLATE by sending the non-standard toc_compress command before signing on. This reduces bandwidth on metered links but is only supported by modern clients and proxies. Vintage clients are unaffected because they never request it."`
+	TOCEvilSenderTTLSecs     int      `envconfig:"TOC_EVIL_SENDER_TTL_SECS" required:"false" val:"0" description:"The number of seconds after receiving an instant message during which a TOC user can warn the sender. Warnings of users who have not sent an instant message within this window are rejected. Set to 0 to allow warning any user."`
+	TOCIMRecipientLimit      int      `envconfig:"TOC_IM_RECIPIENT_LIMIT" required:"false" val:"20" description:"The maximum number of instant messages a TOC user can send to a single recipient within TOC_IM_RECIPIENT_WINDOW_SECS. Messages that exceed the limit are rejected. Set to 0 to disable."`
+	TOCIMRecipientWindowSecs int      `envconfig:"TOC_IM_RECIPIENT_WINDOW_SECS" required:"false" val:"10" description:"The length in seconds of the sliding window used by TOC_IM_RECIPIENT_LIMIT."`
+	TOCIdleUpdateSecs        int      `envconfig:"TOC_IDLE_UPDATE_SECS" required:"false" val:"60" description:"How often, in seconds, the idle time of idle TOC users is re-announced to their buddies so that it keeps growing after the client reports it once. Set to 0 to disable."`
+	TOCInfoLookupsPerMin     int      `envconfig:"TOC_INFO_LOOKUPS_PER_MIN" required:"false" val:"0" description:"The maximum number of profile, directory info, and directory search lookups per minute a TOC user can make. Lookups that exceed the limit are rejected, which curbs bulk harvesting of profiles and directory info. Set to 0 to disable."`
+	TOCInitDoneTimeoutSecs   int      `envconfig:"TOC_INIT_DONE_TIMEOUT_SECS" required:"false" val:"30" description:"The number of seconds a TOC client has to send toc_init_done after signing on before it is disconnected. Set to 0 to disable."`
+	TOCLoginBanner           string   `envconfig:"TOC_LOGIN_BANNER" required:"false" val:"" description:"A message sent to TOC users right after they sign on whose client version matches no entry in TOC_LOGIN_BANNERS. Banners that start with http:// or https:// are sent as a URL for the client to open. Other banners are sent as an instant message from [System]. Leave empty to disable."`
+	TOCLoginBanners          []string `envconfig:"TOC_LOGIN_BANNERS" required:"false" val:"" description:"Comma-separated list of client version pattern=banner pairs that send client-specific login banners to TOC users (e.g. 'TIC:TiK*=Welcome TiK user!,*gaim*=https://example.com/pidgin-setup'). Patterns may contain '*' wildcards and are case-insensitive. The first matching entry applies. Banners can't contain commas."`
+	TOCMaxOfflineIMs         int      `envconfig:"TOC_MAX_OFFLINE_IMS" required:"false" val:"0" description:"The maximum number of offline instant messages that can be stored for a user. When TOC_OFFLINE_IMS is enabled, TOC users who send a message to an offline user whose queue is full receive an error. Set to 0 to disable."`
+	TOCMaxProfileLen         int      `envconfig:"TOC_MAX_PROFILE_LEN" required:"false" val:"0" description:"The maximum length in bytes of profiles set by TOC clients that don't match an entry in TOC_PROFILE_LEN_LIMITS. Longer profiles are rejected. Set to 0 to disable."`
+	TOCMaxProtocolViolations int      `envconfig:"TOC_MAX_PROTOCOL_VIOLATIONS" required:"false" val:"0" description:"The maximum number of consecutive malformed or unsupported commands a TOC client can send before it is disconnected. The count resets whenever the client sends a valid command. Set to 0 to disable."`
+	TOCOfflineIMs            bool     `envconfig:"TOC_OFFLINE_IMS" required:"false" val:"false" description:"Store instant messages sent from TOC clients to users who are offline so that they can be delivered at next sign-on. Messages sent to screen names that are not registered are rejected with an error regardless of this setting."`
+	TOCProfileLenLimits      []string `envconfig:"TOC_PROFILE_LEN_LIMITS" required:"false" val:"" description:"Comma-separated list of client version:max length pairs that limit the length in bytes of profiles set by TOC clients whose version string contains the given text (e.g. 'TiK:1024,TOC2:4096'). Version text is case-insensitive and the first matching entry applies. Longer profiles are rejected."`
+	TOCReadTimeoutSecs       int      `envconfig:"TOC_READ_TIMEOUT_SECS" required:"false" val:"0" description:"The number of seconds the TOC server waits to receive the next command from a client, including during sign-on, before closing the connection. Set to 0 to disable. When enabled, set it well above the client keepalive interval so that idle users aren't disconnected."`
+	TOCRejectStaleBuddyList  bool     `envconfig:"TOC_REJECT_STALE_BUDDY_LIST" required:"false" val:"false" description:"Reject TOC sign-ons for users whose buddy list is still registered from a previous session that was not cleanly signed out, such as after a crash or an abrupt disconnect. When disabled, the stale buddy list is cleared and the sign-on proceeds."`
+	TOCRoastingString        string   `envconfig:"TOC_ROASTING_STRING" required:"false" val:"Tic/Toc" description:"The roasting string that TOC clients use to obfuscate passwords at sign-on. Change it only to interoperate with clients that roast passwords with a different string, because clients that use the standard string can't sign on otherwise."`
+	TOCUnconfirmedDirListing bool     `envconfig:"TOC_UNCONFIRMED_DIR_LISTING" required:"false" val:"true" description:"Allow TOC users whose accounts are unconfirmed to list themselves in the user directory."`
+	TOCUnconfirmedIMsPerMin  int      `envconfig:"TOC_UNCONFIRMED_IMS_PER_MIN" required:"false" val:"0" description:"The maximum number of instant messages per minute a TOC user whose account is unconfirmed can send. Set to 0 to disable."`
+	TOCUnconfirmedMaxBuddies int      `envconfig:"TOC_UNCONFIRMED_MAX_BUDDIES" required:"false" val:"0" description:"The maximum number of buddies a TOC user whose account is unconfirmed can add to their buddy list. Set to 0 to disable."`
+	TOCWarnRateLimits        []string `envconfig:"TOC_WARN_RATE_LIMITS" required:"false" val:"" description:"Comma-separated list of warning percent:messages per minute pairs that slow down how fast warned TOC users can send instant and chat messages (e.g. '25:20,50:10,90:2'). The entry with the highest warning percent that the user's warning level reaches applies, and messages sent faster than its rate are rejected with ERROR:903. Users whose warning level is below every entry are not limited. Leave empty to disable."`
+	TOCWarnResult            bool     `envconfig:"TOC_WARN_RESULT" required:"false" val:"false" description:"Reply to TOC users who warn another user with the non-standard WARN_RESULT:<user>:<new evil> message, which reports the warned user's updated warning level percentage. Leave disabled for clients that don't expect it."`
+	TOCWriteTimeoutSecs      int      `envconfig:"TOC_WRITE_TIMEOUT_SECS" required:"false" val:"0" description:"The number of seconds the TOC server waits to send a message to a client before closing the connection. Set to 0 to disable."`
+	WarningDecayPerMin       int      `envconfig:"WARNING_DECAY_PER_MIN" required:"false" val:"0" description:"The number of percentage points a user's warning level drops each minute (e.g. 10 clears a 100% warning in 10 minutes, as AIM did). Users' buddies see the lower warning level the next time they receive the user's presence. Set to 0 to keep warning levels until sign-off."`
 }
 
 type Build struct {
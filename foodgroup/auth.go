@@ -70,6 +70,9 @@ func (s AuthService) RegisterChatSession(ctx context.Context, authCookie []byte)
 	if err := wire.UnmarshalBE(&c, bytes.NewBuffer(token)); err != nil {
 		return nil, err
 	}
+	if c.Audience != cookieAudienceChat {
+		return nil, errCookieAudienceMismatch
+	}
 	sess, err := s.chatSessionRegistry.AddSession(ctx, c.ChatCookie, c.ScreenName)
 	if err != nil {
 		return nil, fmt.Errorf("AddSession: %w", err)
@@ -77,9 +80,22 @@ func (s AuthService) RegisterChatSession(ctx context.Context, authCookie []byte)
 	return sess, err
 }
 
+// Cookie audiences identify which registration path an auth cookie was issued
+// for. They prevent a cookie minted for one service from being accepted by
+// another.
+const (
+	cookieAudienceBOS  uint8 = 1
+	cookieAudienceChat uint8 = 2
+)
+
+// errCookieAudienceMismatch indicates that an auth cookie was presented to a
+// service it was not issued for.
+var errCookieAudienceMismatch = errors.New("auth cookie audience mismatch")
+
 // bosCookie represents a token containing client metadata passed to the BOS
 // service upon connection.
 type bosCookie struct {
+	Audience   uint8
 	ScreenName state.DisplayScreenName `oscar:"len_prefix=uint8"`
 	ClientID   string                  `oscar:"len_prefix=uint8"`
 }
@@ -95,6 +111,9 @@ func (s AuthService) RegisterBOSSession(ctx context.Context, authCookie []byte)
 	if err := wire.UnmarshalBE(&c, bytes.NewBuffer(buf)); err != nil {
 		return nil, err
 	}
+	if c.Audience != cookieAudienceBOS {
+		return nil, errCookieAudienceMismatch
+	}
 
 	u, err := s.userManager.User(c.ScreenName.IdentScreenName())
 	if err != nil {
@@ -122,6 +141,10 @@ func (s AuthService) RegisterBOSSession(ctx context.Context, authCookie []byte)
 	// set string containing OSCAR client name and version
 	sess.SetClientID(c.ClientID)
 
+	if s.config.WarningDecayPerMin > 0 {
+		sess.SetWarningDecay(uint16(s.config.WarningDecayPerMin * 10))
+	}
+
 	if u.DisplayScreenName.IsUIN() {
 		sess.SetUserInfoFlag(wire.OServiceUserFlagICQ)
 
@@ -146,6 +169,9 @@ func (s AuthService) RetrieveBOSSession(authCookie []byte) (*state.Session, erro
 	if err := wire.UnmarshalBE(&c, bytes.NewBuffer(buf)); err != nil {
 		return nil, err
 	}
+	if c.Audience != cookieAudienceBOS {
+		return nil, errCookieAudienceMismatch
+	}
 
 	u, err := s.userManager.User(c.ScreenName.IdentScreenName())
 	if err != nil {
@@ -371,7 +397,7 @@ func (s AuthService) login(
 	case props.isBUCPAuth:
 		loginOK = user.ValidateHash(props.passwordHash)
 	case props.isTOCAuth:
-		loginOK = user.ValidateRoastedTOCPass(props.roastedPass)
+		loginOK = user.ValidateRoastedTOCPass(props.roastedPass, s.config.TOCRoastingString)
 	default:
 		loginOK = user.ValidateRoastedPass(props.roastedPass)
 	}
@@ -421,6 +447,7 @@ func (s AuthService) createUser(
 
 func (s AuthService) loginSuccessResponse(props loginProperties) (wire.TLVRestBlock, error) {
 	loginCookie := bosCookie{
+		Audience:   cookieAudienceBOS,
 		ScreenName: props.screenName,
 		ClientID:   props.clientID,
 	}
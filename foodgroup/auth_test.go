@@ -69,6 +69,7 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -125,6 +126,7 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 									ClientID:   "ICQ 2000b",
 								}
@@ -345,6 +347,7 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -484,6 +487,7 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -580,6 +584,9 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 
 	// roastedPassword the roasted form of "the_password"
 	roastedPassword := []byte{0x87, 0x4E, 0xE4, 0x9B, 0x49, 0xE7, 0xA8, 0xE1, 0x06, 0xCC, 0xCB, 0x82}
+	// customRoastedTOCPassword is "the_password" roasted with a non-standard
+	// TOC roasting string
+	customRoastedTOCPassword := wire.RoastTOCPassword([]byte("the_password"), "Roast/Me")
 
 	cases := []struct {
 		// name is the unit test name
@@ -626,6 +633,7 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -674,6 +682,7 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 									ClientID:   "ICQ 2000b",
 								}
@@ -725,6 +734,86 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "TOC password roasted with configured roasting string, login OK",
+			cfg: config.Config{
+				OSCARHost:         "127.0.0.1",
+				BOSPort:           "1234",
+				TOCRoastingString: "Roast/Me",
+			},
+			inputSNAC: wire.FLAPSignonFrame{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, customRoastedTOCPassword),
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					},
+				},
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     &user,
+						},
+					},
+				},
+				cookieBakerParams: cookieBakerParams{
+					cookieIssueParams: cookieIssueParams{
+						{
+							dataIn: func() []byte {
+								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
+									ScreenName: user.DisplayScreenName,
+								}
+								buf := &bytes.Buffer{}
+								assert.NoError(t, wire.MarshalBE(loginCookie, buf))
+								return buf.Bytes()
+							}(),
+							cookieOut: []byte("the-cookie"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsReconnectHere, "127.0.0.1:1234"),
+					wire.NewTLVBE(wire.LoginTLVTagsAuthorizationCookie, []byte("the-cookie")),
+				},
+			},
+		},
+		{
+			name: "TOC password roasted with a different roasting string than configured, login fails",
+			cfg: config.Config{
+				OSCARHost: "127.0.0.1",
+				BOSPort:   "1234",
+			},
+			inputSNAC: wire.FLAPSignonFrame{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, customRoastedTOCPassword),
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					},
+				},
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     &user,
+						},
+					},
+				},
+			},
+			expectOutput: wire.TLVRestBlock{
+				TLVList: []wire.TLV{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrInvalidPassword),
+				},
+			},
+		},
 		{
 			name: "AIM account doesn't exist, login fails",
 			cfg: config.Config{
@@ -822,6 +911,7 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -873,6 +963,7 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 						{
 							dataIn: func() []byte {
 								loginCookie := bosCookie{
+									Audience:   cookieAudienceBOS,
 									ScreenName: user.DisplayScreenName,
 								}
 								buf := &bytes.Buffer{}
@@ -1129,6 +1220,7 @@ func TestAuthService_RegisterChatSession_HappyPath(t *testing.T) {
 		Return(sess, nil)
 
 	c := chatLoginCookie{
+		Audience:   cookieAudienceChat,
 		ChatCookie: chatCookie,
 		ScreenName: sess.DisplayScreenName(),
 	}
@@ -1148,9 +1240,53 @@ func TestAuthService_RegisterChatSession_HappyPath(t *testing.T) {
 	assert.Equal(t, sess, have)
 }
 
+func TestAuthService_RegisterChatSession_BOSCookie(t *testing.T) {
+	c := bosCookie{
+		Audience:   cookieAudienceBOS,
+		ScreenName: "ScreenName",
+	}
+	bosCookieBuf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(c, bosCookieBuf))
+
+	authCookie := []byte("the-auth-cookie")
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Crack(authCookie).
+		Return(bosCookieBuf.Bytes(), nil)
+
+	svc := NewAuthService(config.Config{}, nil, nil, nil, cookieBaker, nil, nil, nil)
+
+	have, err := svc.RegisterChatSession(context.Background(), authCookie)
+	assert.ErrorIs(t, err, errCookieAudienceMismatch)
+	assert.Nil(t, have)
+}
+
+func TestAuthService_RegisterBOSSession_ChatCookie(t *testing.T) {
+	c := chatLoginCookie{
+		Audience:   cookieAudienceChat,
+		ChatCookie: "the-chat-cookie",
+		ScreenName: "ScreenName",
+	}
+	chatCookieBuf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(c, chatCookieBuf))
+
+	authCookie := []byte("the-auth-cookie")
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Crack(authCookie).
+		Return(chatCookieBuf.Bytes(), nil)
+
+	svc := NewAuthService(config.Config{}, nil, nil, nil, cookieBaker, nil, nil, nil)
+
+	have, err := svc.RegisterBOSSession(context.Background(), authCookie)
+	assert.ErrorIs(t, err, errCookieAudienceMismatch)
+	assert.Nil(t, have)
+}
+
 func TestAuthService_RegisterBOSSession(t *testing.T) {
 	screenName := state.DisplayScreenName("UserScreenName")
 	aimAuthCookie := bosCookie{
+		Audience:   cookieAudienceBOS,
 		ScreenName: screenName,
 	}
 	buf := &bytes.Buffer{}
@@ -1159,6 +1295,7 @@ func TestAuthService_RegisterBOSSession(t *testing.T) {
 
 	uin := state.DisplayScreenName("100003")
 	icqAuthCookie := bosCookie{
+		Audience:   cookieAudienceBOS,
 		ScreenName: uin,
 	}
 	buf = &bytes.Buffer{}
@@ -1314,6 +1451,7 @@ func TestAuthService_RetrieveBOSSession_HappyPath(t *testing.T) {
 	sess := newTestSession("screenName")
 
 	aimAuthCookie := bosCookie{
+		Audience:   cookieAudienceBOS,
 		ScreenName: sess.DisplayScreenName(),
 	}
 	buf := &bytes.Buffer{}
@@ -1346,6 +1484,7 @@ func TestAuthService_RetrieveBOSSession_SessionNotFound(t *testing.T) {
 	sess := newTestSession("screenName")
 
 	aimAuthCookie := bosCookie{
+		Audience:   cookieAudienceBOS,
 		ScreenName: sess.DisplayScreenName(),
 	}
 	buf := &bytes.Buffer{}
@@ -1,6 +1,7 @@
 package foodgroup
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"fmt"
@@ -27,17 +28,26 @@ func NewBARTService(
 ) BARTService {
 	return BARTService{
 		bartManager:            bartManager,
+		buddyListRetriever:     buddyListRetriever,
 		buddyUpdateBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
 		logger:                 logger,
+		sessionRetriever:       sessionRetriever,
 	}
 }
 
 type BARTService struct {
 	bartManager            BARTManager
+	buddyListRetriever     BuddyListRetriever
 	buddyUpdateBroadcaster buddyBroadcaster
 	logger                 *slog.Logger
+	sessionRetriever       SessionRetriever
 }
 
+// UpsertItem stores a BART item uploaded by the client. If the item is a new
+// buddy icon that the user's feedbag references, the user's buddies are sent
+// their updated presence so that they fetch the new icon. Icons that were
+// already stored were announced to buddies when the feedbag was updated, so
+// re-uploading them doesn't notify buddies again.
 func (s BARTService) UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x10_0x02_BARTUploadQuery) (wire.SNACMessage, error) {
 	h := md5.New()
 	if _, err := h.Write(inBody.Data); err != nil {
@@ -45,14 +55,21 @@ func (s BARTService) UpsertItem(ctx context.Context, sess *state.Session, inFram
 	}
 	hash := h.Sum(nil)
 
+	existing, err := s.bartManager.BARTRetrieve(hash)
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
+
 	if err := s.bartManager.BARTUpsert(hash, inBody.Data); err != nil {
 		return wire.SNACMessage{}, err
 	}
 
 	s.logger.DebugContext(ctx, "successfully uploaded buddy icon", "hash", fmt.Sprintf("%x", hash))
 
-	if err := s.buddyUpdateBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
-		return wire.SNACMessage{}, err
+	if len(existing) == 0 && inBody.Type == wire.BARTTypesBuddyIcon {
+		if err := s.broadcastIconUpload(ctx, sess, hash); err != nil {
+			return wire.SNACMessage{}, err
+		}
 	}
 
 	return wire.SNACMessage{
@@ -74,6 +91,29 @@ func (s BARTService) UpsertItem(ctx context.Context, sess *state.Session, inFram
 	}, nil
 }
 
+// broadcastIconUpload sends the user's buddies their updated presence if the
+// uploaded icon is the one referenced by the user's feedbag. The upload may
+// arrive on a BART service connection, so the presence comes from the user's
+// BOS session, which carries their away, idle, and other status.
+func (s BARTService) broadcastIconUpload(ctx context.Context, sess *state.Session, hash []byte) error {
+	icon, err := s.buddyListRetriever.BuddyIconRefByName(sess.IdentScreenName())
+	if err != nil {
+		return fmt.Errorf("retrieve buddy icon ref: %w", err)
+	}
+	if icon == nil || !bytes.Equal(icon.Hash, hash) {
+		// the user's buddies don't see this icon
+		return nil
+	}
+
+	bosSess := s.sessionRetriever.RetrieveSession(sess.IdentScreenName())
+	if bosSess == nil {
+		// the user isn't signed on, so there's no presence to update
+		return nil
+	}
+
+	return s.buddyUpdateBroadcaster.BroadcastBuddyArrived(ctx, bosSess)
+}
+
 // RetrieveItem fetches a BART item from the data store. The item is selected
 // based on inBody.Hash. It's unclear what effect inBody.Flags is supposed to
 // have on the request.
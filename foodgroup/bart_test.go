@@ -12,10 +12,23 @@ import (
 )
 
 func TestBARTService_UpsertItem(t *testing.T) {
+	itemData := []byte{'i', 't', 'e', 'm', 'd', 'a', 't', 'a'}
+	itemHash := []byte{0x4e, 0xd9, 0xc1, 0x96, 0x45, 0xdb, 0x5a, 0xec, 0xdb, 0xf5, 0xc7, 0xa2, 0x4e, 0x8e, 0xa0, 0xed}
+	iconRef := &wire.BARTID{
+		Type: wire.BARTTypesBuddyIcon,
+		BARTInfo: wire.BARTInfo{
+			Flags: wire.BARTFlagsCustom,
+			Hash:  itemHash,
+		},
+	}
+	// bosSession is the uploader's BOS session, which holds the presence
+	// sent to buddies
+	bosSession := newTestSession("user_screen_name", sessOptCannedAwayMessage, sessOptCannedSignonTime)
+
 	cases := []struct {
 		// name is the unit test name
 		name string
-		// userSession is the session of the user adding to feedbag
+		// userSession is the session of the user uploading the item
 		userSession *state.Session
 		// inputSNAC is the SNAC sent from the client to the server
 		inputSNAC wire.SNACMessage
@@ -26,30 +39,69 @@ func TestBARTService_UpsertItem(t *testing.T) {
 		expectOutput wire.SNACMessage
 	}{
 		{
-			name:        "upsert item",
+			name:        "upload new buddy icon, buddies receive the new icon",
 			userSession: newTestSession("user_screen_name"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
 				},
 				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
-					Type: 1,
-					Data: []byte{'i', 't', 'e', 'm', 'd', 'a', 't', 'a'},
+					Type: wire.BARTTypesBuddyIcon,
+					Data: itemData,
 				},
 			},
 			mockParams: mockParams{
 				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: itemHash,
+							result:   []byte{},
+						},
+					},
 					bartManagerUpsertParams: bartManagerUpsertParams{
 						{
-							itemHash: []byte{0x4e, 0xd9, 0xc1, 0x96, 0x45, 0xdb, 0x5a, 0xec, 0xdb, 0xf5, 0xc7, 0xa2, 0x4e, 0x8e, 0xa0, 0xed},
-							payload:  []byte{'i', 't', 'e', 'm', 'd', 'a', 't', 'a'},
+							itemHash: itemHash,
+							payload:  itemData,
 						},
 					},
 				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
 						{
 							screenName: state.NewIdentScreenName("user_screen_name"),
+							result:     iconRef,
+						},
+					},
+					allRelationshipsParams: allRelationshipsParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+							result: []state.Relationship{
+								{
+									User:          state.NewIdentScreenName("buddy"),
+									IsOnTheirList: true,
+								},
+								{
+									User:          state.NewIdentScreenName("blocked-buddy"),
+									IsOnTheirList: true,
+									YouBlock:      true,
+								},
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+							result:     bosSession,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNamesParams: relayToScreenNamesParams{
+						{
+							screenNames: []state.IdentScreenName{state.NewIdentScreenName("buddy")},
+							message:     newBuddyArrivedNotif(userInfoWithBARTIcon(bosSession, *iconRef)),
 						},
 					},
 				},
@@ -66,7 +118,174 @@ func TestBARTService_UpsertItem(t *testing.T) {
 						Type: wire.BARTTypesBuddyIcon,
 						BARTInfo: wire.BARTInfo{
 							Flags: wire.BARTFlagsKnown,
-							Hash:  []byte{0x4e, 0xd9, 0xc1, 0x96, 0x45, 0xdb, 0x5a, 0xec, 0xdb, 0xf5, 0xc7, 0xa2, 0x4e, 0x8e, 0xa0, 0xed},
+							Hash:  itemHash,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "upload icon that's already stored, don't notify buddies again",
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: itemData,
+				},
+			},
+			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: itemHash,
+							result:   itemData,
+						},
+					},
+					bartManagerUpsertParams: bartManagerUpsertParams{
+						{
+							itemHash: itemHash,
+							payload:  itemData,
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesSuccess,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+						BARTInfo: wire.BARTInfo{
+							Flags: wire.BARTFlagsKnown,
+							Hash:  itemHash,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "upload icon that the feedbag doesn't reference, don't notify buddies",
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: itemData,
+				},
+			},
+			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: itemHash,
+							result:   []byte{},
+						},
+					},
+					bartManagerUpsertParams: bartManagerUpsertParams{
+						{
+							itemHash: itemHash,
+							payload:  itemData,
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+							result: &wire.BARTID{
+								Type: wire.BARTTypesBuddyIcon,
+								BARTInfo: wire.BARTInfo{
+									Hash: []byte("otherhash"),
+								},
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesSuccess,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+						BARTInfo: wire.BARTInfo{
+							Flags: wire.BARTFlagsKnown,
+							Hash:  itemHash,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "upload new buddy icon while not signed on to BOS, don't notify buddies",
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: itemData,
+				},
+			},
+			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: itemHash,
+							result:   []byte{},
+						},
+					},
+					bartManagerUpsertParams: bartManagerUpsertParams{
+						{
+							itemHash: itemHash,
+							payload:  itemData,
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+							result:     iconRef,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+							result:     nil,
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesSuccess,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+						BARTInfo: wire.BARTInfo{
+							Flags: wire.BARTFlagsKnown,
+							Hash:  itemHash,
 						},
 					},
 				},
@@ -77,19 +296,40 @@ func TestBARTService_UpsertItem(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			bartManager := newMockBARTManager(t)
+			for _, params := range tc.mockParams.bartManagerRetrieveParams {
+				bartManager.EXPECT().
+					BARTRetrieve(params.itemHash).
+					Return(params.result, nil)
+			}
 			for _, params := range tc.mockParams.bartManagerUpsertParams {
 				bartManager.EXPECT().
 					BARTUpsert(params.itemHash, params.payload).
 					Return(nil)
 			}
-			buddyUpdateBroadcaster := newMockbuddyBroadcaster(t)
-			for _, params := range tc.mockParams.broadcastBuddyArrivedParams {
-				buddyUpdateBroadcaster.EXPECT().
-					BroadcastBuddyArrived(mock.Anything, matchSession(params.screenName)).
-					Return(params.err)
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			for _, params := range tc.mockParams.buddyIconRefByNameParams {
+				buddyListRetriever.EXPECT().
+					BuddyIconRefByName(params.screenName).
+					Return(params.result, params.err)
 			}
-			svc := NewBARTService(slog.Default(), bartManager, nil, nil, nil)
-			svc.buddyUpdateBroadcaster = buddyUpdateBroadcaster
+			for _, params := range tc.mockParams.allRelationshipsParams {
+				buddyListRetriever.EXPECT().
+					AllRelationships(params.screenName, params.filter).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.relayToScreenNamesParams {
+				messageRelayer.EXPECT().
+					RelayToScreenNames(mock.Anything, params.screenNames, params.message)
+			}
+
+			svc := NewBARTService(slog.Default(), bartManager, messageRelayer, buddyListRetriever, sessionRetriever)
 
 			output, err := svc.UpsertItem(nil, tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x10_0x02_BARTUploadQuery))
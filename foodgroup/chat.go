@@ -6,14 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"math/rand/v2"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -34,14 +38,89 @@ var (
 	rollDiceRgxp = regexp.MustCompile(`^//roll(?:-(dice|sides)([0-9]{1,3}))?(?:-(dice|sides)([0-9]{1,3}))?\s*$`)
 )
 
+// defaultChatTLVOrder is the order of the TLVs in chat messages sent to
+// clients that have no TLV order quirk. The order matters for AIM 2.x. if out
+// of order, screen names do not appear with each chat message.
+var defaultChatTLVOrder = []uint16{
+	wire.ChatTLVSenderInformation,
+	wire.ChatTLVPublicWhisperFlag,
+	wire.ChatTLVMessageInfo,
+}
+
+// chatTLVNames maps the TLV names used in config.ChatTLVOrderQuirks to chat
+// message TLV tags.
+var chatTLVNames = map[string]uint16{
+	"sender":  wire.ChatTLVSenderInformation,
+	"whisper": wire.ChatTLVPublicWhisperFlag,
+	"message": wire.ChatTLVMessageInfo,
+}
+
+// chatTLVOrderQuirk is the order of the TLVs in chat messages expected by
+// clients whose client ID contains clientID.
+type chatTLVOrderQuirk struct {
+	clientID string
+	order    []uint16
+}
+
+// parseChatTLVOrderQuirks parses config.ChatTLVOrderQuirks entries. Malformed
+// entries are skipped.
+func parseChatTLVOrderQuirks(entries []string) []chatTLVOrderQuirk {
+	var quirks []chatTLVOrderQuirk
+	for _, entry := range entries {
+		// client IDs may contain colons, so split on the last one
+		idx := strings.LastIndex(entry, ":")
+		if idx < 0 {
+			continue
+		}
+		clientID := strings.ToLower(strings.TrimSpace(entry[:idx]))
+		if clientID == "" {
+			continue
+		}
+		var order []uint16
+		for _, name := range strings.Split(entry[idx+1:], "+") {
+			tag, ok := chatTLVNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				order = nil
+				break
+			}
+			order = append(order, tag)
+		}
+		if len(order) == 0 {
+			continue
+		}
+		quirks = append(quirks, chatTLVOrderQuirk{clientID: clientID, order: order})
+	}
+	return quirks
+}
+
 // NewChatService creates a new instance of ChatService.
-func NewChatService(chatMessageRelayer ChatMessageRelayer) *ChatService {
+func NewChatService(
+	cfg config.Config,
+	logger *slog.Logger,
+	chatMessageRelayer ChatMessageRelayer,
+	sessionRetriever SessionRetriever,
+	transcriptStore ChatTranscriptStore,
+) *ChatService {
+	transcriptRooms := make(map[string]bool)
+	for _, room := range cfg.ChatTranscriptRooms {
+		if room = strings.TrimSpace(room); room != "" {
+			transcriptRooms[strings.ToLower(room)] = true
+		}
+	}
 	return &ChatService{
 		chatMessageRelayer: chatMessageRelayer,
+		logger:             logger,
 		randRollDie: func(sides int) int {
 			// generate random number between 1 and sides
 			return rand.IntN(sides) + 1
 		},
+		sessionRetriever: sessionRetriever,
+		timeNow:          time.Now,
+		tlvOrderQuirks:   parseChatTLVOrderQuirks(cfg.ChatTLVOrderQuirks),
+		transcriptMaxAge: time.Duration(cfg.ChatTranscriptMaxAgeDays) * 24 * time.Hour,
+		transcriptPurge:  &transcriptPurgeSchedule{},
+		transcriptRooms:  transcriptRooms,
+		transcriptStore:  transcriptStore,
 	}
 }
 
@@ -49,7 +128,15 @@ func NewChatService(chatMessageRelayer ChatMessageRelayer) *ChatService {
 // responsible for sending and receiving chat messages.
 type ChatService struct {
 	chatMessageRelayer ChatMessageRelayer
+	logger             *slog.Logger
 	randRollDie        func(sides int) int
+	sessionRetriever   SessionRetriever
+	timeNow            func() time.Time
+	tlvOrderQuirks     []chatTLVOrderQuirk
+	transcriptMaxAge   time.Duration
+	transcriptPurge    *transcriptPurgeSchedule
+	transcriptRooms    map[string]bool
+	transcriptStore    ChatTranscriptStore
 }
 
 // ChannelMsgToHost relays wire.ChatChannelMsgToClient SNAC sent from a user
@@ -70,21 +157,39 @@ func (s ChatService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 		bodyOut.Channel = wire.ICBMChannelMIME
 	}
 
-	var err error
-	bodyOut.TLVRestBlock, err = s.transformChatMessage(inBody, sess)
+	sender, msg, err := s.transformChatMessage(inBody, sess)
 	if err != nil {
 		return nil, err
 	}
 
-	// send message to all the participants except sender
-	s.chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
-		Frame: frameOut,
-		Body:  bodyOut,
-	})
+	if len(s.tlvOrderQuirks) == 0 {
+		// send message to all the participants except sender
+		bodyOut.TLVRestBlock = newChatMessageBlock(defaultChatTLVOrder, sender, msg)
+		s.chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
+			Frame: frameOut,
+			Body:  bodyOut,
+		})
+	} else {
+		// send each participant except sender the message in the TLV order
+		// its client expects
+		for _, participant := range s.chatMessageRelayer.AllSessions(sess.ChatRoomCookie()) {
+			if participant.IdentScreenName() == sess.IdentScreenName() {
+				continue
+			}
+			bodyOut.TLVRestBlock = newChatMessageBlock(s.tlvOrder(participant), sender, msg)
+			s.chatMessageRelayer.RelayToScreenName(ctx, sess.ChatRoomCookie(), participant.IdentScreenName(), wire.SNACMessage{
+				Frame: frameOut,
+				Body:  bodyOut,
+			})
+		}
+	}
+
+	s.recordTranscript(ctx, sess, inBody)
 
 	var ret *wire.SNACMessage
 	if _, ackMsg := inBody.Bytes(wire.ChatTLVEnableReflectionFlag); ackMsg {
 		// reflect the message back to the sender
+		bodyOut.TLVRestBlock = newChatMessageBlock(s.tlvOrder(sess), sender, msg)
 		ret = &wire.SNACMessage{
 			Frame: frameOut,
 			Body:  bodyOut,
@@ -95,40 +200,139 @@ func (s ChatService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 	return ret, nil
 }
 
+// recordTranscript records a chat message in the transcript of the sender's
+// room if the room is configured to keep one, then deletes the transcript
+// entries that are older than the maximum age, at most once per
+// transcriptPurgeInterval. It's called after
+// the message is relayed, and failures are logged rather than returned so
+// that they don't hold up the chat.
+func (s ChatService) recordTranscript(ctx context.Context, sender *state.Session, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) {
+	room := chatRoomName(sender.ChatRoomCookie())
+	if !s.transcriptRooms[strings.ToLower(room)] {
+		return
+	}
+
+	msgInfo, _ := inBody.Bytes(wire.ChatTLVMessageInfo)
+	text, err := wire.UnmarshalChatMessageText(msgInfo)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "unable to read chat message for transcript", "room", room, "err", err.Error())
+		return
+	}
+
+	now := s.timeNow().UTC()
+	entry := state.ChatTranscriptEntry{
+		Room:    room,
+		Sender:  sender.IdentScreenName(),
+		Message: text,
+		Sent:    now,
+	}
+	if err := s.transcriptStore.AddChatTranscriptEntry(entry); err != nil {
+		s.logger.ErrorContext(ctx, "unable to record chat transcript entry", "room", room, "err", err.Error())
+		return
+	}
+
+	if s.transcriptMaxAge > 0 && s.transcriptPurge.due(now) {
+		if err := s.transcriptStore.DeleteChatTranscriptEntries(now.Add(-s.transcriptMaxAge)); err != nil {
+			s.logger.ErrorContext(ctx, "unable to delete expired chat transcript entries", "err", err.Error())
+		}
+	}
+}
+
+// transcriptPurgeInterval is the minimum time between deletions of expired
+// chat transcript entries.
+const transcriptPurgeInterval = time.Hour
+
+// transcriptPurgeSchedule tracks when expired chat transcript entries were
+// last deleted so that the deletion doesn't run for every chat message.
+type transcriptPurgeSchedule struct {
+	lastRun time.Time  // When expired entries were last deleted.
+	m       sync.Mutex // Synchronization primitive for concurrent access.
+}
+
+// due reports whether expired entries should be deleted at now. If so, now is
+// recorded as the time of the last deletion.
+func (p *transcriptPurgeSchedule) due(now time.Time) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.lastRun.IsZero() && now.Sub(p.lastRun) < transcriptPurgeInterval {
+		return false
+	}
+	p.lastRun = now
+	return true
+}
+
+// chatRoomName returns the room name segment of a chat room cookie, which
+// has the format exchange-instance-name.
+func chatRoomName(cookie string) string {
+	parts := strings.SplitN(cookie, "-", 3)
+	return parts[len(parts)-1]
+}
+
+// tlvOrder returns the order of the TLVs in chat messages sent to a chat
+// participant. It's the order of the first TLV order quirk whose client ID
+// text the participant's client ID contains, or defaultChatTLVOrder if none
+// match. Chat sessions don't carry a client ID, so it's looked up from the
+// participant's BOS session.
+func (s ChatService) tlvOrder(participant *state.Session) []uint16 {
+	if len(s.tlvOrderQuirks) == 0 {
+		return defaultChatTLVOrder
+	}
+	bosSess := s.sessionRetriever.RetrieveSession(participant.IdentScreenName())
+	if bosSess == nil {
+		return defaultChatTLVOrder
+	}
+	clientID := strings.ToLower(bosSess.ClientID())
+	for _, quirk := range s.tlvOrderQuirks {
+		if strings.Contains(clientID, quirk.clientID) {
+			return quirk.order
+		}
+	}
+	return defaultChatTLVOrder
+}
+
+// newChatMessageBlock assembles the TLVs of a chat message sent by sender in
+// the given order.
+func newChatMessageBlock(order []uint16, sender *state.Session, msg any) wire.TLVRestBlock {
+	block := wire.TLVRestBlock{}
+	for _, tag := range order {
+		switch tag {
+		case wire.ChatTLVSenderInformation:
+			block.Append(wire.NewTLVBE(wire.ChatTLVSenderInformation, sender.TLVUserInfo()))
+		case wire.ChatTLVPublicWhisperFlag:
+			block.Append(wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}))
+		case wire.ChatTLVMessageInfo:
+			block.Append(wire.NewTLVBE(wire.ChatTLVMessageInfo, msg))
+		}
+	}
+	return block
+}
+
 // transformChatMessage inspects and modifies the incoming chat message payload.
+// It returns the session of the user the message appears to be sent by along
+// with the message info payload.
 //   - If message contains a properly formatted //roll command, return a roll
 //     die response.
 //   - Else return the unmodified incoming message.
 //
 // In the future, this function will validate the incoming message for correct form.
-func (s ChatService) transformChatMessage(inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost, sender *state.Session) (wire.TLVRestBlock, error) {
+func (s ChatService) transformChatMessage(inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost, sender *state.Session) (*state.Session, any, error) {
 	messageBlob, hasMessage := inBody.Bytes(wire.ChatTLVMessageInfo)
 	if !hasMessage {
-		return wire.TLVRestBlock{}, errors.New("SNAC(0x0E,0x05) does not contain a message TLV")
+		return nil, nil, errors.New("SNAC(0x0E,0x05) does not contain a message TLV")
 	}
 	messageText, err := textFromChatMsgBlob(messageBlob)
 	if err != nil {
-		return wire.TLVRestBlock{}, err
-	}
-
-	newMessageBlock := func(sess *state.Session, msg any) wire.TLVRestBlock {
-		block := wire.TLVRestBlock{}
-		// the order of these TLVs matters for AIM 2.x. if out of order, screen
-		// names do not appear with each chat message.
-		block.Append(wire.NewTLVBE(wire.ChatTLVSenderInformation, sess.TLVUserInfo()))
-		block.Append(wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}))
-		block.Append(wire.NewTLVBE(wire.ChatTLVMessageInfo, msg))
-		return block
+		return nil, nil, err
 	}
 
 	if doRoll, dice, sides := parseDiceCommand(messageText); doRoll {
 		payload := s.rollDice(sender, dice, sides)
 		// send die roll results from OnlineHost user
-		return newMessageBlock(sessOnlineHost, payload), nil
+		return sessOnlineHost, payload, nil
 	}
 
 	// return the incoming payload without modification
-	return newMessageBlock(sender, messageBlob), nil
+	return sender, messageBlob, nil
 }
 
 // rollDice generates a chat response for the results of a die roll.
@@ -270,7 +474,7 @@ func alertUserLeft(ctx context.Context, sess *state.Session, chatMessageRelayer
 	})
 }
 
-func sendChatRoomInfoUpdate(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer, room state.ChatRoom) {
+func sendChatRoomInfoUpdate(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer, room state.ChatRoom, settings state.ExchangeSettings) {
 	chatMessageRelayer.RelayToScreenName(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.Chat,
@@ -282,7 +486,7 @@ func sendChatRoomInfoUpdate(ctx context.Context, sess *state.Session, chatMessag
 			InstanceNumber: room.InstanceNumber(),
 			DetailLevel:    room.DetailLevel(),
 			TLVBlock: wire.TLVBlock{
-				TLVList: room.TLVList(),
+				TLVList: room.TLVList(settings),
 			},
 		},
 	})
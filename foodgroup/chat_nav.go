@@ -5,23 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
-var defaultExchangeCfg = wire.TLVBlock{
-	TLVList: wire.TLVList{
-		wire.NewTLVBE(wire.ChatRoomTLVMaxConcurrentRooms, uint8(10)),
-		wire.NewTLVBE(wire.ChatRoomTLVClassPerms, uint16(0x0010)),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxNameLen, uint16(100)),
-		wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
-		wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
-		wire.NewTLVBE(wire.ChatRoomTLVCharSet1, "us-ascii"),
-		wire.NewTLVBE(wire.ChatRoomTLVLang1, "en"),
-		wire.NewTLVBE(wire.ChatRoomTLVCharSet2, "us-ascii"),
-		wire.NewTLVBE(wire.ChatRoomTLVLang2, "en"),
-	},
+// exchangeCfg returns the exchange configuration advertised to clients for
+// an exchange with the given room settings.
+func exchangeCfg(settings state.ExchangeSettings) wire.TLVBlock {
+	return wire.TLVBlock{
+		TLVList: wire.TLVList{
+			wire.NewTLVBE(wire.ChatRoomTLVMaxConcurrentRooms, uint8(10)),
+			wire.NewTLVBE(wire.ChatRoomTLVClassPerms, uint16(0x0010)),
+			wire.NewTLVBE(wire.ChatRoomTLVMaxNameLen, uint16(100)),
+			wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
+			wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
+			wire.NewTLVBE(wire.ChatRoomTLVCharSet1, settings.Charset),
+			wire.NewTLVBE(wire.ChatRoomTLVLang1, settings.Lang),
+			wire.NewTLVBE(wire.ChatRoomTLVCharSet2, settings.Charset),
+			wire.NewTLVBE(wire.ChatRoomTLVLang2, settings.Lang),
+		},
+	}
 }
 
 var (
@@ -31,19 +37,36 @@ var (
 	errChatNavMismatchedExchange = errors.New("chat room exchange does not match requested exchange")
 )
 
+// chatRoomCreateMu serializes the creation of chat rooms while the created
+// room limit is enabled, so that concurrent creations by the same user can't
+// all get past the limit. It's shared by every ChatNavService because OSCAR
+// and TOC users create rooms through separate instances.
+var chatRoomCreateMu sync.Mutex
+
 // NewChatNavService creates a new instance of NewChatNavService.
-func NewChatNavService(logger *slog.Logger, chatRoomManager ChatRoomRegistry) *ChatNavService {
+func NewChatNavService(
+	cfg config.Config,
+	logger *slog.Logger,
+	chatRoomManager ChatRoomRegistry,
+	chatOccupantCounter ChatOccupantCounter,
+) *ChatNavService {
 	return &ChatNavService{
-		logger:          logger,
-		chatRoomManager: chatRoomManager,
+		chatExchanges:       state.NewChatExchanges(cfg),
+		logger:              logger,
+		chatOccupantCounter: chatOccupantCounter,
+		chatRoomManager:     chatRoomManager,
+		maxCreatedRooms:     cfg.ChatMaxCreatedRooms,
 	}
 }
 
 // ChatNavService provides functionality for the ChatNav food group, which
 // handles chat room creation and serving chat room metadata.
 type ChatNavService struct {
-	logger          *slog.Logger
-	chatRoomManager ChatRoomRegistry
+	chatExchanges       state.ChatExchanges
+	logger              *slog.Logger
+	chatOccupantCounter ChatOccupantCounter
+	chatRoomManager     ChatRoomRegistry
+	maxCreatedRooms     int
 }
 
 // RequestChatRights returns SNAC wire.ChatNavNavInfo, which contains chat
@@ -61,11 +84,11 @@ func (s ChatNavService) RequestChatRights(_ context.Context, inFrame wire.SNACFr
 					wire.NewTLVBE(wire.ChatNavTLVMaxConcurrentRooms, uint8(10)),
 					wire.NewTLVBE(wire.ChatNavTLVExchangeInfo, wire.SNAC_0x0D_0x09_TLVExchangeInfo{
 						Identifier: state.PrivateExchange,
-						TLVBlock:   defaultExchangeCfg,
+						TLVBlock:   exchangeCfg(s.chatExchanges.Settings(state.PrivateExchange)),
 					}),
 					wire.NewTLVBE(wire.ChatNavTLVExchangeInfo, wire.SNAC_0x0D_0x09_TLVExchangeInfo{
 						Identifier: state.PublicExchange,
-						TLVBlock:   defaultExchangeCfg,
+						TLVBlock:   exchangeCfg(s.chatExchanges.Settings(state.PublicExchange)),
 					}),
 				},
 			},
@@ -75,8 +98,10 @@ func (s ChatNavService) RequestChatRights(_ context.Context, inFrame wire.SNACFr
 
 // CreateRoom creates and returns a chat room or returns an existing chat
 // room. It returns SNAC wire.ChatNavNavInfo, which contains metadata for the
-// chat room.
-func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate) (wire.SNACMessage, error) {
+// chat room. Creating a room is refused with wire.ErrorCodeRequestDenied if
+// the user already created as many occupied rooms as the configured limit
+// allows.
+func (s ChatNavService) CreateRoom(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate) (wire.SNACMessage, error) {
 	if err := validateExchange(inBody.Exchange); err != nil {
 		s.logger.Debug("error validating exchange: " + err.Error())
 		return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeNotSupportedByHost)
@@ -90,7 +115,6 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 		return wire.SNACMessage{}, errChatNavRoomNameMissing
 	}
 
-	// todo call ChatRoomByName and CreateChatRoom in a txn
 	room, err := s.chatRoomManager.ChatRoomByName(inBody.Exchange, name)
 
 	switch {
@@ -100,12 +124,35 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 			return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeNoMatch)
 		}
 
+		if s.maxCreatedRooms > 0 {
+			chatRoomCreateMu.Lock()
+			defer chatRoomCreateMu.Unlock()
+
+			count, err := s.occupiedRoomCount(sess.IdentScreenName())
+			if err != nil {
+				return wire.SNACMessage{}, fmt.Errorf("%w: %w", errChatNavRetrieveFailed, err)
+			}
+			if count >= s.maxCreatedRooms {
+				s.logger.InfoContext(ctx, "user reached chat room creation limit", "limit", s.maxCreatedRooms)
+				return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeRequestDenied)
+			}
+		}
+
 		room = state.NewChatRoom(name, sess.IdentScreenName(), inBody.Exchange)
 
-		if err := s.chatRoomManager.CreateChatRoom(&room); err != nil {
+		err = s.chatRoomManager.CreateChatRoom(&room)
+		if errors.Is(err, state.ErrDupChatRoom) {
+			// another user created the room after it was looked up, which
+			// happens when two users join the same new room at once. join
+			// the room they created.
+			room, err = s.chatRoomManager.ChatRoomByName(inBody.Exchange, name)
+			if err != nil {
+				return wire.SNACMessage{}, fmt.Errorf("%w: %w", errChatNavRetrieveFailed, err)
+			}
+		}
+		if err != nil {
 			return wire.SNACMessage{}, fmt.Errorf("%w: %w", errChatNavRoomCreateFailed, err)
 		}
-		break
 	case err != nil:
 		return wire.SNACMessage{}, fmt.Errorf("%w: %w", errChatNavRetrieveFailed, err)
 	}
@@ -124,7 +171,7 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 						DetailLevel:    room.DetailLevel(),
 						InstanceNumber: room.InstanceNumber(),
 						TLVBlock: wire.TLVBlock{
-							TLVList: room.TLVList(),
+							TLVList: room.TLVList(s.chatExchanges.Settings(room.Exchange())),
 						},
 					}),
 				},
@@ -133,6 +180,23 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 	}, nil
 }
 
+// occupiedRoomCount returns the number of rooms created by creator that
+// currently have people in them. Empty rooms don't count against the created
+// room limit, since nothing is using them.
+func (s ChatNavService) occupiedRoomCount(creator state.IdentScreenName) (int, error) {
+	rooms, err := s.chatRoomManager.ChatRoomsByCreator(creator)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, room := range rooms {
+		if s.chatOccupantCounter.OccupantCount(room.Cookie()) > 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // RequestRoomInfo returns wire.ChatNavNavInfo, which contains metadata for
 // the chat room specified in the inFrame.hmacCookie.
 func (s ChatNavService) RequestRoomInfo(_ context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0D_0x04_ChatNavRequestRoomInfo) (wire.SNACMessage, error) {
@@ -165,7 +229,7 @@ func (s ChatNavService) RequestRoomInfo(_ context.Context, inFrame wire.SNACFram
 						DetailLevel:    room.DetailLevel(),
 						InstanceNumber: room.InstanceNumber(),
 						TLVBlock: wire.TLVBlock{
-							TLVList: room.TLVList(),
+							TLVList: room.TLVList(s.chatExchanges.Settings(room.Exchange())),
 						},
 					}),
 				},
@@ -191,7 +255,7 @@ func (s ChatNavService) ExchangeInfo(_ context.Context, inFrame wire.SNACFrame,
 					wire.NewTLVBE(wire.ChatNavTLVMaxConcurrentRooms, uint8(10)),
 					wire.NewTLVBE(wire.ChatNavTLVExchangeInfo, wire.SNAC_0x0D_0x09_TLVExchangeInfo{
 						Identifier: inBody.Exchange,
-						TLVBlock:   defaultExchangeCfg,
+						TLVBlock:   exchangeCfg(s.chatExchanges.Settings(inBody.Exchange)),
 					}),
 				},
 			},
@@ -1,15 +1,22 @@
 package foodgroup
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestChatNavService_CreateRoom(t *testing.T) {
@@ -63,7 +70,7 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 									InstanceNumber: basicChatRoom.InstanceNumber(),
 									DetailLevel:    basicChatRoom.DetailLevel(),
 									TLVBlock: wire.TLVBlock{
-										TLVList: basicChatRoom.TLVList(),
+										TLVList: basicChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(basicChatRoom.Exchange())),
 									},
 								},
 							),
@@ -120,7 +127,7 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 									InstanceNumber: basicChatRoom.InstanceNumber(),
 									DetailLevel:    basicChatRoom.DetailLevel(),
 									TLVBlock: wire.TLVBlock{
-										TLVList: basicChatRoom.TLVList(),
+										TLVList: basicChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(basicChatRoom.Exchange())),
 									},
 								},
 							),
@@ -148,6 +155,77 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 				return basicChatRoom
 			},
 		},
+		{
+			name:     "create private room that another user creates concurrently, join their room",
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavNavInfo,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(
+								wire.ChatNavRequestRoomInfo,
+								wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       basicChatRoom.Exchange(),
+									Cookie:         basicChatRoom.Cookie(),
+									InstanceNumber: basicChatRoom.InstanceNumber(),
+									DetailLevel:    basicChatRoom.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: basicChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(basicChatRoom.Exchange())),
+									},
+								},
+							),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							room:     basicChatRoom,
+						},
+					},
+					createChatRoomParams: createChatRoomParams{
+						{
+							room: &basicChatRoom,
+							err:  fmt.Errorf("CreateChatRoom: %w", state.ErrDupChatRoom),
+						},
+					},
+				},
+			},
+			fnNewChatRoom: func() state.ChatRoom {
+				return basicChatRoom
+			},
+		},
 		{
 			name:     "create public room that already exists",
 			chatRoom: &publicChatRoom,
@@ -185,7 +263,7 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 									InstanceNumber: publicChatRoom.InstanceNumber(),
 									DetailLevel:    publicChatRoom.DetailLevel(),
 									TLVBlock: wire.TLVBlock{
-										TLVList: publicChatRoom.TLVList(),
+										TLVList: publicChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(publicChatRoom.Exchange())),
 									},
 								},
 							),
@@ -390,7 +468,8 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 			for _, params := range tt.mockParams.chatRoomByNameParams {
 				chatRoomRegistry.EXPECT().
 					ChatRoomByName(params.exchange, params.name).
-					Return(params.room, params.err)
+					Return(params.room, params.err).
+					Once()
 			}
 			for _, params := range tt.mockParams.createChatRoomParams {
 				chatRoomRegistry.EXPECT().
@@ -398,7 +477,7 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 					Return(params.err)
 			}
 
-			svc := NewChatNavService(slog.Default(), chatRoomRegistry)
+			svc := NewChatNavService(config.Config{}, slog.Default(), chatRoomRegistry, nil)
 			outputSNAC, err := svc.CreateRoom(context.Background(), tt.sess, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate))
 			assert.ErrorIs(t, err, tt.wantErr)
 			assert.Equal(t, tt.want, outputSNAC)
@@ -406,6 +485,287 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 	}
 }
 
+// racingChatRoomRegistry is a ChatRoomRegistry whose lookups of missing rooms
+// wait for each other, so that concurrent callers all find that a room
+// doesn't exist before any of them creates it.
+type racingChatRoomRegistry struct {
+	ChatRoomRegistry
+	lookups sync.WaitGroup
+	rooms   map[string]state.ChatRoom
+	m       sync.Mutex
+}
+
+func (r *racingChatRoomRegistry) ChatRoomByName(_ uint16, name string) (state.ChatRoom, error) {
+	r.m.Lock()
+	room, ok := r.rooms[name]
+	r.m.Unlock()
+	if ok {
+		return room, nil
+	}
+	r.lookups.Done()
+	r.lookups.Wait()
+	return state.ChatRoom{}, state.ErrChatRoomNotFound
+}
+
+func (r *racingChatRoomRegistry) CreateChatRoom(chatRoom *state.ChatRoom) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, ok := r.rooms[chatRoom.Name()]; ok {
+		return fmt.Errorf("CreateChatRoom: %w", state.ErrDupChatRoom)
+	}
+	r.rooms[chatRoom.Name()] = *chatRoom
+	return nil
+}
+
+func TestChatNavService_CreateRoom_ConcurrentJoins(t *testing.T) {
+	const joiners = 2
+
+	registry := &racingChatRoomRegistry{
+		rooms: make(map[string]state.ChatRoom),
+	}
+	registry.lookups.Add(joiners)
+
+	svc := NewChatNavService(config.Config{}, slog.Default(), registry, nil)
+
+	inBody := wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+		Exchange: state.PrivateExchange,
+		Cookie:   "create",
+		TLVBlock: wire.TLVBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatRoomTLVRoomName, "the-new-room"),
+			},
+		},
+	}
+
+	replies := make([]wire.SNACMessage, joiners)
+	errs := make([]error, joiners)
+	wg := sync.WaitGroup{}
+	for i := 0; i < joiners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sess := newTestSession(state.DisplayScreenName(fmt.Sprintf("user%d", i)))
+			replies[i], errs[i] = svc.CreateRoom(context.Background(), sess, wire.SNACFrame{}, inBody)
+		}(i)
+	}
+	wg.Wait()
+
+	// every joiner gets the room created by whoever won the race
+	winner := registry.rooms["the-new-room"]
+	for i := 0; i < joiners; i++ {
+		if assert.NoError(t, errs[i]) {
+			body := replies[i].Body.(wire.SNAC_0x0D_0x09_ChatNavNavInfo)
+			b, ok := body.Bytes(wire.ChatNavTLVRoomInfo)
+			if assert.True(t, ok) {
+				roomInfo := wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{}
+				assert.NoError(t, wire.UnmarshalBE(&roomInfo, bytes.NewReader(b)))
+				assert.Equal(t, winner.Cookie(), roomInfo.Cookie)
+			}
+		}
+	}
+	assert.Len(t, registry.rooms, 1)
+}
+
+func TestChatNavService_CreateRoom_CreatedRoomLimit(t *testing.T) {
+	me := state.NewIdentScreenName("me")
+	room1 := state.NewChatRoom("room 1", me, state.PrivateExchange)
+	room2 := state.NewChatRoom("room 2", me, state.PrivateExchange)
+
+	inBody := wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+		Exchange: state.PrivateExchange,
+		Cookie:   "create",
+		TLVBlock: wire.TLVBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatRoomTLVRoomName, "the-new-room"),
+			},
+		},
+	}
+
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// existingRoom is the room returned by the name lookup, if any
+		existingRoom *state.ChatRoom
+		// createdRooms are the rooms previously created by the user
+		createdRooms []state.ChatRoom
+		// createdRoomsErr is the error returned by the created rooms lookup
+		createdRoomsErr error
+		// occupants maps room cookies to their occupant counts
+		occupants map[string]int
+		// wantCreate indicates whether the room is expected to be created
+		wantCreate bool
+		// wantErrCode is the expected SNAC error code, if any
+		wantErrCode uint16
+		// wantErr is the expected error
+		wantErr error
+	}{
+		{
+			name:         "create room, empty rooms don't count against the limit",
+			createdRooms: []state.ChatRoom{room1, room2},
+			occupants: map[string]int{
+				room1.Cookie(): 3,
+				room2.Cookie(): 0,
+			},
+			wantCreate: true,
+		},
+		{
+			name:         "create room, limit reached",
+			createdRooms: []state.ChatRoom{room1, room2},
+			occupants: map[string]int{
+				room1.Cookie(): 3,
+				room2.Cookie(): 1,
+			},
+			wantErrCode: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "join existing room at limit",
+			existingRoom: func() *state.ChatRoom {
+				room := state.NewChatRoom("the-new-room", state.NewIdentScreenName("them"), state.PrivateExchange)
+				return &room
+			}(),
+		},
+		{
+			name:            "create room, created rooms lookup fails",
+			createdRoomsErr: io.EOF,
+			wantErr:         errChatNavRetrieveFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatRoomRegistry := newMockChatRoomRegistry(t)
+			occupantCounter := newMockChatOccupantCounter(t)
+
+			if tt.existingRoom != nil {
+				chatRoomRegistry.EXPECT().
+					ChatRoomByName(state.PrivateExchange, "the-new-room").
+					Return(*tt.existingRoom, nil)
+			} else {
+				chatRoomRegistry.EXPECT().
+					ChatRoomByName(state.PrivateExchange, "the-new-room").
+					Return(state.ChatRoom{}, state.ErrChatRoomNotFound)
+				chatRoomRegistry.EXPECT().
+					ChatRoomsByCreator(me).
+					Return(tt.createdRooms, tt.createdRoomsErr)
+				for cookie, count := range tt.occupants {
+					occupantCounter.EXPECT().
+						OccupantCount(cookie).
+						Return(count)
+				}
+			}
+			if tt.wantCreate {
+				chatRoomRegistry.EXPECT().
+					CreateChatRoom(mock.Anything).
+					Return(nil)
+			}
+
+			cfg := config.Config{
+				ChatMaxCreatedRooms: 2,
+			}
+			svc := NewChatNavService(cfg, slog.Default(), chatRoomRegistry, occupantCounter)
+			outputSNAC, err := svc.CreateRoom(context.Background(), newTestSession("me"), wire.SNACFrame{}, inBody)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+
+			if tt.wantErrCode != 0 {
+				assert.Equal(t, wire.SNACError{Code: tt.wantErrCode}, outputSNAC.Body)
+			} else {
+				assert.IsType(t, wire.SNAC_0x0D_0x09_ChatNavNavInfo{}, outputSNAC.Body)
+			}
+		})
+	}
+}
+
+// countingChatRoomRegistry is a racingChatRoomRegistry whose lookups of a
+// user's created rooms wait briefly for each other before returning, so that
+// creators count their rooms at the same time unless room creation is
+// serialized.
+type countingChatRoomRegistry struct {
+	*racingChatRoomRegistry
+	counts sync.WaitGroup
+}
+
+func (r *countingChatRoomRegistry) ChatRoomsByCreator(creator state.IdentScreenName) ([]state.ChatRoom, error) {
+	r.m.Lock()
+	var rooms []state.ChatRoom
+	for _, room := range r.rooms {
+		if room.Creator() == creator {
+			rooms = append(rooms, room)
+		}
+	}
+	r.m.Unlock()
+
+	r.counts.Done()
+	done := make(chan struct{})
+	go func() {
+		r.counts.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+	}
+	return rooms, nil
+}
+
+func TestChatNavService_CreateRoom_ConcurrentCreationsAtLimit(t *testing.T) {
+	const creators = 2
+
+	registry := &countingChatRoomRegistry{
+		racingChatRoomRegistry: &racingChatRoomRegistry{
+			rooms: make(map[string]state.ChatRoom),
+		},
+	}
+	registry.lookups.Add(creators)
+	registry.counts.Add(creators)
+
+	// every created room is occupied by its creator
+	occupantCounter := newMockChatOccupantCounter(t)
+	occupantCounter.EXPECT().
+		OccupantCount(mock.Anything).
+		Return(1).
+		Maybe()
+
+	cfg := config.Config{
+		ChatMaxCreatedRooms: 1,
+	}
+	svc := NewChatNavService(cfg, slog.Default(), registry, occupantCounter)
+
+	replies := make([]wire.SNACMessage, creators)
+	errs := make([]error, creators)
+	wg := sync.WaitGroup{}
+	for i := 0; i < creators; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			inBody := wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+				Exchange: state.PrivateExchange,
+				Cookie:   "create",
+				TLVBlock: wire.TLVBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatRoomTLVRoomName, fmt.Sprintf("room %d", i)),
+					},
+				},
+			}
+			replies[i], errs[i] = svc.CreateRoom(context.Background(), newTestSession("me"), wire.SNACFrame{}, inBody)
+		}(i)
+	}
+	wg.Wait()
+
+	// both lookups find no room, but only one creation gets past the limit
+	denied := 0
+	for i := 0; i < creators; i++ {
+		assert.NoError(t, errs[i])
+		if body, ok := replies[i].Body.(wire.SNACError); ok && body.Code == wire.ErrorCodeRequestDenied {
+			denied++
+		}
+	}
+	assert.Equal(t, 1, denied)
+	assert.Len(t, registry.rooms, 1)
+}
+
 func TestChatNavService_RequestRoomInfo(t *testing.T) {
 	privateChatRoom := state.NewChatRoom("the-chat-room", state.NewIdentScreenName("the-user"), state.PrivateExchange)
 	publicChatRoom := state.NewChatRoom("the-chat-room", state.NewIdentScreenName("the-user"), state.PublicExchange)
@@ -443,7 +803,7 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 								Exchange:       privateChatRoom.Exchange(),
 								InstanceNumber: privateChatRoom.InstanceNumber(),
 								TLVBlock: wire.TLVBlock{
-									TLVList: privateChatRoom.TLVList(),
+									TLVList: privateChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(privateChatRoom.Exchange())),
 								},
 							}),
 						},
@@ -487,7 +847,7 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 								Exchange:       publicChatRoom.Exchange(),
 								InstanceNumber: publicChatRoom.InstanceNumber(),
 								TLVBlock: wire.TLVBlock{
-									TLVList: publicChatRoom.TLVList(),
+									TLVList: publicChatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(publicChatRoom.Exchange())),
 								},
 							}),
 						},
@@ -585,7 +945,7 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 					Return(params.room, params.err)
 			}
 
-			svc := NewChatNavService(slog.Default(), chatRoomRegistry)
+			svc := NewChatNavService(config.Config{}, slog.Default(), chatRoomRegistry, nil)
 			got, err := svc.RequestRoomInfo(nil, tt.inputSNAC.Frame,
 				tt.inputSNAC.Body.(wire.SNAC_0x0D_0x04_ChatNavRequestRoomInfo))
 			assert.ErrorIs(t, err, tt.wantErr)
@@ -598,7 +958,13 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 }
 
 func TestChatNavService_RequestChatRights(t *testing.T) {
-	svc := NewChatNavService(nil, nil)
+	// the public exchange is configured with a different charset and
+	// language than the private exchange
+	cfg := config.Config{
+		ChatPublicCharset: "iso-8859-1",
+		ChatPublicLang:    "de",
+	}
+	svc := NewChatNavService(cfg, nil, nil, nil)
 
 	have := svc.RequestChatRights(nil, wire.SNACFrame{RequestID: 1234})
 
@@ -637,10 +1003,10 @@ func TestChatNavService_RequestChatRights(t *testing.T) {
 								wire.NewTLVBE(wire.ChatRoomTLVMaxNameLen, uint16(100)),
 								wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
 								wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
-								wire.NewTLVBE(wire.ChatRoomTLVCharSet1, "us-ascii"),
-								wire.NewTLVBE(wire.ChatRoomTLVLang1, "en"),
-								wire.NewTLVBE(wire.ChatRoomTLVCharSet2, "us-ascii"),
-								wire.NewTLVBE(wire.ChatRoomTLVLang2, "en"),
+								wire.NewTLVBE(wire.ChatRoomTLVCharSet1, "iso-8859-1"),
+								wire.NewTLVBE(wire.ChatRoomTLVLang1, "de"),
+								wire.NewTLVBE(wire.ChatRoomTLVCharSet2, "iso-8859-1"),
+								wire.NewTLVBE(wire.ChatRoomTLVLang2, "de"),
 							},
 						},
 					}),
@@ -767,7 +1133,7 @@ func TestChatNavService_ExchangeInfo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			svc := NewChatNavService(slog.Default(), nil)
+			svc := NewChatNavService(config.Config{}, slog.Default(), nil, nil)
 			outputSNAC, err := svc.ExchangeInfo(context.Background(), tt.inputSNAC.Frame,
 				tt.inputSNAC.Body.(wire.SNAC_0x0D_0x03_ChatNavRequestExchangeInfo))
 			assert.ErrorIs(t, err, tt.wantErr)
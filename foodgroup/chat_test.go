@@ -2,9 +2,13 @@ package foodgroup
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"math"
 	"testing"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -354,7 +358,7 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 					RelayToAllExcept(mock.Anything, params.cookie, params.screenName, params.message)
 			}
 
-			svc := NewChatService(chatMessageRelayer)
+			svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, nil, nil)
 			svc.randRollDie = tc.randRollDie
 			outputSNAC, err := svc.ChannelMsgToHost(context.Background(), tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x0E_0x05_ChatChannelMsgToHost))
@@ -364,6 +368,262 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 	}
 }
 
+func TestChatService_ChannelMsgToHost_TLVOrderQuirks(t *testing.T) {
+	cfg := config.Config{
+		ChatTLVOrderQuirks: []string{
+			"bad entry",
+			"ICQ 2000:message+bogus",
+			"icq 2000:message+sender",
+		},
+	}
+
+	sender := newTestSession("me", sessOptCannedSignonTime, sessOptChatRoomCookie("the-chat-cookie"))
+	icqUser := newTestSession("icq_user", sessOptChatRoomCookie("the-chat-cookie"))
+	aimUser := newTestSession("aim_user", sessOptChatRoomCookie("the-chat-cookie"))
+
+	msgInfo := wire.TLVRestBlock{
+		TLVList: wire.TLVList{
+			wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<HTML><BODY>Hello</BODY></HTML>"),
+		},
+	}
+	inBody := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Cookie:  1234,
+		Channel: wire.ICBMChannelMIME,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, msgInfo),
+			},
+		},
+	}
+	newMsg := func(requestID uint32, list wire.TLVList) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Chat,
+				SubGroup:  wire.ChatChannelMsgToClient,
+				RequestID: requestID,
+			},
+			Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+				Cookie:  1234,
+				Channel: wire.ICBMChannelMIME,
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: list,
+				},
+			},
+		}
+	}
+	defaultOrder := wire.TLVList{
+		wire.NewTLVBE(wire.ChatTLVSenderInformation, sender.TLVUserInfo()),
+		wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+		wire.NewTLVBE(wire.ChatTLVMessageInfo, msgInfo),
+	}
+	icqOrder := wire.TLVList{
+		wire.NewTLVBE(wire.ChatTLVMessageInfo, msgInfo),
+		wire.NewTLVBE(wire.ChatTLVSenderInformation, sender.TLVUserInfo()),
+	}
+
+	chatMessageRelayer := newMockChatMessageRelayer(t)
+	chatMessageRelayer.EXPECT().
+		AllSessions("the-chat-cookie").
+		Return([]*state.Session{sender, icqUser, aimUser})
+	chatMessageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, "the-chat-cookie", icqUser.IdentScreenName(), newMsg(0, icqOrder))
+	chatMessageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, "the-chat-cookie", aimUser.IdentScreenName(), newMsg(0, defaultOrder))
+
+	// chat sessions don't carry the client ID, so it comes from BOS sessions
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(icqUser.IdentScreenName()).
+		Return(newTestSession("icq_user", sessClientID("ICQ 2000b")))
+	sessionRetriever.EXPECT().
+		RetrieveSession(aimUser.IdentScreenName()).
+		Return(newTestSession("aim_user", sessClientID("AOL Instant Messenger (TM), version 2.1.1236/WIN32")))
+	sessionRetriever.EXPECT().
+		RetrieveSession(sender.IdentScreenName()).
+		Return(nil)
+
+	svc := NewChatService(cfg, slog.Default(), chatMessageRelayer, sessionRetriever, nil)
+	outputSNAC, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1234}, inBody)
+	assert.NoError(t, err)
+
+	// the sender's BOS session is gone, so the reflected message has the
+	// default order
+	want := newMsg(1234, defaultOrder)
+	assert.Equal(t, &want, outputSNAC)
+}
+
+func TestChatService_ChannelMsgToHost_Transcript(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newMsgBody := func(text string) wire.SNAC_0x0E_0x05_ChatChannelMsgToHost {
+		return wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			Channel: wire.ICBMChannelMIME,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVMessageInfoText, text),
+						},
+					}),
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the application config
+		cfg config.Config
+		// cookie is the sender's chat room cookie
+		cookie string
+		// addErr is the error returned when recording the entry
+		addErr error
+		// wantEntry is the expected transcript entry, if any
+		wantEntry *state.ChatTranscriptEntry
+		// wantDeleteBefore is the expected expiry cutoff, if any
+		wantDeleteBefore *time.Time
+	}{
+		{
+			name: "message in transcribed room is recorded and expired entries are deleted",
+			cfg: config.Config{
+				ChatTranscriptRooms:      []string{"Help Desk", " lobby "},
+				ChatTranscriptMaxAgeDays: 30,
+			},
+			cookie: "4-2-Lobby",
+			wantEntry: &state.ChatTranscriptEntry{
+				Room:    "Lobby",
+				Sender:  state.NewIdentScreenName("me"),
+				Message: "<HTML>hello</HTML>",
+				Sent:    now,
+			},
+			wantDeleteBefore: func() *time.Time {
+				t := now.Add(-30 * 24 * time.Hour)
+				return &t
+			}(),
+		},
+		{
+			name: "message in transcribed room is kept forever",
+			cfg: config.Config{
+				ChatTranscriptRooms: []string{"Lobby"},
+			},
+			cookie: "4-0-Lobby",
+			wantEntry: &state.ChatTranscriptEntry{
+				Room:    "Lobby",
+				Sender:  state.NewIdentScreenName("me"),
+				Message: "<HTML>hello</HTML>",
+				Sent:    now,
+			},
+		},
+		{
+			name: "message in transcribed room fails to record, message is still relayed",
+			cfg: config.Config{
+				ChatTranscriptRooms:      []string{"Lobby"},
+				ChatTranscriptMaxAgeDays: 30,
+			},
+			cookie: "4-0-Lobby",
+			addErr: io.EOF,
+			wantEntry: &state.ChatTranscriptEntry{
+				Room:    "Lobby",
+				Sender:  state.NewIdentScreenName("me"),
+				Message: "<HTML>hello</HTML>",
+				Sent:    now,
+			},
+		},
+		{
+			name: "message in room that isn't transcribed is not recorded",
+			cfg: config.Config{
+				ChatTranscriptRooms: []string{"Lobby"},
+			},
+			cookie: "4-0-Private Room",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			me := newTestSession("me", sessOptChatRoomCookie(tc.cookie))
+
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			chatMessageRelayer.EXPECT().
+				RelayToAllExcept(mock.Anything, tc.cookie, me.IdentScreenName(), mock.Anything)
+
+			transcriptStore := newMockChatTranscriptStore(t)
+			if tc.wantEntry != nil {
+				transcriptStore.EXPECT().
+					AddChatTranscriptEntry(*tc.wantEntry).
+					Return(tc.addErr)
+			}
+			if tc.wantDeleteBefore != nil {
+				transcriptStore.EXPECT().
+					DeleteChatTranscriptEntries(*tc.wantDeleteBefore).
+					Return(nil)
+			}
+
+			svc := NewChatService(tc.cfg, slog.Default(), chatMessageRelayer, nil, transcriptStore)
+			svc.timeNow = func() time.Time { return now }
+
+			_, err := svc.ChannelMsgToHost(context.Background(), me, wire.SNACFrame{}, newMsgBody("<HTML>hello</HTML>"))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestChatService_ChannelMsgToHost_TranscriptPurgeInterval(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	me := newTestSession("me", sessOptChatRoomCookie("4-0-Lobby"))
+
+	inBody := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Channel: wire.ICBMChannelMIME,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<HTML>hello</HTML>"),
+					},
+				}),
+			},
+		},
+	}
+
+	chatMessageRelayer := newMockChatMessageRelayer(t)
+	chatMessageRelayer.EXPECT().
+		RelayToAllExcept(mock.Anything, me.ChatRoomCookie(), me.IdentScreenName(), mock.Anything)
+
+	maxAge := 30 * 24 * time.Hour
+	transcriptStore := newMockChatTranscriptStore(t)
+	transcriptStore.EXPECT().
+		AddChatTranscriptEntry(mock.Anything).
+		Return(nil).
+		Times(3)
+	// expired entries are deleted for the first message and again once the
+	// interval has passed, but not for the message in between
+	transcriptStore.EXPECT().
+		DeleteChatTranscriptEntries(start.Add(-maxAge)).
+		Return(nil).
+		Once()
+	transcriptStore.EXPECT().
+		DeleteChatTranscriptEntries(start.Add(transcriptPurgeInterval).Add(-maxAge)).
+		Return(nil).
+		Once()
+
+	cfg := config.Config{
+		ChatTranscriptRooms:      []string{"Lobby"},
+		ChatTranscriptMaxAgeDays: 30,
+	}
+	svc := NewChatService(cfg, slog.Default(), chatMessageRelayer, nil, transcriptStore)
+
+	for _, sent := range []time.Time{
+		start,
+		start.Add(transcriptPurgeInterval / 2),
+		start.Add(transcriptPurgeInterval),
+	} {
+		svc.timeNow = func() time.Time { return sent }
+		_, err := svc.ChannelMsgToHost(context.Background(), me, wire.SNACFrame{}, inBody)
+		assert.NoError(t, err)
+	}
+}
+
 func TestParseDiceCommand(t *testing.T) {
 	tests := []struct {
 		input         []byte
@@ -731,6 +731,26 @@ func TestLocateService_SetInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "clear away message after sign on flow",
+			userSession: newTestSession("user_screen_name", sessOptSignonComplete, sessOptCannedAwayMessage),
+			inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, ""),
+					},
+				},
+			},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
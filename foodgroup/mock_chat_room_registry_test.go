@@ -133,6 +133,64 @@ func (_c *mockChatRoomRegistry_ChatRoomByName_Call) RunAndReturn(run func(uint16
 	return _c
 }
 
+// ChatRoomsByCreator provides a mock function with given fields: creator
+func (_m *mockChatRoomRegistry) ChatRoomsByCreator(creator state.IdentScreenName) ([]state.ChatRoom, error) {
+	ret := _m.Called(creator)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChatRoomsByCreator")
+	}
+
+	var r0 []state.ChatRoom
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) ([]state.ChatRoom, error)); ok {
+		return rf(creator)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) []state.ChatRoom); ok {
+		r0 = rf(creator)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]state.ChatRoom)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(creator)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockChatRoomRegistry_ChatRoomsByCreator_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ChatRoomsByCreator'
+type mockChatRoomRegistry_ChatRoomsByCreator_Call struct {
+	*mock.Call
+}
+
+// ChatRoomsByCreator is a helper method to define mock.On call
+//   - creator state.IdentScreenName
+func (_e *mockChatRoomRegistry_Expecter) ChatRoomsByCreator(creator interface{}) *mockChatRoomRegistry_ChatRoomsByCreator_Call {
+	return &mockChatRoomRegistry_ChatRoomsByCreator_Call{Call: _e.mock.On("ChatRoomsByCreator", creator)}
+}
+
+func (_c *mockChatRoomRegistry_ChatRoomsByCreator_Call) Run(run func(creator state.IdentScreenName)) *mockChatRoomRegistry_ChatRoomsByCreator_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockChatRoomRegistry_ChatRoomsByCreator_Call) Return(_a0 []state.ChatRoom, _a1 error) *mockChatRoomRegistry_ChatRoomsByCreator_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockChatRoomRegistry_ChatRoomsByCreator_Call) RunAndReturn(run func(state.IdentScreenName) ([]state.ChatRoom, error)) *mockChatRoomRegistry_ChatRoomsByCreator_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CreateChatRoom provides a mock function with given fields: chatRoom
 func (_m *mockChatRoomRegistry) CreateChatRoom(chatRoom *state.ChatRoom) error {
 	ret := _m.Called(chatRoom)
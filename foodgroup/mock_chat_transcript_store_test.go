@@ -0,0 +1,129 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package foodgroup
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// mockChatTranscriptStore is an autogenerated mock type for the ChatTranscriptStore type
+type mockChatTranscriptStore struct {
+	mock.Mock
+}
+
+type mockChatTranscriptStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockChatTranscriptStore) EXPECT() *mockChatTranscriptStore_Expecter {
+	return &mockChatTranscriptStore_Expecter{mock: &_m.Mock}
+}
+
+// AddChatTranscriptEntry provides a mock function with given fields: entry
+func (_m *mockChatTranscriptStore) AddChatTranscriptEntry(entry state.ChatTranscriptEntry) error {
+	ret := _m.Called(entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddChatTranscriptEntry")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.ChatTranscriptEntry) error); ok {
+		r0 = rf(entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockChatTranscriptStore_AddChatTranscriptEntry_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddChatTranscriptEntry'
+type mockChatTranscriptStore_AddChatTranscriptEntry_Call struct {
+	*mock.Call
+}
+
+// AddChatTranscriptEntry is a helper method to define mock.On call
+//   - entry state.ChatTranscriptEntry
+func (_e *mockChatTranscriptStore_Expecter) AddChatTranscriptEntry(entry interface{}) *mockChatTranscriptStore_AddChatTranscriptEntry_Call {
+	return &mockChatTranscriptStore_AddChatTranscriptEntry_Call{Call: _e.mock.On("AddChatTranscriptEntry", entry)}
+}
+
+func (_c *mockChatTranscriptStore_AddChatTranscriptEntry_Call) Run(run func(entry state.ChatTranscriptEntry)) *mockChatTranscriptStore_AddChatTranscriptEntry_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.ChatTranscriptEntry))
+	})
+	return _c
+}
+
+func (_c *mockChatTranscriptStore_AddChatTranscriptEntry_Call) Return(_a0 error) *mockChatTranscriptStore_AddChatTranscriptEntry_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockChatTranscriptStore_AddChatTranscriptEntry_Call) RunAndReturn(run func(state.ChatTranscriptEntry) error) *mockChatTranscriptStore_AddChatTranscriptEntry_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteChatTranscriptEntries provides a mock function with given fields: before
+func (_m *mockChatTranscriptStore) DeleteChatTranscriptEntries(before time.Time) error {
+	ret := _m.Called(before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteChatTranscriptEntries")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Time) error); ok {
+		r0 = rf(before)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockChatTranscriptStore_DeleteChatTranscriptEntries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteChatTranscriptEntries'
+type mockChatTranscriptStore_DeleteChatTranscriptEntries_Call struct {
+	*mock.Call
+}
+
+// DeleteChatTranscriptEntries is a helper method to define mock.On call
+//   - before time.Time
+func (_e *mockChatTranscriptStore_Expecter) DeleteChatTranscriptEntries(before interface{}) *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call {
+	return &mockChatTranscriptStore_DeleteChatTranscriptEntries_Call{Call: _e.mock.On("DeleteChatTranscriptEntries", before)}
+}
+
+func (_c *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call) Run(run func(before time.Time)) *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Time))
+	})
+	return _c
+}
+
+func (_c *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call) Return(_a0 error) *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call) RunAndReturn(run func(time.Time) error) *mockChatTranscriptStore_DeleteChatTranscriptEntries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockChatTranscriptStore creates a new instance of mockChatTranscriptStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockChatTranscriptStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockChatTranscriptStore {
+	mock := &mockChatTranscriptStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -20,6 +20,52 @@ func (_m *mockOfflineMessageManager) EXPECT() *mockOfflineMessageManager_Expecte
 	return &mockOfflineMessageManager_Expecter{mock: &_m.Mock}
 }
 
+// DeleteMessage provides a mock function with given fields: id
+func (_m *mockOfflineMessageManager) DeleteMessage(id int64) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMessage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockOfflineMessageManager_DeleteMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMessage'
+type mockOfflineMessageManager_DeleteMessage_Call struct {
+	*mock.Call
+}
+
+// DeleteMessage is a helper method to define mock.On call
+//   - id int64
+func (_e *mockOfflineMessageManager_Expecter) DeleteMessage(id interface{}) *mockOfflineMessageManager_DeleteMessage_Call {
+	return &mockOfflineMessageManager_DeleteMessage_Call{Call: _e.mock.On("DeleteMessage", id)}
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) Run(run func(id int64)) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) Return(_a0 error) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) RunAndReturn(run func(int64) error) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteMessages provides a mock function with given fields: recip
 func (_m *mockOfflineMessageManager) DeleteMessages(recip state.IdentScreenName) error {
 	ret := _m.Called(recip)
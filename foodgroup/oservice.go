@@ -608,11 +608,13 @@ func NewOServiceServiceForBOS(
 	chatRoomManager ChatRoomRegistry,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	offlineMessageManager OfflineMessageManager,
 ) *OServiceServiceForBOS {
 	return &OServiceServiceForBOS{
-		chatRoomManager: chatRoomManager,
-		cookieIssuer:    cookieIssuer,
-		messageRelayer:  messageRelayer,
+		chatRoomManager:       chatRoomManager,
+		cookieIssuer:          cookieIssuer,
+		messageRelayer:        messageRelayer,
+		offlineMessageManager: offlineMessageManager,
 		OServiceService: OServiceService{
 			buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
 			cfg:              cfg,
@@ -637,14 +639,16 @@ func NewOServiceServiceForBOS(
 // running on the BOS server.
 type OServiceServiceForBOS struct {
 	OServiceService
-	chatRoomManager ChatRoomRegistry
-	cookieIssuer    CookieBaker
-	messageRelayer  MessageRelayer
+	chatRoomManager       ChatRoomRegistry
+	cookieIssuer          CookieBaker
+	messageRelayer        MessageRelayer
+	offlineMessageManager OfflineMessageManager
 }
 
 // chatLoginCookie represents credentials used to authenticate a user chat
 // session.
 type chatLoginCookie struct {
+	Audience   uint8
 	ChatCookie string                  `oscar:"len_prefix=uint8"`
 	ScreenName state.DisplayScreenName `oscar:"len_prefix=uint8"`
 }
@@ -663,6 +667,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 	switch inBody.FoodGroup {
 	case wire.Admin:
 		cookie, err := fnIssueCookie(bosCookie{
+			Audience:   cookieAudienceBOS,
 			ScreenName: sess.DisplayScreenName(),
 		})
 		if err != nil {
@@ -688,6 +693,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 		}, nil
 	case wire.Alert:
 		cookie, err := fnIssueCookie(bosCookie{
+			Audience:   cookieAudienceBOS,
 			ScreenName: sess.DisplayScreenName(),
 		})
 		if err != nil {
@@ -713,6 +719,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 		}, nil
 	case wire.BART:
 		cookie, err := fnIssueCookie(bosCookie{
+			Audience:   cookieAudienceBOS,
 			ScreenName: sess.DisplayScreenName(),
 		})
 		if err != nil {
@@ -738,6 +745,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 		}, nil
 	case wire.ChatNav:
 		cookie, err := fnIssueCookie(bosCookie{
+			Audience:   cookieAudienceBOS,
 			ScreenName: sess.DisplayScreenName(),
 		})
 		if err != nil {
@@ -778,6 +786,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 		}
 
 		cookie, err := fnIssueCookie(chatLoginCookie{
+			Audience:   cookieAudienceChat,
 			ChatCookie: room.Cookie(),
 			ScreenName: sess.DisplayScreenName(),
 		})
@@ -805,6 +814,7 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 		}, nil
 	case wire.ODir:
 		cookie, err := fnIssueCookie(bosCookie{
+			Audience:   cookieAudienceBOS,
 			ScreenName: sess.DisplayScreenName(),
 		})
 		if err != nil {
@@ -845,7 +855,12 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 
 // ClientOnline runs when the current user is ready to join.
 // It announces current user's arrival to users who have the current user on
-// their buddy list.
+// their buddy list and delivers the messages queued while the current user
+// was offline. ICQ users are skipped, because ICQ clients retrieve their
+// queued messages with an offline message request. Delivery is also skipped
+// if the service has no offline message manager, which is how the TOC
+// server, which delivers queued messages itself, creates it. Failure to
+// deliver queued messages is logged rather than failing sign-on.
 func (s OServiceServiceForBOS) ClientOnline(ctx context.Context, _ wire.SNAC_0x01_0x02_OServiceClientOnline, sess *state.Session) error {
 	sess.SetSignonComplete()
 
@@ -853,6 +868,70 @@ func (s OServiceServiceForBOS) ClientOnline(ctx context.Context, _ wire.SNAC_0x0
 		return fmt.Errorf("unable to send buddy arrival notification: %w", err)
 	}
 
+	if s.offlineMessageManager != nil && sess.UIN() == 0 {
+		if err := s.deliverOfflineMessages(ctx, sess); err != nil {
+			s.logger.ErrorContext(ctx, "unable to deliver offline messages", "err", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// deliverOfflineMessages relays the instant messages queued for sess,
+// oldest first, as channel 1 ICBMs that carry the time each message was
+// sent. Each message is removed from the queue once it has been relayed.
+// Delivery stops at the first message that can't be relayed, such as when
+// the session's queue fills up, so that it and the messages after it are
+// delivered at next sign-on. Queued messages for other channels can only be
+// delivered to ICQ clients and are left in the queue.
+func (s OServiceServiceForBOS) deliverOfflineMessages(ctx context.Context, sess *state.Session) error {
+	msgs, err := s.offlineMessageManager.RetrieveMessages(sess.IdentScreenName())
+	if err != nil {
+		return fmt.Errorf("retrieving messages: %w", err)
+	}
+
+	for _, msg := range msgs {
+		if msg.Message.ChannelID != wire.ICBMChannelIM {
+			continue
+		}
+
+		sender := msg.Sender.String()
+		if msg.Sender == state.SystemNoticeSender.IdentScreenName() {
+			sender = state.SystemNoticeSender.String()
+		}
+
+		clientIM := wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:    msg.Message.Cookie,
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: sender,
+			},
+		}
+		for _, tlv := range msg.Message.TLVRestBlock.TLVList {
+			if tlv.Tag == wire.ICBMTLVStore || tlv.Tag == wire.ICBMTLVRequestHostAck {
+				continue
+			}
+			clientIM.Append(tlv)
+		}
+		clientIM.Append(wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(msg.Sent.Unix())))
+
+		status := sess.RelayMessage(wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.ICBM,
+				SubGroup:  wire.ICBMChannelMsgToClient,
+			},
+			Body: clientIM,
+		})
+		if status != state.SessSendOK {
+			s.logger.InfoContext(ctx, "unable to relay offline message, leaving messages queued", "status", status)
+			return nil
+		}
+
+		if err := s.offlineMessageManager.DeleteMessage(msg.ID); err != nil {
+			return fmt.Errorf("deleting message: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -876,6 +955,7 @@ func NewOServiceServiceForChat(
 				wire.Chat,
 			},
 		},
+		chatExchanges:      state.NewChatExchanges(cfg),
 		chatRoomManager:    chatRoomManager,
 		chatMessageRelayer: chatMessageRelayer,
 	}
@@ -885,6 +965,7 @@ func NewOServiceServiceForChat(
 // running on the Chat server.
 type OServiceServiceForChat struct {
 	OServiceService
+	chatExchanges      state.ChatExchanges
 	chatRoomManager    ChatRoomRegistry
 	chatMessageRelayer ChatMessageRelayer
 }
@@ -904,7 +985,7 @@ func (s OServiceServiceForChat) ClientOnline(ctx context.Context, _ wire.SNAC_0x
 	// requires this exact sequence, otherwise the chat session prematurely
 	// closes seconds after users join a chat room.
 	setOnlineChatUsers(ctx, sess, s.chatMessageRelayer)
-	sendChatRoomInfoUpdate(ctx, sess, s.chatMessageRelayer, room)
+	sendChatRoomInfoUpdate(ctx, sess, s.chatMessageRelayer, room, s.chatExchanges.Settings(room.Exchange()))
 	alertUserJoined(ctx, sess, s.chatMessageRelayer)
 
 	return nil
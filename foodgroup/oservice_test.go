@@ -2,6 +2,7 @@ package foodgroup
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"testing"
 	"time"
@@ -96,6 +97,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 					cookieIssueParams: cookieIssueParams{
 						{
 							dataIn: []byte{
+								0x01, // BOS audience
 								0x02, 'm', 'e',
 								0x0, // no client ID
 							},
@@ -143,6 +145,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 					cookieIssueParams: cookieIssueParams{
 						{
 							dataIn: []byte{
+								0x01, // BOS audience
 								0x02, 'm', 'e',
 								0x0, // no client ID
 							},
@@ -190,6 +193,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 					cookieIssueParams: cookieIssueParams{
 						{
 							dataIn: []byte{
+								0x01, // BOS audience
 								0x02, 'm', 'e',
 								0x0, // no client ID
 							},
@@ -255,6 +259,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 						cookieIssueParams: cookieIssueParams{
 							{
 								dataIn: []byte{
+									0x02, // chat audience
 									0x11, '4', '-', '0', '-', 't', 'h', 'e', '-', 'c', 'h', 'a', 't', '-', 'r', 'o', 'o', 'm',
 									0x02, 'm', 'e',
 								},
@@ -323,7 +328,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 			//
 			// send input SNAC
 			//
-			svc := NewOServiceServiceForBOS(tc.cfg, nil, slog.Default(), cookieIssuer, chatRoomManager, nil, nil)
+			svc := NewOServiceServiceForBOS(tc.cfg, nil, slog.Default(), cookieIssuer, chatRoomManager, nil, nil, nil)
 
 			outputSNAC, err := svc.ServiceRequest(nil, tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x01_0x04_OServiceServiceRequest))
@@ -399,6 +404,44 @@ func TestSetUserInfoFields(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "set ICQ user status to do not disturb",
+			userSession: newTestSession("100003", sessOptUIN(100003)),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceUserInfoStatus, uint32(0x0013)),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceUserInfoUpdate,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+					TLVUserInfo: newTestSession("100003", sessOptUIN(100003), func(session *state.Session) {
+						session.SetUserStatusBitmask(wire.OServiceUserStatusAway |
+							wire.OServiceUserStatusBusy | wire.OServiceUserStatusDND)
+					}).TLVUserInfo(),
+				},
+			},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+			},
+		},
 		{
 			name:        "set user status to invisible",
 			userSession: newTestSession("me"),
@@ -1553,7 +1596,7 @@ func TestOServiceService_RateParamsQuery(t *testing.T) {
 
 func TestOServiceServiceForBOS_OServiceHostOnline(t *testing.T) {
 	cookieIssuer := newMockCookieBaker(t)
-	svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), cookieIssuer, nil, nil, nil)
+	svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), cookieIssuer, nil, nil, nil, nil)
 
 	want := wire.SNACMessage{
 		Frame: wire.SNACFrame{
@@ -1712,6 +1755,49 @@ func TestOServiceService_IdleNotification(t *testing.T) {
 }
 
 func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	fnOfflineIM := func(sender state.IdentScreenName, cookie uint64) state.OfflineMessage {
+		return state.OfflineMessage{
+			ID:        int64(cookie),
+			Sender:    sender,
+			Recipient: state.NewIdentScreenName("me"),
+			Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     cookie,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: "me",
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3}),
+						wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+					},
+				},
+			},
+			Sent: sent,
+		}
+	}
+	fnClientIM := func(sender string, cookie uint64) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.ICBM,
+				SubGroup:  wire.ICBMChannelMsgToClient,
+			},
+			Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+				Cookie:    cookie,
+				ChannelID: wire.ICBMChannelIM,
+				TLVUserInfo: wire.TLVUserInfo{
+					ScreenName: sender,
+				},
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3}),
+						wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(sent.Unix())),
+					},
+				},
+			},
+		}
+	}
+
 	tests := []struct {
 		// name is the name of the test
 		name string
@@ -1720,6 +1806,9 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 		// bodyIn is the SNAC body sent from the arriving user's client to the
 		// server
 		bodyIn wire.SNAC_0x01_0x02_OServiceClientOnline
+		// queuedMsgs is the number of messages already waiting in the
+		// session's queue
+		queuedMsgs int
 		// wantErr is the expected error from the handler
 		wantErr error
 		// mockParams is the list of params sent to mocks that satisfy this
@@ -1727,6 +1816,8 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 		mockParams mockParams
 		// wantSess is the expected session state after the method is called
 		wantSess *state.Session
+		// wantRelayed are the messages relayed to the arriving user
+		wantRelayed []wire.SNACMessage
 	}{
 		{
 			name:   "notify that user is online",
@@ -1742,8 +1833,180 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 						},
 					},
 				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+		},
+		{
+			name:   "deliver offline messages oldest first, leaving messages for other channels queued",
+			sess:   newTestSession("me", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+							messagesOut: []state.OfflineMessage{
+								fnOfflineIM(state.SystemNoticeSender.IdentScreenName(), 1),
+								fnOfflineIM(state.NewIdentScreenName("them"), 2),
+								{
+									ID:        3,
+									Sender:    state.NewIdentScreenName("them"),
+									Recipient: state.NewIdentScreenName("me"),
+									Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+										ChannelID: wire.ICBMChannelICQ,
+									},
+									Sent: sent,
+								},
+							},
+						},
+					},
+					deleteMessageParams: deleteMessageParams{
+						{
+							idIn: 1,
+						},
+						{
+							idIn: 2,
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+			wantRelayed: []wire.SNACMessage{
+				fnClientIM(state.SystemNoticeSender.String(), 1),
+				fnClientIM("them", 2),
+			},
+		},
+		{
+			name:       "relay fails partway, leave undelivered messages queued",
+			sess:       newTestSession("me", sessOptCannedSignonTime),
+			bodyIn:     wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			queuedMsgs: 999,
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+							messagesOut: []state.OfflineMessage{
+								fnOfflineIM(state.NewIdentScreenName("them"), 1),
+								fnOfflineIM(state.NewIdentScreenName("them"), 2),
+							},
+						},
+					},
+					deleteMessageParams: deleteMessageParams{
+						{
+							idIn: 1,
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+			wantRelayed: []wire.SNACMessage{
+				fnClientIM("them", 1),
+			},
+		},
+		{
+			name:   "delete delivered offline message, receive error, log and continue sign-on",
+			sess:   newTestSession("me", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+							messagesOut: []state.OfflineMessage{
+								fnOfflineIM(state.NewIdentScreenName("them"), 1),
+								fnOfflineIM(state.NewIdentScreenName("them"), 2),
+							},
+						},
+					},
+					deleteMessageParams: deleteMessageParams{
+						{
+							idIn: 1,
+							err:  io.EOF,
+						},
+					},
+				},
 			},
 			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+			wantRelayed: []wire.SNACMessage{
+				fnClientIM("them", 1),
+			},
+		},
+		{
+			name:   "retrieve offline messages, receive error, log and continue sign-on",
+			sess:   newTestSession("me", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+							err:     io.EOF,
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+		},
+		{
+			name:   "notify that ICQ user is online, leaving offline messages for ICQ offline message request",
+			sess:   newTestSession("100003", sessOptCannedSignonTime, sessOptUIN(100003)),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("100003"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("100003", sessOptCannedSignonTime, sessOptSignonComplete),
 		},
 	}
 	for _, tt := range tests {
@@ -1754,16 +2017,58 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, params.doSendDepartures).
 					Return(params.err)
 			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tt.mockParams.retrieveMessagesParams {
+				offlineMessageManager.EXPECT().
+					RetrieveMessages(params.recipIn).
+					Return(params.messagesOut, params.err)
+			}
+			for _, params := range tt.mockParams.deleteMessageParams {
+				offlineMessageManager.EXPECT().
+					DeleteMessage(params.idIn).
+					Return(params.err)
+			}
 
-			svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), nil, nil, nil, nil)
+			for i := 0; i < tt.queuedMsgs; i++ {
+				tt.sess.RelayMessage(wire.SNACMessage{})
+			}
+
+			svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), nil, nil, nil, nil, offlineMessageManager)
 			svc.buddyBroadcaster = buddyUpdateBroadcaster
 			haveErr := svc.ClientOnline(nil, tt.bodyIn, tt.sess)
-			assert.ErrorIs(t, tt.wantErr, haveErr)
+			assert.ErrorIs(t, haveErr, tt.wantErr)
 			assert.Equal(t, tt.wantSess.SignonComplete(), tt.sess.SignonComplete())
+
+			var haveRelayed []wire.SNACMessage
+			for i := 0; i < tt.queuedMsgs+len(tt.wantRelayed); i++ {
+				msg := <-tt.sess.ReceiveMessage()
+				if i >= tt.queuedMsgs {
+					haveRelayed = append(haveRelayed, msg)
+				}
+			}
+			assert.Equal(t, tt.wantRelayed, haveRelayed)
+			assert.Empty(t, tt.sess.ReceiveMessage())
 		})
 	}
 }
 
+func TestOServiceServiceForBOS_ClientOnline_NoOfflineMessageManager(t *testing.T) {
+	sess := newTestSession("me", sessOptCannedSignonTime)
+
+	buddyUpdateBroadcaster := newMockbuddyBroadcaster(t)
+	buddyUpdateBroadcaster.EXPECT().
+		BroadcastVisibility(mock.Anything, matchSession(state.NewIdentScreenName("me")), []state.IdentScreenName(nil), false).
+		Return(nil)
+
+	// the TOC server delivers queued messages itself, so the service doesn't
+	// look for any
+	svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), nil, nil, nil, nil, nil)
+	svc.buddyBroadcaster = buddyUpdateBroadcaster
+	assert.NoError(t, svc.ClientOnline(nil, wire.SNAC_0x01_0x02_OServiceClientOnline{}, sess))
+	assert.True(t, sess.SignonComplete())
+	assert.Empty(t, sess.ReceiveMessage())
+}
+
 func TestOServiceServiceForChat_ClientOnline(t *testing.T) {
 	chatRoom := state.NewChatRoom("the-chat-room", state.NewIdentScreenName("creator"), state.PrivateExchange)
 	chatter1 := newTestSession("chatter-1", sessOptChatRoomCookie(chatRoom.Cookie()))
@@ -1829,7 +2134,7 @@ func TestOServiceServiceForChat_ClientOnline(t *testing.T) {
 									InstanceNumber: chatRoom.InstanceNumber(),
 									DetailLevel:    chatRoom.DetailLevel(),
 									TLVBlock: wire.TLVBlock{
-										TLVList: chatRoom.TLVList(),
+										TLVList: chatRoom.TLVList(state.NewChatExchanges(config.Config{}).Settings(chatRoom.Exchange())),
 									},
 								},
 							},
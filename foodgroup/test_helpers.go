@@ -69,11 +69,19 @@ type relationshipParams []struct {
 // offlineMessageManagerParams is a helper struct that contains mock parameters for
 // OfflineMessageManager methods
 type offlineMessageManagerParams struct {
+	deleteMessageParams
 	deleteMessagesParams
 	retrieveMessagesParams
 	saveMessageParams
 }
 
+// deleteMessageParams is the list of parameters passed at the mock
+// OfflineMessageManager.DeleteMessage call site
+type deleteMessageParams []struct {
+	idIn int64
+	err  error
+}
+
 // deleteMessagesParams is the list of parameters passed at the mock
 // OfflineMessageManager.DeleteMessages call site
 type deleteMessagesParams []struct {
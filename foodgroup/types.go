@@ -88,6 +88,26 @@ type ChatMessageRelayer interface {
 	RelayToScreenName(ctx context.Context, chatCookie string, recipient state.IdentScreenName, msg wire.SNACMessage)
 }
 
+// ChatOccupantCounter defines the interface for counting the participants of
+// a chat room.
+type ChatOccupantCounter interface {
+	// OccupantCount returns the number of participants in the chat room
+	// identified by chatCookie.
+	OccupantCount(chatCookie string) int
+}
+
+// ChatTranscriptStore defines the interface for recording chat room
+// transcripts.
+type ChatTranscriptStore interface {
+	// AddChatTranscriptEntry records a chat message in a chat room
+	// transcript.
+	AddChatTranscriptEntry(entry state.ChatTranscriptEntry) error
+
+	// DeleteChatTranscriptEntries deletes the chat transcript entries of all
+	// rooms that were sent before a given time.
+	DeleteChatTranscriptEntries(before time.Time) error
+}
+
 // ChatRoomRegistry defines the interface for storing and retrieving chat
 // rooms in a persistent store. The persistent store has two purposes:
 // - Remember user-created chat rooms (exchange 4) so that clients can
@@ -103,6 +123,9 @@ type ChatRoomRegistry interface {
 	// ErrChatRoomNotFound if the room does not exist for exchange and name.
 	ChatRoomByName(exchange uint16, name string) (state.ChatRoom, error)
 
+	// ChatRoomsByCreator returns the chat rooms created by creator.
+	ChatRoomsByCreator(creator state.IdentScreenName) ([]state.ChatRoom, error)
+
 	// CreateChatRoom creates a new chat room.
 	CreateChatRoom(chatRoom *state.ChatRoom) error
 }
@@ -174,6 +197,7 @@ type MessageRelayer interface {
 }
 
 type OfflineMessageManager interface {
+	DeleteMessage(id int64) error
 	DeleteMessages(recip state.IdentScreenName) error
 	RetrieveMessages(recip state.IdentScreenName) ([]state.OfflineMessage, error)
 	SaveMessage(offlineMessage state.OfflineMessage) error
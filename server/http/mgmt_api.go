@@ -31,6 +31,7 @@ func NewManagementAPI(
 	chatSessionRetriever ChatSessionRetriever,
 	directoryManager DirectoryManager,
 	messageRelayer MessageRelayer,
+	offlineMessageSaver OfflineMessageSaver,
 	bartRetriever BARTRetriever,
 	feedbagRetriever FeedBagRetriever,
 	accountManager AccountManager,
@@ -73,6 +74,11 @@ func NewManagementAPI(
 		getUserBuddyIconHandler(w, r, userManager, feedbagRetriever, bartRetriever, logger)
 	})
 
+	// Handlers for '/user/{screenname}/notice' route
+	mux.HandleFunc("POST /user/{screenname}/notice", func(w http.ResponseWriter, r *http.Request) {
+		postUserNoticeHandler(w, r, userManager, offlineMessageSaver, time.Now, logger)
+	})
+
 	// Handlers for '/session' route
 	mux.HandleFunc("GET /session", func(w http.ResponseWriter, r *http.Request) {
 		getSessionHandler(w, r, sessionRetriever, time.Since)
@@ -595,6 +601,62 @@ func postInstantMessageHandler(w http.ResponseWriter, r *http.Request, messageRe
 	_, _ = fmt.Fprintln(w, "Message sent successfully.")
 }
 
+// postUserNoticeHandler handles the POST /user/{screenname}/notice endpoint. It
+// queues a system notice that's delivered the next time the user signs on.
+func postUserNoticeHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, offlineMessageSaver OfflineMessageSaver, timeNow func() time.Time, logger *slog.Logger) {
+	input := systemNotice{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "malformed input", http.StatusBadRequest)
+		return
+	}
+	if input.Text == "" {
+		http.Error(w, "notice text is required", http.StatusBadRequest)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	user, err := userManager.User(screenName)
+	if err != nil {
+		logger.Error("error in POST /user/{screenname}/notice", "err", err.Error())
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	tlv, err := wire.ICBMFragmentList(input.Text)
+	if err != nil {
+		logger.Error("error in POST /user/{screenname}/notice", "err", err.Error())
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	msg := state.OfflineMessage{
+		Sender:    state.SystemNoticeSender.IdentScreenName(),
+		Recipient: screenName,
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			ChannelID:  wire.ICBMChannelIM,
+			ScreenName: screenName.String(),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, tlv),
+				},
+			},
+		},
+		Sent: timeNow().UTC(),
+	}
+	if err := offlineMessageSaver.SaveMessage(msg); err != nil {
+		logger.Error("error in POST /user/{screenname}/notice", "err", err.Error())
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, _ = fmt.Fprintln(w, "Notice queued successfully.")
+}
+
 // getUserBuddyIconHandler handles the GET /user/{screenname}/icon endpoint.
 func getUserBuddyIconHandler(w http.ResponseWriter, r *http.Request, u UserManager, f FeedBagRetriever, b BARTRetriever, logger *slog.Logger) {
 	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
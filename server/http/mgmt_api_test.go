@@ -1623,6 +1623,113 @@ func TestInstantMessageHandler_POST(t *testing.T) {
 	}
 }
 
+func TestUserNoticeHandler_POST(t *testing.T) {
+	sentTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		body              string
+		user              *state.User
+		wantNotice        string
+		saveErr           error
+		want              string
+		statusCode        int
+	}{
+		{
+			name:              "queue a notice",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"text":"server maintenance tonight"}`,
+			user: &state.User{
+				IdentScreenName: state.NewIdentScreenName("userA"),
+			},
+			wantNotice: "server maintenance tonight",
+			want:       `Notice queued successfully.`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:              "queue a notice for unknown user",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"text":"server maintenance tonight"}`,
+			want:              `user not found`,
+			statusCode:        http.StatusNotFound,
+		},
+		{
+			name:              "queue a notice with empty text",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"text":""}`,
+			want:              `notice text is required`,
+			statusCode:        http.StatusBadRequest,
+		},
+		{
+			name:              "queue a notice with runtime error",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"text":"server maintenance tonight"}`,
+			user: &state.User{
+				IdentScreenName: state.NewIdentScreenName("userA"),
+			},
+			wantNotice: "server maintenance tonight",
+			saveErr:    io.EOF,
+			want:       `internal server error`,
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:              "with malformed body",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"text":"server maintenance tonight"`,
+			want:              `malformed input`,
+			statusCode:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/user/"+tc.requestScreenName.String()+"/notice", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			if tc.statusCode != http.StatusBadRequest {
+				userManager.EXPECT().
+					User(tc.requestScreenName).
+					Return(tc.user, nil)
+			}
+
+			offlineMessageSaver := newMockOfflineMessageSaver(t)
+			if tc.wantNotice != "" {
+				validateMsg := func(msg state.OfflineMessage) bool {
+					assert.Equal(t, state.SystemNoticeSender.IdentScreenName(), msg.Sender)
+					assert.Equal(t, tc.requestScreenName, msg.Recipient)
+					assert.Equal(t, wire.ICBMChannelIM, msg.Message.ChannelID)
+					assert.Equal(t, sentTime, msg.Sent)
+
+					b, ok := msg.Message.Bytes(wire.ICBMTLVAOLIMData)
+					assert.True(t, ok)
+
+					txt, err := wire.UnmarshalICBMMessageText(b)
+					assert.NoError(t, err)
+					assert.Equal(t, tc.wantNotice, txt)
+					return true
+				}
+				offlineMessageSaver.EXPECT().
+					SaveMessage(mock.MatchedBy(validateMsg)).
+					Return(tc.saveErr)
+			}
+
+			timeNow := func() time.Time { return sentTime }
+			postUserNoticeHandler(responseRecorder, request, userManager, offlineMessageSaver, timeNow, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
 func TestVersionHandler_GET(t *testing.T) {
 	tt := []struct {
 		name       string
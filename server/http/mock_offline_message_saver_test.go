@@ -0,0 +1,81 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package http
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockOfflineMessageSaver is an autogenerated mock type for the OfflineMessageSaver type
+type mockOfflineMessageSaver struct {
+	mock.Mock
+}
+
+type mockOfflineMessageSaver_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockOfflineMessageSaver) EXPECT() *mockOfflineMessageSaver_Expecter {
+	return &mockOfflineMessageSaver_Expecter{mock: &_m.Mock}
+}
+
+// SaveMessage provides a mock function with given fields: offlineMessage
+func (_m *mockOfflineMessageSaver) SaveMessage(offlineMessage state.OfflineMessage) error {
+	ret := _m.Called(offlineMessage)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveMessage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.OfflineMessage) error); ok {
+		r0 = rf(offlineMessage)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockOfflineMessageSaver_SaveMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveMessage'
+type mockOfflineMessageSaver_SaveMessage_Call struct {
+	*mock.Call
+}
+
+// SaveMessage is a helper method to define mock.On call
+//   - offlineMessage state.OfflineMessage
+func (_e *mockOfflineMessageSaver_Expecter) SaveMessage(offlineMessage interface{}) *mockOfflineMessageSaver_SaveMessage_Call {
+	return &mockOfflineMessageSaver_SaveMessage_Call{Call: _e.mock.On("SaveMessage", offlineMessage)}
+}
+
+func (_c *mockOfflineMessageSaver_SaveMessage_Call) Run(run func(offlineMessage state.OfflineMessage)) *mockOfflineMessageSaver_SaveMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.OfflineMessage))
+	})
+	return _c
+}
+
+func (_c *mockOfflineMessageSaver_SaveMessage_Call) Return(_a0 error) *mockOfflineMessageSaver_SaveMessage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockOfflineMessageSaver_SaveMessage_Call) RunAndReturn(run func(state.OfflineMessage) error) *mockOfflineMessageSaver_SaveMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockOfflineMessageSaver creates a new instance of mockOfflineMessageSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockOfflineMessageSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockOfflineMessageSaver {
+	mock := &mockOfflineMessageSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
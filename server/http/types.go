@@ -38,6 +38,10 @@ type MessageRelayer interface {
 	RelayToScreenName(ctx context.Context, screenName state.IdentScreenName, msg wire.SNACMessage)
 }
 
+type OfflineMessageSaver interface {
+	SaveMessage(offlineMessage state.OfflineMessage) error
+}
+
 type AccountManager interface {
 	EmailAddressByName(screenName state.IdentScreenName) (*mail.Address, error)
 	RegStatusByName(screenName state.IdentScreenName) (uint16, error)
@@ -132,6 +136,10 @@ type instantMessage struct {
 	Text string `json:"text"`
 }
 
+type systemNotice struct {
+	Text string `json:"text"`
+}
+
 type directoryKeyword struct {
 	ID   uint8  `json:"id"`
 	Name string `json:"name"`
@@ -105,11 +105,27 @@ func (rt AuthServer) handleNewConnection(rwc io.ReadWriteCloser) error {
 	return rt.processBUCPAuth(flapc, err)
 }
 
+// processFLAPAuth authenticates a FLAP-auth client and sends the login result
+// in a sign-off frame. The caller is expected to close the connection once the
+// sign-off frame is sent, regardless of the login outcome.
 func (rt AuthServer) processFLAPAuth(signonFrame wire.FLAPSignonFrame, flapc *wire.FlapClient) error {
 	tlv, err := rt.AuthService.FLAPLogin(signonFrame, state.NewStubUser)
 	if err != nil {
 		return err
 	}
+
+	if _, hasCookie := tlv.Bytes(wire.LoginTLVTagsAuthorizationCookie); !hasCookie {
+		// the login failed. the client relies on the error subcode to display
+		// a login error, so make sure it's always present.
+		code, hasCode := tlv.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+		if !hasCode {
+			code = wire.LoginErrInvalidUsernameOrPassword
+			tlv.Append(wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, code))
+		}
+		screenName, _ := tlv.String(wire.LoginTLVTagsScreenName)
+		rt.Logger.Debug("failed FLAP login", "screen_name", screenName, "code", code)
+	}
+
 	return flapc.SendSignoffFrame(tlv)
 }
 
@@ -95,3 +95,93 @@ func TestBUCPAuthService_handleNewConnection(t *testing.T) {
 	}
 	assert.NoError(t, rt.handleNewConnection(rwc))
 }
+
+func TestFLAPAuthService_handleNewConnection_LoginFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		loginTLV wire.TLVRestBlock
+		wantTLV  wire.TLVRestBlock
+	}{
+		{
+			name: "login failure carries error subcode from auth service",
+			loginTLV: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, "screenName"),
+					wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrInvalidPassword),
+				},
+			},
+			wantTLV: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, "screenName"),
+					wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrInvalidPassword),
+				},
+			},
+		},
+		{
+			name: "login failure without error subcode gets default error subcode",
+			loginTLV: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, "screenName"),
+				},
+			},
+			wantTLV: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, "screenName"),
+					wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrInvalidUsernameOrPassword),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientReader, serverWriter := io.Pipe()
+			serverReader, clientWriter := io.Pipe()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				flapc := wire.NewFlapClient(0, clientReader, clientWriter)
+
+				// < receive FLAPSignonFrame
+				_, err := flapc.ReceiveSignonFrame()
+				assert.NoError(t, err)
+
+				// > send FLAPSignonFrame with FLAP auth credentials
+				assert.NoError(t, flapc.SendSignonFrame([]wire.TLV{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, "screenName"),
+					wire.NewTLVBE(wire.LoginTLVTagsRoastedPassword, []byte("roasted")),
+				}))
+
+				// < receive sign-off frame with login failure
+				flap, err := flapc.ReceiveFLAP()
+				assert.NoError(t, err)
+				assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+
+				have := wire.TLVRestBlock{}
+				assert.NoError(t, wire.UnmarshalBE(&have, bytes.NewBuffer(flap.Payload)))
+				assert.Equal(t, tt.wantTLV, have)
+
+				// the server closes the connection after the sign-off frame
+				_, err = flapc.ReceiveFLAP()
+				assert.ErrorIs(t, err, io.EOF)
+			}()
+
+			authService := newMockAuthService(t)
+			authService.EXPECT().
+				FLAPLogin(mock.Anything, mock.Anything).
+				Return(tt.loginTLV, nil)
+
+			rt := AuthServer{
+				AuthService: authService,
+				Logger:      slog.Default(),
+			}
+			rwc := pipeRWC{
+				PipeReader: serverReader,
+				PipeWriter: serverWriter,
+			}
+			assert.NoError(t, rt.handleNewConnection(rwc))
+			<-done
+		})
+	}
+}
@@ -161,7 +161,8 @@ func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteClo
 
 	if rt.BuddyListRegistry != nil { // nil check is a hack until server refactor
 		// todo should this check be below defer()?
-		if err := rt.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName()); err != nil {
+		err := rt.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName())
+		if err != nil && !errors.Is(err, state.ErrBuddyListRegistered) {
 			return fmt.Errorf("unable to init buddy list: %w", err)
 		}
 	}
@@ -203,5 +204,5 @@ func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteClo
 		sess.SetRemoteAddr(&ip)
 	}
 
-	return dispatchIncomingMessages(ctx, sess, flapc, rwc, rt.Logger, rt.Handler)
+	return dispatchIncomingMessages(ctx, sess, flapc, rwc, rt.Logger, rt.Handler, newKeepAlive(rt.Config))
 }
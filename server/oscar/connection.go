@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/server/oscar/middleware"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
@@ -25,6 +27,32 @@ func sendInvalidSNACErr(frameIn wire.SNACFrame, rw ResponseWriter) error {
 	return rw.SendSNAC(frameOut, bodyOut)
 }
 
+// keepAlive configures the FLAP keepalive heartbeat of a client connection.
+type keepAlive struct {
+	interval time.Duration // How long the connection can be quiet before the server sends a keepalive. Zero disables.
+	timeout  time.Duration // How long the client can go without sending a frame before it's disconnected. Zero disables.
+}
+
+// newKeepAlive creates a keepAlive from the server configuration.
+func newKeepAlive(cfg config.Config) keepAlive {
+	return keepAlive{
+		interval: time.Duration(cfg.OSCARKeepAliveSecs) * time.Second,
+		timeout:  time.Duration(cfg.OSCARIdleTimeoutSecs) * time.Second,
+	}
+}
+
+// newTimer returns a timer that fires after d along with its channel. If d
+// is zero, it returns a stopped timer and a nil channel, which never fires.
+func newTimer(d time.Duration) (*time.Timer, <-chan time.Time) {
+	if d == 0 {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t, nil
+	}
+	t := time.NewTimer(d)
+	return t, t.C
+}
+
 // dispatchIncomingMessages receives incoming messages and sends them to the
 // appropriate message handler. Messages from the client are sent to the
 // router. Messages relayed from the user session are forwarded to the client.
@@ -32,8 +60,13 @@ func sendInvalidSNACErr(frameIn wire.SNACFrame, rw ResponseWriter) error {
 // types of messages. The function terminates upon receiving a connection error
 // or when the session closes.
 //
+// When the connection has been quiet for the keepalive interval, the server
+// sends the client a keepalive frame. Clients that send no frames, including
+// their own keepalives, within the keepalive timeout are considered dead and
+// disconnected.
+//
 // todo: this method has too many params and should be folded into a new type
-func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *wire.FlapClient, r io.Reader, logger *slog.Logger, router Handler) error {
+func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *wire.FlapClient, r io.Reader, logger *slog.Logger, router Handler, ka keepAlive) error {
 	defer func() {
 		logger.InfoContext(ctx, "user disconnected")
 	}()
@@ -57,12 +90,25 @@ func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *w
 		}
 	}()
 
+	// fires when the connection has been quiet for the keepalive interval
+	sendTimer, sendCh := newTimer(ka.interval)
+	defer sendTimer.Stop()
+	// fires when the client hasn't sent anything for the keepalive timeout
+	recvTimer, recvCh := newTimer(ka.timeout)
+	defer recvTimer.Stop()
+
 	for {
 		select {
 		case flap, ok := <-msgCh:
 			if !ok {
 				return nil
 			}
+			if ka.timeout > 0 {
+				recvTimer.Reset(ka.timeout)
+			}
+			if ka.interval > 0 {
+				sendTimer.Reset(ka.interval)
+			}
 			switch flap.FrameType {
 			case wire.FLAPFrameData:
 				flapBuf := bytes.NewBuffer(flap.Payload)
@@ -102,6 +148,21 @@ func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *w
 				return err
 			}
 			middleware.LogRequest(ctx, logger, m.Frame, m.Body)
+			if ka.interval > 0 {
+				sendTimer.Reset(ka.interval)
+			}
+		case <-sendCh:
+			if err := flapc.SendKeepAliveFrame(); err != nil {
+				return fmt.Errorf("unable to send keepalive. %w", err)
+			}
+			logger.DebugContext(ctx, "sent keepalive heartbeat")
+			sendTimer.Reset(ka.interval)
+		case <-recvCh:
+			logger.InfoContext(ctx, "client connection timed out")
+			if err := flapc.Disconnect(); err != nil {
+				return fmt.Errorf("unable to gracefully disconnect user. %w", err)
+			}
+			return nil
 		case <-sess.Closed():
 			block := wire.TLVRestBlock{}
 			// error code indicating user signed in a different location
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -25,7 +26,7 @@ func TestHandleChatConnection_MessageRelay(t *testing.T) {
 	clientReader, serverWriter := io.Pipe()
 	go func() {
 		flapc := wire.NewFlapClient(0, nil, serverWriter)
-		err := dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil)
+		err := dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, keepAlive{})
 		assert.NoError(t, err)
 	}()
 
@@ -144,7 +145,7 @@ func TestHandleChatConnection_ClientRequest(t *testing.T) {
 	clientReader, serverWriter := io.Pipe()
 	go func() {
 		flapc := wire.NewFlapClient(0, nil, serverWriter)
-		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router))
+		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router, keepAlive{}))
 	}()
 
 	// send client messages
@@ -165,3 +166,70 @@ func TestHandleChatConnection_ClientRequest(t *testing.T) {
 	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
 	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
 }
+
+func TestHandleChatConnection_KeepAlive(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	// start the server connection handler in the background
+	serverReader, _ := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		ka := keepAlive{interval: 10 * time.Millisecond}
+		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, ka))
+	}()
+
+	// with no other traffic, the server sends keepalives at each interval
+	for i := 0; i < 2; i++ {
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+		assert.Equal(t, wire.FLAPFrameKeepAlive, flap.FrameType)
+		assert.Equal(t, uint16(i), flap.Sequence)
+		assert.Empty(t, flap.Payload)
+	}
+
+	// stop the session, which terminates the connection handler goroutine
+	sess.Close()
+	for {
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+		if flap.FrameType == wire.FLAPFrameSignoff {
+			break
+		}
+	}
+}
+
+func TestHandleChatConnection_KeepAliveTimeout(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	// start the server connection handler in the background
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		ka := keepAlive{timeout: 50 * time.Millisecond}
+		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, ka))
+	}()
+
+	// client keepalives hold the connection open past the timeout
+	flapc := wire.NewFlapClient(0, nil, clientWriter)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, flapc.SendKeepAliveFrame())
+		time.Sleep(20 * time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("connection closed while the client was sending keepalives")
+	default:
+	}
+
+	// once the client goes quiet, the server disconnects it
+	flap := wire.FLAPFrameDisconnect{}
+	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+	<-done
+}
@@ -3,18 +3,26 @@ package toc
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/csv"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -22,29 +30,65 @@ import (
 var (
 	// capChat is the UUID that represents an OSCAR client's ability to chat
 	capChat = uuid.MustParse("748F2420-6287-11D1-8222-444553540000")
+	// errMalformedCmd indicates that a TOC command could not be parsed
+	errMalformedCmd = errors.New("malformed command")
 )
 
 // NewChatRegistry creates a new ChatRegistry instances.
 func NewChatRegistry() *ChatRegistry {
 	chatRegistry := &ChatRegistry{
+		invites:  make(map[int]pendingInvite),
 		lookup:   make(map[int]wire.ICBMRoomInfo),
+		rvous:    make(map[RvousProposal]struct{}),
 		sessions: make(map[int]*state.Session),
+		timeNow:  time.Now,
 		m:        sync.RWMutex{},
 	}
 	return chatRegistry
 }
 
+// ChatInvite describes a chat invitation that the user has received but not
+// yet accepted or declined.
+type ChatInvite struct {
+	Inviter string // Screen name of the user who sent the invitation.
+	Cookie  uint64 // Rendezvous cookie that identifies the invitation.
+}
+
+// chatInviteTTL is how long a chat invitation stays pending. Invitations that
+// are neither accepted nor declined in time are discarded, so that ignored
+// invitations don't accumulate for the rest of the TOC session.
+const chatInviteTTL = 10 * time.Minute
+
+// pendingInvite is a chat invitation along with the time it expires.
+type pendingInvite struct {
+	ChatInvite
+	expires time.Time
+}
+
+// RvousProposal identifies a non-chat rendezvous proposal, such as a file
+// transfer, that the user has received but not yet cancelled.
+type RvousProposal struct {
+	Proposer   state.IdentScreenName // Screen name of the user who sent the proposal.
+	Cookie     uint64                // Rendezvous cookie that identifies the proposal.
+	Capability uuid.UUID             // The proposed service.
+}
+
 // ChatRegistry manages the chat rooms that a user is connected to during a TOC
 // session. It maintains mappings between chat room identifiers, metadata, and
 // active chat sessions.
 //
 // This struct provides thread-safe operations for adding, retrieving, and managing
-// chat room metadata and associated sessions.
+// chat room metadata and associated sessions. It also tracks the other
+// rendezvous proposals the user has received, so that accepts and cancels
+// can be matched to them.
 type ChatRegistry struct {
-	lookup   map[int]wire.ICBMRoomInfo // Maps chat room IDs to their metadata.
-	sessions map[int]*state.Session    // Tracks active chat sessions by chat room ID.
-	nextID   int                       // Incremental identifier for newly added chat rooms.
-	m        sync.RWMutex              // Synchronization primitive for concurrent access.
+	invites  map[int]pendingInvite      // Tracks pending chat invitations by chat room ID.
+	lookup   map[int]wire.ICBMRoomInfo  // Maps chat room IDs to their metadata.
+	rvous    map[RvousProposal]struct{} // Tracks pending non-chat rendezvous proposals.
+	sessions map[int]*state.Session     // Tracks active chat sessions by chat room ID.
+	nextID   int                        // Incremental identifier for newly added chat rooms.
+	timeNow  func() time.Time           // Returns the current time.
+	m        sync.RWMutex               // Synchronization primitive for concurrent access.
 }
 
 // Add registers metadata for a newly joined chat room and returns a unique
@@ -52,6 +96,11 @@ type ChatRegistry struct {
 func (c *ChatRegistry) Add(room wire.ICBMRoomInfo) int {
 	c.m.Lock()
 	defer c.m.Unlock()
+	return c.add(room)
+}
+
+// add is Add for callers that hold the lock.
+func (c *ChatRegistry) add(room wire.ICBMRoomInfo) int {
 	for chatID, r := range c.lookup {
 		if r == room {
 			return chatID
@@ -63,6 +112,106 @@ func (c *ChatRegistry) Add(room wire.ICBMRoomInfo) int {
 	return id
 }
 
+// AddInvite registers metadata for a chat room the user was invited to and
+// records the pending invitation, which expires after chatInviteTTL. It
+// returns the chat room's unique identifier. Expired invitations are
+// discarded first, along with the rooms they were for unless the user joined
+// them.
+func (c *ChatRegistry) AddInvite(room wire.ICBMRoomInfo, invite ChatInvite) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := c.timeNow()
+	for chatID, pending := range c.invites {
+		if now.Before(pending.expires) {
+			continue
+		}
+		delete(c.invites, chatID)
+		if _, joined := c.sessions[chatID]; !joined {
+			delete(c.lookup, chatID)
+		}
+	}
+	chatID := c.add(room)
+	c.invites[chatID] = pendingInvite{
+		ChatInvite: invite,
+		expires:    now.Add(chatInviteTTL),
+	}
+	return chatID
+}
+
+// LookupInvite retrieves the pending invitation for chatID. It returns the
+// invitation and a boolean indicating whether an unexpired invitation was
+// found.
+func (c *ChatRegistry) LookupInvite(chatID int) (ChatInvite, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	pending, found := c.invites[chatID]
+	if !found || !c.timeNow().Before(pending.expires) {
+		return ChatInvite{}, false
+	}
+	return pending.ChatInvite, true
+}
+
+// AddRvous records a pending rendezvous proposal.
+func (c *ChatRegistry) AddRvous(proposal RvousProposal) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.rvous[proposal] = struct{}{}
+}
+
+// LookupRvous reports whether proposal is pending.
+func (c *ChatRegistry) LookupRvous(proposal RvousProposal) bool {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	_, found := c.rvous[proposal]
+	return found
+}
+
+// RemoveRvous discards a pending rendezvous proposal. It reports whether the
+// proposal was pending.
+func (c *ChatRegistry) RemoveRvous(proposal RvousProposal) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	_, found := c.rvous[proposal]
+	delete(c.rvous, proposal)
+	return found
+}
+
+// Remove removes the metadata, session, and pending invitation associated
+// with chatID.
+func (c *ChatRegistry) Remove(chatID int) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.invites, chatID)
+	delete(c.lookup, chatID)
+	delete(c.sessions, chatID)
+}
+
+// RemoveAllSess removes every chat room that has a registered session along
+// with its metadata and pending invitation. It returns the removed sessions
+// by chat ID. Rooms are removed in a single step, so a room joined
+// concurrently is either returned or left in place, never partially removed.
+func (c *ChatRegistry) RemoveAllSess() map[int]*state.Session {
+	c.m.Lock()
+	defer c.m.Unlock()
+	sessions := c.sessions
+	for chatID := range sessions {
+		delete(c.invites, chatID)
+		delete(c.lookup, chatID)
+	}
+	c.sessions = make(map[int]*state.Session)
+	return sessions
+}
+
+// CloseAll signs out and closes every registered chat session and removes
+// the rooms from the registry. It's called when the TOC connection ends so
+// that the user doesn't linger as an occupant in rooms they never left.
+func (c *ChatRegistry) CloseAll(ctx context.Context, authService AuthService) {
+	for _, sess := range c.RemoveAllSess() {
+		authService.SignoutChat(ctx, sess)
+		sess.Close()
+	}
+}
+
 // LookupRoom retrieves metadata for the chat room registered with chatID.
 // It returns the room metadata and a boolean indicating whether the chat ID
 // was found.
@@ -99,21 +248,43 @@ func (c *ChatRegistry) RetrieveSess(chatID int) *state.Session {
 //   - Receives incoming messages from the OSCAR server and translates them into
 //     TOC responses for the client.
 type OSCARProxy struct {
-	AdminService        AdminService
-	AuthService         AuthService
-	BuddyListRegistry   BuddyListRegistry
-	BuddyService        BuddyService
-	ChatNavService      ChatNavService
-	ChatService         ChatService
-	CookieBaker         CookieBaker
-	DirSearchService    DirSearchService
-	ICBMService         ICBMService
-	LocateService       LocateService
-	Logger              *slog.Logger
-	OServiceServiceBOS  OServiceService
-	OServiceServiceChat OServiceService
-	PermitDenyService   PermitDenyService
-	TOCConfigStore      TOCConfigStore
+	AbuseReportStore      AbuseReportStore
+	AdminService          AdminService
+	AuthService           AuthService
+	BARTService           BARTService
+	BuddyCommentStore     BuddyCommentStore
+	BuddyCounter          BuddyCounter
+	BuddyLister           BuddyLister
+	BuddyListRegistry     BuddyListRegistry
+	BuddyService          BuddyService
+	ChatMessageRelayer    ChatMessageRelayer
+	ChatNavService        ChatNavService
+	ChatOccupantCounter   ChatOccupantCounter
+	ChatRoomManager       ChatRoomManager
+	ChatService           ChatService
+	Config                config.Config
+	ConfigLocker          *ConfigLocker
+	CookieBaker           CookieBaker
+	DirSearchService      DirSearchService
+	ErrorLogLimiter       *ErrorLogLimiter
+	FeedbagService        FeedbagService
+	ICBMService           ICBMService
+	IMHistory             *IMHistory
+	IMThrottle            *IMThrottle
+	LocateService         LocateService
+	Logger                *slog.Logger
+	LookupThrottle        *IMThrottle
+	OfflineMessageManager OfflineMessageManager
+	OServiceServiceBOS    OServiceService
+	OServiceServiceChat   OServiceService
+	PermitDenyService     PermitDenyService
+	RecentIMSenders       *RecentIMSenders
+	ReportThrottle        *IMThrottle
+	TOCConfigStore        TOCConfigStore
+	UnconfirmedIMThrottle *IMThrottle
+	UserManager           UserManager
+	ViolationCounter      *ViolationCounter
+	WarnThrottle          *WarnThrottle
 }
 
 // RecvClientCmd processes a client TOC command and returns a server reply.
@@ -124,7 +295,13 @@ type OSCARProxy struct {
 // * toCh is the channel that transports messages to client
 // * doAsync performs async tasks, is auto-cleaned up by caller
 //
-// It returns true if the server can continue processing commands.
+// It returns true if the server can continue processing commands. Otherwise,
+// reply is the message that explains to the client why it's being
+// disconnected. Unsupported commands are answered with ERROR:911 (error
+// validating input) followed by the command name, and the connection stays
+// open. Clients that send more
+// consecutive malformed or unsupported commands than TOCMaxProtocolViolations
+// allows are disconnected.
 func (s OSCARProxy) RecvClientCmd(
 	ctx context.Context,
 	sessBOS *state.Session,
@@ -132,6 +309,39 @@ func (s OSCARProxy) RecvClientCmd(
 	payload []byte,
 	toCh chan<- []byte,
 	doAsync func(f func() error),
+) (reply string, ok bool) {
+	me := sessBOS.IdentScreenName()
+	before := s.ViolationCounter.Count(me)
+
+	reply, ok = s.dispatchClientCmd(ctx, sessBOS, chatRegistry, payload, toCh, doAsync)
+	if !ok {
+		return reply, ok
+	}
+
+	violations := s.ViolationCounter.Count(me)
+	switch {
+	case violations == before:
+		if violations > 0 {
+			// the client recovered from its errors
+			s.ViolationCounter.Clear(me)
+		}
+	case s.Config.TOCMaxProtocolViolations > 0 && violations >= s.Config.TOCMaxProtocolViolations:
+		s.Logger.InfoContext(ctx, "disconnecting client after repeated protocol violations", "violations", violations)
+		return tocError(989, "disconnected (too many invalid commands)"), false
+	}
+
+	return reply, ok
+}
+
+// dispatchClientCmd routes a client TOC command to its handler. Its return
+// values are the same as RecvClientCmd's.
+func (s OSCARProxy) dispatchClientCmd(
+	ctx context.Context,
+	sessBOS *state.Session,
+	chatRegistry *ChatRegistry,
+	payload []byte,
+	toCh chan<- []byte,
+	doAsync func(f func() error),
 ) (reply string, ok bool) {
 	cmd := payload
 	if idx := bytes.IndexByte(payload, ' '); idx > -1 {
@@ -148,13 +358,29 @@ func (s OSCARProxy) RecvClientCmd(
 	case "toc_send_im":
 		return s.SendIM(ctx, sessBOS, payload), true
 	case "toc_init_done":
-		return s.InitDone(ctx, sessBOS, payload), true
+		msg := s.InitDone(ctx, sessBOS, payload)
+		if msg == cmdInitDoneRepeated {
+			return msg, false
+		}
+		if msg == "" {
+			s.deliverOfflineMessages(ctx, sessBOS, toCh)
+			s.autoJoinRooms(ctx, sessBOS, chatRegistry, toCh, doAsync)
+		}
+		return msg, true
 	case "toc_add_buddy":
 		return s.AddBuddy(ctx, sessBOS, payload), true
 	case "toc_get_status":
 		return s.GetStatus(ctx, sessBOS, payload), true
+	case "toc_get_presence":
+		return s.GetPresence(ctx, sessBOS, payload), true
 	case "toc_remove_buddy":
 		return s.RemoveBuddy(ctx, sessBOS, payload), true
+	case "toc_rvous_propose":
+		return s.RvousPropose(ctx, sessBOS, payload), true
+	case "toc_rvous_accept":
+		return s.RvousAccept(ctx, sessBOS, chatRegistry, payload), true
+	case "toc_rvous_cancel":
+		return s.RvousCancel(ctx, sessBOS, chatRegistry, payload), true
 	case "toc_add_permit":
 		return s.AddPermit(ctx, sessBOS, payload), true
 	case "toc_add_deny":
@@ -183,7 +409,11 @@ func (s OSCARProxy) RecvClientCmd(
 
 		if msg == cmdInternalSvcErr {
 			// todo idk if this is worth cancelling the connection over
-			return "", false
+			return tocError(989, "disconnected (unable to join chat room)"), false
+		}
+		if strings.HasPrefix(msg, "ERROR:") {
+			// the room could not be joined
+			return msg, true
 		}
 
 		doAsync(func() error {
@@ -193,28 +423,71 @@ func (s OSCARProxy) RecvClientCmd(
 		})
 
 		return msg, true
+	case "toc_chat_decline":
+		return s.ChatDecline(ctx, sessBOS, chatRegistry, payload), true
 	case "toc_chat_send":
-		return s.ChatSend(ctx, chatRegistry, payload), true
+		return s.ChatSend(ctx, sessBOS, chatRegistry, payload), true
 	case "toc_chat_leave":
 		return s.ChatLeave(ctx, chatRegistry, payload), true
+	case "toc_chat_leave_all":
+		for _, msg := range s.ChatLeaveAll(ctx, chatRegistry, payload) {
+			select {
+			case toCh <- []byte(msg):
+			case <-ctx.Done():
+				return "", true
+			}
+		}
+		return "", true
+	case "toc_chat_get_topic":
+		return s.ChatGetTopic(ctx, chatRegistry, payload), true
+	case "toc_chat_set_topic":
+		return s.ChatSetTopic(ctx, sessBOS, chatRegistry, payload), true
 	case "toc_set_info":
 		return s.SetInfo(ctx, sessBOS, payload), true
 	case "toc_set_dir":
 		return s.SetDir(ctx, sessBOS, payload), true
+	case "toc_set_dir_keywords":
+		return s.SetDirKeywords(ctx, sessBOS, payload), true
+	case "toc_set_icon":
+		return s.SetIcon(ctx, sessBOS, payload), true
 	case "toc_set_idle":
 		return s.SetIdle(ctx, sessBOS, payload), true
 	case "toc_set_config":
 		return s.SetConfig(ctx, sessBOS, payload), true
+	case "toc_set_config2":
+		return s.SetConfig2(ctx, sessBOS, payload), true
 	case "toc_chat_invite":
 		return s.ChatInvite(ctx, sessBOS, chatRegistry, payload), true
 	case "toc_dir_search":
 		return s.GetDirSearchURL(ctx, sessBOS, payload), true
 	case "toc_get_dir":
 		return s.GetDirURL(ctx, sessBOS, payload), true
+	case "toc_get_dir_keywords":
+		return s.GetDirKeywords(ctx, payload), true
+	case "toc_ping":
+		return s.Ping(ctx, payload), true
+	case "toc_report_user":
+		return s.ReportUser(ctx, sessBOS, payload), true
+	case "toc_set_status":
+		return s.SetStatus(ctx, sessBOS, payload), true
+	case "toc_get_buddy_comment":
+		return s.GetBuddyComment(ctx, sessBOS, payload), true
+	case "toc_set_buddy_comment":
+		return s.SetBuddyComment(ctx, sessBOS, payload), true
+	case "toc_get_buddies":
+		for _, msg := range s.GetBuddies(ctx, sessBOS, payload) {
+			select {
+			case toCh <- []byte(msg):
+			case <-ctx.Done():
+				return "", true
+			}
+		}
+		return "", true
 	}
 
-	s.Logger.ErrorContext(ctx, fmt.Sprintf("unsupported TOC command %s", cmd))
-	return "", true
+	s.ViolationCounter.Add(sessBOS.IdentScreenName())
+	s.ErrorLogLimiter.Log(ctx, s.Logger, sessBOS.IdentScreenName(), fmt.Sprintf("unsupported TOC command %s", cmd))
+	return tocError(911, string(cmd)), true
 }
 
 // AddBuddy handles the toc_add_buddy TOC command.
@@ -223,6 +496,12 @@ func (s OSCARProxy) RecvClientCmd(
 //
 //	Add buddies to your buddy list. This does not change your saved config.
 //
+// If the user's account is unconfirmed and the request would grow the buddy
+// list past the configured cap, no buddies are added and ERROR:911 is
+// returned. If the user's saved config allows only buddies to make contact,
+// added buddies are also added to the permit list. A command with no users is
+// a no-op.
+//
 // Command syntax: toc_add_buddy <Buddy User 1> [<Buddy User2> [<Buddy User 3> [...]]]
 func (s OSCARProxy) AddBuddy(ctx context.Context, me *state.Session, cmd []byte) string {
 	users, err := parseArgs(cmd, "toc_add_buddy")
@@ -230,17 +509,44 @@ func (s OSCARProxy) AddBuddy(ctx context.Context, me *state.Session, cmd []byte)
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if len(users) == 0 {
+		return ""
+	}
+
+	if limit := s.Config.TOCUnconfirmedMaxBuddies; limit > 0 && unconfirmed(me) {
+		count, err := s.BuddyCounter.BuddyCount(me.IdentScreenName())
+		if err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("BuddyCounter.BuddyCount: %w", err))
+		}
+		if count+len(users) > limit {
+			s.Logger.InfoContext(ctx, "unconfirmed user reached buddy list limit", "limit", limit)
+			return tocError(911)
+		}
+	}
+
 	snac := wire.SNAC_0x03_0x04_BuddyAddBuddies{}
 	for _, sn := range users {
 		snac.Buddies = append(snac.Buddies, struct {
 			ScreenName string `oscar:"len_prefix=uint8"`
-		}{ScreenName: sn})
+		}{ScreenName: state.NewIdentScreenName(sn).String()})
 	}
 
 	if err := s.BuddyService.AddBuddies(ctx, me, snac); err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("BuddyService.AddBuddies: %w", err))
 	}
 
+	buddiesOnly, err := s.buddiesOnly(me.IdentScreenName())
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("buddiesOnly: %w", err))
+	}
+	if buddiesOnly && len(snac.Buddies) > 0 {
+		// keep the permit list in sync with the buddy list
+		permits := wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{Users: snac.Buddies}
+		if err := s.PermitDenyService.AddPermListEntries(ctx, me, permits); err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("PermitDenyService.AddPermListEntries: %w", err))
+		}
+	}
+
 	return ""
 }
 
@@ -260,11 +566,14 @@ func (s OSCARProxy) AddPermit(ctx context.Context, me *state.Session, cmd []byte
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	// with no users, an empty permit list is still sent. from deny mode, this
+	// switches to permit-some with nobody permitted. in permit-some mode, the
+	// mode is already set, so the permit list remains unchanged.
 	snac := wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{}
 	for _, sn := range users {
 		snac.Users = append(snac.Users, struct {
 			ScreenName string `oscar:"len_prefix=uint8"`
-		}{ScreenName: sn})
+		}{ScreenName: state.NewIdentScreenName(sn).String()})
 	}
 
 	if err := s.PermitDenyService.AddPermListEntries(ctx, me, snac); err != nil {
@@ -289,11 +598,14 @@ func (s OSCARProxy) AddDeny(ctx context.Context, me *state.Session, cmd []byte)
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	// with no users, an empty deny list is still sent. from permit mode, this
+	// switches to deny-some with nobody denied. in deny-some mode, the mode is
+	// already set, so the deny list remains unchanged.
 	snac := wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{}
 	for _, sn := range users {
 		snac.Users = append(snac.Users, struct {
 			ScreenName string `oscar:"len_prefix=uint8"`
-		}{ScreenName: sn})
+		}{ScreenName: state.NewIdentScreenName(sn).String()})
 	}
 
 	if err := s.PermitDenyService.AddDenyListEntries(ctx, me, snac); err != nil {
@@ -309,6 +621,11 @@ func (s OSCARProxy) AddDeny(ctx context.Context, me *state.Session, cmd []byte)
 //	Change a user's password. An ADMIN_PASSWD_STATUS or ERROR message will be
 //	sent back to the client.
 //
+// The change goes through AdminService, which validates the existing password
+// against the stored hash the same way it does for OSCAR clients. ERROR:912
+// means the existing password is wrong. ERROR:911 means the new password has
+// an invalid length.
+//
 // Command syntax: toc_change_passwd <existing_passwd> <new_passwd>
 func (s OSCARProxy) ChangePassword(ctx context.Context, me *state.Session, cmd []byte) string {
 	var oldPass, newPass string
@@ -333,18 +650,18 @@ func (s OSCARProxy) ChangePassword(ctx context.Context, me *state.Session, cmd [
 
 	replyBody, ok := reply.Body.(wire.SNAC_0x07_0x05_AdminChangeReply)
 	if !ok {
-		return s.runtimeErr(ctx, fmt.Errorf("AdminService.InfoChangeRequest: unexpected response type %v", replyBody))
+		return s.runtimeErr(ctx, fmt.Errorf("AdminService.InfoChangeRequest: unexpected response type %T", reply.Body))
 	}
 
 	code, ok := replyBody.Uint16BE(wire.AdminTLVErrorCode)
 	if ok {
 		switch code {
 		case wire.AdminInfoErrorInvalidPasswordLength:
-			return "ERROR:911"
+			return tocError(911)
 		case wire.AdminInfoErrorValidatePassword:
-			return "ERROR:912"
+			return tocError(912)
 		default:
-			return "ERROR:913"
+			return tocError(913)
 		}
 	}
 
@@ -358,6 +675,9 @@ func (s OSCARProxy) ChangePassword(ctx context.Context, me *state.Session, cmd [
 //	Accept a CHAT_INVITE message from TOC. The server will send a CHAT_JOIN in
 //	response.
 //
+// If extended join responses are enabled, the CHAT_JOIN message includes the
+// room's exchange and instance number.
+//
 // Command syntax: toc_chat_accept <Chat Room ID>
 func (s OSCARProxy) ChatAccept(
 	ctx context.Context,
@@ -392,7 +712,7 @@ func (s OSCARProxy) ChatAccept(
 
 	reqRoomReplyBody, ok := reqRoomReply.Body.(wire.SNAC_0x0D_0x09_ChatNavNavInfo)
 	if !ok {
-		return 0, s.runtimeErr(ctx, fmt.Errorf("chatNavService.RequestRoomInfo: unexpected response type %v", reqRoomReplyBody))
+		return 0, s.runtimeErr(ctx, fmt.Errorf("chatNavService.RequestRoomInfo: unexpected response type %T", reqRoomReply.Body))
 	}
 	b, hasInfo := reqRoomReplyBody.Bytes(wire.ChatNavTLVRoomInfo)
 	if !hasInfo {
@@ -409,6 +729,11 @@ func (s OSCARProxy) ChatAccept(
 		return 0, s.runtimeErr(ctx, errors.New("roomInfo.Bytes: missing wire.ChatRoomTLVRoomName"))
 	}
 
+	if s.roomBlocked(string(roomName), me.IdentScreenName()) {
+		s.Logger.InfoContext(ctx, "user is blocked from joining chat room", "room", string(roomName))
+		return 0, tocError(950, string(roomName))
+	}
+
 	svcReqSNAC := wire.SNAC_0x01_0x04_OServiceServiceRequest{
 		FoodGroup: wire.Chat,
 		TLVRestBlock: wire.TLVRestBlock{
@@ -426,7 +751,7 @@ func (s OSCARProxy) ChatAccept(
 
 	svcReqReplyBody, ok := svcReqReply.Body.(wire.SNAC_0x01_0x05_OServiceServiceResponse)
 	if !ok {
-		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ServiceRequest: unexpected response type %v", svcReqReplyBody))
+		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ServiceRequest: unexpected response type %T", svcReqReply.Body))
 	}
 
 	loginCookie, hasCookie := svcReqReplyBody.Bytes(wire.OServiceTLVTagsLoginCookie)
@@ -442,10 +767,172 @@ func (s OSCARProxy) ChatAccept(
 	chatRegistry.RegisterSess(chatID, chatSess)
 
 	if err := s.OServiceServiceChat.ClientOnline(ctx, wire.SNAC_0x01_0x02_OServiceClientOnline{}, chatSess); err != nil {
+		s.abortChatJoin(ctx, chatRegistry, chatID, chatSess)
 		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceChat.ClientOnline: %w", err))
 	}
 
-	return chatID, fmt.Sprintf("CHAT_JOIN:%d:%s", chatID, roomName)
+	return chatID, s.chatJoinMsg(chatID, string(roomName), chatInfo)
+}
+
+// ChatDecline handles the toc_chat_decline TOC command.
+//
+// This command is not part of the TiK documentation. It declines a
+// CHAT_INVITE message, notifying the inviter that the invitation was
+// declined. The chat room ID is no longer valid once the invitation is
+// declined. Invitations that are neither accepted nor declined expire after
+// chatInviteTTL, after which they can no longer be declined.
+//
+// Command syntax: toc_chat_decline <Chat Room ID>
+func (s OSCARProxy) ChatDecline(ctx context.Context, me *state.Session, chatRegistry *ChatRegistry, cmd []byte) string {
+	var chatIDStr string
+
+	if _, err := parseArgs(cmd, "toc_chat_decline", &chatIDStr); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("strconv.Atoi: %w", err))
+	}
+
+	invite, found := chatRegistry.LookupInvite(chatID)
+	if !found {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.LookupInvite: no invite found for chat ID %d", chatID))
+	}
+	if chatRegistry.RetrieveSess(chatID) != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.RetrieveSess: invite for chat ID %d already accepted", chatID))
+	}
+
+	roomInfo, _ := chatRegistry.LookupRoom(chatID)
+
+	snac := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		ChannelID:  wire.ICBMChannelRendezvous,
+		ScreenName: invite.Inviter,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       wire.ICBMRdvMessageCancel,
+					Cookie:     invite.Cookie,
+					Capability: capChat,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, roomInfo),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	// the invite is gone regardless of whether the inviter receives the
+	// notification
+	chatRegistry.Remove(chatID)
+
+	if _, err := s.ICBMService.ChannelMsgToHost(ctx, me, wire.SNACFrame{}, snac); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: %w", err))
+	}
+
+	return ""
+}
+
+// ChatGetTopic handles the toc_chat_get_topic TOC command.
+//
+// This is a non-standard command that retrieves the topic of a chat room the
+// user has joined. The server responds with a CHAT_TOPIC message, which
+// contains an empty topic if none has been set.
+//
+// Command syntax: toc_chat_get_topic <Chat Room ID>
+func (s OSCARProxy) ChatGetTopic(ctx context.Context, chatRegistry *ChatRegistry, cmd []byte) string {
+	var chatIDStr string
+
+	if _, err := parseArgs(cmd, "toc_chat_get_topic", &chatIDStr); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("strconv.Atoi: %w", err))
+	}
+
+	roomInfo, found := chatRegistry.LookupRoom(chatID)
+	if !found {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.LookupRoom: chat ID `%d` not found", chatID))
+	}
+
+	room, err := s.ChatRoomManager.ChatRoomByCookie(roomInfo.Cookie)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ChatRoomManager.ChatRoomByCookie: %w", err))
+	}
+
+	return fmt.Sprintf("CHAT_TOPIC:%d:%s", chatID, room.Topic())
+}
+
+// ChatSetTopic handles the toc_chat_set_topic TOC command.
+//
+// This is a non-standard command that sets the topic of a chat room the user
+// has joined. Only the room creator may set the topic; anyone else receives
+// ERROR:950. The new topic is broadcast to all room occupants, including the
+// sender, as a CHAT_TOPIC message. Remember to quote and encode the topic.
+//
+// Command syntax: toc_chat_set_topic <Chat Room ID> <Topic>
+func (s OSCARProxy) ChatSetTopic(ctx context.Context, me *state.Session, chatRegistry *ChatRegistry, cmd []byte) string {
+	var chatIDStr, topic string
+
+	if _, err := parseArgs(cmd, "toc_chat_set_topic", &chatIDStr, &topic); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("strconv.Atoi: %w", err))
+	}
+
+	roomInfo, found := chatRegistry.LookupRoom(chatID)
+	if !found {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.LookupRoom: chat ID `%d` not found", chatID))
+	}
+
+	room, err := s.ChatRoomManager.ChatRoomByCookie(roomInfo.Cookie)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ChatRoomManager.ChatRoomByCookie: %w", err))
+	}
+
+	if room.Creator() != me.IdentScreenName() {
+		s.Logger.InfoContext(ctx, "only the room creator can set the topic", "room", room.Name())
+		return tocError(950, room.Name())
+	}
+
+	if err := s.ChatRoomManager.SetChatRoomTopic(roomInfo.Cookie, topic); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ChatRoomManager.SetChatRoomTopic: %w", err))
+	}
+
+	room.SetTopic(topic)
+
+	tlvs := room.TLVList(state.NewChatExchanges(s.Config).Settings(room.Exchange()))
+	if room.Topic() == "" {
+		// the room metadata omits empty topics, so include it explicitly to
+		// let occupants know the topic was cleared
+		tlvs = append(tlvs, wire.NewTLVBE(wire.ChatRoomTLVTopic, ""))
+	}
+
+	s.ChatMessageRelayer.RelayToAllExcept(ctx, roomInfo.Cookie, state.IdentScreenName{}, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Chat,
+			SubGroup:  wire.ChatRoomInfoUpdate,
+		},
+		Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+			Exchange:       room.Exchange(),
+			Cookie:         room.Cookie(),
+			InstanceNumber: room.InstanceNumber(),
+			DetailLevel:    room.DetailLevel(),
+			TLVBlock: wire.TLVBlock{
+				TLVList: tlvs,
+			},
+		},
+	})
+
+	return ""
 }
 
 // ChatInvite handles the toc_chat_invite TOC command.
@@ -477,7 +964,7 @@ func (s OSCARProxy) ChatInvite(ctx context.Context, me *state.Session, chatRegis
 	for _, guest := range users {
 		snac := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
 			ChannelID:  wire.ICBMChannelRendezvous,
-			ScreenName: guest,
+			ScreenName: state.NewIdentScreenName(guest).String(),
 			TLVRestBlock: wire.TLVRestBlock{
 				TLVList: wire.TLVList{
 					wire.NewTLVBE(0x05, wire.ICBMCh2Fragment{
@@ -487,7 +974,7 @@ func (s OSCARProxy) ChatInvite(ctx context.Context, me *state.Session, chatRegis
 							TLVList: wire.TLVList{
 								wire.NewTLVBE(10, uint16(1)),
 								wire.NewTLVBE(12, msg),
-								wire.NewTLVBE(13, "us-ascii"),
+								wire.NewTLVBE(13, wire.ChatMessageCharset(msg)),
 								wire.NewTLVBE(14, "en"),
 								wire.NewTLVBE(10001, roomInfo),
 							},
@@ -518,6 +1005,16 @@ func (s OSCARProxy) ChatInvite(ctx context.Context, me *state.Session, chatRegis
 //	if the room couldn't be joined or a CHAT_JOIN message. The Chat Room Name
 //	is case-insensitive and consecutive spaces are removed.
 //
+// Rooms that the chat services refuse to create or connect to, such as a new
+// room that would put the user over the created room limit, are reported with
+// the TOC error that corresponds to the OSCAR error, or ERROR:950 if there is
+// no equivalent.
+//
+// If room replication is enabled and the room is full, the user joins the
+// next instance of the room that has space. The instance number is appended
+// to the room name in the CHAT_JOIN message, e.g. CHAT_JOIN:1:Lobby (1),
+// unless extended join responses are enabled.
+//
 // Command syntax: toc_chat_join <Exchange> <Chat Room Name>
 func (s OSCARProxy) ChatJoin(
 	ctx context.Context,
@@ -531,14 +1028,33 @@ func (s OSCARProxy) ChatJoin(
 		return 0, s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
-	// create room or retrieve the room if it already exists
 	exchange, err := strconv.Atoi(exchangeStr)
 	if err != nil {
 		return 0, s.runtimeErr(ctx, fmt.Errorf("strconv.Atoi: %w", err))
 	}
 
+	return s.joinRoom(ctx, me, chatRegistry, uint16(exchange), roomName)
+}
+
+// joinRoom joins the chat room roomName on exchange, creating the room if it
+// doesn't exist, and registers the chat session in chatRegistry. It returns
+// the chat room ID and a CHAT_JOIN message, or an ERROR message if the room
+// couldn't be joined.
+func (s OSCARProxy) joinRoom(
+	ctx context.Context,
+	me *state.Session,
+	chatRegistry *ChatRegistry,
+	exchange uint16,
+	roomName string,
+) (int, string) {
+	if s.roomBlocked(roomName, me.IdentScreenName()) {
+		s.Logger.InfoContext(ctx, "user is blocked from joining chat room", "room", roomName)
+		return 0, tocError(950, roomName)
+	}
+
+	// create room or retrieve the room if it already exists
 	mkRoomReq := wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
-		Exchange: uint16(exchange),
+		Exchange: exchange,
 		Cookie:   "create",
 		TLVBlock: wire.TLVBlock{
 			TLVList: wire.TLVList{
@@ -551,9 +1067,17 @@ func (s OSCARProxy) ChatJoin(
 		return 0, s.runtimeErr(ctx, fmt.Errorf("ChatNavService.CreateRoom: %w", err))
 	}
 
+	if v, ok := mkRoomReply.Body.(wire.SNACError); ok {
+		s.Logger.InfoContext(ctx, "unable to create chat room", "room", roomName, "code", v.Code)
+		if msg, ok := snacErrToTOC(v.Code, roomName); ok {
+			return 0, msg
+		}
+		return 0, tocError(950, roomName)
+	}
+
 	mkRoomReplyBody, ok := mkRoomReply.Body.(wire.SNAC_0x0D_0x09_ChatNavNavInfo)
 	if !ok {
-		return 0, s.runtimeErr(ctx, fmt.Errorf("chatNavService.CreateRoom: unexpected response type %v", mkRoomReplyBody))
+		return 0, s.runtimeErr(ctx, fmt.Errorf("chatNavService.CreateRoom: unexpected response type %T", mkRoomReply.Body))
 	}
 	buf, ok := mkRoomReplyBody.Bytes(wire.ChatNavTLVRoomInfo)
 	if !ok {
@@ -565,6 +1089,25 @@ func (s OSCARProxy) ChatJoin(
 		return 0, s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
 	}
 
+	if s.Config.TOCChatRoomReplication {
+		room, err := s.ChatRoomManager.ChatRoomByCookie(inBody.Cookie)
+		if err != nil {
+			return 0, s.runtimeErr(ctx, fmt.Errorf("ChatRoomManager.ChatRoomByCookie: %w", err))
+		}
+		room, ok = s.availableInstance(room)
+		if !ok {
+			s.Logger.InfoContext(ctx, "all chat room instances are full", "room", roomName)
+			return 0, tocError(950, roomName)
+		}
+		inBody.Cookie = room.Cookie()
+		inBody.InstanceNumber = room.InstanceNumber()
+		if inBody.InstanceNumber > 0 && !s.Config.TOCChatJoinExtended {
+			// let the user know which instance they landed in. clients that
+			// get extended join responses see the instance number instead.
+			roomName = fmt.Sprintf("%s (%d)", roomName, inBody.InstanceNumber)
+		}
+	}
+
 	svcReqSNAC := wire.SNAC_0x01_0x04_OServiceServiceRequest{
 		FoodGroup: wire.Chat,
 		TLVRestBlock: wire.TLVRestBlock{
@@ -580,9 +1123,17 @@ func (s OSCARProxy) ChatJoin(
 		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ServiceRequest: %w", err))
 	}
 
+	if v, ok := svcReqReply.Body.(wire.SNACError); ok {
+		s.Logger.InfoContext(ctx, "unable to connect to chat service", "room", roomName, "code", v.Code)
+		if msg, ok := snacErrToTOC(v.Code, roomName); ok {
+			return 0, msg
+		}
+		return 0, tocError(950, roomName)
+	}
+
 	svcReqReplyBody, ok := svcReqReply.Body.(wire.SNAC_0x01_0x05_OServiceServiceResponse)
 	if !ok {
-		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ServiceRequest: unexpected response type %v", svcReqReplyBody))
+		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ServiceRequest: unexpected response type %T", svcReqReply.Body))
 	}
 
 	loginCookie, hasCookie := svcReqReplyBody.Bytes(wire.OServiceTLVTagsLoginCookie)
@@ -604,10 +1155,55 @@ func (s OSCARProxy) ChatJoin(
 	chatRegistry.RegisterSess(chatID, chatSess)
 
 	if err := s.OServiceServiceChat.ClientOnline(ctx, wire.SNAC_0x01_0x02_OServiceClientOnline{}, chatSess); err != nil {
+		s.abortChatJoin(ctx, chatRegistry, chatID, chatSess)
 		return 0, s.runtimeErr(ctx, fmt.Errorf("OServiceServiceChat.ClientOnline: %w", err))
 	}
 
-	return chatID, fmt.Sprintf("CHAT_JOIN:%d:%s", chatID, roomName)
+	return chatID, s.chatJoinMsg(chatID, roomName, roomInfo)
+}
+
+// abortChatJoin rolls back a chat join that failed after the chat session was
+// registered. It removes the room from the registry and signs out the chat
+// session so that neither outlives the failed join.
+func (s OSCARProxy) abortChatJoin(ctx context.Context, chatRegistry *ChatRegistry, chatID int, chatSess *state.Session) {
+	chatRegistry.Remove(chatID)
+	s.AuthService.SignoutChat(ctx, chatSess)
+	chatSess.Close()
+}
+
+// chatJoinMsg creates the CHAT_JOIN message for a chat room the user joined.
+// If extended join responses are enabled, the room's exchange and instance
+// number are appended so that clients can tell apart instances of rooms with
+// the same name. Because room names may contain colons, clients should parse
+// the extended fields from the end of the message.
+//
+// Command syntax: CHAT_JOIN:<Chat Room Id>:<Chat Room Name>[:<Exchange>:<Instance>]
+func (s OSCARProxy) chatJoinMsg(chatID int, roomName string, roomInfo wire.ICBMRoomInfo) string {
+	if s.Config.TOCChatJoinExtended {
+		return fmt.Sprintf("CHAT_JOIN:%d:%s:%d:%d", chatID, roomName, roomInfo.Exchange, roomInfo.Instance)
+	}
+	return fmt.Sprintf("CHAT_JOIN:%d:%s", chatID, roomName)
+}
+
+// maxChatRoomInstances is the number of instances of a replicated chat room
+// that are checked for space before a join is rejected.
+const maxChatRoomInstances = 16
+
+// availableInstance returns the first instance of room, starting from room's
+// own instance, that has space for another occupant. At most
+// maxChatRoomInstances instances are checked. It returns false if all of
+// them are full.
+func (s OSCARProxy) availableInstance(room state.ChatRoom) (state.ChatRoom, bool) {
+	maxOccupancy := int(state.NewChatExchanges(s.Config).Settings(room.Exchange()).MaxOccupancy)
+	first := int(room.InstanceNumber())
+	last := min(first+maxChatRoomInstances-1, math.MaxUint16)
+	for i := first; i <= last; i++ {
+		instance := room.WithInstance(uint16(i))
+		if s.ChatOccupantCounter.OccupantCount(instance.Cookie()) < maxOccupancy {
+			return instance, true
+		}
+	}
+	return state.ChatRoom{}, false
 }
 
 // ChatLeave handles the toc_chat_leave TOC command.
@@ -635,12 +1231,88 @@ func (s OSCARProxy) ChatLeave(ctx context.Context, chatRegistry *ChatRegistry, c
 	}
 
 	s.AuthService.SignoutChat(ctx, me)
+	// forget the room so that the registry doesn't grow with every room the
+	// user has left
+	chatRegistry.Remove(chatID)
 
 	me.Close() // stop async server SNAC reply handler for this chat room
 
 	return fmt.Sprintf("CHAT_LEFT:%d", chatID)
 }
 
+// ChatLeaveAll handles the toc_chat_leave_all TOC command.
+//
+// This is a non-standard command that leaves every chat room the user is in
+// at once, such as when the client shuts down. It returns a CHAT_LEFT message
+// for each room, in chat room ID order.
+//
+// Command syntax: toc_chat_leave_all
+func (s OSCARProxy) ChatLeaveAll(ctx context.Context, chatRegistry *ChatRegistry, cmd []byte) []string {
+	if _, err := parseArgs(cmd, "toc_chat_leave_all"); err != nil {
+		return []string{s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))}
+	}
+
+	sessions := chatRegistry.RemoveAllSess()
+
+	msgs := make([]string, 0, len(sessions))
+	for _, chatID := range slices.Sorted(maps.Keys(sessions)) {
+		me := sessions[chatID]
+		s.AuthService.SignoutChat(ctx, me)
+		me.Close() // stop async server SNAC reply handler for this chat room
+		msgs = append(msgs, fmt.Sprintf("CHAT_LEFT:%d", chatID))
+	}
+
+	return msgs
+}
+
+// pdModeBuddiesOnly is the config permit/deny mode that allows only users on
+// the buddy list to make contact.
+const pdModeBuddiesOnly = "5"
+
+// buddiesOnly indicates whether the saved config for user has the
+// buddies-only permit/deny mode set.
+func (s OSCARProxy) buddiesOnly(user state.IdentScreenName) (bool, error) {
+	u, err := s.TOCConfigStore.User(user)
+	if err != nil {
+		return false, err
+	}
+	if u == nil {
+		return false, nil
+	}
+	mode := ""
+	for _, item := range strings.Split(u.TOCConfig, "\n") {
+		itemType, value, _ := strings.Cut(strings.TrimRight(item, "\r"), " ")
+		if itemType == "m" {
+			mode = value
+		}
+	}
+	return mode == pdModeBuddiesOnly, nil
+}
+
+// unconfirmed indicates whether sess belongs to a user whose account has not
+// been confirmed yet.
+func unconfirmed(sess *state.Session) bool {
+	return sess.UserInfoBitmask()&wire.OServiceUserFlagUnconfirmed != 0
+}
+
+// roomBlocked indicates whether the operator has blocked user from joining
+// the chat room named roomName.
+func (s OSCARProxy) roomBlocked(roomName string, user state.IdentScreenName) bool {
+	for _, entry := range s.Config.TOCChatRoomBlocks {
+		// screen names can't contain colons, so split on the last one in
+		// case the room name contains a colon
+		idx := strings.LastIndex(entry, ":")
+		if idx < 0 {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(entry[:idx]), roomName) &&
+			state.NewIdentScreenName(strings.TrimSpace(entry[idx+1:])) == user {
+			return true
+		}
+	}
+	return false
+}
+
 // ChatSend handles the toc_chat_send TOC command.
 //
 // From the TiK documentation:
@@ -650,8 +1322,17 @@ func (s OSCARProxy) ChatLeave(ctx context.Context, chatRegistry *ChatRegistry, c
 //	chat UI, since you will get a CHAT_IN with the message. Remember to quote
 //	and encode the message.
 //
+// Messages that contain non-ASCII characters are sent as UTF-8. When room
+// charset enforcement is enabled, they are instead converted to the room's
+// charset, and messages with characters the charset can't represent are
+// rejected with ERROR:911.
+//
+// Users who have been warned are held to a lower send rate (see WarnThrottle)
+// and receive ERROR:903 when they send too fast. Sending a message clears the
+// user's idle time, as it does in AIM.
+//
 // Command syntax: toc_chat_send <Chat Room ID> <Message>
-func (s OSCARProxy) ChatSend(ctx context.Context, chatRegistry *ChatRegistry, cmd []byte) string {
+func (s OSCARProxy) ChatSend(ctx context.Context, sessBOS *state.Session, chatRegistry *ChatRegistry, cmd []byte) string {
 	var chatIDStr, msg string
 
 	if _, err := parseArgs(cmd, "toc_chat_send", &chatIDStr, &msg); err != nil {
@@ -668,15 +1349,40 @@ func (s OSCARProxy) ChatSend(ctx context.Context, chatRegistry *ChatRegistry, cm
 		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.RetrieveSess: session for chat ID `%d` not found", chatID))
 	}
 
+	// warnings are tracked on the BOS session
+	if !s.WarnThrottle.Allow(sessBOS.IdentScreenName(), sessBOS.Warning()) {
+		s.Logger.InfoContext(ctx, "throttled chat messages from warned user", "warning", sessBOS.Warning())
+		return tocError(903)
+	}
+
+	charset := wire.ChatMessageCharset(msg)
+	text := []byte(msg)
+	if s.Config.TOCChatStrictCharset && charset != wire.ChatCharsetASCII {
+		if room, ok := chatRegistry.LookupRoom(chatID); ok {
+			charset = state.NewChatExchanges(s.Config).Settings(room.Exchange).Charset
+			if text, ok = wire.EncodeChatCharset(msg, charset); !ok {
+				s.Logger.InfoContext(ctx, "rejected chat message with characters outside the room charset", "charset", charset)
+				return tocError(911)
+			}
+		}
+	}
+
+	if err := s.clearIdle(ctx, sessBOS); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("clearIdle: %w", err))
+	}
+
 	block := wire.TLVRestBlock{}
-	// the order of these TLVs matters for AIM 2.x. if out of order, screen
-	// names do not appear with each chat message.
+	// the chat service reassembles these TLVs in the order each recipient's
+	// client expects (see config.ChatTLVOrderQuirks), so this order only
+	// needs to follow the AIM 2.x convention.
 	block.Append(wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)))
 	block.Append(wire.NewTLVBE(wire.ChatTLVSenderInformation, me.TLVUserInfo()))
 	block.Append(wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}))
 	block.Append(wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
 		TLVList: wire.TLVList{
-			wire.NewTLVBE(wire.ChatTLVMessageInfoText, msg),
+			wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, charset),
+			wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+			wire.NewTLVBE(wire.ChatTLVMessageInfoText, text),
 		},
 	}))
 
@@ -690,7 +1396,7 @@ func (s OSCARProxy) ChatSend(ctx context.Context, chatRegistry *ChatRegistry, cm
 	}
 
 	if reply == nil {
-		return s.runtimeErr(ctx, errors.New("ChatService.ChannelMsgToHost: missing response "))
+		return s.runtimeErr(ctx, errors.New("ChatService.ChannelMsgToHost: missing response"))
 	}
 
 	switch v := reply.Body.(type) {
@@ -714,9 +1420,14 @@ func (s OSCARProxy) ChatSend(ctx context.Context, chatRegistry *ChatRegistry, cm
 			return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
 		}
 
-		return fmt.Sprintf("CHAT_IN:%d:%s:F:%s", chatID, userInfo.ScreenName, reflectMsg)
+		return chatInMessage(chatID, userInfo.ScreenName, isWhisper(v.TLVRestBlock), reflectMsg)
+	case wire.SNACError:
+		if msg, ok := snacErrToTOC(v.Code, chatIDStr); ok {
+			return msg
+		}
+		return s.runtimeErr(ctx, fmt.Errorf("ChatService.ChannelMsgToHost: unexpected error code %d", v.Code))
 	default:
-		return s.runtimeErr(ctx, errors.New("ChatService.ChannelMsgToHost: unexpected response"))
+		return s.runtimeErr(ctx, fmt.Errorf("ChatService.ChannelMsgToHost: unexpected response type %T", v))
 	}
 }
 
@@ -729,7 +1440,20 @@ func (s OSCARProxy) ChatSend(ctx context.Context, chatRegistry *ChatRegistry, cm
 //	people who have recently sent you ims. The higher someones evil level, the
 //	slower they can send message.
 //
+// If the server limits warnings to recent IM senders, warning a user who
+// hasn't sent an IM within the configured window returns ERROR:902.
+//
+// Warnings rejected by the ICBM service are reported with the TOC error that
+// corresponds to the OSCAR error, e.g. ERROR:901 if the user is offline, or
+// ERROR:902 if there is no equivalent.
+//
+// If enabled, a successful warning, normal or anonymous, is answered with the
+// non-standard WARN_RESULT message, which carries the warned user's updated
+// warning level as a percentage.
+//
 // Command syntax: toc_evil <User> <norm|anon>
+//
+// Response syntax: WARN_RESULT:<User>:<New Evil>
 func (s OSCARProxy) Evil(ctx context.Context, me *state.Session, cmd []byte) string {
 	var user, scope string
 
@@ -738,7 +1462,7 @@ func (s OSCARProxy) Evil(ctx context.Context, me *state.Session, cmd []byte) str
 	}
 
 	snac := wire.SNAC_0x04_0x08_ICBMEvilRequest{
-		ScreenName: user,
+		ScreenName: state.NewIdentScreenName(user).String(),
 	}
 
 	switch scope {
@@ -750,6 +1474,11 @@ func (s OSCARProxy) Evil(ctx context.Context, me *state.Session, cmd []byte) str
 		return s.runtimeErr(ctx, fmt.Errorf("incorrect warning type `%s`. allowed values: anon, norm", scope))
 	}
 
+	if !s.RecentIMSenders.IsRecent(me.IdentScreenName(), state.NewIdentScreenName(user)) {
+		s.Logger.InfoContext(ctx, "user is not a recent IM sender, can't warn", "user", user)
+		return tocError(902, user)
+	}
+
 	response, err := s.ICBMService.EvilRequest(ctx, me, wire.SNACFrame{}, snac)
 	if err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.EvilRequest: %w", err))
@@ -757,14 +1486,19 @@ func (s OSCARProxy) Evil(ctx context.Context, me *state.Session, cmd []byte) str
 
 	switch v := response.Body.(type) {
 	case wire.SNAC_0x04_0x09_ICBMEvilReply:
+		if s.Config.TOCWarnResult {
+			return fmt.Sprintf("WARN_RESULT:%s:%d", user, v.UpdatedEvilValue/10)
+		}
 		return ""
 	case wire.SNACError:
 		s.Logger.InfoContext(ctx, "unable to warn user", "code", v.Code)
+		if msg, ok := snacErrToTOC(v.Code, user); ok {
+			return msg
+		}
+		return tocError(902, user)
 	default:
-		return s.runtimeErr(ctx, errors.New("unexpected response"))
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.EvilRequest: unexpected response type %T", v))
 	}
-
-	return ""
 }
 
 // FormatNickname handles the toc_format_nickname TOC command.
@@ -800,16 +1534,16 @@ func (s OSCARProxy) FormatNickname(ctx context.Context, me *state.Session, cmd [
 
 	replyBody, ok := reply.Body.(wire.SNAC_0x07_0x05_AdminChangeReply)
 	if !ok {
-		return s.runtimeErr(ctx, fmt.Errorf("AdminService.InfoChangeRequest: unexpected response type %v", replyBody))
+		return s.runtimeErr(ctx, fmt.Errorf("AdminService.InfoChangeRequest: unexpected response type %T", reply.Body))
 	}
 
 	code, ok := replyBody.Uint16BE(wire.AdminTLVErrorCode)
 	if ok {
 		switch code {
 		case wire.AdminInfoErrorInvalidNickNameLength, wire.AdminInfoErrorInvalidNickName:
-			return "ERROR:911"
+			return tocError(911)
 		default:
-			return "ERROR:913"
+			return tocError(913)
 		}
 	}
 
@@ -831,6 +1565,8 @@ func (s OSCARProxy) FormatNickname(ctx context.Context, me *state.Session, cmd [
 //
 //	Returns either a GOTO_URL or ERROR msg.
 //
+// Users who exceed the lookup rate limit receive ERROR:903.
+//
 // Command syntax: toc_dir_search <info information>
 func (s OSCARProxy) GetDirSearchURL(ctx context.Context, me *state.Session, cmd []byte) string {
 	var info string
@@ -839,6 +1575,10 @@ func (s OSCARProxy) GetDirSearchURL(ctx context.Context, me *state.Session, cmd
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if !s.allowLookup(ctx, me) {
+		return tocError(903)
+	}
+
 	params := strings.Split(info, ":")
 	labels := []string{
 		"first_name",
@@ -884,6 +1624,8 @@ func (s OSCARProxy) GetDirSearchURL(ctx context.Context, me *state.Session, cmd
 //
 //	Gets a user's dir info a GOTO_URL or ERROR message will be sent back to the client.
 //
+// Users who exceed the lookup rate limit receive ERROR:903.
+//
 // Command syntax: toc_get_dir <username>
 func (s OSCARProxy) GetDirURL(ctx context.Context, me *state.Session, cmd []byte) string {
 	var user string
@@ -892,6 +1634,10 @@ func (s OSCARProxy) GetDirURL(ctx context.Context, me *state.Session, cmd []byte
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if !s.allowLookup(ctx, me) {
+		return tocError(903)
+	}
+
 	cookie, err := s.newHTTPAuthToken(me.IdentScreenName())
 	if err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("newHTTPAuthToken: %w", err))
@@ -899,17 +1645,74 @@ func (s OSCARProxy) GetDirURL(ctx context.Context, me *state.Session, cmd []byte
 
 	p := url.Values{}
 	p.Add("cookie", cookie)
-	p.Add("user", user)
+	p.Add("user", state.NewIdentScreenName(user).String())
 
 	return fmt.Sprintf("GOTO_URL:directory info:dir_info?%s", p.Encode())
 }
 
+// GetDirKeywords handles the toc_get_dir_keywords TOC command.
+//
+// This is a non-standard command that lists the interest keywords that can
+// be searched in the user directory with toc_dir_search, which lets clients
+// offer the keywords in a dropdown. Each entry is a type followed by a name,
+// where the type is C for a category or K for a keyword. Keywords follow the
+// category they belong to, and keywords without a category are listed on
+// their own. The response has no entries if no keywords are configured.
+//
+// Command syntax: toc_get_dir_keywords
+//
+// Response syntax: DIR_KEYWORDS[:<type>:<name>[:<type>:<name>[...]]]
+func (s OSCARProxy) GetDirKeywords(ctx context.Context, cmd []byte) string {
+	if _, err := parseArgs(cmd, "toc_get_dir_keywords"); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	reply, err := s.DirSearchService.KeywordListQuery(ctx, wire.SNACFrame{})
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("DirSearchService.KeywordListQuery: %w", err))
+	}
+
+	v, ok := reply.Body.(wire.SNAC_0x0F_0x04_KeywordListReply)
+	if !ok {
+		return s.runtimeErr(ctx, fmt.Errorf("DirSearchService.KeywordListQuery: unexpected response type %T", reply.Body))
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("DIR_KEYWORDS")
+	for _, item := range v.Interests {
+		switch item.Type {
+		case wire.ODirKeywordCategory:
+			sb.WriteString(":C:")
+		case wire.ODirKeyword:
+			sb.WriteString(":K:")
+		default:
+			continue
+		}
+		sb.WriteString(item.Name)
+	}
+
+	return sb.String()
+}
+
+// allowLookup records a profile or directory lookup by me and reports whether
+// it's within the lookup rate limit. The limit is keyed on me alone, so it
+// caps the user's overall lookup rate regardless of whose info is fetched.
+func (s OSCARProxy) allowLookup(ctx context.Context, me *state.Session) bool {
+	if s.LookupThrottle.Allow(me.IdentScreenName(), state.IdentScreenName{}) {
+		return true
+	}
+	s.Logger.InfoContext(ctx, "throttled profile and directory lookups")
+	return false
+}
+
 // GetInfoURL handles the toc_get_info TOC command.
 //
 // From the TiK documentation:
 //
 //	Gets a user's info a GOTO_URL or ERROR message will be sent back to the client.
 //
+// Users who exceed the lookup rate limit receive ERROR:903.
+//
 // Command syntax: toc_get_info <username>
 func (s OSCARProxy) GetInfoURL(ctx context.Context, me *state.Session, cmd []byte) string {
 	var user string
@@ -918,6 +1721,10 @@ func (s OSCARProxy) GetInfoURL(ctx context.Context, me *state.Session, cmd []byt
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if !s.allowLookup(ctx, me) {
+		return tocError(903)
+	}
+
 	cookie, err := s.newHTTPAuthToken(me.IdentScreenName())
 	if err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("newHTTPAuthToken: %w", err))
@@ -926,11 +1733,35 @@ func (s OSCARProxy) GetInfoURL(ctx context.Context, me *state.Session, cmd []byt
 	p := url.Values{}
 	p.Add("cookie", cookie)
 	p.Add("from", me.IdentScreenName().String())
-	p.Add("user", user)
+	p.Add("user", state.NewIdentScreenName(user).String())
 
 	return fmt.Sprintf("GOTO_URL:profile:info?%s", p.Encode())
 }
 
+// GetBuddyComment handles the toc_get_buddy_comment TOC command.
+//
+// This is a non-standard command that retrieves the private comment the user
+// attached to a buddy with toc_set_buddy_comment. The comment is empty if the
+// user hasn't commented on the buddy.
+//
+// Command syntax: toc_get_buddy_comment <buddy>
+//
+// Response syntax: BUDDY_COMMENT:<buddy>:<comment>
+func (s OSCARProxy) GetBuddyComment(ctx context.Context, me *state.Session, cmd []byte) string {
+	var buddy string
+
+	if _, err := parseArgs(cmd, "toc_get_buddy_comment", &buddy); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	comment, err := s.BuddyCommentStore.BuddyComment(me.IdentScreenName(), state.NewIdentScreenName(buddy))
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("BuddyCommentStore.BuddyComment: %w", err))
+	}
+
+	return fmt.Sprintf("BUDDY_COMMENT:%s:%s", buddy, comment)
+}
+
 // GetStatus handles the toc_get_status TOC command.
 //
 // From the TOC2 documentation:
@@ -939,6 +1770,11 @@ func (s OSCARProxy) GetInfoURL(ctx context.Context, me *state.Session, cmd []byt
 //	UPDATE_BUDDY message or an ERROR message depending on whether or not the
 //	guy appears to be online.
 //
+// Offline users are reported with an UPDATE_BUDDY message that has the
+// online flag cleared, the same way toc_get_buddies reports them, rather than
+// an ERROR message. Users who block or are blocked by the user appear
+// offline.
+//
 // Command syntax: toc_get_status <screenname>
 func (s OSCARProxy) GetStatus(ctx context.Context, me *state.Session, cmd []byte) string {
 	var them string
@@ -948,7 +1784,7 @@ func (s OSCARProxy) GetStatus(ctx context.Context, me *state.Session, cmd []byte
 	}
 
 	inBody := wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-		ScreenName: them,
+		ScreenName: state.NewIdentScreenName(them).String(),
 	}
 
 	info, err := s.LocateService.UserInfoQuery(ctx, me, wire.SNACFrame{}, inBody)
@@ -959,15 +1795,111 @@ func (s OSCARProxy) GetStatus(ctx context.Context, me *state.Session, cmd []byte
 	switch v := info.Body.(type) {
 	case wire.SNACError:
 		if v.Code == wire.ErrorCodeNotLoggedOn {
-			return fmt.Sprintf("ERROR:901:%s", them)
+			return fmt.Sprintf("UPDATE_BUDDY:%s:F:0:0:0:   ", s.displayScreenName(ctx, them))
 		} else {
 			return s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery error code: %d", v.Code))
 		}
 	case wire.SNAC_0x02_0x06_LocateUserInfoReply:
 		return userInfoToUpdateBuddy(v.TLVUserInfo)
 	default:
-		return s.runtimeErr(ctx, fmt.Errorf("AdminService.InfoChangeRequest: unexpected response type %v", v))
+		return s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery: unexpected response type %T", v))
+	}
+}
+
+// GetPresence handles the toc_get_presence TOC command.
+//
+// This is a non-standard command that reports whether a user is online and
+// away without the rest of the user info that toc_get_status returns. Users
+// who block or are blocked by the user appear offline.
+//
+// Command syntax: toc_get_presence <screenname>
+//
+// Response syntax: PRESENCE:<screenname>:<online T/F>:<away T/F>
+func (s OSCARProxy) GetPresence(ctx context.Context, me *state.Session, cmd []byte) string {
+	var them string
+
+	if _, err := parseArgs(cmd, "toc_get_presence", &them); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	inBody := wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+		ScreenName: state.NewIdentScreenName(them).String(),
+	}
+
+	info, err := s.LocateService.UserInfoQuery(ctx, me, wire.SNACFrame{}, inBody)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery: %w", err))
+	}
+
+	switch v := info.Body.(type) {
+	case wire.SNACError:
+		if v.Code != wire.ErrorCodeNotLoggedOn {
+			return s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery error code: %d", v.Code))
+		}
+		return fmt.Sprintf("PRESENCE:%s:F:F", them)
+	case wire.SNAC_0x02_0x06_LocateUserInfoReply:
+		away := "F"
+		if v.TLVUserInfo.IsAway() {
+			away = "T"
+		}
+		return fmt.Sprintf("PRESENCE:%s:T:%s", v.TLVUserInfo.ScreenName, away)
+	default:
+		return s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery: unexpected response type %T", v))
+	}
+}
+
+// maxBuddyRefresh is the maximum number of buddies reported by
+// toc_get_buddies.
+const maxBuddyRefresh = 500
+
+// GetBuddies handles the toc_get_buddies TOC command.
+//
+// This is a non-standard command that refreshes the presence of every buddy
+// on the user's buddy list at once. It returns an UPDATE_BUDDY message for
+// each buddy, in alphabetical order. Buddies who are offline, or who block or
+// are blocked by the user, are reported as offline. Only the first
+// maxBuddyRefresh buddies are reported.
+//
+// Command syntax: toc_get_buddies
+func (s OSCARProxy) GetBuddies(ctx context.Context, me *state.Session, cmd []byte) []string {
+	if _, err := parseArgs(cmd, "toc_get_buddies"); err != nil {
+		return []string{s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))}
+	}
+
+	buddies, err := s.BuddyLister.Buddies(me.IdentScreenName())
+	if err != nil {
+		return []string{s.runtimeErr(ctx, fmt.Errorf("BuddyLister.Buddies: %w", err))}
+	}
+
+	if len(buddies) > maxBuddyRefresh {
+		s.Logger.InfoContext(ctx, "truncating buddy list refresh", "buddies", len(buddies), "limit", maxBuddyRefresh)
+		buddies = buddies[:maxBuddyRefresh]
+	}
+
+	msgs := make([]string, 0, len(buddies))
+	for _, buddy := range buddies {
+		inBody := wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+			ScreenName: buddy.String(),
+		}
+		info, err := s.LocateService.UserInfoQuery(ctx, me, wire.SNACFrame{}, inBody)
+		if err != nil {
+			return []string{s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery: %w", err))}
+		}
+
+		switch v := info.Body.(type) {
+		case wire.SNACError:
+			if v.Code != wire.ErrorCodeNotLoggedOn {
+				return []string{s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery error code: %d", v.Code))}
+			}
+			msgs = append(msgs, fmt.Sprintf("UPDATE_BUDDY:%s:F:0:0:0:   ", s.displayScreenName(ctx, buddy.String())))
+		case wire.SNAC_0x02_0x06_LocateUserInfoReply:
+			msgs = append(msgs, userInfoToUpdateBuddy(v.TLVUserInfo))
+		default:
+			return []string{s.runtimeErr(ctx, fmt.Errorf("LocateService.UserInfoQuery: unexpected response type %T", v))}
+		}
 	}
+
+	return msgs
 }
 
 // InitDone handles the toc_init_done TOC command.
@@ -981,27 +1913,181 @@ func (s OSCARProxy) GetStatus(ctx context.Context, me *state.Session, cmd []byte
 //	received. Calling this before or multiple times after a SIGN_ON will cause
 //	the connection to be dropped.
 //
-// Note: The business logic described in the last 3 sentences are not yet
-// implemented.
+// Server enforces the 30-second deadline, which is configurable. A repeated
+// toc_init_done returns an error that RecvClientCmd treats as fatal rather
+// than announcing the user's arrival again. toc_init_done can't arrive before
+// SIGN_ON because Signon rejects any first command other than toc_signon.
+//
+// Once the user is online, RecvClientCmd delivers the messages queued while
+// they were offline and joins them to the configured auto-join rooms.
 //
 // Command syntax: toc_init_done
 func (s OSCARProxy) InitDone(ctx context.Context, sess *state.Session, cmd []byte) string {
 	if _, err := parseArgs(cmd, "toc_init_done"); err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
+	if sess.SignonComplete() {
+		s.Logger.InfoContext(ctx, "client sent toc_init_done more than once")
+		return cmdInitDoneRepeated
+	}
 	if err := s.OServiceServiceBOS.ClientOnline(ctx, wire.SNAC_0x01_0x02_OServiceClientOnline{}, sess); err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.ClientOnliney: %w", err))
 	}
 	return ""
 }
 
+// deliverOfflineMessages sends the user the messages queued while they were
+// offline as IM_IN messages. This is the only place TOC users receive queued
+// messages, because the TOC server's OServiceServiceBOS is created without an
+// offline message manager. System notices from state.SystemNoticeSender are
+// always delivered. Instant messages from other users are delivered only if
+// offline IMs are enabled. Each message is removed from the offline message
+// store once it's delivered, as are malformed messages that can never be
+// delivered; other offline messages are left untouched.
+func (s OSCARProxy) deliverOfflineMessages(ctx context.Context, me *state.Session, toCh chan<- []byte) {
+	msgs, err := s.OfflineMessageManager.RetrieveMessages(me.IdentScreenName())
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "unable to retrieve offline messages", "err", err.Error())
+		return
+	}
+
+	for _, msg := range msgs {
+		isNotice := msg.Sender == state.SystemNoticeSender.IdentScreenName()
+		if !isNotice && (!s.Config.TOCOfflineIMs || msg.Message.ChannelID != wire.ICBMChannelIM) {
+			continue
+		}
+
+		sender := msg.Sender.String()
+		tlvs := msg.Message.TLVRestBlock
+		if isNotice {
+			sender = state.SystemNoticeSender.String()
+		}
+		// mark the message as stored so that IMIn flags it
+		tlvs.TLVList = append(slices.Clone(tlvs.TLVList), wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(msg.Sent.Unix())))
+
+		im := s.IMIn(ctx, nil, wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: sender,
+			},
+			TLVRestBlock: tlvs,
+		})
+		// a malformed message, already logged by IMIn, is dropped
+		if !strings.HasPrefix(im, "ERROR:") {
+			select {
+			case toCh <- []byte(im):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := s.OfflineMessageManager.DeleteMessage(msg.ID); err != nil {
+			s.Logger.ErrorContext(ctx, "unable to delete delivered offline message", "err", err.Error())
+		}
+	}
+}
+
+// autoJoinRooms joins the user to each of the configured auto-join rooms as if
+// they had sent toc_chat_join for exchange 4, sending a CHAT_JOIN message to
+// the client for each joined room. Rooms that can't be joined are skipped.
+func (s OSCARProxy) autoJoinRooms(
+	ctx context.Context,
+	me *state.Session,
+	chatRegistry *ChatRegistry,
+	toCh chan<- []byte,
+	doAsync func(f func() error),
+) {
+	for _, roomName := range s.Config.TOCAutoJoinRooms {
+		chatID, msg := s.joinRoom(ctx, me, chatRegistry, 4, roomName)
+		if strings.HasPrefix(msg, "ERROR:") {
+			s.Logger.InfoContext(ctx, "unable to auto-join chat room", "room", roomName)
+			continue
+		}
+
+		doAsync(func() error {
+			sess := chatRegistry.RetrieveSess(chatID)
+			s.RecvChat(ctx, sess, chatID, toCh)
+			return nil
+		})
+
+		select {
+		case toCh <- []byte(msg):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Ping handles the toc_ping TOC command.
+//
+// This is a non-standard command that returns the current server time as a
+// Unix timestamp. Clients can use it to synchronize clocks, measure latency,
+// or keep an idle connection alive at the application level.
+//
+// Command syntax: toc_ping
+//
+// Response syntax: PONG:<Unix Timestamp>
+func (s OSCARProxy) Ping(ctx context.Context, cmd []byte) string {
+	if _, err := parseArgs(cmd, "toc_ping"); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+	return fmt.Sprintf("PONG:%d", time.Now().Unix())
+}
+
+// ReportUser handles the toc_report_user TOC command.
+//
+// This is a non-standard command that reports another user's abusive
+// behavior to the server operators. The report is stored for operator review
+// along with the optional reason and the last few instant messages the user
+// received from the reported user. Users who exceed the report rate limit
+// receive ERROR:903, and ERROR:981 is returned if abuse reports are disabled.
+//
+// Command syntax: toc_report_user <username> [<reason>]
+func (s OSCARProxy) ReportUser(ctx context.Context, me *state.Session, cmd []byte) string {
+	var user string
+
+	reason, err := parseArgs(cmd, "toc_report_user", &user)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	if !s.Config.TOCAbuseReports {
+		return tocError(981)
+	}
+
+	if !s.ReportThrottle.Allow(me.IdentScreenName(), state.IdentScreenName{}) {
+		s.Logger.InfoContext(ctx, "throttled abuse reports")
+		return tocError(903)
+	}
+
+	target := state.NewIdentScreenName(user)
+	report := state.AbuseReport{
+		Reporter: me.IdentScreenName(),
+		Target:   target,
+		Reason:   strings.Join(reason, " "),
+		Context:  strings.Join(s.IMHistory.From(me.IdentScreenName(), target), "\n"),
+		Created:  time.Now().UTC(),
+	}
+	if err := s.AbuseReportStore.AddAbuseReport(report); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("AbuseReportStore.AddAbuseReport: %w", err))
+	}
+
+	// notify operators through the server log
+	s.Logger.WarnContext(ctx, "user filed an abuse report", "target", target.String(), "reason", report.Reason)
+
+	return ""
+}
+
 // RemoveBuddy handles the toc_remove_buddy TOC command.
 //
 // From the TiK documentation:
 //
 //	Remove buddies from your buddy list. This does not change your saved config.
 //
-// Command syntax:
+// If the user's saved config allows only buddies to make contact, removed
+// buddies are also removed from the permit list.
+//
+// Command syntax: toc_remove_buddy <Buddy User 1> [<Buddy User2> [<Buddy User 3> [...]]]
 func (s OSCARProxy) RemoveBuddy(ctx context.Context, me *state.Session, cmd []byte) string {
 	users, err := parseArgs(cmd, "toc_remove_buddy")
 	if err != nil {
@@ -1012,15 +2098,278 @@ func (s OSCARProxy) RemoveBuddy(ctx context.Context, me *state.Session, cmd []by
 	for _, sn := range users {
 		snac.Buddies = append(snac.Buddies, struct {
 			ScreenName string `oscar:"len_prefix=uint8"`
-		}{ScreenName: sn})
+		}{ScreenName: state.NewIdentScreenName(sn).String()})
 	}
 
 	if err := s.BuddyService.DelBuddies(ctx, me, snac); err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("BuddyService.DelBuddies: %w", err))
 	}
+
+	buddiesOnly, err := s.buddiesOnly(me.IdentScreenName())
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("buddiesOnly: %w", err))
+	}
+	if buddiesOnly {
+		// keep the permit list in sync with the buddy list
+		permits := wire.SNAC_0x09_0x06_PermitDenyDelPermListEntries{Users: snac.Buddies}
+		if err := s.PermitDenyService.DelPermListEntries(ctx, me, permits); err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("PermitDenyService.DelPermListEntries: %w", err))
+		}
+	}
+
 	return ""
 }
 
+// RvousPropose handles the toc_rvous_propose TOC command.
+//
+// This is a non-standard command that proposes a rendezvous, such as a file
+// transfer or direct IM, to another user. The service is identified by its
+// capability UUID, and the proposal by a base64-encoded 8-byte cookie and a
+// sequence number that starts at 1 and increases with each counter-proposal.
+// The optional rendezvous data is a base64-encoded block of OSCAR rendezvous
+// TLVs, such as the proposer's IP address and port. The server sets the
+// sequence number TLV itself and replaces any verified IP address with the
+// address the client connects from. Chat invitations must be sent with
+// toc_chat_invite instead. Delivery failures are reported with the
+// corresponding TOC error.
+//
+// Command syntax: toc_rvous_propose <Destination User> <Service UUID> <Cookie> <Seq> [<Rendezvous Data>]
+func (s OSCARProxy) RvousPropose(ctx context.Context, me *state.Session, cmd []byte) string {
+	var recip, svcUUID, cookieStr, seqStr string
+
+	rdvArgs, err := parseArgs(cmd, "toc_rvous_propose", &recip, &svcUUID, &cookieStr, &seqStr)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	capability, err := uuid.Parse(svcUUID)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("uuid.Parse: %w", err))
+	}
+	if capability == capChat {
+		return s.runtimeErr(ctx, errors.New("chat invitations must be sent with toc_chat_invite"))
+	}
+
+	cookie, err := decodeRvousCookie(cookieStr)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("decodeRvousCookie: %w", err))
+	}
+
+	seq, err := strconv.ParseUint(seqStr, 10, 16)
+	if err != nil || seq == 0 {
+		return s.runtimeErr(ctx, fmt.Errorf("invalid rendezvous sequence number %q", seqStr))
+	}
+
+	rdvData := wire.TLVRestBlock{}
+	if len(rdvArgs) > 0 {
+		b, err := base64.StdEncoding.DecodeString(rdvArgs[0])
+		if err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("base64.DecodeString: %w", err))
+		}
+		if err := wire.UnmarshalBE(&rdvData, bytes.NewReader(b)); err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
+		}
+	}
+
+	// the sequence number and verified IP are set by the server, so that
+	// clients can't spoof them
+	tlvs := wire.TLVList{wire.NewTLVBE(wire.ICBMRdvTLVTagsSeqNum, uint16(seq))}
+	for _, tlv := range rdvData.TLVList {
+		if tlv.Tag == wire.ICBMRdvTLVTagsSeqNum || tlv.Tag == wire.ICBMRdvTLVTagsVerifiedIP {
+			continue
+		}
+		tlvs.Append(tlv)
+	}
+	if addr := me.RemoteAddr(); addr != nil && addr.Addr().Unmap().Is4() {
+		ip := addr.Addr().Unmap().As4()
+		tlvs.Append(wire.NewTLVBE(wire.ICBMRdvTLVTagsVerifiedIP, binary.BigEndian.Uint32(ip[:])))
+	}
+
+	snac := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		ChannelID:  wire.ICBMChannelRendezvous,
+		ScreenName: state.NewIdentScreenName(recip).String(),
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       wire.ICBMRdvMessagePropose,
+					Cookie:     cookie,
+					Capability: capability,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: tlvs,
+					},
+				}),
+			},
+		},
+	}
+
+	reply, err := s.ICBMService.ChannelMsgToHost(ctx, me, wire.SNACFrame{}, snac)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: %w", err))
+	}
+
+	return s.rvousReply(ctx, reply, recip)
+}
+
+// RvousAccept handles the toc_rvous_accept TOC command.
+//
+// This is a non-standard command that accepts a rendezvous proposal received
+// in a RVOUS_PROPOSE message. The proposer, cookie, and service UUID must
+// match a pending proposal. The optional rendezvous data is a base64-encoded
+// block of OSCAR rendezvous TLVs that is passed on to the proposer. If the
+// proposer has signed off since making the proposal, the proposal is
+// discarded and ERROR:901 is returned.
+//
+// Command syntax: toc_rvous_accept <Proposer User> <Cookie> <Service UUID> [<Rendezvous Data>]
+func (s OSCARProxy) RvousAccept(ctx context.Context, me *state.Session, chatRegistry *ChatRegistry, cmd []byte) string {
+	proposal, rdvArgs, err := parseRvousReply(cmd, "toc_rvous_accept")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseRvousReply: %w", err))
+	}
+
+	if !chatRegistry.LookupRvous(proposal) {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.LookupRvous: no rendezvous proposal found for cookie %d", proposal.Cookie))
+	}
+
+	rdvData := wire.TLVRestBlock{}
+	if len(rdvArgs) > 0 {
+		b, err := base64.StdEncoding.DecodeString(rdvArgs[0])
+		if err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("base64.DecodeString: %w", err))
+		}
+		if err := wire.UnmarshalBE(&rdvData, bytes.NewReader(b)); err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
+		}
+	}
+
+	return s.sendRvousReply(ctx, me, chatRegistry, proposal, wire.ICBMRdvMessageAccept, rdvData.TLVList)
+}
+
+// RvousCancel handles the toc_rvous_cancel TOC command.
+//
+// This is a non-standard command that rejects a rendezvous proposal received
+// in a RVOUS_PROPOSE message, or cancels one that was already accepted. The
+// proposer, cookie, and service UUID must match a pending proposal, which is
+// discarded.
+//
+// Command syntax: toc_rvous_cancel <Proposer User> <Cookie> <Service UUID>
+func (s OSCARProxy) RvousCancel(ctx context.Context, me *state.Session, chatRegistry *ChatRegistry, cmd []byte) string {
+	proposal, _, err := parseRvousReply(cmd, "toc_rvous_cancel")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseRvousReply: %w", err))
+	}
+
+	if !chatRegistry.RemoveRvous(proposal) {
+		return s.runtimeErr(ctx, fmt.Errorf("chatRegistry.RemoveRvous: no rendezvous proposal found for cookie %d", proposal.Cookie))
+	}
+
+	tlvs := wire.TLVList{
+		wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+	}
+	return s.sendRvousReply(ctx, me, chatRegistry, proposal, wire.ICBMRdvMessageCancel, tlvs)
+}
+
+// parseRvousReply parses the proposer, cookie, and service UUID arguments of
+// a toc_rvous_accept or toc_rvous_cancel command. It returns any remaining
+// arguments.
+func parseRvousReply(cmd []byte, name string) (RvousProposal, []string, error) {
+	var proposer, cookieStr, svcUUID string
+
+	varArgs, err := parseArgs(cmd, name, &proposer, &cookieStr, &svcUUID)
+	if err != nil {
+		return RvousProposal{}, nil, fmt.Errorf("parseArgs: %w", err)
+	}
+
+	cookie, err := decodeRvousCookie(cookieStr)
+	if err != nil {
+		return RvousProposal{}, nil, fmt.Errorf("decodeRvousCookie: %w", err)
+	}
+
+	capability, err := uuid.Parse(svcUUID)
+	if err != nil {
+		return RvousProposal{}, nil, fmt.Errorf("uuid.Parse: %w", err)
+	}
+
+	return RvousProposal{
+		Proposer:   state.NewIdentScreenName(proposer),
+		Cookie:     cookie,
+		Capability: capability,
+	}, varArgs, nil
+}
+
+// sendRvousReply sends an accept or cancel rendezvous message for proposal
+// to the proposer. If the proposer is no longer signed on, the proposal is
+// discarded.
+func (s OSCARProxy) sendRvousReply(
+	ctx context.Context,
+	me *state.Session,
+	chatRegistry *ChatRegistry,
+	proposal RvousProposal,
+	msgType uint16,
+	tlvs wire.TLVList,
+) string {
+	snac := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		ChannelID:  wire.ICBMChannelRendezvous,
+		ScreenName: proposal.Proposer.String(),
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       msgType,
+					Cookie:     proposal.Cookie,
+					Capability: proposal.Capability,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: tlvs,
+					},
+				}),
+			},
+		},
+	}
+
+	reply, err := s.ICBMService.ChannelMsgToHost(ctx, me, wire.SNACFrame{}, snac)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: %w", err))
+	}
+
+	if reply != nil {
+		if v, ok := reply.Body.(wire.SNACError); ok && v.Code == wire.ErrorCodeNotLoggedOn {
+			// the proposer signed off, so the proposal can't go anywhere
+			chatRegistry.RemoveRvous(proposal)
+		}
+	}
+
+	return s.rvousReply(ctx, reply, proposal.Proposer.String())
+}
+
+// rvousReply converts the ICBM service's reply to a rendezvous message into
+// a TOC response. It returns an empty string if the message was delivered.
+func (s OSCARProxy) rvousReply(ctx context.Context, reply *wire.SNACMessage, recip string) string {
+	if reply == nil {
+		return ""
+	}
+
+	v, ok := reply.Body.(wire.SNACError)
+	if !ok {
+		// the message was delivered
+		return ""
+	}
+	s.Logger.InfoContext(ctx, "unable to deliver rendezvous message", "recipient", recip, "code", v.Code)
+	if msg, ok := snacErrToTOC(v.Code, recip); ok {
+		return msg
+	}
+	return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: unexpected error code %d", v.Code))
+}
+
+// decodeRvousCookie decodes a base64-encoded 8-byte rendezvous cookie.
+func decodeRvousCookie(s string) (uint64, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 8 {
+		return 0, fmt.Errorf("rendezvous cookie is %d bytes, want 8", len(b))
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
 // SendIM handles the toc_send_im TOC command.
 //
 // From the TiK documentation:
@@ -1029,6 +2378,18 @@ func (s OSCARProxy) RemoveBuddy(ctx context.Context, me *state.Session, cmd []by
 //	If the optional string "auto" is the last argument, then the auto response
 //	flag will be turned on for the IM.
 //
+// Messages sent to screen names that are not registered are rejected with
+// ERROR:901. Messages sent to registered users who are offline are also
+// rejected with ERROR:901, unless offline IMs are enabled, in which case they
+// are stored for delivery at next sign-on. If the recipient's offline message
+// queue is full, the message is rejected with ERROR:901 after all. Other
+// delivery failures are reported with the corresponding TOC error. Recipients who block the sender,
+// or whom the sender blocks, also result in ERROR:901, so that blocking looks
+// the same as being offline. Users whose accounts are unconfirmed may be held
+// to a lower send rate, and so are users who have been warned (see
+// WarnThrottle), who receive ERROR:903 when they send too fast. Sending a
+// message clears the sender's idle time, as it does in AIM.
+//
 // Command syntax: toc_send_im <Destination User> <Message> [auto]
 func (s OSCARProxy) SendIM(ctx context.Context, sender *state.Session, cmd []byte) string {
 	var recip, msg string
@@ -1038,14 +2399,45 @@ func (s OSCARProxy) SendIM(ctx context.Context, sender *state.Session, cmd []byt
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if !s.IMThrottle.Allow(sender.IdentScreenName(), state.NewIdentScreenName(recip)) {
+		s.Logger.InfoContext(ctx, "throttled instant messages to recipient", "recipient", recip)
+		return tocError(960, recip)
+	}
+
+	// the unconfirmed throttle is keyed on the sender alone, so it limits the
+	// sender's overall send rate
+	if unconfirmed(sender) && !s.UnconfirmedIMThrottle.Allow(sender.IdentScreenName(), state.IdentScreenName{}) {
+		s.Logger.InfoContext(ctx, "throttled instant messages from unconfirmed user", "recipient", recip)
+		return tocError(960, recip)
+	}
+
+	if !s.WarnThrottle.Allow(sender.IdentScreenName(), sender.Warning()) {
+		s.Logger.InfoContext(ctx, "throttled instant messages from warned user", "warning", sender.Warning())
+		return tocError(903)
+	}
+
+	u, err := s.UserManager.User(state.NewIdentScreenName(recip))
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("UserManager.User: %w", err))
+	}
+	if u == nil {
+		// the recipient has never existed, let the sender know instead of
+		// dropping the message
+		return tocError(901, recip)
+	}
+
 	frags, err := wire.ICBMFragmentList(msg)
 	if err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("wire.ICBMFragmentList: %w", err))
 	}
 
+	if err := s.clearIdle(ctx, sender); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("clearIdle: %w", err))
+	}
+
 	snac := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
 		ChannelID:  wire.ICBMChannelIM,
-		ScreenName: recip,
+		ScreenName: state.NewIdentScreenName(recip).String(),
 		TLVRestBlock: wire.TLVRestBlock{
 			TLVList: wire.TLVList{
 				wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
@@ -1057,17 +2449,80 @@ func (s OSCARProxy) SendIM(ctx context.Context, sender *state.Session, cmd []byt
 		snac.Append(wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}))
 	}
 
-	// send message and ignore response since there is no TOC error code to
-	// handle errors such as "user is offline", etc.
-	_, err = s.ICBMService.ChannelMsgToHost(ctx, sender, wire.SNACFrame{}, snac)
+	// without a queue limit, the message can be stored in the same request
+	// if the recipient is offline. otherwise, the queue is only checked once
+	// the recipient turns out to be offline.
+	storeOffline := s.Config.TOCOfflineIMs && s.Config.TOCMaxOfflineIMs == 0
+	if storeOffline {
+		// store the message if the recipient is offline
+		snac.Append(wire.NewTLVBE(wire.ICBMTLVStore, []byte{}))
+	}
+
+	reply, err := s.ICBMService.ChannelMsgToHost(ctx, sender, wire.SNACFrame{}, snac)
 	if err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: %w", err))
 	}
 
+	if reply == nil {
+		return ""
+	}
+
+	switch v := reply.Body.(type) {
+	case wire.SNACError:
+		if v.Code == wire.ErrorCodeNotLoggedOn && storeOffline {
+			// the message was stored for delivery at the recipient's next
+			// sign-on
+			return ""
+		}
+		if v.Code == wire.ErrorCodeNotLoggedOn && s.Config.TOCOfflineIMs {
+			return s.storeOfflineIM(ctx, sender, recip, snac)
+		}
+		s.Logger.InfoContext(ctx, "unable to deliver instant message", "recipient", recip, "code", v.Code)
+		if msg, ok := snacErrToTOC(v.Code, recip); ok {
+			return msg
+		}
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: unexpected error code %d", v.Code))
+	default:
+		// the message was delivered
+		return ""
+	}
+}
+
+// storeOfflineIM resends an instant message to an offline recipient with the
+// store flag set, so that it's delivered at their next sign-on. The message
+// is rejected instead if the recipient's offline message queue already holds
+// TOCMaxOfflineIMs messages.
+func (s OSCARProxy) storeOfflineIM(ctx context.Context, sender *state.Session, recip string, snac wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) string {
+	queued, err := s.OfflineMessageManager.RetrieveMessages(state.NewIdentScreenName(recip))
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("OfflineMessageManager.RetrieveMessages: %w", err))
+	}
+	if len(queued) >= s.Config.TOCMaxOfflineIMs {
+		s.Logger.InfoContext(ctx, "offline message queue is full", "recipient", recip)
+		return tocError(901, recip)
+	}
+
+	snac.Append(wire.NewTLVBE(wire.ICBMTLVStore, []byte{}))
+	reply, err := s.ICBMService.ChannelMsgToHost(ctx, sender, wire.SNACFrame{}, snac)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: %w", err))
+	}
+	if reply == nil {
+		return ""
+	}
+	if v, ok := reply.Body.(wire.SNACError); ok && v.Code != wire.ErrorCodeNotLoggedOn {
+		s.Logger.InfoContext(ctx, "unable to store instant message", "recipient", recip, "code", v.Code)
+		if msg, ok := snacErrToTOC(v.Code, recip); ok {
+			return msg
+		}
+		return s.runtimeErr(ctx, fmt.Errorf("ICBMService.ChannelMsgToHost: unexpected error code %d", v.Code))
+	}
+	// the message was stored, or delivered if the recipient signed on in the
+	// meantime
 	return ""
 }
 
-// SetAway handles the toc_chat_join TOC command.
+// SetAway handles the toc_set_away TOC command.
 //
 // From the TiK documentation:
 //
@@ -1076,6 +2531,9 @@ func (s OSCARProxy) SendIM(ctx context.Context, sender *state.Session, cmd []byt
 //	flag is unset. The away message is basic HTML, remember to encode the
 //	information.
 //
+// Once sign-on is complete, LocateService.SetInfo broadcasts the change so
+// that users watching me receive an UPDATE_BUDDY with the new away state.
+//
 // Command syntax: toc_set_away [<away message>]
 func (s OSCARProxy) SetAway(ctx context.Context, me *state.Session, cmd []byte) string {
 	maybeMsg, err := parseArgs(cmd, "toc_set_away")
@@ -1088,21 +2546,50 @@ func (s OSCARProxy) SetAway(ctx context.Context, me *state.Session, cmd []byte)
 		msg = maybeMsg[0]
 	}
 
-	snac := wire.SNAC_0x02_0x04_LocateSetInfo{
-		TLVRestBlock: wire.TLVRestBlock{
-			TLVList: wire.TLVList{
-				wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, msg),
-			},
-		},
+	if err := s.setAwayMessage(ctx, me, msg); err != nil {
+		return s.runtimeErr(ctx, err)
 	}
 
-	if err := s.LocateService.SetInfo(ctx, me, snac); err != nil {
-		return s.runtimeErr(ctx, fmt.Errorf("LocateService.SetInfo: %w", err))
+	return ""
+}
+
+// maxBuddyCommentLen is the maximum length in bytes of a buddy comment.
+const maxBuddyCommentLen = 255
+
+// SetBuddyComment handles the toc_set_buddy_comment TOC command.
+//
+// This is a non-standard command that attaches a private comment to a buddy,
+// such as a note about who they are. The comment is stored server-side so
+// that it's available from any client the user signs on with, and is never
+// shown to other users. Omitting the comment removes the existing comment.
+// Comments longer than maxBuddyCommentLen are rejected with ERROR:911.
+//
+// Command syntax: toc_set_buddy_comment <buddy> [<comment>]
+func (s OSCARProxy) SetBuddyComment(ctx context.Context, me *state.Session, cmd []byte) string {
+	var buddy string
+
+	varArgs, err := parseArgs(cmd, "toc_set_buddy_comment", &buddy)
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	comment := strings.TrimSpace(strings.Join(varArgs, " "))
+	if len(comment) > maxBuddyCommentLen {
+		s.Logger.InfoContext(ctx, "rejected oversized buddy comment", "len", len(comment), "limit", maxBuddyCommentLen)
+		return tocError(911, buddy)
+	}
+
+	if err := s.BuddyCommentStore.SetBuddyComment(me.IdentScreenName(), state.NewIdentScreenName(buddy), comment); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("BuddyCommentStore.SetBuddyComment: %w", err))
 	}
 
 	return ""
 }
 
+// maxCaps is the maximum number of capabilities a user can set, including the
+// chat capability.
+const maxCaps = 32
+
 // SetCaps handles the toc_set_caps TOC command.
 //
 // From the TiK documentation:
@@ -1112,7 +2599,8 @@ func (s OSCARProxy) SetAway(ctx context.Context, me *state.Session, cmd []byte)
 //
 // This method automatically adds the "chat" capability since it doesn't seem
 // to be sent explicitly by the official clients, even though they support
-// chat.
+// chat. Duplicate capabilities and empty arguments are ignored. Setting more
+// than maxCaps capabilities results in ERROR:911.
 //
 // Command syntax: toc_set_caps [ <Capability 1> [<Capability 2> [...]]]
 func (s OSCARProxy) SetCaps(ctx context.Context, me *state.Session, cmd []byte) string {
@@ -1121,15 +2609,24 @@ func (s OSCARProxy) SetCaps(ctx context.Context, me *state.Session, cmd []byte)
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
-	caps := make([]uuid.UUID, 0, 16*(len(params)+1))
-	for _, capStr := range params {
+	caps := make([]uuid.UUID, 0, len(params)+1)
+	for _, capStr := range append(params, capChat.String()) {
+		if strings.TrimSpace(capStr) == "" {
+			continue
+		}
 		uid, err := uuid.Parse(capStr)
 		if err != nil {
 			return s.runtimeErr(ctx, fmt.Errorf("UUID.Parse: %w", err))
 		}
+		if slices.Contains(caps, uid) {
+			continue
+		}
+		if len(caps) == maxCaps {
+			s.Logger.InfoContext(ctx, "rejected too many capabilities", "limit", maxCaps)
+			return tocError(911)
+		}
 		caps = append(caps, uid)
 	}
-	caps = append(caps, capChat)
 
 	snac := wire.SNAC_0x02_0x04_LocateSetInfo{
 		TLVRestBlock: wire.TLVRestBlock{
@@ -1167,39 +2664,98 @@ func (s OSCARProxy) SetCaps(ctx context.Context, me *state.Session, cmd []byte)
 //		- 3 - Permit Some
 //		- 4 - Deny Some
 //
+// In addition to the TiK modes, mode 5 allows only users on the buddy list to
+// contact the user. While it's set, the permit list follows the buddy list as
+// buddies are added or removed with toc_add_buddy and toc_remove_buddy.
+//
+// Config writes for the same user are applied one at a time, in the order
+// they are received, so that the stored config always matches the last
+// write.
+//
 // Command syntax: toc_set_config <Config Info>
 func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte) string {
-	// replace curly braces with quotes so that the string can be properly
-	// split up by the space-delimited reader
-	for i, c := range cmd {
-		if c == '{' || c == '}' {
-			cmd[i] = '"'
-		}
+	info, err := parseConfigArg(cmd, "toc_set_config")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseConfigArg: %w", err))
+	}
+
+	unlock := s.ConfigLocker.Lock(me.IdentScreenName())
+	defer unlock()
+
+	if msg := s.applyConfig(ctx, me, s.parseConfigItems(ctx, me, info, " ")); msg != "" {
+		return msg
+	}
+
+	if err := s.TOCConfigStore.SetTOCConfig(me.IdentScreenName(), info); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("TOCConfigStore.SaveTOCConfig: %w", err))
+	}
+
+	return ""
+}
+
+// SetConfig2 handles the toc_set_config2 TOC command.
+//
+// This is a non-standard command that sets the config of a TOC2 client. The
+// config has the same items as the toc_set_config config, except that each
+// item type is followed by a colon instead of a space, as in the CONFIG2
+// message. A "done:" item may end the config. The config argument is enclosed
+// in quotes or curly braces, as it is for toc_set_config.
+//
+// The config is applied the same way as a toc_set_config config but is stored
+// separately, so that TOC and TOC2 clients signing on to the same account
+// don't overwrite each other's config. TOC2 clients receive it in a CONFIG2
+// message at sign on.
+//
+// Command syntax: toc_set_config2 <Config Info>
+func (s OSCARProxy) SetConfig2(ctx context.Context, me *state.Session, cmd []byte) string {
+	info, err := parseConfigArg(cmd, "toc_set_config2")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseConfigArg: %w", err))
 	}
-	cmd = bytes.TrimSpace(cmd)
 
-	var info string
-	if _, err := parseArgs(cmd, "toc_set_config", &info); err != nil {
-		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	unlock := s.ConfigLocker.Lock(me.IdentScreenName())
+	defer unlock()
+
+	if msg := s.applyConfig(ctx, me, s.parseConfigItems(ctx, me, info, ":")); msg != "" {
+		return msg
+	}
+
+	if err := s.TOCConfigStore.SetTOCConfig2(me.IdentScreenName(), info); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("TOCConfigStore.SetTOCConfig2: %w", err))
 	}
 
-	config := strings.Split(info, "\n")
+	return ""
+}
 
+// parseConfigItems splits a TOC config into its item type and value pairs.
+// sep separates each item type from its value. Items without a value, such
+// as the TOC2 "done:" item, are skipped.
+func (s OSCARProxy) parseConfigItems(ctx context.Context, me *state.Session, info string, sep string) [][2]string {
 	var cfg [][2]string
-	for _, item := range config {
-		parts := strings.Split(item, " ")
-		if len(parts) != 2 {
+	for _, item := range strings.Split(info, "\n") {
+		// the item value is everything after the first separator, which
+		// allows values such as group names to contain spaces
+		itemType, value, found := strings.Cut(strings.TrimRight(item, "\r"), sep)
+		if !found || value == "" {
 			s.Logger.InfoContext(ctx, "invalid config item", "item", item, "user", me.DisplayScreenName())
 			continue
 		}
-		cfg = append(cfg, [2]string{parts[0], parts[1]})
+		cfg = append(cfg, [2]string{itemType, value})
 	}
+	return cfg
+}
 
+// applyConfig sets the user's permit/deny mode and lists and buddy list from
+// the items of a TOC config. It returns a TOC error message if the config
+// can't be applied.
+func (s OSCARProxy) applyConfig(ctx context.Context, me *state.Session, cfg [][2]string) string {
 	mode := wire.FeedbagPDModePermitAll
+	buddiesOnly := false
 	for _, c := range cfg {
 		if c[0] != "m" {
 			continue
 		}
+		buddiesOnly = false
 		switch c[1] {
 		case "1":
 			mode = wire.FeedbagPDModePermitAll
@@ -1209,6 +2765,10 @@ func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte
 			mode = wire.FeedbagPDModePermitSome
 		case "4":
 			mode = wire.FeedbagPDModeDenySome
+		case pdModeBuddiesOnly:
+			// permit only the users on the buddy list
+			mode = wire.FeedbagPDModePermitSome
+			buddiesOnly = true
 		default:
 			return s.runtimeErr(ctx, fmt.Errorf("config: invalid mode `%s`", c[1]))
 		}
@@ -1242,14 +2802,18 @@ func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte
 			return s.runtimeErr(ctx, fmt.Errorf("PermitDenyService.AddPermListEntrie: %w", err))
 		}
 	case wire.FeedbagPDModePermitSome:
+		listType := "p"
+		if buddiesOnly {
+			listType = "b"
+		}
 		snac := wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{}
 		for _, c := range cfg {
-			if c[0] != "p" {
+			if c[0] != listType {
 				continue
 			}
 			snac.Users = append(snac.Users, struct {
 				ScreenName string `oscar:"len_prefix=uint8"`
-			}{ScreenName: c[1]})
+			}{ScreenName: state.NewIdentScreenName(c[1]).String()})
 		}
 		if err := s.PermitDenyService.AddPermListEntries(ctx, me, snac); err != nil {
 			return s.runtimeErr(ctx, fmt.Errorf("PermitDenyService.AddPermListEntrie: %w", err))
@@ -1262,7 +2826,7 @@ func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte
 			}
 			snac.Users = append(snac.Users, struct {
 				ScreenName string `oscar:"len_prefix=uint8"`
-			}{ScreenName: c[1]})
+			}{ScreenName: state.NewIdentScreenName(c[1]).String()})
 		}
 		if err := s.PermitDenyService.AddDenyListEntries(ctx, me, snac); err != nil {
 			return s.runtimeErr(ctx, fmt.Errorf("PermitDenyService.AddDenyListEntries: %w", err))
@@ -1276,17 +2840,13 @@ func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte
 		}
 		snac.Buddies = append(snac.Buddies, struct {
 			ScreenName string `oscar:"len_prefix=uint8"`
-		}{ScreenName: c[1]})
+		}{ScreenName: state.NewIdentScreenName(c[1]).String()})
 	}
 
 	if err := s.BuddyService.AddBuddies(ctx, me, snac); err != nil {
 		return s.runtimeErr(ctx, fmt.Errorf("BuddyService.AddBuddies: %w", err))
 	}
 
-	if err := s.TOCConfigStore.SetTOCConfig(me.IdentScreenName(), info); err != nil {
-		return s.runtimeErr(ctx, fmt.Errorf("TOCConfigStore.SaveTOCConfig: %w", err))
-	}
-
 	return ""
 }
 
@@ -1303,6 +2863,8 @@ func (s OSCARProxy) SetConfig(ctx context.Context, me *state.Session, cmd []byte
 //	Otherwise, they'd have to use the client.
 //
 // The fields "email" and "allow web searches" are ignored by this method.
+// Users whose accounts are unconfirmed receive ERROR:979 if the server does
+// not allow them to list themselves in the directory.
 //
 // Command syntax: toc_set_dir <info information>
 func (s OSCARProxy) SetDir(ctx context.Context, me *state.Session, cmd []byte) string {
@@ -1312,6 +2874,11 @@ func (s OSCARProxy) SetDir(ctx context.Context, me *state.Session, cmd []byte) s
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if !s.Config.TOCUnconfirmedDirListing && unconfirmed(me) {
+		s.Logger.InfoContext(ctx, "unconfirmed user is not allowed to set directory info")
+		return tocError(979, "please confirm your account to list yourself in the directory")
+	}
+
 	rawFields := strings.Split(info, ":")
 
 	var finalFields [9]string
@@ -1343,6 +2910,178 @@ func (s OSCARProxy) SetDir(ctx context.Context, me *state.Session, cmd []byte) s
 	return ""
 }
 
+// maxDirKeywords is the maximum number of directory keywords a user can set.
+const maxDirKeywords = 5
+
+// SetDirKeywords handles the toc_set_dir_keywords TOC command.
+//
+// This is a non-standard command that sets the interest keywords that other
+// users can find the user by in a directory keyword search. Keywords must be
+// chosen from the list returned by toc_get_dir_keywords and are matched
+// case-insensitively. Omitting the keywords clears them. Sending more than
+// maxDirKeywords keywords results in ERROR:911, and an unknown keyword results
+// in ERROR:911 followed by the keyword. Users whose accounts are unconfirmed
+// receive ERROR:979 if the server does not allow them to list themselves in
+// the directory.
+//
+// Command syntax: toc_set_dir_keywords [<keyword 1> [<keyword 2> [...]]]
+func (s OSCARProxy) SetDirKeywords(ctx context.Context, me *state.Session, cmd []byte) string {
+	keywords, err := parseArgs(cmd, "toc_set_dir_keywords")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	if !s.Config.TOCUnconfirmedDirListing && unconfirmed(me) {
+		s.Logger.InfoContext(ctx, "unconfirmed user is not allowed to set directory keywords")
+		return tocError(979, "please confirm your account to list yourself in the directory")
+	}
+
+	if len(keywords) > maxDirKeywords {
+		s.Logger.InfoContext(ctx, "rejected too many directory keywords", "count", len(keywords), "limit", maxDirKeywords)
+		return tocError(911)
+	}
+
+	reply, err := s.DirSearchService.KeywordListQuery(ctx, wire.SNACFrame{})
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("DirSearchService.KeywordListQuery: %w", err))
+	}
+	list, ok := reply.Body.(wire.SNAC_0x0F_0x04_KeywordListReply)
+	if !ok {
+		return s.runtimeErr(ctx, fmt.Errorf("DirSearchService.KeywordListQuery: unexpected response type %T", reply.Body))
+	}
+
+	// map keywords to the names they're stored under
+	allowed := make(map[string]string)
+	for _, item := range list.Interests {
+		if item.Type == wire.ODirKeyword {
+			allowed[strings.ToLower(item.Name)] = item.Name
+		}
+	}
+
+	snac := wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{}
+	for _, keyword := range keywords {
+		name, found := allowed[strings.ToLower(strings.TrimSpace(keyword))]
+		if !found {
+			return tocError(911, keyword)
+		}
+		snac.Append(wire.NewTLVBE(wire.ODirTLVInterest, name))
+	}
+
+	if _, err := s.LocateService.SetKeywordInfo(ctx, me, wire.SNACFrame{}, snac); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("LocateService.SetKeywordInfo: %w", err))
+	}
+
+	return ""
+}
+
+// maxIconLen is the maximum size in bytes of a buddy icon set with
+// toc_set_icon, which matches the limit enforced by the AIM clients.
+const maxIconLen = 7168
+
+// SetIcon handles the toc_set_icon TOC command.
+//
+// This is a non-standard command that sets the user's buddy icon to the
+// base64-encoded image data, or clears the icon if no data is given. Icons
+// larger than maxIconLen bytes are rejected with ERROR:911.
+//
+// The icon is set the same way as by OSCAR clients: a reference to the icon's
+// MD5 hash is saved to the user's feedbag, then the icon is uploaded to the
+// BART store. Buddies using OSCAR clients receive the icon reference with the
+// user's presence.
+//
+// Command syntax: toc_set_icon [<Base64 Icon Data>]
+func (s OSCARProxy) SetIcon(ctx context.Context, me *state.Session, cmd []byte) string {
+	params, err := parseArgs(cmd, "toc_set_icon")
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	var icon []byte
+	if len(params) > 0 {
+		icon, err = base64.StdEncoding.DecodeString(params[0])
+		if err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("base64.DecodeString: %w", err))
+		}
+		if len(icon) > maxIconLen {
+			s.Logger.InfoContext(ctx, "rejected oversized buddy icon", "len", len(icon), "limit", maxIconLen)
+			return tocError(911)
+		}
+	}
+
+	hash := wire.GetClearIconHash()
+	if len(icon) > 0 {
+		sum := md5.Sum(icon)
+		hash = sum[:]
+	}
+
+	reply, err := s.FeedbagService.Query(ctx, me, wire.SNACFrame{})
+	if err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("FeedbagService.Query: %w", err))
+	}
+	feedbag, ok := reply.Body.(wire.SNAC_0x13_0x06_FeedbagReply)
+	if !ok {
+		return s.runtimeErr(ctx, fmt.Errorf("FeedbagService.Query: unexpected response type %T", reply.Body))
+	}
+
+	item := iconFeedbagItem(feedbag.Items, hash)
+	if _, err := s.FeedbagService.UpsertItem(ctx, me, wire.SNACFrame{}, []wire.FeedbagItem{item}); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("FeedbagService.UpsertItem: %w", err))
+	}
+
+	if len(icon) == 0 {
+		return ""
+	}
+
+	// uploading the icon tells buddies about the new icon
+	snac := wire.SNAC_0x10_0x02_BARTUploadQuery{
+		Type: wire.BARTTypesBuddyIcon,
+		Data: icon,
+	}
+	if _, err := s.BARTService.UpsertItem(ctx, me, wire.SNACFrame{}, snac); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("BARTService.UpsertItem: %w", err))
+	}
+
+	return ""
+}
+
+// iconFeedbagItem returns a feedbag item that references the buddy icon with
+// the given hash. The item replaces the user's current buddy icon item if
+// there is one. Otherwise, it's assigned an item ID that's not in use in the
+// root group.
+func iconFeedbagItem(items []wire.FeedbagItem, hash []byte) wire.FeedbagItem {
+	item := wire.FeedbagItem{
+		Name:    strconv.Itoa(int(wire.BARTTypesBuddyIcon)),
+		ClassID: wire.FeedbagClassIdBart,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+					Flags: wire.BARTFlagsCustom,
+					Hash:  hash,
+				}),
+			},
+		},
+	}
+
+	inUse := make(map[uint16]bool)
+	for _, existing := range items {
+		if existing.ClassID == wire.FeedbagClassIdBart && existing.Name == item.Name {
+			item.GroupID = existing.GroupID
+			item.ItemID = existing.ItemID
+			return item
+		}
+		if existing.GroupID == 0 {
+			inUse[existing.ItemID] = true
+		}
+	}
+
+	// item ID 0 in the root group is the root group itself
+	item.ItemID = 1
+	for inUse[item.ItemID] {
+		item.ItemID++
+	}
+	return item
+}
+
 // SetIdle handles the toc_set_idle TOC command.
 //
 // From the TiK documentation:
@@ -1352,6 +3091,16 @@ func (s OSCARProxy) SetDir(ctx context.Context, me *state.Session, cmd []byte) s
 //	<idle secs> number of seconds. The server will automatically keep
 //	incrementing this number, so do not repeatedly call with new idle times.
 //
+// Negative idle times are treated as 0, and idle times are capped at the
+// longest idle time that can be reported to buddies.
+//
+// Buddies are sent an UPDATE_BUDDY with the idle time in minutes, which is
+// reported independently of the away flag, so a user who is both idle and
+// away shows both. Setting the idle time to 0 clears the idle time without
+// affecting the away flag. While the user stays idle, IdleUpdate periodically
+// re-announces the growing idle time. Users who stay idle past the
+// configured threshold are marked away by AutoAway.
+//
 // Command syntax: toc_set_idle <idle secs>
 func (s OSCARProxy) SetIdle(ctx context.Context, me *state.Session, cmd []byte) string {
 	var idleTimeStr string
@@ -1365,6 +3114,11 @@ func (s OSCARProxy) SetIdle(ctx context.Context, me *state.Session, cmd []byte)
 		return s.runtimeErr(ctx, fmt.Errorf("strconv.Atoi: %w", err))
 	}
 
+	if time < 0 || time > maxIdleSecs {
+		s.Logger.InfoContext(ctx, "clamping out-of-range idle time", "idle_secs", time)
+		time = min(max(time, 0), maxIdleSecs)
+	}
+
 	snac := wire.SNAC_0x01_0x11_OServiceIdleNotification{
 		IdleTime: uint32(time),
 	}
@@ -1375,12 +3129,183 @@ func (s OSCARProxy) SetIdle(ctx context.Context, me *state.Session, cmd []byte)
 	return ""
 }
 
+// icqStatuses maps toc_set_status arguments to ICQ user status bitmasks. The
+// combined bitmasks match what ICQ clients send for each status.
+var icqStatuses = map[string]uint32{
+	"online":    wire.OServiceUserStatusAvailable,
+	"away":      wire.OServiceUserStatusAway,
+	"na":        wire.OServiceUserStatusAway | wire.OServiceUserStatusOut,
+	"occupied":  wire.OServiceUserStatusAway | wire.OServiceUserStatusBusy,
+	"dnd":       wire.OServiceUserStatusAway | wire.OServiceUserStatusBusy | wire.OServiceUserStatusDND,
+	"ffc":       wire.OServiceUserStatusChat,
+	"invisible": wire.OServiceUserStatusInvisible,
+}
+
+// SetStatus handles the toc_set_status TOC command.
+//
+// This is a non-standard command that sets the ICQ status of a user signed on
+// with a UIN and broadcasts it to their buddies, so that ICQ clients show the
+// right status icon. Status is one of online, away, na, occupied, dnd, ffc
+// (free for chat) or invisible. Users who aren't signed on with a UIN receive
+// ERROR:911.
+//
+// Command syntax: toc_set_status <status>
+func (s OSCARProxy) SetStatus(ctx context.Context, me *state.Session, cmd []byte) string {
+	var statusStr string
+
+	if _, err := parseArgs(cmd, "toc_set_status", &statusStr); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
+	}
+
+	if me.UIN() == 0 {
+		return tocError(911, statusStr)
+	}
+
+	status, ok := icqStatuses[strings.ToLower(statusStr)]
+	if !ok {
+		return s.runtimeErr(ctx, fmt.Errorf("unknown ICQ status `%s`", statusStr))
+	}
+
+	snac := wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.OServiceUserInfoStatus, status),
+			},
+		},
+	}
+	if _, err := s.OServiceServiceBOS.SetUserInfoFields(ctx, me, wire.SNACFrame{}, snac); err != nil {
+		return s.runtimeErr(ctx, fmt.Errorf("OServiceServiceBOS.SetUserInfoFields: %w", err))
+	}
+
+	return ""
+}
+
+// maxIdleSecs is the longest idle time a user can set. Idle times are
+// reported to buddies in minutes as a 16-bit value, so longer idle times
+// can't be represented.
+const maxIdleSecs = math.MaxUint16 * 60
+
+// autoAwayMessage is the away message set on behalf of users who have been
+// idle longer than the auto-away threshold.
+const autoAwayMessage = "I am away from my computer right now."
+
+// IdleUpdate re-announces the idle time of an idle user each time tick
+// fires, so that buddies see it keep growing after the client reports it
+// once, as the TiK documentation describes for toc_set_idle. The idle time
+// is measured from when the user went idle to the time sent on tick. Users
+// who aren't idle, or whose idle time has reached the longest idle time that
+// can be reported, are left alone. It returns when ctx is done.
+func (s OSCARProxy) IdleUpdate(ctx context.Context, me *state.Session, tick <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-tick:
+			if !me.Idle() {
+				continue
+			}
+			idleSecs := int(now.Sub(me.IdleTime()).Seconds())
+			if idleSecs <= 0 || idleSecs > maxIdleSecs {
+				continue
+			}
+			snac := wire.SNAC_0x01_0x11_OServiceIdleNotification{
+				IdleTime: uint32(idleSecs),
+			}
+			if err := s.OServiceServiceBOS.IdleNotification(ctx, me, snac); err != nil {
+				return fmt.Errorf("OServiceServiceBOS.IdleNotification: %w", err)
+			}
+		}
+	}
+}
+
+// clearIdle clears the idle time of a user who is active, such as by sending
+// a message, and notifies their buddies. It's a no-op if the user isn't idle.
+func (s OSCARProxy) clearIdle(ctx context.Context, me *state.Session) error {
+	if !me.Idle() {
+		return nil
+	}
+	snac := wire.SNAC_0x01_0x11_OServiceIdleNotification{
+		IdleTime: 0,
+	}
+	if err := s.OServiceServiceBOS.IdleNotification(ctx, me, snac); err != nil {
+		return fmt.Errorf("OServiceServiceBOS.IdleNotification: %w", err)
+	}
+	return nil
+}
+
+// AutoAway sets an away message on behalf of a user who has been idle longer
+// than the configured auto-away threshold and clears it once the user becomes
+// active again. The idle state is checked each time tick fires. It returns
+// when ctx is done. It's a no-op if auto-away is disabled.
+func (s OSCARProxy) AutoAway(ctx context.Context, me *state.Session, tick <-chan time.Time) error {
+	if s.Config.TOCAutoAwayMins <= 0 {
+		return nil
+	}
+
+	isAutoAway := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+			var err error
+			if isAutoAway, err = s.updateAutoAway(ctx, me, isAutoAway); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// updateAutoAway sets or clears the auto-away message depending on how long
+// the user has been idle. isAutoAway indicates whether the current away
+// message was set automatically. It returns the updated auto-away state.
+func (s OSCARProxy) updateAutoAway(ctx context.Context, me *state.Session, isAutoAway bool) (bool, error) {
+	threshold := time.Duration(s.Config.TOCAutoAwayMins) * time.Minute
+
+	switch {
+	case isAutoAway && me.AwayMessage() != autoAwayMessage:
+		// the user replaced or removed the away message, leave it alone
+		return false, nil
+	case isAutoAway && !me.Idle():
+		if err := s.setAwayMessage(ctx, me, ""); err != nil {
+			return isAutoAway, err
+		}
+		return false, nil
+	case !isAutoAway && me.Idle() && me.AwayMessage() == "" && time.Since(me.IdleTime()) >= threshold:
+		if err := s.setAwayMessage(ctx, me, autoAwayMessage); err != nil {
+			return isAutoAway, err
+		}
+		return true, nil
+	}
+
+	return isAutoAway, nil
+}
+
+// setAwayMessage sets the user's away message and notifies their buddies.
+func (s OSCARProxy) setAwayMessage(ctx context.Context, me *state.Session, msg string) error {
+	snac := wire.SNAC_0x02_0x04_LocateSetInfo{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, msg),
+			},
+		},
+	}
+	if err := s.LocateService.SetInfo(ctx, me, snac); err != nil {
+		return fmt.Errorf("LocateService.SetInfo: %w", err)
+	}
+	return nil
+}
+
 // SetInfo handles the toc_set_info TOC command.
 //
 // From the TiK documentation:
 //
 //	Set the LOCATE user information. This is basic HTML. Remember to encode the info.
 //
+// Historical AIM versions supported different maximum profile sizes, so the
+// maximum length depends on the client version sent with toc_signon. Profiles
+// that exceed the client's limit are rejected with ERROR:911.
+//
 // Command syntax: toc_set_info <info information>
 func (s OSCARProxy) SetInfo(ctx context.Context, me *state.Session, cmd []byte) string {
 	var info string
@@ -1389,6 +3314,11 @@ func (s OSCARProxy) SetInfo(ctx context.Context, me *state.Session, cmd []byte)
 		return s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))
 	}
 
+	if limit := s.profileLenLimit(me.ClientID()); limit > 0 && len(info) > limit {
+		s.Logger.InfoContext(ctx, "rejected oversized profile", "len", len(info), "limit", limit)
+		return tocError(911)
+	}
+
 	snac := wire.SNAC_0x02_0x04_LocateSetInfo{
 		TLVRestBlock: wire.TLVRestBlock{
 			TLVList: wire.TLVList{
@@ -1403,6 +3333,31 @@ func (s OSCARProxy) SetInfo(ctx context.Context, me *state.Session, cmd []byte)
 	return ""
 }
 
+// profileLenLimit returns the maximum profile length for a client with the
+// given version string. It's the limit of the first entry in
+// TOCProfileLenLimits whose version text the client version contains, or
+// TOCMaxProfileLen if none match. A limit of 0 means no limit.
+func (s OSCARProxy) profileLenLimit(clientVersion string) int {
+	clientVersion = strings.ToLower(clientVersion)
+	for _, entry := range s.Config.TOCProfileLenLimits {
+		// version strings may contain colons (e.g. TIC:TiK), so split on the
+		// last one
+		idx := strings.LastIndex(entry, ":")
+		if idx < 0 {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(entry[idx+1:]))
+		if err != nil {
+			continue
+		}
+		version := strings.ToLower(strings.TrimSpace(entry[:idx]))
+		if version != "" && strings.Contains(clientVersion, version) {
+			return limit
+		}
+	}
+	return s.Config.TOCMaxProfileLen
+}
+
 // Signon handles the toc_signon TOC command.
 //
 // From the TiK documentation:
@@ -1424,17 +3379,70 @@ func (s OSCARProxy) SetInfo(ctx context.Context, me *state.Session, cmd []byte)
 //
 //	The Roasting String is Tic/Toc.
 //
+// Passwords are unroasted with the configured roasting string, which is
+// Tic/Toc unless a client needs a different one. Sign-ons with a password
+// that isn't a "0x"-prefixed hex string are rejected with ERROR:980.
+//
+// After the CONFIG message, the login banner configured for the client
+// version is sent, if any.
+//
+// Sign-ons for users whose buddy list is still registered from a previous
+// session are rejected with ERROR:989 if TOCRejectStaleBuddyList is set.
+//
+// TOC2 clients, such as Pidgin, sign on with toc2_signon instead. It takes
+// the same arguments as toc_signon followed by a code computed from the
+// screen name and password, which must match the code the server computes
+// from the same credentials. Sign-ons with a bad code are rejected with
+// ERROR:980. TOC2 clients are told that the server speaks TOC2.0 in the
+// SIGN_ON reply. They receive the config saved with toc_set_config2 in a
+// CONFIG2 message, or the toc_set_config config if they have never saved one.
+//
 // Command syntax: toc_signon <authorizer host> <authorizer port> <User Name> <Password> <language> <version>
+//
+// Command syntax: toc2_signon <authorizer host> <authorizer port> <User Name> <Password> <language> <version> 160 <code>
 func (s OSCARProxy) Signon(ctx context.Context, cmd []byte) (*state.Session, []string) {
-	var userName, password string
+	var userName, password, version, code string
+
+	toc2 := bytes.HasPrefix(cmd, []byte("toc2_signon"))
+	if toc2 {
+		if _, err := parseArgs(cmd, "toc2_signon", nil, nil, &userName, &password, nil, &version, nil, &code); err != nil {
+			return nil, []string{s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))}
+		}
+	} else {
+		varArgs, err := parseArgs(cmd, "toc_signon", nil, nil, &userName, &password)
+		if err != nil {
+			return nil, []string{s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))}
+		}
+		// the version is optional because some clients omit the language
+		// and version arguments
+		if len(varArgs) > 1 {
+			version = strings.TrimSpace(varArgs[1])
+		}
+	}
 
-	if _, err := parseArgs(cmd, "toc_signon", nil, nil, &userName, &password); err != nil {
-		return nil, []string{s.runtimeErr(ctx, fmt.Errorf("parseArgs: %w", err))}
+	if !s.clientAllowed(version) {
+		s.Logger.InfoContext(ctx, "rejected sign on from unsupported client", "version", version)
+		return nil, []string{tocError(989, "this client version is not supported, please upgrade your client")}
 	}
 
-	passwordHash, err := hex.DecodeString(password[2:])
+	// the roasted password is hex-encoded and prefixed with "0x"
+	hexPassword, found := strings.CutPrefix(password, "0x")
+	if !found || hexPassword == "" {
+		s.Logger.DebugContext(ctx, "login failed, malformed roasted password")
+		return nil, []string{tocError(980)}
+	}
+	passwordHash, err := hex.DecodeString(hexPassword)
 	if err != nil {
-		return nil, []string{s.runtimeErr(ctx, fmt.Errorf("hex.DecodeString: %w", err))}
+		s.Logger.DebugContext(ctx, "login failed, malformed roasted password", "err", err.Error())
+		return nil, []string{tocError(980)}
+	}
+
+	if toc2 {
+		wantCode := toc2SignonCode(state.NewIdentScreenName(userName), wire.RoastTOCPassword(passwordHash, s.Config.TOCRoastingString))
+		if code != strconv.Itoa(wantCode) {
+			s.Logger.DebugContext(ctx, "login failed, bad toc2_signon code", "code", code)
+			return nil, []string{tocError(980)}
+		}
 	}
 
 	signonFrame := wire.FLAPSignonFrame{}
@@ -1448,7 +3456,7 @@ func (s OSCARProxy) Signon(ctx context.Context, cmd []byte) (*state.Session, []s
 
 	if block.HasTag(wire.LoginTLVTagsErrorSubcode) {
 		s.Logger.DebugContext(ctx, "login failed")
-		return nil, []string{"ERROR:980"} // bad username/password
+		return nil, []string{tocError(980)} // bad username/password
 	}
 
 	authCookie, ok := block.Bytes(wire.OServiceTLVTagsLoginCookie)
@@ -1463,20 +3471,156 @@ func (s OSCARProxy) Signon(ctx context.Context, cmd []byte) (*state.Session, []s
 
 	// set chat capability so that... tk
 	sess.SetCaps([][16]byte{capChat})
+	sess.SetClientID(version)
 
-	if err := s.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName()); err != nil {
-		return nil, []string{s.runtimeErr(ctx, fmt.Errorf("BuddyListRegistry.RegisterBuddyList: %w", err))}
+	if msg := s.registerBuddyList(ctx, sess); msg != "" {
+		return nil, []string{msg}
 	}
 
 	u, err := s.TOCConfigStore.User(sess.IdentScreenName())
 	if err != nil {
 		return nil, []string{s.runtimeErr(ctx, fmt.Errorf("TOCConfigStore.User: %w", err))}
 	}
-	if u == nil {
-		return nil, []string{s.runtimeErr(ctx, fmt.Errorf("TOCConfigStore.User: user not found"))}
+
+	// a user that has never saved a TOC config (e.g. a user auto-created at
+	// sign on) gets an empty config. the config is persisted the first time
+	// the client sends toc_set_config.
+	var tocConfig string
+	configMsg := "CONFIG"
+	switch {
+	case u == nil:
+		s.Logger.DebugContext(ctx, "TOC config not found, sending empty config")
+	case toc2 && u.TOCConfig2 != "":
+		tocConfig = u.TOCConfig2
+		configMsg = "CONFIG2"
+	default:
+		tocConfig = u.TOCConfig
+	}
+
+	signOnMsg := "SIGN_ON:TOC1.0"
+	if toc2 {
+		signOnMsg = "SIGN_ON:TOC2.0"
+	}
+
+	msgs := []string{signOnMsg, fmt.Sprintf("%s:%s", configMsg, tocConfig)}
+	if banner := s.loginBanner(version); banner != "" {
+		msgs = append(msgs, banner)
 	}
 
-	return sess, []string{"SIGN_ON:TOC1.0", fmt.Sprintf("CONFIG:%s", u.TOCConfig)}
+	return sess, msgs
+}
+
+// toc2SignonCode computes the toc2_signon code for a screen name and clear
+// text password. It's derived from the first character of each. It returns 0
+// if either is empty.
+func toc2SignonCode(screenName state.IdentScreenName, password []byte) int {
+	if screenName.String() == "" || len(password) == 0 {
+		return 0
+	}
+	sn := int(screenName.String()[0]) - 96
+	pw := int(password[0]) - 96
+	a := sn*7696 + 738816
+	b := sn * 746512
+	c := pw * a
+	return c - a + b + 71665152
+}
+
+// loginBanner returns the message that shows the login banner configured for
+// a client identified by version, or an empty string if there is none. It's
+// the banner of the first TOCLoginBanners entry whose pattern matches
+// version, or TOCLoginBanner if none match. Banners that are URLs are sent as
+// GOTO_URL so that the client opens them. Other banners are sent as an IM
+// from state.SystemNoticeSender.
+func (s OSCARProxy) loginBanner(version string) string {
+	banner := s.Config.TOCLoginBanner
+	for _, entry := range s.Config.TOCLoginBanners {
+		pattern, text, found := strings.Cut(entry, "=")
+		if found && matchClientPattern(pattern, version) {
+			banner = text
+			break
+		}
+	}
+
+	banner = strings.TrimSpace(banner)
+	switch {
+	case banner == "":
+		return ""
+	case strings.HasPrefix(banner, "http://") || strings.HasPrefix(banner, "https://"):
+		return fmt.Sprintf("GOTO_URL:banner:%s", banner)
+	default:
+		return fmt.Sprintf("IM_IN:%s:F:%s", state.SystemNoticeSender, banner)
+	}
+}
+
+// registerBuddyList registers the buddy list of a user signing on. A buddy
+// list left registered by a session that was not cleanly signed out is
+// either cleared and re-registered or, if configured, causes the sign-on to
+// be rejected. It returns a TOC error message if the buddy list can't be
+// registered, in which case the session is signed out.
+func (s OSCARProxy) registerBuddyList(ctx context.Context, sess *state.Session) string {
+	err := s.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName())
+	if errors.Is(err, state.ErrBuddyListRegistered) {
+		if s.Config.TOCRejectStaleBuddyList {
+			s.Logger.InfoContext(ctx, "rejected sign on, buddy list is already registered")
+			s.AuthService.Signout(ctx, sess)
+			return tocError(989, "this screen name is already signed on, please try again later")
+		}
+		s.Logger.DebugContext(ctx, "clearing stale buddy list registration")
+		if err = s.BuddyListRegistry.UnregisterBuddyList(sess.IdentScreenName()); err == nil {
+			err = s.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName())
+		}
+	}
+	if err != nil {
+		s.AuthService.Signout(ctx, sess)
+		return s.runtimeErr(ctx, fmt.Errorf("BuddyListRegistry.RegisterBuddyList: %w", err))
+	}
+	return ""
+}
+
+// clientAllowed indicates whether a client identified by version may sign on
+// according to the configured allowed and blocked client patterns. Blocked
+// patterns take precedence over allowed patterns. All clients are allowed
+// when no patterns are configured.
+func (s OSCARProxy) clientAllowed(version string) bool {
+	for _, pattern := range s.Config.TOCBlockedClients {
+		if matchClientPattern(pattern, version) {
+			return false
+		}
+	}
+	if len(s.Config.TOCAllowedClients) == 0 {
+		return true
+	}
+	for _, pattern := range s.Config.TOCAllowedClients {
+		if matchClientPattern(pattern, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchClientPattern reports whether version matches pattern, where each '*'
+// in pattern matches any sequence of characters. Matching is
+// case-insensitive.
+func matchClientPattern(pattern, version string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	version = strings.ToLower(version)
+
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == version
+	}
+	if !strings.HasPrefix(version, parts[0]) {
+		return false
+	}
+	version = version[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(version, part)
+		if i < 0 {
+			return false
+		}
+		version = version[i+len(part):]
+	}
+	return strings.HasSuffix(version, parts[len(parts)-1])
 }
 
 // Signout terminates a TOC session. It sends departure notifications to
@@ -1489,6 +3633,10 @@ func (s OSCARProxy) Signout(ctx context.Context, me *state.Session) {
 		s.Logger.ErrorContext(ctx, "error removing buddy list entry", "err", err.Error())
 	}
 	s.AuthService.Signout(ctx, me)
+	s.IMHistory.Clear(me.IdentScreenName())
+	s.ErrorLogLimiter.Clear(ctx, s.Logger, me.IdentScreenName())
+	s.ViolationCounter.Clear(me.IdentScreenName())
+	s.WarnThrottle.Clear(me.IdentScreenName())
 }
 
 // newHTTPAuthToken creates a HMAC token for authenticating TOC HTTP requests
@@ -1502,6 +3650,31 @@ func (s OSCARProxy) newHTTPAuthToken(me state.IdentScreenName) (string, error) {
 	return hex.EncodeToString(cookie), nil
 }
 
+// httpAuthTokenLen is the length of the HMAC cookie wrapped by an HTTP auth
+// token before its padding is trimmed.
+const httpAuthTokenLen = 256
+
+// crackHTTPAuthToken validates a token created by newHTTPAuthToken and returns
+// the screen name it was issued for. The trimmed padding is restored before
+// cracking, since the trim may have also removed trailing zeros that belong
+// to the HMAC signature.
+func (s OSCARProxy) crackHTTPAuthToken(token string) (state.IdentScreenName, error) {
+	cookie, err := hex.DecodeString(token)
+	if err != nil {
+		return state.IdentScreenName{}, fmt.Errorf("hex.DecodeString: %w", err)
+	}
+	if len(cookie) == 0 || len(cookie) > httpAuthTokenLen {
+		return state.IdentScreenName{}, fmt.Errorf("invalid token length: %d", len(cookie))
+	}
+	cookie = append(cookie, make([]byte, httpAuthTokenLen-len(cookie))...)
+
+	data, err := s.CookieBaker.Crack(cookie)
+	if err != nil {
+		return state.IdentScreenName{}, fmt.Errorf("CookieBaker.Crack: %w", err)
+	}
+	return state.NewIdentScreenName(string(data)), nil
+}
+
 // parseArgs extracts arguments from a TOC command. Each positional argument is
 // assigned to its corresponding args pointer. It returns the remaining
 // arguments as varargs.
@@ -1513,18 +3686,18 @@ func parseArgs(payload []byte, cmd string, args ...*string) (varArgs []string, e
 
 	segs, err := reader.Read()
 	if err != nil {
-		return []string{}, fmt.Errorf("CSV reader error: %w", err)
+		return []string{}, fmt.Errorf("%w: CSV reader error: %w", errMalformedCmd, err)
 	}
 
 	// sanity check the command name
 	if segs[0] != cmd {
-		return []string{}, fmt.Errorf("command mismatch. expected %s, got %s", cmd, segs[0])
+		return []string{}, fmt.Errorf("%w: command mismatch. expected %s, got %s", errMalformedCmd, cmd, segs[0])
 	}
 
 	// all elements after the command are arguments
 	segs = segs[1:]
 	if len(segs) < len(args) {
-		return []string{}, fmt.Errorf("command contains fewer arguments than expected")
+		return []string{}, fmt.Errorf("%w: command contains fewer arguments than expected", errMalformedCmd)
 	}
 
 	// populate placeholder pointers with their corresponding values
@@ -1538,9 +3711,118 @@ func parseArgs(payload []byte, cmd string, args ...*string) (varArgs []string, e
 	return segs[len(args):], err
 }
 
+// parseConfigArg extracts the config argument from a toc_set_config command.
+// Unlike parseArgs, it treats the argument as a single opaque value so that
+// config items containing quotes, braces, or other punctuation are preserved.
+// The argument may be enclosed in curly braces, in which case its contents are
+// taken verbatim, or in double quotes, in which case backslash-escaped
+// characters are unescaped.
+func parseConfigArg(payload []byte, cmd string) (string, error) {
+	arg, found := bytes.CutPrefix(bytes.TrimSpace(payload), []byte(cmd))
+	if !found || (len(arg) > 0 && arg[0] != ' ') {
+		return "", fmt.Errorf("%w: command mismatch. expected %s, got %s", errMalformedCmd, cmd, payload)
+	}
+
+	arg = bytes.TrimSpace(arg)
+	if len(arg) == 0 {
+		return "", fmt.Errorf("%w: command contains fewer arguments than expected", errMalformedCmd)
+	}
+
+	var val string
+	switch {
+	case arg[0] == '{':
+		end := bytes.LastIndexByte(arg, '}')
+		if end < 1 {
+			return "", fmt.Errorf("%w: config is missing closing brace", errMalformedCmd)
+		}
+		val = string(arg[1:end])
+	case arg[0] == '"':
+		end := bytes.LastIndexByte(arg, '"')
+		if end < 1 {
+			return "", fmt.Errorf("%w: config is missing closing quote", errMalformedCmd)
+		}
+		val = unescapeArg(arg[1:end])
+	default:
+		val = unescapeArg(arg)
+	}
+
+	return strings.TrimSpace(val), nil
+}
+
+// unescapeArg removes backslash escapes from a TOC command argument.
+func unescapeArg(arg []byte) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(arg); i++ {
+		if arg[i] == '\\' && i+1 < len(arg) {
+			i++
+		}
+		sb.WriteByte(arg[i])
+	}
+	return sb.String()
+}
+
 // runtimeErr is a convenience function that logs an error and returns a TOC
 // internal server error.
 func (s OSCARProxy) runtimeErr(ctx context.Context, err error) string {
+	if errors.Is(err, errMalformedCmd) {
+		// a buggy client may send the same malformed command in a loop
+		me, _ := ctx.Value("screenName").(state.IdentScreenName)
+		s.ViolationCounter.Add(me)
+		s.ErrorLogLimiter.Log(ctx, s.Logger, me, "internal service error", "err", err.Error())
+		return cmdInternalSvcErr
+	}
 	s.Logger.ErrorContext(ctx, "internal service error", "err", err.Error())
 	return cmdInternalSvcErr
 }
+
+// tocErrCodes maps OSCAR SNAC error codes to the TOC error codes documented
+// in the TOC protocol.
+var tocErrCodes = map[uint16]int{
+	wire.ErrorCodeRateToHost:         903, // message dropped, sender exceeded the rate limit
+	wire.ErrorCodeRateToClient:       903, // message dropped, sender exceeded the rate limit
+	wire.ErrorCodeNotLoggedOn:        901, // <user> not currently available
+	wire.ErrorCodeServiceUnavailable: 981, // service temporarily unavailable
+	wire.ErrorCodeInLocalPermitDeny:  901, // <user> not currently available
+	wire.ErrorCodeTooEvilSender:      903, // message dropped, sender's warning level is too high
+	wire.ErrorCodeTooEvilReceiver:    901, // <user> not currently available
+	wire.ErrorCodeUserTempUnavail:    901, // <user> not currently available
+}
+
+// tocErrHasArg indicates which TOC error codes name the user or chat room
+// that the error pertains to.
+var tocErrHasArg = map[int]bool{
+	901: true,
+	902: true,
+	950: true,
+	960: true,
+	961: true,
+	962: true,
+}
+
+// snacErrToTOC translates an OSCAR SNAC error code into a TOC ERROR message.
+// Param arg is the user or chat room the error pertains to. It returns false
+// if the code has no TOC equivalent.
+func snacErrToTOC(code uint16, arg string) (string, bool) {
+	tocCode, ok := tocErrCodes[code]
+	if !ok {
+		return "", false
+	}
+	if tocErrHasArg[tocCode] {
+		return tocError(tocCode, arg), true
+	}
+	return tocError(tocCode), true
+}
+
+// tocError formats a TOC ERROR message with the given error code, followed
+// by args, which fill in the placeholders of the error's message on the
+// client. For example, tocError(901, "them") returns ERROR:901:them.
+//
+// TOC has no way to escape a colon in a server message, so colons within an
+// arg are replaced with spaces to keep each arg in a single field.
+func tocError(code int, args ...string) string {
+	msg := "ERROR:" + strconv.Itoa(code)
+	for _, arg := range args {
+		msg += ":" + strings.ReplaceAll(arg, ":", " ")
+	}
+	return msg
+}
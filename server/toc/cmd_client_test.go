@@ -2,15 +2,24 @@ package toc
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -21,6 +30,8 @@ func TestOSCARProxy_AddBuddy(t *testing.T) {
 		name string
 		// me is the TOC user session
 		me *state.Session
+		// cfg is the server configuration
+		cfg config.Config
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// wantMsg is the expected TOC response
@@ -34,6 +45,14 @@ func TestOSCARProxy_AddBuddy(t *testing.T) {
 			me:       newTestSession("me"),
 			givenCmd: []byte("toc_add_buddy friend1 friend2 friend3"),
 			mockParams: mockParams{
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
 				buddyParams: buddyParams{
 					addBuddiesParams: addBuddiesParams{
 						{
@@ -52,6 +71,37 @@ func TestOSCARProxy_AddBuddy(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "differently-formatted screen names resolve to the same buddy",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_add_buddy "Friend One" friendone FRIENDONE`),
+			mockParams: mockParams{
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friendone"},
+									{ScreenName: "friendone"},
+									{ScreenName: "friendone"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:     "add buddies, receive error from buddy service",
 			me:       newTestSession("me"),
@@ -75,6 +125,211 @@ func TestOSCARProxy_AddBuddy(t *testing.T) {
 			},
 			wantMsg: cmdInternalSvcErr,
 		},
+		{
+			name: "unconfirmed user adds buddies within buddy list limit",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			cfg: config.Config{
+				TOCUnconfirmedMaxBuddies: 3,
+			},
+			givenCmd: []byte("toc_add_buddy friend2 friend3"),
+			mockParams: mockParams{
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
+				buddyCounterParams: buddyCounterParams{
+					buddyCountParams: buddyCountParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							count: 1,
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend2"},
+									{ScreenName: "friend3"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "add no buddies",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			cfg: config.Config{
+				TOCUnconfirmedMaxBuddies: 3,
+			},
+			givenCmd: []byte("toc_add_buddy"),
+		},
+		{
+			name: "unconfirmed user exceeds buddy list limit",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			cfg: config.Config{
+				TOCUnconfirmedMaxBuddies: 3,
+			},
+			givenCmd: []byte("toc_add_buddy friend3 friend4"),
+			mockParams: mockParams{
+				buddyCounterParams: buddyCounterParams{
+					buddyCountParams: buddyCountParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							count: 2,
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:911",
+		},
+		{
+			name: "confirmed user is not subject to buddy list limit",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCUnconfirmedMaxBuddies: 1,
+			},
+			givenCmd: []byte("toc_add_buddy friend1 friend2"),
+			mockParams: mockParams{
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unconfirmed user buddy count lookup fails",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			cfg: config.Config{
+				TOCUnconfirmedMaxBuddies: 3,
+			},
+			givenCmd: []byte("toc_add_buddy friend1"),
+			mockParams: mockParams{
+				buddyCounterParams: buddyCounterParams{
+					buddyCountParams: buddyCountParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "buddies-only mode adds buddies to the permit list",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_add_buddy friend1 friend2"),
+			mockParams: mockParams{
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				permitDenyParams: permitDenyParams{
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "m 5\ng Buddies\nb friend0",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "add buddies, receive error from TOC config store",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_add_buddy friend1"),
+			mockParams: mockParams{
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
 		{
 			name:     "bad command",
 			givenCmd: []byte(`toc_add_buddy_bad`),
@@ -93,9 +348,34 @@ func TestOSCARProxy_AddBuddy(t *testing.T) {
 					Return(params.err)
 			}
 
+			buddyCounter := newMockBuddyCounter(t)
+			for _, params := range tc.mockParams.buddyCountParams {
+				buddyCounter.EXPECT().
+					BuddyCount(params.me).
+					Return(params.count, params.err)
+			}
+
+			pdSvc := newMockPermitDenyService(t)
+			for _, params := range tc.mockParams.addPermListEntriesParams {
+				pdSvc.EXPECT().
+					AddPermListEntries(ctx, matchSession(params.me), params.body).
+					Return(params.err)
+			}
+
+			tocCfg := newMockTOCConfigStore(t)
+			for _, params := range tc.mockParams.userParams {
+				tocCfg.EXPECT().
+					User(params.screenName).
+					Return(params.returnedUser, params.err)
+			}
+
 			svc := OSCARProxy{
-				Logger:       slog.Default(),
-				BuddyService: buddySvc,
+				Config:            tc.cfg,
+				Logger:            slog.Default(),
+				BuddyCounter:      buddyCounter,
+				BuddyService:      buddySvc,
+				PermitDenyService: pdSvc,
+				TOCConfigStore:    tocCfg,
 			}
 			msg := svc.AddBuddy(ctx, tc.me, tc.givenCmd)
 
@@ -141,6 +421,21 @@ func TestOSCARProxy_AddPermit(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "permit no buddies",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_add_permit"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:     "permit buddies, receive error from buddy service",
 			me:       newTestSession("me"),
@@ -230,6 +525,21 @@ func TestOSCARProxy_AddDeny(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "deny no buddies",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_add_deny"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:     "deny buddies, receive error from buddy service",
 			me:       newTestSession("me"),
@@ -610,6 +920,8 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 		name string
 		// me is the TOC user session
 		me *state.Session
+		// cfg is the application config
+		cfg config.Config
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// givenChatRegistry is the chat registry passed to the function
@@ -618,13 +930,19 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 		wantMsg string
 		// wantChatID is the expected chat ID
 		wantChatID int
+		// wantChatRemoved indicates whether the chat room is expected to be
+		// removed from the chat registry
+		wantChatRemoved bool
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully accept chat",
-			me:       newTestSession("me"),
+			name: "user is blocked from joining room",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomBlocks: []string{"other room:me", "Cool Room:Me"},
+			},
 			givenCmd: []byte(`toc_chat_accept 0`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
@@ -636,15 +954,12 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 				return reg
 			}(),
 			mockParams: mockParams{
-				chatNavParams:      fnNewChatNavParams(nil),
-				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
-				authParams:         fnNewAuthParams(nil),
-				oServiceChatParams: fnNewOServiceChatParams(nil),
+				chatNavParams: fnNewChatNavParams(nil),
 			},
-			wantMsg: "CHAT_JOIN:0:cool room",
+			wantMsg: "ERROR:950:cool room",
 		},
 		{
-			name:     "accept chat, receive error from chat oservice svc",
+			name:     "successfully accept chat",
 			me:       newTestSession("me"),
 			givenCmd: []byte(`toc_chat_accept 0`),
 			givenChatRegistry: func() *ChatRegistry {
@@ -660,9 +975,63 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 				chatNavParams:      fnNewChatNavParams(nil),
 				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
 				authParams:         fnNewAuthParams(nil),
-				oServiceChatParams: fnNewOServiceChatParams(io.EOF),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "CHAT_JOIN:0:cool room",
+		},
+		{
+			name: "successfully accept chat with extended join response",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatJoinExtended: true,
+			},
+			givenCmd: []byte(`toc_chat_accept 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{
+					Cookie:   "the-cookie",
+					Exchange: 4,
+					Instance: 0,
+				})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatNavParams:      fnNewChatNavParams(nil),
+				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
+			},
+			wantMsg: "CHAT_JOIN:0:cool room:4:0",
+		},
+		{
+			name:     "accept chat, receive error from chat oservice svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_accept 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{
+					Cookie:   "the-cookie",
+					Exchange: 4,
+					Instance: 0,
+				})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatNavParams:     fnNewChatNavParams(nil),
+				oServiceBOSParams: fnNewOServiceBOSParams(nil),
+				authParams: func() authParams {
+					ret := fnNewAuthParams(nil)
+					ret.signoutChatParams = signoutChatParams{
+						{
+							me: state.NewIdentScreenName("me-chat"),
+						},
+					}
+					return ret
+				}(),
+				oServiceChatParams: fnNewOServiceChatParams(io.EOF),
+			},
+			wantMsg:         cmdInternalSvcErr,
+			wantChatRemoved: true,
 		},
 		{
 			name:     "accept chat, receive error from auth svc",
@@ -721,6 +1090,51 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 			},
 			wantMsg: cmdInternalSvcErr,
 		},
+		{
+			name:     "accept chat, receive empty response from BOS oservice svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_accept 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{
+					Cookie:   "the-cookie",
+					Exchange: 4,
+					Instance: 0,
+				})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				oServiceBOSParams: func() oServiceParams {
+					ret := fnNewOServiceBOSParams(nil)
+					ret.serviceRequestParams[0].msg = wire.SNACMessage{}
+					return ret
+				}(),
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "accept chat, receive empty response from chat nav svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_accept 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{
+					Cookie:   "the-cookie",
+					Exchange: 4,
+					Instance: 0,
+				})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatNavParams: func() chatNavParams {
+					ret := fnNewChatNavParams(nil)
+					ret.requestRoomInfoParams[0].msg = wire.SNACMessage{}
+					return ret
+				}(),
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
 		{
 			name:              "chat doesn't exist",
 			givenCmd:          []byte(`toc_chat_accept 0`),
@@ -767,10 +1181,14 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 					RegisterChatSession(ctx, params.authCookie).
 					Return(params.sess, params.err)
 			}
+			for _, params := range tc.mockParams.authParams.signoutChatParams {
+				authSvc.EXPECT().SignoutChat(ctx, matchSession(params.me))
+			}
 
 			svc := OSCARProxy{
 				AuthService:         authSvc,
 				ChatNavService:      chatNavSvc,
+				Config:              tc.cfg,
 				Logger:              slog.Default(),
 				OServiceServiceBOS:  bosOServiceSvc,
 				OServiceServiceChat: chatOServiceSvc,
@@ -779,11 +1197,29 @@ func TestOSCARProxy_ChatAccept(t *testing.T) {
 
 			assert.Equal(t, tc.wantMsg, msg)
 			assert.Equal(t, tc.wantChatID, chatID)
+
+			if tc.wantChatRemoved {
+				_, found := tc.givenChatRegistry.LookupRoom(0)
+				assert.False(t, found)
+				assert.Nil(t, tc.givenChatRegistry.RetrieveSess(0))
+				for _, params := range tc.mockParams.authParams.registerChatSessionParams {
+					select {
+					case <-params.sess.Closed():
+					default:
+						t.Error("chat session was not closed")
+					}
+				}
+			}
 		})
 	}
 }
 
-func TestOSCARProxy_ChatInvite(t *testing.T) {
+func TestOSCARProxy_ChatDecline(t *testing.T) {
+	roomInfo := wire.ICBMRoomInfo{
+		Exchange: 4,
+		Cookie:   "4-0-the room",
+	}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -795,21 +1231,20 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 		givenChatRegistry *ChatRegistry
 		// wantMsg is the expected TOC response
 		wantMsg string
+		// wantRemoved indicates whether the chat room should be removed from
+		// the chat registry
+		wantRemoved bool
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully send chat invitation",
+			name:     "successfully decline chat invite",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_invite 0 "join my chat!" friend1`),
+			givenCmd: []byte(`toc_chat_decline 0`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
-				reg.Add(wire.ICBMRoomInfo{
-					Exchange: 4,
-					Cookie:   "the-cookie",
-					Instance: 0,
-				})
+				reg.AddInvite(roomInfo, ChatInvite{Inviter: "them", Cookie: 1234})
 				return reg
 			}(),
 			mockParams: mockParams{
@@ -819,23 +1254,17 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 							sender: state.NewIdentScreenName("me"),
 							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
 								ChannelID:  wire.ICBMChannelRendezvous,
-								ScreenName: "friend1",
+								ScreenName: "them",
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(0x05, wire.ICBMCh2Fragment{
-											Type:       0,
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessageCancel,
+											Cookie:     1234,
 											Capability: capChat,
 											TLVRestBlock: wire.TLVRestBlock{
 												TLVList: wire.TLVList{
-													wire.NewTLVBE(10, uint16(1)),
-													wire.NewTLVBE(12, "join my chat!"),
-													wire.NewTLVBE(13, "us-ascii"),
-													wire.NewTLVBE(14, "en"),
-													wire.NewTLVBE(10001, wire.ICBMRoomInfo{
-														Exchange: 4,
-														Cookie:   "the-cookie",
-														Instance: 0,
-													}),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, roomInfo),
 												},
 											},
 										}),
@@ -846,18 +1275,15 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 					},
 				},
 			},
+			wantRemoved: true,
 		},
 		{
-			name:     "send chat invitation, receive error from ICBM svc",
+			name:     "decline chat invite, receive error from ICBM service",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_invite 0 "join my chat!" friend1`),
+			givenCmd: []byte(`toc_chat_decline 0`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
-				reg.Add(wire.ICBMRoomInfo{
-					Exchange: 4,
-					Cookie:   "the-cookie",
-					Instance: 0,
-				})
+				reg.AddInvite(roomInfo, ChatInvite{Inviter: "them", Cookie: 1234})
 				return reg
 			}(),
 			mockParams: mockParams{
@@ -867,23 +1293,17 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 							sender: state.NewIdentScreenName("me"),
 							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
 								ChannelID:  wire.ICBMChannelRendezvous,
-								ScreenName: "friend1",
+								ScreenName: "them",
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(0x05, wire.ICBMCh2Fragment{
-											Type:       0,
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessageCancel,
+											Cookie:     1234,
 											Capability: capChat,
 											TLVRestBlock: wire.TLVRestBlock{
 												TLVList: wire.TLVList{
-													wire.NewTLVBE(10, uint16(1)),
-													wire.NewTLVBE(12, "join my chat!"),
-													wire.NewTLVBE(13, "us-ascii"),
-													wire.NewTLVBE(14, "en"),
-													wire.NewTLVBE(10001, wire.ICBMRoomInfo{
-														Exchange: 4,
-														Cookie:   "the-cookie",
-														Instance: 0,
-													}),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, roomInfo),
 												},
 											},
 										}),
@@ -895,23 +1315,40 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 					},
 				},
 			},
+			wantMsg:     cmdInternalSvcErr,
+			wantRemoved: true,
+		},
+		{
+			name:     "decline chat invite that was already accepted",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_decline 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.AddInvite(roomInfo, ChatInvite{Inviter: "them", Cookie: 1234})
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:              "send chat invitation to non-existent room",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_invite 0 "join my chat!" friend1`),
-			givenChatRegistry: NewChatRegistry(),
-			wantMsg:           cmdInternalSvcErr,
+			name:     "decline chat that has no invite",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_decline 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(roomInfo)
+				return reg
+			}(),
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "bad chat room ID",
-			givenCmd: []byte(`toc_chat_invite zero "join my chat!" friend1`),
+			name:     "chat room ID with invalid format",
+			givenCmd: []byte(`toc_chat_decline zero`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_chat_invite`),
+			givenCmd: []byte(`toc_chat_decline`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -923,206 +1360,99 @@ func TestOSCARProxy_ChatInvite(t *testing.T) {
 			icbmSvc := newMockICBMService(t)
 			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
 				icbmSvc.EXPECT().
-					ChannelMsgToHost(ctx, matchSession(params.sender), wire.SNACFrame{}, params.inBody).
-					Return(nil, params.err)
+					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
+					Return(params.result, params.err)
 			}
 
 			svc := OSCARProxy{
 				Logger:      slog.Default(),
 				ICBMService: icbmSvc,
 			}
-			msg := svc.ChatInvite(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
+			msg := svc.ChatDecline(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_ChatJoin(t *testing.T) {
-	fnNewChatNavParams := func(err error) chatNavParams {
-		ret := chatNavParams{
-			createRoomParams: createRoomParams{
-				{
-					me: state.NewIdentScreenName("me"),
-					inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
-						Exchange: 4,
-						Cookie:   "create",
-						TLVBlock: wire.TLVBlock{
-							TLVList: wire.TLVList{
-								wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
-							},
-						},
-					},
-				},
-			},
-		}
-		if err != nil {
-			ret.createRoomParams[0].err = err
-		} else {
-			ret.createRoomParams[0].msg = wire.SNACMessage{
-				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
-					TLVRestBlock: wire.TLVRestBlock{
-						TLVList: wire.TLVList{
-							wire.NewTLVBE(wire.ChatNavTLVRoomInfo, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
-								Cookie: "the-cookie",
-							}),
-						},
-					},
-				},
-			}
-		}
-		return ret
-	}
 
-	fnNewOServiceBOSParams := func(err error) oServiceParams {
-		ret := oServiceParams{
-			serviceRequestParams: serviceRequestParams{
-				{
-					me: state.NewIdentScreenName("me"),
-					bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
-						FoodGroup: wire.Chat,
-						TLVRestBlock: wire.TLVRestBlock{
-							TLVList: wire.TLVList{
-								wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
-									Cookie: "the-cookie",
-								}),
-							},
-						},
-					},
-				},
-			},
-		}
-		if err != nil {
-			ret.serviceRequestParams[0].err = err
-		} else {
-			ret.serviceRequestParams[0].msg = wire.SNACMessage{
-				Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
-					TLVRestBlock: wire.TLVRestBlock{
-						TLVList: wire.TLVList{
-							wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
-						},
-					},
-				},
+			if tc.wantRemoved {
+				_, hasRoom := tc.givenChatRegistry.LookupRoom(0)
+				assert.False(t, hasRoom)
+				_, hasInvite := tc.givenChatRegistry.LookupInvite(0)
+				assert.False(t, hasInvite)
 			}
-		}
-		return ret
-	}
-
-	fnNewAuthParams := func(err error) authParams {
-		ret := authParams{
-			registerChatSessionParams: registerChatSessionParams{
-				{
-					authCookie: []byte("chat-auth-cookie"),
-				},
-			},
-		}
-		if err != nil {
-			ret.registerChatSessionParams[0].err = err
-		} else {
-			ret.registerChatSessionParams[0].sess = newTestSession("me-chat")
-		}
-		return ret
+		})
 	}
+}
 
-	fnNewOServiceChatParams := func(err error) oServiceParams {
-		ret := oServiceParams{
-			clientOnlineParams: clientOnlineParams{
-				{
-					body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
-					me:   state.NewIdentScreenName("me-chat"),
-				},
-			},
-		}
-		if err != nil {
-			ret.clientOnlineParams[0].err = err
-		}
-		return ret
-	}
+func TestOSCARProxy_ChatGetTopic(t *testing.T) {
+	roomWithTopic := state.NewChatRoom("the room", state.NewIdentScreenName("creator"), state.PrivateExchange)
+	roomWithTopic.SetTopic("the topic")
 
 	cases := []struct {
 		// name is the unit test name
 		name string
-		// me is the TOC user session
-		me *state.Session
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// givenChatRegistry is the chat registry passed to the function
 		givenChatRegistry *ChatRegistry
 		// wantMsg is the expected TOC response
 		wantMsg string
-		// wantChatID is the expected chat ID
-		wantChatID int
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:              "successfully join chat",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
-			givenChatRegistry: NewChatRegistry(),
-			mockParams: mockParams{
-				chatNavParams:      fnNewChatNavParams(nil),
-				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
-				authParams:         fnNewAuthParams(nil),
-				oServiceChatParams: fnNewOServiceChatParams(nil),
-			},
-			wantMsg: "CHAT_JOIN:0:cool room",
-		},
-		{
-			name:              "join chat, receive error from chat oservice svc",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
-			givenChatRegistry: NewChatRegistry(),
-			mockParams: mockParams{
-				chatNavParams:      fnNewChatNavParams(nil),
-				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
-				authParams:         fnNewAuthParams(nil),
-				oServiceChatParams: fnNewOServiceChatParams(io.EOF),
-			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:              "join chat, receive error from auth svc",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
-			givenChatRegistry: NewChatRegistry(),
+			name:     "successfully get chat room topic",
+			givenCmd: []byte(`toc_chat_get_topic 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{Cookie: roomWithTopic.Cookie()})
+				return reg
+			}(),
 			mockParams: mockParams{
-				chatNavParams:     fnNewChatNavParams(nil),
-				oServiceBOSParams: fnNewOServiceBOSParams(nil),
-				authParams:        fnNewAuthParams(io.EOF),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: roomWithTopic.Cookie(),
+							room:   roomWithTopic,
+						},
+					},
+				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "CHAT_TOPIC:0:the topic",
 		},
 		{
-			name:              "join chat, receive error from BOS oservice svc",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
-			givenChatRegistry: NewChatRegistry(),
+			name:     "get chat room topic, room not found",
+			givenCmd: []byte(`toc_chat_get_topic 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{Cookie: roomWithTopic.Cookie()})
+				return reg
+			}(),
 			mockParams: mockParams{
-				chatNavParams:     fnNewChatNavParams(nil),
-				oServiceBOSParams: fnNewOServiceBOSParams(io.EOF),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: roomWithTopic.Cookie(),
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
 			},
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:              "join chat, receive error from chat nav svc",
-			me:                newTestSession("me"),
-			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			name:              "chat ID not in registry",
+			givenCmd:          []byte(`toc_chat_get_topic 0`),
 			givenChatRegistry: NewChatRegistry(),
-			mockParams: mockParams{
-				chatNavParams: fnNewChatNavParams(io.EOF),
-			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg:           cmdInternalSvcErr,
 		},
 		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_chat_join`),
+			name:     "chat room ID with invalid format",
+			givenCmd: []byte(`toc_chat_get_topic zero`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 		{
-			name:     "bad exchange number",
-			givenCmd: []byte(`toc_chat_join four "cool room"`),
+			name:     "bad command",
+			givenCmd: []byte(`toc_chat_get_topic`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -1131,47 +1461,30 @@ func TestOSCARProxy_ChatJoin(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			chatNavSvc := newMockChatNavService(t)
-			for _, params := range tc.mockParams.createRoomParams {
-				chatNavSvc.EXPECT().
-					CreateRoom(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
-					Return(params.msg, params.err)
-			}
-			bosOServiceSvc := newMockOServiceService(t)
-			for _, params := range tc.mockParams.oServiceBOSParams.serviceRequestParams {
-				bosOServiceSvc.EXPECT().
-					ServiceRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.bodyIn).
-					Return(params.msg, params.err)
-			}
-			chatOServiceSvc := newMockOServiceService(t)
-			for _, params := range tc.mockParams.oServiceChatParams.clientOnlineParams {
-				chatOServiceSvc.EXPECT().
-					ClientOnline(ctx, params.body, matchSession(params.me)).
-					Return(params.err)
-			}
-			authSvc := newMockAuthService(t)
-			for _, params := range tc.mockParams.authParams.registerChatSessionParams {
-				authSvc.EXPECT().
-					RegisterChatSession(ctx, params.authCookie).
-					Return(params.sess, params.err)
+			chatRoomManager := newMockChatRoomManager(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomManager.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.room, params.err)
 			}
 
 			svc := OSCARProxy{
-				AuthService:         authSvc,
-				ChatNavService:      chatNavSvc,
-				Logger:              slog.Default(),
-				OServiceServiceBOS:  bosOServiceSvc,
-				OServiceServiceChat: chatOServiceSvc,
+				Logger:          slog.Default(),
+				ChatRoomManager: chatRoomManager,
 			}
-			chatID, msg := svc.ChatJoin(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
+			msg := svc.ChatGetTopic(ctx, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
-			assert.Equal(t, tc.wantChatID, chatID)
 		})
 	}
 }
 
-func TestOSCARProxy_ChatLeave(t *testing.T) {
+func TestOSCARProxy_ChatSetTopic(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("me"), state.PrivateExchange)
+
+	roomWithTopic := room
+	roomWithTopic.SetTopic("the new topic")
+
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -1188,39 +1501,169 @@ func TestOSCARProxy_ChatLeave(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully leave chat",
+			name:     "successfully set chat room topic",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_leave 0`),
+			givenCmd: []byte(`toc_chat_set_topic 0 "the new topic"`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
-				reg.RegisterSess(0, newTestSession("me"))
+				reg.Add(wire.ICBMRoomInfo{Cookie: room.Cookie()})
 				return reg
 			}(),
 			mockParams: mockParams{
-				authParams: authParams{
-					signoutChatParams: signoutChatParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
 						{
-							me: state.NewIdentScreenName("me"),
+							cookie: room.Cookie(),
+							room:   room,
+						},
+					},
+					setChatRoomTopicParams: setChatRoomTopicParams{
+						{
+							cookie: room.Cookie(),
+							topic:  "the new topic",
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					relayToAllExceptParams: relayToAllExceptParams{
+						{
+							cookie: room.Cookie(),
+							msg: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatRoomInfoUpdate,
+								},
+								Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       roomWithTopic.Exchange(),
+									Cookie:         roomWithTopic.Cookie(),
+									InstanceNumber: roomWithTopic.InstanceNumber(),
+									DetailLevel:    roomWithTopic.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: roomWithTopic.TLVList(state.NewChatExchanges(config.Config{}).Settings(roomWithTopic.Exchange())),
+									},
+								},
+							},
 						},
 					},
 				},
 			},
-			wantMsg: "CHAT_LEFT:0",
+			wantMsg: "",
 		},
 		{
-			name:     "chat room ID with invalid format",
-			givenCmd: []byte(`toc_chat_leave zero`),
-			wantMsg:  cmdInternalSvcErr,
+			name:     "clear chat room topic",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_set_topic 0 ""`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{Cookie: roomWithTopic.Cookie()})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: roomWithTopic.Cookie(),
+							room:   roomWithTopic,
+						},
+					},
+					setChatRoomTopicParams: setChatRoomTopicParams{
+						{
+							cookie: roomWithTopic.Cookie(),
+							topic:  "",
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					relayToAllExceptParams: relayToAllExceptParams{
+						{
+							cookie: room.Cookie(),
+							msg: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatRoomInfoUpdate,
+								},
+								Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       room.Exchange(),
+									Cookie:         room.Cookie(),
+									InstanceNumber: room.InstanceNumber(),
+									DetailLevel:    room.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: append(room.TLVList(state.NewChatExchanges(config.Config{}).Settings(room.Exchange())), wire.NewTLVBE(wire.ChatRoomTLVTopic, "")),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "",
 		},
 		{
-			name:              "missing chat session",
-			givenCmd:          []byte(`toc_chat_leave 0`),
+			name:     "set chat room topic, not the room creator",
+			me:       newTestSession("someone-else"),
+			givenCmd: []byte(`toc_chat_set_topic 0 "the new topic"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{Cookie: room.Cookie()})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							room:   room,
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:950:the room",
+		},
+		{
+			name:     "set chat room topic, store error",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_set_topic 0 "the new topic"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.Add(wire.ICBMRoomInfo{Cookie: room.Cookie()})
+				return reg
+			}(),
+			mockParams: mockParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							room:   room,
+						},
+					},
+					setChatRoomTopicParams: setChatRoomTopicParams{
+						{
+							cookie: room.Cookie(),
+							topic:  "the new topic",
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:              "chat ID not in registry",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_set_topic 0 "the new topic"`),
 			givenChatRegistry: NewChatRegistry(),
 			wantMsg:           cmdInternalSvcErr,
 		},
+		{
+			name:     "chat room ID with invalid format",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_set_topic zero "the new topic"`),
+			wantMsg:  cmdInternalSvcErr,
+		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_chat_leave`),
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_chat_set_topic`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -1229,23 +1672,37 @@ func TestOSCARProxy_ChatLeave(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			authSvc := newMockAuthService(t)
-			for _, params := range tc.mockParams.signoutChatParams {
-				authSvc.EXPECT().SignoutChat(ctx, matchSession(params.me))
+			chatRoomManager := newMockChatRoomManager(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomManager.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.room, params.err)
+			}
+			for _, params := range tc.mockParams.setChatRoomTopicParams {
+				chatRoomManager.EXPECT().
+					SetChatRoomTopic(params.cookie, params.topic).
+					Return(params.err)
+			}
+
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			for _, params := range tc.mockParams.relayToAllExceptParams {
+				chatMessageRelayer.EXPECT().
+					RelayToAllExcept(ctx, params.cookie, params.except, params.msg)
 			}
 
 			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				AuthService: authSvc,
+				Logger:             slog.Default(),
+				ChatMessageRelayer: chatMessageRelayer,
+				ChatRoomManager:    chatRoomManager,
 			}
-			msg := svc.ChatLeave(ctx, tc.givenChatRegistry, tc.givenCmd)
+			msg := svc.ChatSetTopic(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_ChatSend(t *testing.T) {
+func TestOSCARProxy_ChatInvite(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -1262,81 +1719,91 @@ func TestOSCARProxy_ChatSend(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully send chat message",
+			name:     "successfully send chat invitation",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenCmd: []byte(`toc_chat_invite 0 "join my chat!" friend1`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
-				reg.RegisterSess(0, newTestSession("me"))
+				reg.Add(wire.ICBMRoomInfo{
+					Exchange: 4,
+					Cookie:   "the-cookie",
+					Instance: 0,
+				})
 				return reg
 			}(),
 			mockParams: mockParams{
-				chatParams: chatParams{
-					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
 						{
 							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
-								Channel: wire.ICBMChannelMIME,
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "friend1",
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
-										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
-										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
-										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
-											TLVList: wire.TLVList{
-												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+										wire.NewTLVBE(0x05, wire.ICBMCh2Fragment{
+											Type:       0,
+											Capability: capChat,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(10, uint16(1)),
+													wire.NewTLVBE(12, "join my chat!"),
+													wire.NewTLVBE(13, "us-ascii"),
+													wire.NewTLVBE(14, "en"),
+													wire.NewTLVBE(10001, wire.ICBMRoomInfo{
+														Exchange: 4,
+														Cookie:   "the-cookie",
+														Instance: 0,
+													}),
+												},
 											},
 										}),
 									},
 								},
 							},
-							result: &wire.SNACMessage{
-								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
-									Channel: wire.ICBMChannelMIME,
-									TLVRestBlock: wire.TLVRestBlock{
-										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.ChatTLVSenderInformation,
-												newTestSession("me").TLVUserInfo()),
-											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
-											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
-												TLVList: wire.TLVList{
-													wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
-												},
-											}),
-										},
-									},
-								},
-							},
 						},
 					},
 				},
 			},
-			wantMsg: "CHAT_IN:0:me:F:Hello world!",
 		},
 		{
-			name:     "send chat message, receive error from chat svc",
+			name:     "send chat invitation, receive error from ICBM svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenCmd: []byte(`toc_chat_invite 0 "join my chat!" friend1`),
 			givenChatRegistry: func() *ChatRegistry {
 				reg := NewChatRegistry()
-				reg.RegisterSess(0, newTestSession("me"))
+				reg.Add(wire.ICBMRoomInfo{
+					Exchange: 4,
+					Cookie:   "the-cookie",
+					Instance: 0,
+				})
 				return reg
 			}(),
 			mockParams: mockParams{
-				chatParams: chatParams{
-					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
 						{
 							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
-								Channel: wire.ICBMChannelMIME,
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "friend1",
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
-										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
-										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
-										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
-											TLVList: wire.TLVList{
-												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+										wire.NewTLVBE(0x05, wire.ICBMCh2Fragment{
+											Type:       0,
+											Capability: capChat,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(10, uint16(1)),
+													wire.NewTLVBE(12, "join my chat!"),
+													wire.NewTLVBE(13, "us-ascii"),
+													wire.NewTLVBE(14, "en"),
+													wire.NewTLVBE(10001, wire.ICBMRoomInfo{
+														Exchange: 4,
+														Cookie:   "the-cookie",
+														Instance: 0,
+													}),
+												},
 											},
 										}),
 									},
@@ -1350,93 +1817,20 @@ func TestOSCARProxy_ChatSend(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "send chat message, receive nil response from chat svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
-			givenChatRegistry: func() *ChatRegistry {
-				reg := NewChatRegistry()
-				reg.RegisterSess(0, newTestSession("me"))
-				return reg
-			}(),
-			mockParams: mockParams{
-				chatParams: chatParams{
-					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
-						{
-							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
-								Channel: wire.ICBMChannelMIME,
-								TLVRestBlock: wire.TLVRestBlock{
-									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
-										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
-										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
-										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
-											TLVList: wire.TLVList{
-												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
-											},
-										}),
-									},
-								},
-							},
-							result: nil,
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "send chat message, receive unexpected response from chat svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
-			givenChatRegistry: func() *ChatRegistry {
-				reg := NewChatRegistry()
-				reg.RegisterSess(0, newTestSession("me"))
-				return reg
-			}(),
-			mockParams: mockParams{
-				chatParams: chatParams{
-					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
-						{
-							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
-								Channel: wire.ICBMChannelMIME,
-								TLVRestBlock: wire.TLVRestBlock{
-									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
-										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
-										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
-										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
-											TLVList: wire.TLVList{
-												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
-											},
-										}),
-									},
-								},
-							},
-							result: &wire.SNACMessage{
-								Body: wire.SNACError{},
-							},
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
+			name:              "send chat invitation to non-existent room",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_invite 0 "join my chat!" friend1`),
+			givenChatRegistry: NewChatRegistry(),
+			wantMsg:           cmdInternalSvcErr,
 		},
 		{
-			name:     "chat room ID with invalid format",
-			givenCmd: []byte(`toc_chat_send zero "Hello world!"`),
+			name:     "bad chat room ID",
+			givenCmd: []byte(`toc_chat_invite zero "join my chat!" friend1`),
 			wantMsg:  cmdInternalSvcErr,
 		},
-		{
-			name:              "missing chat session",
-			givenCmd:          []byte(`toc_chat_send 0 "Hello world!"`),
-			givenChatRegistry: NewChatRegistry(),
-			wantMsg:           cmdInternalSvcErr,
-		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_chat_send`),
+			givenCmd: []byte(`toc_chat_invite`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -1445,215 +1839,261 @@ func TestOSCARProxy_ChatSend(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			chatSvc := newMockChatService(t)
-			for _, params := range tc.mockParams.channelMsgToHostParamsChat {
-				chatSvc.EXPECT().
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
+				icbmSvc.EXPECT().
 					ChannelMsgToHost(ctx, matchSession(params.sender), wire.SNACFrame{}, params.inBody).
-					Return(params.result, params.err)
+					Return(nil, params.err)
 			}
 
 			svc := OSCARProxy{
 				Logger:      slog.Default(),
-				ChatService: chatSvc,
+				ICBMService: icbmSvc,
 			}
-			msg := svc.ChatSend(ctx, tc.givenChatRegistry, tc.givenCmd)
+			msg := svc.ChatInvite(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_Evil(t *testing.T) {
+func TestOSCARProxy_ChatJoin(t *testing.T) {
+	fnNewChatNavParams := func(err error) chatNavParams {
+		ret := chatNavParams{
+			createRoomParams: createRoomParams{
+				{
+					me: state.NewIdentScreenName("me"),
+					inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+						Exchange: 4,
+						Cookie:   "create",
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
+							},
+						},
+					},
+				},
+			},
+		}
+		if err != nil {
+			ret.createRoomParams[0].err = err
+		} else {
+			ret.createRoomParams[0].msg = wire.SNACMessage{
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatNavTLVRoomInfo, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "the-cookie",
+							}),
+						},
+					},
+				},
+			}
+		}
+		return ret
+	}
+
+	fnNewOServiceBOSParams := func(err error) oServiceParams {
+		ret := oServiceParams{
+			serviceRequestParams: serviceRequestParams{
+				{
+					me: state.NewIdentScreenName("me"),
+					bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+						FoodGroup: wire.Chat,
+						TLVRestBlock: wire.TLVRestBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+									Cookie: "the-cookie",
+								}),
+							},
+						},
+					},
+				},
+			},
+		}
+		if err != nil {
+			ret.serviceRequestParams[0].err = err
+		} else {
+			ret.serviceRequestParams[0].msg = wire.SNACMessage{
+				Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
+						},
+					},
+				},
+			}
+		}
+		return ret
+	}
+
+	fnNewAuthParams := func(err error) authParams {
+		ret := authParams{
+			registerChatSessionParams: registerChatSessionParams{
+				{
+					authCookie: []byte("chat-auth-cookie"),
+				},
+			},
+		}
+		if err != nil {
+			ret.registerChatSessionParams[0].err = err
+		} else {
+			ret.registerChatSessionParams[0].sess = newTestSession("me-chat")
+		}
+		return ret
+	}
+
+	fnNewOServiceChatParams := func(err error) oServiceParams {
+		ret := oServiceParams{
+			clientOnlineParams: clientOnlineParams{
+				{
+					body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+					me:   state.NewIdentScreenName("me-chat"),
+				},
+			},
+		}
+		if err != nil {
+			ret.clientOnlineParams[0].err = err
+		}
+		return ret
+	}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
+		// cfg is the application config
+		cfg config.Config
 		// givenCmd is the TOC command
 		givenCmd []byte
+		// givenChatRegistry is the chat registry passed to the function
+		givenChatRegistry *ChatRegistry
 		// wantMsg is the expected TOC response
 		wantMsg string
+		// wantChatID is the expected chat ID
+		wantChatID int
+		// wantChatRemoved indicates whether the chat room is expected to be
+		// removed from the chat registry
+		wantChatRemoved bool
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully warn normally",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them norm`),
-			mockParams: mockParams{
-				icbmParams: icbmParams{
-					evilRequestParams: evilRequestParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
-								SendAs:     0,
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name:     "successfully warn anonymously",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them anon`),
-			mockParams: mockParams{
-				icbmParams: icbmParams{
-					evilRequestParams: evilRequestParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
-								SendAs:     1,
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
-							},
-						},
-					},
-				},
+			name: "user is blocked from joining room",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomBlocks: []string{"other room:me", "Cool Room:Me"},
 			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			wantMsg:           "ERROR:950:cool room",
 		},
 		{
-			name:     "warn, receive error from ICBM service",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them anon`),
+			name:              "successfully join chat",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					evilRequestParams: evilRequestParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
-								SendAs:     1,
-								ScreenName: "them",
-							},
-							err: io.EOF,
-						},
-					},
-				},
+				chatNavParams:      fnNewChatNavParams(nil),
+				oServiceBOSParams:  fnNewOServiceBOSParams(nil),
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "CHAT_JOIN:0:cool room",
 		},
 		{
-			name:     "warn, receive snac err",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them anon`),
+			name:              "create chat room, exceed creation limit",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					evilRequestParams: evilRequestParams{
+				chatNavParams: chatNavParams{
+					createRoomParams: createRoomParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
-								SendAs:     1,
-								ScreenName: "them",
+							inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "create",
+								TLVBlock: wire.TLVBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
+									},
+								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNACError{},
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeRequestDenied,
+								},
 							},
 						},
 					},
 				},
 			},
+			wantMsg: "ERROR:950:cool room",
 		},
 		{
-			name:     "warn, ICBM svc returns unexpected snac type",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them anon`),
+			name:              "join chat room on unsupported exchange",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					evilRequestParams: evilRequestParams{
+				chatNavParams: chatNavParams{
+					createRoomParams: createRoomParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
-								SendAs:     1,
-								ScreenName: "them",
+							inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "create",
+								TLVBlock: wire.TLVBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
+									},
+								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{},
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotSupportedByHost,
+								},
 							},
 						},
 					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "warn with incorrect type",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_evil them blah`),
-			wantMsg:  cmdInternalSvcErr,
-		},
-		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_evil`),
-			wantMsg:  cmdInternalSvcErr,
+			wantMsg: "ERROR:950:cool room",
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			icbmSvc := newMockICBMService(t)
-			for _, params := range tc.mockParams.evilRequestParams {
-				icbmSvc.EXPECT().
-					EvilRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
-					Return(params.msg, params.err)
-			}
-
-			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				ICBMService: icbmSvc,
-			}
-			msg := svc.Evil(ctx, tc.me, tc.givenCmd)
-
-			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_ChangePassword(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// me is the TOC user session
-		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-	}{
 		{
-			name:     "successfully change password",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass newpass"),
+			name: "successfully join next instance of full room",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatNavParams: chatNavParams{
+					createRoomParams: createRoomParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
-								TLVRestBlock: wire.TLVRestBlock{
+							inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "create",
+								TLVBlock: wire.TLVBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "newpass"),
+										wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
 									},
 								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
-									Permissions: wire.AdminInfoPermissionsReadWrite,
-									TLVBlock: wire.TLVBlock{
+								Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+									TLVRestBlock: wire.TLVRestBlock{
 										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
+											wire.NewTLVBE(wire.ChatNavTLVRoomInfo, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+												Exchange: 4,
+												Cookie:   "4-0-cool room",
+											}),
 										},
 									},
 								},
@@ -1661,33 +2101,45 @@ func TestOSCARProxy_ChangePassword(t *testing.T) {
 						},
 					},
 				},
-			},
-			wantMsg: "ADMIN_PASSWD_STATUS:0",
-		},
-		{
-			name:     "change password - invalid password length",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass np"),
-			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "4-0-cool room",
+							room:   state.NewChatRoom("cool room", state.NewIdentScreenName("creator"), 4),
+						},
+					},
+				},
+				chatOccupantCounterParams: chatOccupantCounterParams{
+					occupantCountParams: occupantCountParams{
+						{
+							cookie: "4-0-cool room",
+							count:  int(state.NewChatExchanges(config.Config{}).Settings(4).MaxOccupancy),
+						},
+						{
+							cookie: "4-1-cool room",
+							count:  0,
+						},
+					},
+				},
+				oServiceBOSParams: oServiceParams{
+					serviceRequestParams: serviceRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+							bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+								FoodGroup: wire.Chat,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "np"),
+										wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+											Cookie: "4-1-cool room",
+										}),
 									},
 								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
-									Permissions: wire.AdminInfoPermissionsReadWrite,
-									TLVBlock: wire.TLVBlock{
+								Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+									TLVRestBlock: wire.TLVRestBlock{
 										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
-											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorInvalidPasswordLength),
+											wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
 										},
 									},
 								},
@@ -1695,33 +2147,56 @@ func TestOSCARProxy_ChangePassword(t *testing.T) {
 						},
 					},
 				},
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: "ERROR:911",
+			wantMsg: "CHAT_JOIN:0:cool room (1)",
 		},
 		{
-			name:     "change password - incorrect password",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
+			name: "join room with replication, first instance has space",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "the-cookie",
+							room:   state.NewChatRoom("cool room", state.NewIdentScreenName("creator"), 4),
+						},
+					},
+				},
+				chatOccupantCounterParams: chatOccupantCounterParams{
+					occupantCountParams: occupantCountParams{
+						{
+							cookie: "4-0-cool room",
+							count:  1,
+						},
+					},
+				},
+				oServiceBOSParams: oServiceParams{
+					serviceRequestParams: serviceRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+							bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+								FoodGroup: wire.Chat,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+										wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+											Cookie: "4-0-cool room",
+										}),
 									},
 								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
-									Permissions: wire.AdminInfoPermissionsReadWrite,
-									TLVBlock: wire.TLVBlock{
+								Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+									TLVRestBlock: wire.TLVRestBlock{
 										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
-											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorValidatePassword),
+											wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
 										},
 									},
 								},
@@ -1729,33 +2204,42 @@ func TestOSCARProxy_ChangePassword(t *testing.T) {
 						},
 					},
 				},
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: "ERROR:912",
+			wantMsg: "CHAT_JOIN:0:cool room",
 		},
 		{
-			name:     "change password - catch-all error response",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
+			name: "extended join response reports the next instance of full room",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatJoinExtended:    true,
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatNavParams: chatNavParams{
+					createRoomParams: createRoomParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
-								TLVRestBlock: wire.TLVRestBlock{
+							inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "create",
+								TLVBlock: wire.TLVBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+										wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
 									},
 								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
-									Permissions: wire.AdminInfoPermissionsReadWrite,
-									TLVBlock: wire.TLVBlock{
+								Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+									TLVRestBlock: wire.TLVRestBlock{
 										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
-											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorAllOtherErrors),
+											wire.NewTLVBE(wire.ChatNavTLVRoomInfo, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+												Exchange: 4,
+												Cookie:   "4-0-cool room",
+											}),
 										},
 									},
 								},
@@ -1763,159 +2247,266 @@ func TestOSCARProxy_ChangePassword(t *testing.T) {
 						},
 					},
 				},
-			},
-			wantMsg: "ERROR:913",
-		},
-		{
-			name:     "change password - runtime error from admin svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
-			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "4-0-cool room",
+							room:   state.NewChatRoom("cool room", state.NewIdentScreenName("creator"), 4),
+						},
+					},
+				},
+				chatOccupantCounterParams: chatOccupantCounterParams{
+					occupantCountParams: occupantCountParams{
+						{
+							cookie: "4-0-cool room",
+							count:  int(state.NewChatExchanges(config.Config{}).Settings(4).MaxOccupancy),
+						},
+						{
+							cookie: "4-1-cool room",
+							count:  0,
+						},
+					},
+				},
+				oServiceBOSParams: oServiceParams{
+					serviceRequestParams: serviceRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+							bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+								FoodGroup: wire.Chat,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+										wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+											Cookie: "4-1-cool room",
+										}),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
+										},
 									},
 								},
 							},
-							err: io.EOF,
 						},
 					},
 				},
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "CHAT_JOIN:0:cool room:4:1",
 		},
 		{
-			name:     "change password - unexpected response from admin svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
+			name: "join room with replication, every instance is full",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				adminParams: adminParams{
-					infoChangeRequestParams: infoChangeRequestParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "the-cookie",
+							room:   state.NewChatRoom("cool room", state.NewIdentScreenName("creator"), 4),
+						},
+					},
+				},
+				chatOccupantCounterParams: chatOccupantCounterParams{
+					// only the first maxChatRoomInstances instances are
+					// checked
+					occupantCountParams: func() occupantCountParams {
+						params := occupantCountParams{}
+						for i := 0; i < maxChatRoomInstances; i++ {
+							params = append(params, struct {
+								cookie string
+								count  int
+							}{
+								cookie: fmt.Sprintf("4-%d-cool room", i),
+								count:  int(state.NewChatExchanges(config.Config{}).Settings(4).MaxOccupancy),
+							})
+						}
+						return params
+					}(),
+				},
+			},
+			wantMsg: "ERROR:950:cool room",
+		},
+		{
+			name: "extended join response reports the first instance of room",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatJoinExtended:    true,
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			mockParams: mockParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "the-cookie",
+							room:   state.NewChatRoom("cool room", state.NewIdentScreenName("creator"), 4),
+						},
+					},
+				},
+				chatOccupantCounterParams: chatOccupantCounterParams{
+					occupantCountParams: occupantCountParams{
+						{
+							cookie: "4-0-cool room",
+							count:  1,
+						},
+					},
+				},
+				oServiceBOSParams: oServiceParams{
+					serviceRequestParams: serviceRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+							bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+								FoodGroup: wire.Chat,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
-										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+										wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+											Cookie: "4-0-cool room",
+										}),
 									},
 								},
 							},
 							msg: wire.SNACMessage{
-								Body: wire.SNACError{},
+								Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
+										},
+									},
+								},
 							},
 						},
 					},
 				},
+				authParams:         fnNewAuthParams(nil),
+				oServiceChatParams: fnNewOServiceChatParams(nil),
 			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_change_passwd`),
-			wantMsg:  cmdInternalSvcErr,
+			wantMsg: "CHAT_JOIN:0:cool room:4:0",
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			adminSvc := newMockAdminService(t)
-			for _, params := range tc.mockParams.infoChangeRequestParams {
-				adminSvc.EXPECT().
-					InfoChangeRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
-					Return(params.msg, params.err)
-			}
-
-			svc := OSCARProxy{
-				Logger:       slog.Default(),
-				AdminService: adminSvc,
-			}
-			msg := svc.ChangePassword(ctx, tc.me, tc.givenCmd)
-
-			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_GetDirSearchURL(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// me is the TOC user session
-		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-	}{
 		{
-			name:     "successfully request user info",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_dir_search "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email"`),
+			name: "join room with replication, receive error from chat room manager",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatRoomReplication: true,
+			},
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				chatRoomManagerParams: chatRoomManagerParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
 						{
-							data:       []byte("me"),
-							returnData: []byte("monster"),
+							cookie: "the-cookie",
+							err:    io.EOF,
 						},
 					},
 				},
 			},
-			wantMsg: "GOTO_URL:search results:dir_search?city=city&cookie=6d6f6e73746572&country=country&email=email&first_name=first+name&last_name=last+name&maiden_name=maiden+name&middle_name=middle+name&state=state",
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "successfully request user info by keywords",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_dir_search ::::::::::"searchkw"`),
+			name:              "join chat, receive error from chat oservice svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				chatNavParams:     fnNewChatNavParams(nil),
+				oServiceBOSParams: fnNewOServiceBOSParams(nil),
+				authParams: func() authParams {
+					ret := fnNewAuthParams(nil)
+					ret.signoutChatParams = signoutChatParams{
 						{
-							data:       []byte("me"),
-							returnData: []byte("monster"),
+							me: state.NewIdentScreenName("me-chat"),
 						},
-					},
-				},
+					}
+					return ret
+				}(),
+				oServiceChatParams: fnNewOServiceChatParams(io.EOF),
 			},
-			wantMsg: "GOTO_URL:search results:dir_search?cookie=6d6f6e73746572&keyword=searchkw",
+			wantMsg:         cmdInternalSvcErr,
+			wantChatRemoved: true,
 		},
 		{
-			name:     "request user info with too many params",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_dir_search ::::::::::::::::::::"searchkw"`),
-			wantMsg:  cmdInternalSvcErr,
+			name:              "join chat, receive error from auth svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			mockParams: mockParams{
+				chatNavParams:     fnNewChatNavParams(nil),
+				oServiceBOSParams: fnNewOServiceBOSParams(nil),
+				authParams:        fnNewAuthParams(io.EOF),
+			},
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "request user info, get cookie issue error",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_dir_search them`),
+			name:              "join chat, receive error from BOS oservice svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
-						{
-							data:      []byte("me"),
-							returnErr: io.EOF,
-						},
-					},
-				},
+				chatNavParams:     fnNewChatNavParams(nil),
+				oServiceBOSParams: fnNewOServiceBOSParams(io.EOF),
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:              "join chat, receive error from chat nav svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			mockParams: mockParams{
+				chatNavParams: fnNewChatNavParams(io.EOF),
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:              "join chat, receive empty response from BOS oservice svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			mockParams: mockParams{
+				chatNavParams: fnNewChatNavParams(nil),
+				oServiceBOSParams: func() oServiceParams {
+					ret := fnNewOServiceBOSParams(nil)
+					ret.serviceRequestParams[0].msg = wire.SNACMessage{}
+					return ret
+				}(),
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:              "join chat, receive empty response from chat nav svc",
+			me:                newTestSession("me"),
+			givenCmd:          []byte(`toc_chat_join 4 "cool room"`),
+			givenChatRegistry: NewChatRegistry(),
+			mockParams: mockParams{
+				chatNavParams: func() chatNavParams {
+					ret := fnNewChatNavParams(nil)
+					ret.createRoomParams[0].msg = wire.SNACMessage{}
+					return ret
+				}(),
 			},
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_dir_search`),
+			givenCmd: []byte(`toc_chat_join`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad exchange number",
+			givenCmd: []byte(`toc_chat_join four "cool room"`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -1924,74 +2515,187 @@ func TestOSCARProxy_GetDirSearchURL(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			cookieBaker := newMockCookieBaker(t)
-			for _, params := range tc.mockParams.issueParams {
-				cookieBaker.EXPECT().
-					Issue(params.data).
-					Return(params.returnData, params.returnErr)
+			chatNavSvc := newMockChatNavService(t)
+			for _, params := range tc.mockParams.createRoomParams {
+				chatNavSvc.EXPECT().
+					CreateRoom(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+			bosOServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.serviceRequestParams {
+				bosOServiceSvc.EXPECT().
+					ServiceRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.bodyIn).
+					Return(params.msg, params.err)
+			}
+			chatOServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceChatParams.clientOnlineParams {
+				chatOServiceSvc.EXPECT().
+					ClientOnline(ctx, params.body, matchSession(params.me)).
+					Return(params.err)
+			}
+			authSvc := newMockAuthService(t)
+			for _, params := range tc.mockParams.authParams.registerChatSessionParams {
+				authSvc.EXPECT().
+					RegisterChatSession(ctx, params.authCookie).
+					Return(params.sess, params.err)
+			}
+			for _, params := range tc.mockParams.authParams.signoutChatParams {
+				authSvc.EXPECT().SignoutChat(ctx, matchSession(params.me))
 			}
 
-			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				CookieBaker: cookieBaker,
+			chatRoomMgr := newMockChatRoomManager(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomMgr.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.room, params.err)
+			}
+			occupantCounter := newMockChatOccupantCounter(t)
+			for _, params := range tc.mockParams.occupantCountParams {
+				occupantCounter.EXPECT().
+					OccupantCount(params.cookie).
+					Return(params.count)
 			}
-			msg := svc.GetDirSearchURL(ctx, tc.me, tc.givenCmd)
 
-			assert.Equal(t, tc.wantMsg, msg)
+			svc := OSCARProxy{
+				AuthService:         authSvc,
+				ChatNavService:      chatNavSvc,
+				ChatOccupantCounter: occupantCounter,
+				ChatRoomManager:     chatRoomMgr,
+				Config:              tc.cfg,
+				Logger:              slog.Default(),
+				OServiceServiceBOS:  bosOServiceSvc,
+				OServiceServiceChat: chatOServiceSvc,
+			}
+			chatID, msg := svc.ChatJoin(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+			assert.Equal(t, tc.wantChatID, chatID)
+
+			if tc.wantChatRemoved {
+				_, found := tc.givenChatRegistry.LookupRoom(0)
+				assert.False(t, found)
+				assert.Nil(t, tc.givenChatRegistry.RetrieveSess(0))
+				for _, params := range tc.mockParams.authParams.registerChatSessionParams {
+					select {
+					case <-params.sess.Closed():
+					default:
+						t.Error("chat session was not closed")
+					}
+				}
+			}
 		})
 	}
 }
 
-func TestOSCARProxy_GetDirURL(t *testing.T) {
+func TestOSCARProxy_autoJoinRooms(t *testing.T) {
+	fnNewChatNavParams := func(roomName string, err error) createRoomParams {
+		ret := createRoomParams{
+			{
+				me: state.NewIdentScreenName("me"),
+				inBody: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange: 4,
+					Cookie:   "create",
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, roomName),
+						},
+					},
+				},
+				err: err,
+			},
+		}
+		if err == nil {
+			ret[0].msg = wire.SNACMessage{
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatNavTLVRoomInfo, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+								Exchange: 4,
+								Cookie:   "lobby-cookie",
+							}),
+						},
+					},
+				},
+			}
+		}
+		return ret
+	}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
+		// cfg is the application config
+		cfg config.Config
+		// wantMsgs are the expected messages sent to the client
+		wantMsgs []string
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully request user dir info",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_get_dir them`),
+			name: "join auto-join rooms, skipping rooms that can't be joined",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCAutoJoinRooms:  []string{"Lobby", "Welcome", "Gone"},
+				TOCChatRoomBlocks: []string{"Welcome:me"},
+			},
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				chatNavParams: chatNavParams{
+					createRoomParams: append(
+						fnNewChatNavParams("Lobby", nil),
+						fnNewChatNavParams("Gone", io.EOF)...,
+					),
+				},
+				oServiceBOSParams: oServiceParams{
+					serviceRequestParams: serviceRequestParams{
 						{
-							data:       []byte("me"),
-							returnData: []byte("monster"),
+							me: state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+								FoodGroup: wire.Chat,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+											Cookie: "lobby-cookie",
+										}),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x01_0x05_OServiceServiceResponse{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, "chat-auth-cookie"),
+										},
+									},
+								},
+							},
 						},
 					},
 				},
-			},
-			wantMsg: "GOTO_URL:directory info:dir_info?cookie=6d6f6e73746572&user=them",
-		},
-		{
-			name:     "request user info, get cookie issue error",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_get_dir them`),
-			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				authParams: authParams{
+					registerChatSessionParams: registerChatSessionParams{
 						{
-							data:      []byte("me"),
-							returnErr: io.EOF,
+							authCookie: []byte("chat-auth-cookie"),
+							sess:       newTestSession("me-chat"),
+						},
+					},
+				},
+				oServiceChatParams: oServiceParams{
+					clientOnlineParams: clientOnlineParams{
+						{
+							body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+							me:   state.NewIdentScreenName("me-chat"),
 						},
 					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsgs: []string{"CHAT_JOIN:0:Lobby"},
 		},
 		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_get_dir`),
-			wantMsg:  cmdInternalSvcErr,
+			name: "no auto-join rooms configured",
+			me:   newTestSession("me"),
 		},
 	}
 
@@ -1999,74 +2703,212 @@ func TestOSCARProxy_GetDirURL(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			cookieBaker := newMockCookieBaker(t)
-			for _, params := range tc.mockParams.issueParams {
-				cookieBaker.EXPECT().
-					Issue(params.data).
-					Return(params.returnData, params.returnErr)
+			chatNavSvc := newMockChatNavService(t)
+			for _, params := range tc.mockParams.createRoomParams {
+				chatNavSvc.EXPECT().
+					CreateRoom(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+			bosOServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.serviceRequestParams {
+				bosOServiceSvc.EXPECT().
+					ServiceRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.bodyIn).
+					Return(params.msg, params.err)
+			}
+			chatOServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceChatParams.clientOnlineParams {
+				chatOServiceSvc.EXPECT().
+					ClientOnline(ctx, params.body, matchSession(params.me)).
+					Return(params.err)
+			}
+			authSvc := newMockAuthService(t)
+			for _, params := range tc.mockParams.authParams.registerChatSessionParams {
+				authSvc.EXPECT().
+					RegisterChatSession(ctx, params.authCookie).
+					Return(params.sess, params.err)
 			}
 
 			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				CookieBaker: cookieBaker,
+				AuthService:         authSvc,
+				ChatNavService:      chatNavSvc,
+				Config:              tc.cfg,
+				Logger:              slog.Default(),
+				OServiceServiceBOS:  bosOServiceSvc,
+				OServiceServiceChat: chatOServiceSvc,
 			}
-			msg := svc.GetDirURL(ctx, tc.me, tc.givenCmd)
 
-			assert.Equal(t, tc.wantMsg, msg)
+			chatRegistry := NewChatRegistry()
+			toCh := make(chan []byte, len(tc.wantMsgs))
+			asyncCalls := 0
+			doAsync := func(f func() error) {
+				asyncCalls++
+			}
+
+			svc.autoJoinRooms(ctx, tc.me, chatRegistry, toCh, doAsync)
+			close(toCh)
+
+			var gotMsgs []string
+			for msg := range toCh {
+				gotMsgs = append(gotMsgs, string(msg))
+			}
+			assert.Equal(t, tc.wantMsgs, gotMsgs)
+			// a receive loop is started for each joined room
+			assert.Equal(t, len(tc.wantMsgs), asyncCalls)
 		})
 	}
 }
 
-func TestOSCARProxy_GetInfoURL(t *testing.T) {
+func TestOSCARProxy_deliverOfflineMessages(t *testing.T) {
+	fnNewNotice := func(id int64, sender state.IdentScreenName, txt string) state.OfflineMessage {
+		frags, err := wire.ICBMFragmentList(txt)
+		assert.NoError(t, err)
+		return state.OfflineMessage{
+			ID:        id,
+			Sender:    sender,
+			Recipient: state.NewIdentScreenName("me"),
+			Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				ChannelID: wire.ICBMChannelIM,
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+					},
+				},
+			},
+		}
+	}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
+		// cfg is the app configuration
+		cfg config.Config
+		// wantMsgs are the expected messages sent to the client
+		wantMsgs []string
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully request user info",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_get_info them`),
+			name: "deliver notices queued while offline, skipping user messages",
+			me:   newTestSession("me"),
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
 						{
-							data:       []byte("me"),
-							returnData: []byte("monster"),
+							recip: state.NewIdentScreenName("me"),
+							messages: []state.OfflineMessage{
+								fnNewNotice(1, state.SystemNoticeSender.IdentScreenName(), "maintenance tonight"),
+								fnNewNotice(2, state.NewIdentScreenName("them"), "hello"),
+								fnNewNotice(3, state.SystemNoticeSender.IdentScreenName(), "welcome back"),
+							},
 						},
 					},
+					deleteMessageParams: deleteMessageParams{
+						{id: 1},
+						{id: 3},
+					},
 				},
 			},
-			wantMsg: "GOTO_URL:profile:info?cookie=6d6f6e73746572&from=me&user=them",
+			wantMsgs: []string{
+				"IM_IN:[System]:F:maintenance tonight",
+				"IM_IN:[System]:F:welcome back",
+			},
 		},
 		{
-			name:     "request user info, get cookie issue error",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_get_info them`),
+			name: "deliver notices and user messages queued while offline with offline IMs enabled",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCOfflineIMs: true,
+			},
 			mockParams: mockParams{
-				cookieBakerParams: cookieBakerParams{
-					issueParams: issueParams{
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
 						{
-							data:      []byte("me"),
-							returnErr: io.EOF,
+							recip: state.NewIdentScreenName("me"),
+							messages: []state.OfflineMessage{
+								fnNewNotice(1, state.SystemNoticeSender.IdentScreenName(), "maintenance tonight"),
+								fnNewNotice(2, state.NewIdentScreenName("them"), "hello"),
+								fnNewNotice(3, state.NewIdentScreenName("them"), "are you there?"),
+								{
+									ID:        4,
+									Sender:    state.NewIdentScreenName("icqfriend"),
+									Recipient: state.NewIdentScreenName("me"),
+									Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+										ChannelID: wire.ICBMChannelICQ,
+									},
+								},
+							},
 						},
 					},
+					deleteMessageParams: deleteMessageParams{
+						{id: 1},
+						{id: 2},
+						{id: 3},
+					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsgs: []string{
+				"IM_IN:[System]:F:maintenance tonight",
+				"IM_IN:them:T:hello",
+				"IM_IN:them:T:are you there?",
+			},
 		},
 		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_get_info`),
-			wantMsg:  cmdInternalSvcErr,
+			name: "no notices queued",
+			me:   newTestSession("me"),
+			mockParams: mockParams{
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recip: state.NewIdentScreenName("me"),
+							messages: []state.OfflineMessage{
+								fnNewNotice(1, state.NewIdentScreenName("them"), "hello"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "delete delivered notice, receive error, continue delivering",
+			me:   newTestSession("me"),
+			mockParams: mockParams{
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recip: state.NewIdentScreenName("me"),
+							messages: []state.OfflineMessage{
+								fnNewNotice(1, state.SystemNoticeSender.IdentScreenName(), "maintenance tonight"),
+								fnNewNotice(2, state.SystemNoticeSender.IdentScreenName(), "welcome back"),
+							},
+						},
+					},
+					deleteMessageParams: deleteMessageParams{
+						{id: 1, err: io.EOF},
+						{id: 2},
+					},
+				},
+			},
+			wantMsgs: []string{
+				"IM_IN:[System]:F:maintenance tonight",
+				"IM_IN:[System]:F:welcome back",
+			},
+		},
+		{
+			name: "retrieve notices, receive error",
+			me:   newTestSession("me"),
+			mockParams: mockParams{
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recip: state.NewIdentScreenName("me"),
+							err:   io.EOF,
+						},
+					},
+				},
+			},
 		},
 	}
 
@@ -2074,25 +2916,38 @@ func TestOSCARProxy_GetInfoURL(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			cookieBaker := newMockCookieBaker(t)
-			for _, params := range tc.mockParams.issueParams {
-				cookieBaker.EXPECT().
-					Issue(params.data).
-					Return(params.returnData, params.returnErr)
+			offlineMessageMgr := newMockOfflineMessageManager(t)
+			for _, params := range tc.mockParams.retrieveMessagesParams {
+				offlineMessageMgr.EXPECT().
+					RetrieveMessages(params.recip).
+					Return(params.messages, params.err)
+			}
+			for _, params := range tc.mockParams.deleteMessageParams {
+				offlineMessageMgr.EXPECT().
+					DeleteMessage(params.id).
+					Return(params.err)
 			}
 
 			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				CookieBaker: cookieBaker,
+				Config:                tc.cfg,
+				Logger:                slog.Default(),
+				OfflineMessageManager: offlineMessageMgr,
 			}
-			msg := svc.GetInfoURL(ctx, tc.me, tc.givenCmd)
 
-			assert.Equal(t, tc.wantMsg, msg)
+			toCh := make(chan []byte, len(tc.wantMsgs))
+			svc.deliverOfflineMessages(ctx, tc.me, toCh)
+			close(toCh)
+
+			var gotMsgs []string
+			for msg := range toCh {
+				gotMsgs = append(gotMsgs, string(msg))
+			}
+			assert.Equal(t, tc.wantMsgs, gotMsgs)
 		})
 	}
 }
 
-func TestOSCARProxy_GetStatus(t *testing.T) {
+func TestOSCARProxy_ChatLeave(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -2100,6 +2955,8 @@ func TestOSCARProxy_GetStatus(t *testing.T) {
 		me *state.Session
 		// givenCmd is the TOC command
 		givenCmd []byte
+		// givenChatRegistry is the chat registry passed to the function
+		givenChatRegistry *ChatRegistry
 		// wantMsg is the expected TOC response
 		wantMsg string
 		// mockParams is the list of params sent to mocks that satisfy this
@@ -2107,126 +2964,39 @@ func TestOSCARProxy_GetStatus(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully request status",
+			name:     "successfully leave chat",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_get_status them"),
+			givenCmd: []byte(`toc_chat_leave 0`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					userInfoQueryParams: userInfoQueryParams{
+				authParams: authParams{
+					signoutChatParams: signoutChatParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
-									TLVUserInfo: wire.TLVUserInfo{
-										ScreenName:   "them",
-										WarningLevel: 0,
-										TLVBlock: wire.TLVBlock{
-											TLVList: wire.TLVList{
-												wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
-												wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
-											},
-										},
-									},
-								},
-							},
 						},
 					},
 				},
 			},
-			wantMsg: "UPDATE_BUDDY:them:T:0:1234:5678: O ",
+			wantMsg: "CHAT_LEFT:0",
 		},
 		{
-			name:     "request status, receive err from locate svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_get_status them"),
-			mockParams: mockParams{
-				locateParams: locateParams{
-					userInfoQueryParams: userInfoQueryParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-								ScreenName: "them",
-							},
-							err: io.EOF,
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "request status, user not online",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_get_status them"),
-			mockParams: mockParams{
-				locateParams: locateParams{
-					userInfoQueryParams: userInfoQueryParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNACError{
-									Code: wire.ErrorCodeNotLoggedOn,
-								},
-							},
-						},
-					},
-				},
-			},
-			wantMsg: "ERROR:901:them",
-		},
-		{
-			name:     "request status, receive unexpected error code",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_get_status them"),
-			mockParams: mockParams{
-				locateParams: locateParams{
-					userInfoQueryParams: userInfoQueryParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNACError{
-									Code: wire.ErrorCodeInvalidSnac,
-								},
-							},
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
+			name:     "chat room ID with invalid format",
+			givenCmd: []byte(`toc_chat_leave zero`),
+			wantMsg:  cmdInternalSvcErr,
 		},
 		{
-			name:     "request status, unexpected response from locate svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_get_status them"),
-			mockParams: mockParams{
-				locateParams: locateParams{
-					userInfoQueryParams: userInfoQueryParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
-								ScreenName: "them",
-							},
-							msg: wire.SNACMessage{
-								Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{},
-							},
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
+			name:              "missing chat session",
+			givenCmd:          []byte(`toc_chat_leave 0`),
+			givenChatRegistry: NewChatRegistry(),
+			wantMsg:           cmdInternalSvcErr,
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_get_status`),
+			givenCmd: []byte(`toc_chat_leave`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -2235,74 +3005,72 @@ func TestOSCARProxy_GetStatus(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			locateSvc := newMockLocateService(t)
-			for _, params := range tc.mockParams.userInfoQueryParams {
-				locateSvc.EXPECT().
-					UserInfoQuery(mock.Anything, matchSession(params.me), wire.SNACFrame{}, params.inBody).
-					Return(params.msg, params.err)
+			authSvc := newMockAuthService(t)
+			for _, params := range tc.mockParams.signoutChatParams {
+				authSvc.EXPECT().SignoutChat(ctx, matchSession(params.me))
 			}
 
 			svc := OSCARProxy{
-				Logger:        slog.Default(),
-				LocateService: locateSvc,
+				Logger:      slog.Default(),
+				AuthService: authSvc,
 			}
-			msg := svc.GetStatus(ctx, tc.me, tc.givenCmd)
+			msg := svc.ChatLeave(ctx, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
+			if tc.givenChatRegistry != nil {
+				// the room is forgotten once left
+				assert.Nil(t, tc.givenChatRegistry.RetrieveSess(0))
+			}
 		})
 	}
 }
 
-func TestOSCARProxy_InitDone(t *testing.T) {
+func TestOSCARProxy_ChatLeaveAll(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
-		// me is the TOC user session
-		me *state.Session
 		// givenCmd is the TOC command
 		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
+		// givenChatRegistry is the chat registry passed to the function
+		givenChatRegistry *ChatRegistry
+		// wantMsgs are the expected TOC responses
+		wantMsgs []string
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully initialize connection",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_init_done`),
+			name:     "leave several chat rooms",
+			givenCmd: []byte(`toc_chat_leave_all`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				for _, name := range []string{"room-a", "room-b", "room-c"} {
+					chatID := reg.Add(wire.ICBMRoomInfo{Exchange: 4, Cookie: "4-0-" + name})
+					reg.RegisterSess(chatID, newTestSession("me"))
+				}
+				return reg
+			}(),
 			mockParams: mockParams{
-				oServiceBOSParams: oServiceParams{
-					clientOnlineParams: clientOnlineParams{
-						{
-							me:   state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
-						},
+				authParams: authParams{
+					signoutChatParams: signoutChatParams{
+						{me: state.NewIdentScreenName("me")},
+						{me: state.NewIdentScreenName("me")},
+						{me: state.NewIdentScreenName("me")},
 					},
 				},
 			},
+			wantMsgs: []string{"CHAT_LEFT:0", "CHAT_LEFT:1", "CHAT_LEFT:2"},
 		},
 		{
-			name:     "initialize connection, receive err from BOS oservice svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_init_done`),
-			mockParams: mockParams{
-				oServiceBOSParams: oServiceParams{
-					clientOnlineParams: clientOnlineParams{
-						{
-							me:   state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
-							err:  io.EOF,
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
+			name:              "leave with no chat rooms joined",
+			givenCmd:          []byte(`toc_chat_leave_all`),
+			givenChatRegistry: NewChatRegistry(),
+			wantMsgs:          []string{},
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_init_done_diff`),
-			wantMsg:  cmdInternalSvcErr,
+			givenCmd: []byte(`toc_chat_leave_all_bad`),
+			wantMsgs: []string{cmdInternalSvcErr},
 		},
 	}
 
@@ -2310,32 +3078,241 @@ func TestOSCARProxy_InitDone(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			oSvc := newMockOServiceService(t)
-			for _, params := range tc.mockParams.oServiceBOSParams.clientOnlineParams {
-				oSvc.EXPECT().
-					ClientOnline(ctx, params.body, matchSession(params.me)).
-					Return(params.err)
+			authSvc := newMockAuthService(t)
+			for _, params := range tc.mockParams.signoutChatParams {
+				authSvc.EXPECT().SignoutChat(ctx, matchSession(params.me)).Once()
+			}
+
+			var sessions []*state.Session
+			if tc.givenChatRegistry != nil {
+				for chatID := 0; chatID < 3; chatID++ {
+					if sess := tc.givenChatRegistry.RetrieveSess(chatID); sess != nil {
+						sessions = append(sessions, sess)
+					}
+				}
 			}
 
 			svc := OSCARProxy{
-				Logger:             slog.Default(),
-				OServiceServiceBOS: oSvc,
+				Logger:      slog.Default(),
+				AuthService: authSvc,
 			}
-			msg := svc.InitDone(ctx, tc.me, tc.givenCmd)
+			msgs := svc.ChatLeaveAll(ctx, tc.givenChatRegistry, tc.givenCmd)
 
-			assert.Equal(t, tc.wantMsg, msg)
+			assert.Equal(t, tc.wantMsgs, msgs)
+
+			// every chat session is closed and removed from the registry
+			for chatID, sess := range sessions {
+				select {
+				case <-sess.Closed():
+				default:
+					t.Errorf("chat session %d is still open", chatID)
+				}
+				assert.Nil(t, tc.givenChatRegistry.RetrieveSess(chatID))
+				_, found := tc.givenChatRegistry.LookupRoom(chatID)
+				assert.False(t, found)
+			}
 		})
 	}
 }
 
-func TestOSCARProxy_RemoveBuddy(t *testing.T) {
+func TestChatRegistry_Add(t *testing.T) {
+	reg := NewChatRegistry()
+
+	privateLobby := wire.ICBMRoomInfo{Exchange: state.PrivateExchange, Cookie: "4-0-lobby"}
+	publicLobby := wire.ICBMRoomInfo{Exchange: state.PublicExchange, Cookie: "5-0-lobby"}
+
+	privateID := reg.Add(privateLobby)
+	publicID := reg.Add(publicLobby)
+
+	// rooms with the same name on different exchanges are distinct
+	assert.NotEqual(t, privateID, publicID)
+	// adding a room again returns its existing ID
+	assert.Equal(t, privateID, reg.Add(privateLobby))
+	assert.Equal(t, publicID, reg.Add(publicLobby))
+
+	room, found := reg.LookupRoom(privateID)
+	assert.True(t, found)
+	assert.Equal(t, privateLobby, room)
+	room, found = reg.LookupRoom(publicID)
+	assert.True(t, found)
+	assert.Equal(t, publicLobby, room)
+}
+
+func TestChatRegistry_Remove(t *testing.T) {
+	reg := NewChatRegistry()
+
+	lobby := wire.ICBMRoomInfo{Exchange: 4, Cookie: "4-0-lobby"}
+	chatID := reg.Add(lobby)
+	reg.RegisterSess(chatID, newTestSession("me"))
+
+	reg.Remove(chatID)
+
+	_, found := reg.LookupRoom(chatID)
+	assert.False(t, found)
+	assert.Nil(t, reg.RetrieveSess(chatID))
+
+	// rejoining the room registers it like a fresh join
+	rejoinID := reg.Add(lobby)
+	assert.NotEqual(t, chatID, rejoinID)
+	room, found := reg.LookupRoom(rejoinID)
+	assert.True(t, found)
+	assert.Equal(t, lobby, room)
+	assert.Nil(t, reg.RetrieveSess(rejoinID))
+}
+
+func TestChatRegistry_InviteExpiry(t *testing.T) {
+	reg := NewChatRegistry()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	reg.timeNow = func() time.Time { return now }
+
+	ignored := wire.ICBMRoomInfo{Exchange: 4, Cookie: "4-0-ignored"}
+	ignoredID := reg.AddInvite(ignored, ChatInvite{Inviter: "them", Cookie: 1})
+	accepted := wire.ICBMRoomInfo{Exchange: 4, Cookie: "4-0-accepted"}
+	acceptedID := reg.AddInvite(accepted, ChatInvite{Inviter: "them", Cookie: 2})
+	reg.RegisterSess(acceptedID, newTestSession("me"))
+
+	// invites are pending until they expire
+	now = now.Add(chatInviteTTL - time.Second)
+	_, found := reg.LookupInvite(ignoredID)
+	assert.True(t, found)
+
+	now = now.Add(time.Second)
+	_, found = reg.LookupInvite(ignoredID)
+	assert.False(t, found)
+
+	// the next invite discards the expired ones, and with them the rooms the
+	// user didn't join
+	later := wire.ICBMRoomInfo{Exchange: 4, Cookie: "4-0-later"}
+	laterID := reg.AddInvite(later, ChatInvite{Inviter: "them", Cookie: 3})
+	_, found = reg.LookupRoom(ignoredID)
+	assert.False(t, found)
+	_, found = reg.LookupRoom(acceptedID)
+	assert.True(t, found)
+	assert.NotNil(t, reg.RetrieveSess(acceptedID))
+	invite, found := reg.LookupInvite(laterID)
+	assert.True(t, found)
+	assert.Equal(t, ChatInvite{Inviter: "them", Cookie: 3}, invite)
+
+	// a new invite to a room with an expired invite gets a fresh invite
+	reinviteID := reg.AddInvite(ignored, ChatInvite{Inviter: "them", Cookie: 4})
+	room, found := reg.LookupRoom(reinviteID)
+	assert.True(t, found)
+	assert.Equal(t, ignored, room)
+	invite, found = reg.LookupInvite(reinviteID)
+	assert.True(t, found)
+	assert.Equal(t, ChatInvite{Inviter: "them", Cookie: 4}, invite)
+}
+
+func TestChatRegistry_Rvous(t *testing.T) {
+	reg := NewChatRegistry()
+
+	proposal := RvousProposal{
+		Proposer:   state.NewIdentScreenName("them"),
+		Cookie:     0x0102030405060708,
+		Capability: uuid.MustParse("09461343-4C7F-11D1-8222-444553540000"),
+	}
+	reg.AddRvous(proposal)
+	assert.True(t, reg.LookupRvous(proposal))
+
+	// proposals only match when every field matches
+	other := proposal
+	other.Cookie++
+	assert.False(t, reg.LookupRvous(other))
+	assert.False(t, reg.RemoveRvous(other))
+
+	assert.True(t, reg.RemoveRvous(proposal))
+	assert.False(t, reg.LookupRvous(proposal))
+	assert.False(t, reg.RemoveRvous(proposal))
+}
+
+func TestChatRegistry_CloseAll(t *testing.T) {
+	ctx := context.Background()
+	reg := NewChatRegistry()
+
+	sessions := []*state.Session{newTestSession("me"), newTestSession("me")}
+	for i, sess := range sessions {
+		chatID := reg.Add(wire.ICBMRoomInfo{Exchange: 4, Cookie: fmt.Sprintf("4-0-room%d", i)})
+		reg.RegisterSess(chatID, sess)
+	}
+
+	authSvc := newMockAuthService(t)
+	authSvc.EXPECT().SignoutChat(ctx, matchSession(state.NewIdentScreenName("me"))).Times(len(sessions))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// look up sessions while they're being closed
+		for i := 0; i < 100; i++ {
+			reg.RetrieveSess(i % len(sessions))
+		}
+	}()
+	reg.CloseAll(ctx, authSvc)
+	wg.Wait()
+
+	for chatID, sess := range sessions {
+		select {
+		case <-sess.Closed():
+		default:
+			t.Errorf("chat session %d was not closed", chatID)
+		}
+		assert.Nil(t, reg.RetrieveSess(chatID))
+		_, found := reg.LookupRoom(chatID)
+		assert.False(t, found)
+	}
+}
+
+func TestChatRegistry_RemoveAllSess_ConcurrentJoins(t *testing.T) {
+	const joins = 100
+
+	reg := NewChatRegistry()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < joins; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chatID := reg.Add(wire.ICBMRoomInfo{Exchange: 4, Cookie: fmt.Sprintf("4-0-room%d", i)})
+			reg.RegisterSess(chatID, newTestSession("me"))
+		}(i)
+	}
+
+	removed := make(map[int]*state.Session)
+	for i := 0; i < 10; i++ {
+		for chatID, sess := range reg.RemoveAllSess() {
+			_, dup := removed[chatID]
+			assert.False(t, dup, "chat session %d removed twice", chatID)
+			removed[chatID] = sess
+		}
+	}
+	wg.Wait()
+
+	// each joined room is either removed or still registered, never both
+	for chatID := range removed {
+		assert.Nil(t, reg.RetrieveSess(chatID))
+	}
+	for chatID, sess := range reg.RemoveAllSess() {
+		_, dup := removed[chatID]
+		assert.False(t, dup, "chat session %d removed twice", chatID)
+		removed[chatID] = sess
+	}
+	assert.Len(t, removed, joins)
+}
+
+func TestOSCARProxy_ChatSend(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
+		// cfg is the application config
+		cfg config.Config
 		// givenCmd is the TOC command
 		givenCmd []byte
+		// givenChatRegistry is the chat registry passed to the function
+		givenChatRegistry *ChatRegistry
+		// warnThrottle is the send throttle for warned users
+		warnThrottle *WarnThrottle
 		// wantMsg is the expected TOC response
 		wantMsg string
 		// mockParams is the list of params sent to mocks that satisfy this
@@ -2343,205 +3320,266 @@ func TestOSCARProxy_RemoveBuddy(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully remove buddies",
+			name:     "successfully send chat message",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_remove_buddy friend1 friend2 friend3"),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				buddyParams: buddyParams{
-					delBuddiesParams: delBuddiesParams{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-									{ScreenName: "friend2"},
-									{ScreenName: "friend3"},
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+									Channel: wire.ICBMChannelMIME,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ChatTLVSenderInformation,
+												newTestSession("me").TLVUserInfo()),
+											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+												},
+											}),
+										},
+									},
 								},
 							},
 						},
 					},
 				},
 			},
+			wantMsg: "CHAT_IN:0:me:F:Hello world!",
 		},
 		{
-			name:     "remove buddies, receive error from buddy service",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_remove_buddy friend1"),
+			name: "send chat message while idle, clear idle",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(10 * time.Minute)
+			}),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				buddyParams: buddyParams{
-					delBuddiesParams: delBuddiesParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
+							me:     state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{IdleTime: 0},
+						},
+					},
+				},
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+									Channel: wire.ICBMChannelMIME,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ChatTLVSenderInformation,
+												newTestSession("me").TLVUserInfo()),
+											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+												},
+											}),
+										},
+									},
 								},
 							},
-							err: io.EOF,
 						},
 					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_remove_buddy_bad`),
-			wantMsg:  cmdInternalSvcErr,
+			wantMsg: "CHAT_IN:0:me:F:Hello world!",
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			buddySvc := newMockBuddyService(t)
-			for _, params := range tc.mockParams.delBuddiesParams {
-				buddySvc.EXPECT().
-					DelBuddies(ctx, matchSession(params.me), params.inBody).
-					Return(params.err)
-			}
-
-			svc := OSCARProxy{
-				Logger:       slog.Default(),
-				BuddyService: buddySvc,
-			}
-			msg := svc.RemoveBuddy(ctx, tc.me, tc.givenCmd)
-
-			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_SendIM(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// me is the TOC user session
-		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-	}{
 		{
-			name:     "successfully send instant message",
+			name:     "successfully send UTF-8 chat message",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			givenCmd: []byte(`toc_chat_send 0 "Grüße, 世界!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
 							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
-								ChannelID:  wire.ICBMChannelIM,
-								ScreenName: "chattingChuck",
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
-											{
-												ID:      5,
-												Version: 1,
-												Payload: []byte{1, 1, 2},
-											},
-											{
-												ID:      1,
-												Version: 1,
-												Payload: []byte{
-													0x00, 0x00,
-													0x00, 0x00,
-													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
-												},
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "utf-8"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Grüße, 世界!"),
 											},
 										}),
 									},
 								},
 							},
+							result: &wire.SNACMessage{
+								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+									Channel: wire.ICBMChannelMIME,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ChatTLVSenderInformation,
+												newTestSession("me").TLVUserInfo()),
+											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "utf-8"),
+													wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Grüße, 世界!"),
+												},
+											}),
+										},
+									},
+								},
+							},
 						},
 					},
 				},
 			},
+			wantMsg: "CHAT_IN:0:me:F:Grüße, 世界!",
 		},
 		{
-			name:     "successfully auto-reply send instant message",
+			name: "send non-ASCII chat message to ASCII room with strict charset, receive error",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCChatStrictCharset: true,
+			},
+			givenCmd: []byte(`toc_chat_send 0 "Grüße, 世界!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				chatID := reg.Add(wire.ICBMRoomInfo{
+					Exchange: state.PrivateExchange,
+					Cookie:   "4-0-Lobby",
+				})
+				reg.RegisterSess(chatID, newTestSession("me"))
+				return reg
+			}(),
+			wantMsg: "ERROR:911",
+		},
+		{
+			name:     "send chat message, receive error from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_send_im chattingChuck "hello world!" auto`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
 							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
-								ChannelID:  wire.ICBMChannelIM,
-								ScreenName: "chattingChuck",
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
-											{
-												ID:      5,
-												Version: 1,
-												Payload: []byte{1, 1, 2},
-											},
-											{
-												ID:      1,
-												Version: 1,
-												Payload: []byte{
-													0x00, 0x00,
-													0x00, 0x00,
-													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
-												},
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
 											},
 										}),
-										wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}),
 									},
 								},
 							},
+							err: io.EOF,
 						},
 					},
 				},
 			},
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "send instant message, receive error from ICBM service",
+			name:     "send chat message, receive nil response from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				icbmParams: icbmParams{
-					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
 							sender: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
-								ChannelID:  wire.ICBMChannelIM,
-								ScreenName: "chattingChuck",
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
-											{
-												ID:      5,
-												Version: 1,
-												Payload: []byte{1, 1, 2},
-											},
-											{
-												ID:      1,
-												Version: 1,
-												Payload: []byte{
-													0x00, 0x00,
-													0x00, 0x00,
-													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
-												},
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
 											},
 										}),
 									},
 								},
 							},
-							err: io.EOF,
+							result: nil,
 						},
 					},
 				},
@@ -2549,107 +3587,81 @@ func TestOSCARProxy_SendIM(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_send_im`),
-			wantMsg:  cmdInternalSvcErr,
-		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			icbmSvc := newMockICBMService(t)
-			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
-				icbmSvc.EXPECT().
-					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
-					Return(params.result, params.err)
-			}
-
-			svc := OSCARProxy{
-				Logger:      slog.Default(),
-				ICBMService: icbmSvc,
-			}
-			msg := svc.SendIM(ctx, tc.me, tc.givenCmd)
-
-			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_SetAway(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// me is the TOC user session
-		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-	}{
-		{
-			name:     "successfully set away with message",
+			name:     "send chat message, receive rate limit error from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_away "I'm away from my computer right now."`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, "I'm away from my computer right now."),
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
+										}),
 									},
 								},
 							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name:     "successfully set away without message",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_away`),
-			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
-								TLVRestBlock: wire.TLVRestBlock{
-									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, ""),
-									},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeRateToHost,
 								},
 							},
 						},
 					},
 				},
 			},
+			wantMsg: "ERROR:903",
 		},
 		{
-			name:     "set away message, receive error from locate service",
+			name:     "send chat message, receive unexpected response from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_away "I'm away from my computer right now."`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, "I'm away from my computer right now."),
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
+										}),
 									},
 								},
 							},
-							err: io.EOF,
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{},
+							},
 						},
 					},
 				},
@@ -2657,64 +3669,32 @@ func TestOSCARProxy_SetAway(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "bad command",
-			givenCmd: []byte(`toc_set_away_bad`),
-			wantMsg:  cmdInternalSvcErr,
-		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			locateSvc := newMockLocateService(t)
-			for _, params := range tc.mockParams.setInfoParams {
-				locateSvc.EXPECT().
-					SetInfo(ctx, matchSession(params.me), params.inBody).
-					Return(params.err)
-			}
-
-			svc := OSCARProxy{
-				Logger:        slog.Default(),
-				LocateService: locateSvc,
-			}
-			msg := svc.SetAway(ctx, tc.me, tc.givenCmd)
-
-			assert.Equal(t, tc.wantMsg, msg)
-		})
-	}
-}
-
-func TestOSCARProxy_SetCaps(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// me is the TOC user session
-		me *state.Session
-		// givenCmd is the TOC command
-		givenCmd []byte
-		// wantMsg is the expected TOC response
-		wantMsg string
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-	}{
-		{
-			name:     "successfully set capabilities",
+			name:     "send chat message, receive nil response from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000 09460001-4C7F-11D1-8222-444553540000`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
-											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
-											uuid.MustParse("09460001-4C7F-11D1-8222-444553540000"),
-											capChat,
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
 										}),
 									},
 								},
@@ -2723,27 +3703,40 @@ func TestOSCARProxy_SetCaps(t *testing.T) {
 					},
 				},
 			},
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "set capability, receive error from locate service",
+			name:     "send chat message, receive empty response from chat svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000`),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				chatParams: chatParams{
+					channelMsgToHostParamsChat: channelMsgToHostParamsChat{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+								Channel: wire.ICBMChannelMIME,
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
-											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
-											capChat,
+										wire.NewTLVBE(wire.ChatTLVEnableReflectionFlag, uint8(1)),
+										wire.NewTLVBE(wire.ChatTLVSenderInformation, newTestSession("me").TLVUserInfo()),
+										wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+										wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+												wire.NewTLVBE(wire.ChatTLVMessageInfoText, "Hello world!"),
+											},
 										}),
 									},
 								},
 							},
-							err: io.EOF,
+							result: &wire.SNACMessage{},
 						},
 					},
 				},
@@ -2751,14 +3744,37 @@ func TestOSCARProxy_SetCaps(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "set malformed capability UUID",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_caps 09460000-`),
+			name:     "chat room ID with invalid format",
+			givenCmd: []byte(`toc_chat_send zero "Hello world!"`),
 			wantMsg:  cmdInternalSvcErr,
 		},
+		{
+			name: "send chat message, warned user exceeds send rate",
+			me: newTestSession("me", func(session *state.Session) {
+				session.IncrementWarning(500)
+			}),
+			givenCmd: []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: func() *ChatRegistry {
+				reg := NewChatRegistry()
+				reg.RegisterSess(0, newTestSession("me"))
+				return reg
+			}(),
+			warnThrottle: func() *WarnThrottle {
+				throttle := NewWarnThrottle([]string{"50:1"})
+				throttle.Allow(state.NewIdentScreenName("me"), 500)
+				return throttle
+			}(),
+			wantMsg: "ERROR:903",
+		},
+		{
+			name:              "missing chat session",
+			givenCmd:          []byte(`toc_chat_send 0 "Hello world!"`),
+			givenChatRegistry: NewChatRegistry(),
+			wantMsg:           cmdInternalSvcErr,
+		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_set_caps_bad`),
+			givenCmd: []byte(`toc_chat_send`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -2767,30 +3783,44 @@ func TestOSCARProxy_SetCaps(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			locateSvc := newMockLocateService(t)
-			for _, params := range tc.mockParams.setInfoParams {
-				locateSvc.EXPECT().
-					SetInfo(ctx, matchSession(params.me), params.inBody).
+			chatSvc := newMockChatService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsChat {
+				chatSvc.EXPECT().
+					ChannelMsgToHost(ctx, matchSession(params.sender), wire.SNACFrame{}, params.inBody).
+					Return(params.result, params.err)
+			}
+
+			oServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.idleNotificationParams {
+				oServiceSvc.EXPECT().
+					IdleNotification(ctx, matchSession(params.me), params.bodyIn).
 					Return(params.err)
 			}
 
 			svc := OSCARProxy{
-				Logger:        slog.Default(),
-				LocateService: locateSvc,
+				ChatService:        chatSvc,
+				Config:             tc.cfg,
+				Logger:             slog.Default(),
+				OServiceServiceBOS: oServiceSvc,
+				WarnThrottle:       tc.warnThrottle,
 			}
-			msg := svc.SetCaps(ctx, tc.me, tc.givenCmd)
+			msg := svc.ChatSend(ctx, tc.me, tc.givenChatRegistry, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_SetConfig(t *testing.T) {
+func TestOSCARProxy_Evil(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
+		// cfg is the application config
+		cfg config.Config
+		// recentIMSenders tracks users who recently sent me IMs
+		recentIMSenders *RecentIMSenders
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// wantMsg is the expected TOC response
@@ -2800,129 +3830,169 @@ func TestOSCARProxy_SetConfig(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully set permit all config",
+			name:     "successfully warn normally",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\nb friend2\n}\n"),
+			givenCmd: []byte(`toc_evil them norm`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "permit all" mode requires adding a
-					// deny list entry
-					addDenyListEntriesParams: addDenyListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     0,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
 							},
 						},
 					},
 				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
+			},
+		},
+		{
+			name:     "successfully warn differently-formatted screen name",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_evil "The M" norm`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-									{ScreenName: "friend2"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     0,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
 							},
 						},
 					},
 				},
-				tocConfigParams: tocConfigParams{
-					setTOCConfigParams: setTOCConfigParams{
+			},
+		},
+		{
+			name: "successfully warn recent IM sender",
+			me:   newTestSession("me"),
+			recentIMSenders: func() *RecentIMSenders {
+				senders := NewRecentIMSenders(time.Minute)
+				senders.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("them"))
+				return senders
+			}(),
+			givenCmd: []byte(`toc_evil them norm`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
-							user:   state.NewIdentScreenName("me"),
-							config: "m 1\ng Buddies\nb friend1\nb friend2",
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     0,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:     "set permit all config, receive err from config store svc",
+			name: "warn user who hasn't recently sent an IM",
+			me:   newTestSession("me"),
+			recentIMSenders: func() *RecentIMSenders {
+				senders := NewRecentIMSenders(time.Minute)
+				senders.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("other"))
+				return senders
+			}(),
+			givenCmd: []byte(`toc_evil them norm`),
+			wantMsg:  "ERROR:902:them",
+		},
+		{
+			name:     "successfully warn anonymously",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\n}\n"),
+			givenCmd: []byte(`toc_evil them anon`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "permit all" mode requires adding a
-					// deny list entry
-					addDenyListEntriesParams: addDenyListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{},
 							},
 						},
 					},
 				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
+			},
+		},
+		{
+			name:     "successfully warn normally, reply with warning level",
+			me:       newTestSession("me"),
+			cfg:      config.Config{TOCWarnResult: true},
+			givenCmd: []byte(`toc_evil them norm`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     0,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{
+									EvilDeltaApplied: 100,
+									UpdatedEvilValue: 100,
 								},
 							},
 						},
 					},
 				},
-				tocConfigParams: tocConfigParams{
-					setTOCConfigParams: setTOCConfigParams{
-						{
-							user:   state.NewIdentScreenName("me"),
-							config: "m 1\ng Buddies\nb friend1",
-							err:    io.EOF,
-						},
-					},
-				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "WARN_RESULT:them:10",
 		},
 		{
-			name:     "set permit all config, receive err from buddy svc",
+			name:     "successfully warn anonymously, reply with warning level",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\n}\n"),
+			cfg:      config.Config{TOCWarnResult: true},
+			givenCmd: []byte(`toc_evil them anon`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "permit all" mode requires adding a
-					// deny list entry
-					addDenyListEntriesParams: addDenyListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x09_ICBMEvilReply{
+									EvilDeltaApplied: 30,
+									UpdatedEvilValue: 130,
 								},
 							},
 						},
 					},
 				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
+			},
+			wantMsg: "WARN_RESULT:them:13",
+		},
+		{
+			name:     "warn, receive error from ICBM service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_evil them anon`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
 							},
 							err: io.EOF,
 						},
@@ -2932,95 +4002,87 @@ func TestOSCARProxy_SetConfig(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "set permit all config, receive err from permit-deny svc",
+			name:     "warn, receive snac err",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\nb friend2\n}\n"),
+			givenCmd: []byte(`toc_evil them anon`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "permit all" mode requires adding a
-					// deny list entry
-					addDenyListEntriesParams: addDenyListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{},
 							},
-							err: io.EOF,
 						},
 					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "ERROR:902:them",
 		},
 		{
-			name:     "successfully set deny all config",
+			name:     "warn, receive snac err for offline user",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 2\ng Buddies\nb friend1\nb friend2\n}\n"),
+			givenCmd: []byte(`toc_evil them anon`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "deny all" mode requires adding a
-					// permit list entry
-					addPermListEntriesParams: addPermListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
 								},
 							},
 						},
 					},
 				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
+			},
+			wantMsg: "ERROR:901:them",
+		},
+		{
+			name:     "warn, ICBM svc returns unexpected snac type",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_evil them anon`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-									{ScreenName: "friend2"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{},
 							},
-						},
-					},
-				},
-				tocConfigParams: tocConfigParams{
-					setTOCConfigParams: setTOCConfigParams{
-						{
-							user:   state.NewIdentScreenName("me"),
-							config: "m 2\ng Buddies\nb friend1\nb friend2",
 						},
 					},
 				},
 			},
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "set deny all config, receive err from permit-deny svc",
+			name:     "warn, ICBM svc returns empty response",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 2\ng Buddies\nb friend1\nb friend2\n}\n"),
+			givenCmd: []byte(`toc_evil them anon`),
 			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					// confusingly, setting "deny all" mode requires adding a
-					// permit list entry
-					addPermListEntriesParams: addPermListEntriesParams{
+				icbmParams: icbmParams{
+					evilRequestParams: evilRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "me"},
-								},
+							inBody: wire.SNAC_0x04_0x08_ICBMEvilRequest{
+								SendAs:     1,
+								ScreenName: "them",
 							},
-							err: io.EOF,
+							msg: wire.SNACMessage{},
 						},
 					},
 				},
@@ -3028,152 +4090,14 @@ func TestOSCARProxy_SetConfig(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "successfully set permit some config",
+			name:     "warn with incorrect type",
 			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
-			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					addPermListEntriesParams: addPermListEntriesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend3"},
-									{ScreenName: "friend4"},
-								},
-							},
-						},
-					},
-				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-									{ScreenName: "friend2"},
-								},
-							},
-						},
-					},
-				},
-				tocConfigParams: tocConfigParams{
-					setTOCConfigParams: setTOCConfigParams{
-						{
-							user:   state.NewIdentScreenName("me"),
-							config: "m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2",
-						},
-					},
-				},
-			},
-		},
-		{
-			name:     "set permit some config, receive err from permit-deny svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
-			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					addPermListEntriesParams: addPermListEntriesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend3"},
-									{ScreenName: "friend4"},
-								},
-							},
-							err: io.EOF,
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "successfully set deny some config",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
-			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					addDenyListEntriesParams: addDenyListEntriesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend3"},
-									{ScreenName: "friend4"},
-								},
-							},
-						},
-					},
-				},
-				buddyParams: buddyParams{
-					addBuddiesParams: addBuddiesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
-								Buddies: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend1"},
-									{ScreenName: "friend2"},
-								},
-							},
-						},
-					},
-				},
-				tocConfigParams: tocConfigParams{
-					setTOCConfigParams: setTOCConfigParams{
-						{
-							user:   state.NewIdentScreenName("me"),
-							config: "m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2",
-						},
-					},
-				},
-			},
-		},
-		{
-			name:     "set deny some config, receive err from permit-deny svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
-			mockParams: mockParams{
-				permitDenyParams: permitDenyParams{
-					addDenyListEntriesParams: addDenyListEntriesParams{
-						{
-							me: state.NewIdentScreenName("me"),
-							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
-								Users: []struct {
-									ScreenName string `oscar:"len_prefix=uint8"`
-								}{
-									{ScreenName: "friend3"},
-									{ScreenName: "friend4"},
-								},
-							},
-							err: io.EOF,
-						},
-					},
-				},
-			},
-			wantMsg: cmdInternalSvcErr,
-		},
-		{
-			name:     "set unknown PD mode",
-			me:       newTestSession("me"),
-			givenCmd: []byte("toc_set_config {m 5\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			givenCmd: []byte(`toc_evil them blah`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_chat_leave`),
+			givenCmd: []byte(`toc_evil`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -3182,44 +4106,27 @@ func TestOSCARProxy_SetConfig(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			pdSvc := newMockPermitDenyService(t)
-			for _, params := range tc.mockParams.addDenyListEntriesParams {
-				pdSvc.EXPECT().
-					AddDenyListEntries(ctx, matchSession(params.me), params.body).
-					Return(params.err)
-			}
-			for _, params := range tc.mockParams.addPermListEntriesParams {
-				pdSvc.EXPECT().
-					AddPermListEntries(ctx, matchSession(params.me), params.body).
-					Return(params.err)
-			}
-			buddySvc := newMockBuddyService(t)
-			for _, params := range tc.mockParams.addBuddiesParams {
-				buddySvc.EXPECT().
-					AddBuddies(ctx, matchSession(params.me), params.inBody).
-					Return(params.err)
-			}
-			tocConfigSvc := newMockTOCConfigStore(t)
-			for _, params := range tc.mockParams.setTOCConfigParams {
-				tocConfigSvc.EXPECT().
-					SetTOCConfig(params.user, params.config).
-					Return(params.err)
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.evilRequestParams {
+				icbmSvc.EXPECT().
+					EvilRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
 			}
 
 			svc := OSCARProxy{
-				BuddyService:      buddySvc,
-				Logger:            slog.Default(),
-				PermitDenyService: pdSvc,
-				TOCConfigStore:    tocConfigSvc,
+				Config:          tc.cfg,
+				Logger:          slog.Default(),
+				ICBMService:     icbmSvc,
+				RecentIMSenders: tc.recentIMSenders,
 			}
-			msg := svc.SetConfig(ctx, tc.me, tc.givenCmd)
+			msg := svc.Evil(ctx, tc.me, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_SetDir(t *testing.T) {
+func TestOSCARProxy_ChangePassword(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -3234,24 +4141,29 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully set directory info with quoted fields",
+			name:     "successfully change password",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_dir "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email":"allow web searches"`),
+			givenCmd: []byte("toc_change_passwd oldpass newpass"),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setDirInfoParams: setDirInfoParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
-										wire.NewTLVBE(wire.ODirTLVMiddleName, "middle name"),
-										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
-										wire.NewTLVBE(wire.ODirTLVMaidenName, "maiden name"),
-										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
-										wire.NewTLVBE(wire.ODirTLVState, "state"),
-										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "newpass"),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
+									Permissions: wire.AdminInfoPermissionsReadWrite,
+									TLVBlock: wire.TLVBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
+										},
 									},
 								},
 							},
@@ -3259,26 +4171,33 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 					},
 				},
 			},
+			wantMsg: "ADMIN_PASSWD_STATUS:0",
 		},
 		{
-			name:     "successfully set directory info with some blank fields",
+			name:     "change password - invalid password length",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country":"email":"allow web searches"`),
+			givenCmd: []byte("toc_change_passwd oldpass np"),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setDirInfoParams: setDirInfoParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
-										wire.NewTLVBE(wire.ODirTLVMiddleName, ""),
-										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
-										wire.NewTLVBE(wire.ODirTLVMaidenName, ""),
-										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
-										wire.NewTLVBE(wire.ODirTLVState, "state"),
-										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "np"),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
+									Permissions: wire.AdminInfoPermissionsReadWrite,
+									TLVBlock: wire.TLVBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
+											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorInvalidPasswordLength),
+										},
 									},
 								},
 							},
@@ -3286,26 +4205,33 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 					},
 				},
 			},
+			wantMsg: "ERROR:911",
 		},
 		{
-			name:     "successfully set directory info with last two fields absent",
+			name:     "change password - incorrect password",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country"`),
+			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setDirInfoParams: setDirInfoParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
-										wire.NewTLVBE(wire.ODirTLVMiddleName, ""),
-										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
-										wire.NewTLVBE(wire.ODirTLVMaidenName, ""),
-										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
-										wire.NewTLVBE(wire.ODirTLVState, "state"),
-										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
+									Permissions: wire.AdminInfoPermissionsReadWrite,
+									TLVBlock: wire.TLVBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
+											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorValidatePassword),
+										},
 									},
 								},
 							},
@@ -3313,26 +4239,56 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 					},
 				},
 			},
+			wantMsg: "ERROR:912",
 		},
 		{
-			name:     "set directory info, receive error from locate svc",
+			name:     "change password - catch-all error response",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_dir "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email":"allow web searches"`),
+			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setDirInfoParams: setDirInfoParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
 						{
 							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
 								TLVRestBlock: wire.TLVRestBlock{
 									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
-										wire.NewTLVBE(wire.ODirTLVMiddleName, "middle name"),
-										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
-										wire.NewTLVBE(wire.ODirTLVMaidenName, "maiden name"),
-										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
-										wire.NewTLVBE(wire.ODirTLVState, "state"),
-										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x07_0x05_AdminChangeReply{
+									Permissions: wire.AdminInfoPermissionsReadWrite,
+									TLVBlock: wire.TLVBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.AdminTLVNewPassword, []byte{}),
+											wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorAllOtherErrors),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:913",
+		},
+		{
+			name:     "change password - runtime error from admin svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
+			mockParams: mockParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
 									},
 								},
 							},
@@ -3344,14 +4300,34 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 			wantMsg: cmdInternalSvcErr,
 		},
 		{
-			name:     "set directory with too many fields present",
+			name:     "change password - unexpected response from admin svc",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country":"email":"allow web searches":"extra":"extra"`),
-			wantMsg:  cmdInternalSvcErr,
+			givenCmd: []byte("toc_change_passwd oldpass baddpass"),
+			mockParams: mockParams{
+				adminParams: adminParams{
+					infoChangeRequestParams: infoChangeRequestParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.AdminTLVOldPassword, "oldpass"),
+										wire.NewTLVBE(wire.AdminTLVNewPassword, "baddpass"),
+									},
+								},
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
 		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_set_dir`),
+			givenCmd: []byte(`toc_change_passwd`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -3360,25 +4336,25 @@ func TestOSCARProxy_SetDir(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			locateSvc := newMockLocateService(t)
-			for _, params := range tc.mockParams.setDirInfoParams {
-				locateSvc.EXPECT().
-					SetDirInfo(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+			adminSvc := newMockAdminService(t)
+			for _, params := range tc.mockParams.infoChangeRequestParams {
+				adminSvc.EXPECT().
+					InfoChangeRequest(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
 					Return(params.msg, params.err)
 			}
 
 			svc := OSCARProxy{
-				Logger:        slog.Default(),
-				LocateService: locateSvc,
+				Logger:       slog.Default(),
+				AdminService: adminSvc,
 			}
-			msg := svc.SetDir(ctx, tc.me, tc.givenCmd)
+			msg := svc.ChangePassword(ctx, tc.me, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_SetIdle(t *testing.T) {
+func TestOSCARProxy_GetDirSearchURL(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -3393,49 +4369,62 @@ func TestOSCARProxy_SetIdle(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully set idle status",
+			name:     "successfully request user info",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_idle 10`),
+			givenCmd: []byte(`toc_dir_search "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email"`),
 			mockParams: mockParams{
-				oServiceBOSParams: oServiceParams{
-					idleNotificationParams: idleNotificationParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
 						{
-							me: state.NewIdentScreenName("me"),
-							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
-								IdleTime: uint32(10),
-							},
+							data:       []byte("me"),
+							returnData: []byte("monster"),
 						},
 					},
 				},
 			},
+			wantMsg: "GOTO_URL:search results:dir_search?city=city&cookie=6d6f6e73746572&country=country&email=email&first_name=first+name&last_name=last+name&maiden_name=maiden+name&middle_name=middle+name&state=state",
 		},
 		{
-			name:     "set idle status, receive err from BOS oservice svc",
+			name:     "successfully request user info by keywords",
 			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_idle 10`),
+			givenCmd: []byte(`toc_dir_search ::::::::::"searchkw"`),
 			mockParams: mockParams{
-				oServiceBOSParams: oServiceParams{
-					idleNotificationParams: idleNotificationParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
 						{
-							me: state.NewIdentScreenName("me"),
-							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
-								IdleTime: uint32(10),
-							},
-							err: io.EOF,
+							data:       []byte("me"),
+							returnData: []byte("monster"),
 						},
 					},
 				},
 			},
-			wantMsg: cmdInternalSvcErr,
+			wantMsg: "GOTO_URL:search results:dir_search?cookie=6d6f6e73746572&keyword=searchkw",
 		},
 		{
-			name:     "bad secs param",
-			givenCmd: []byte(`toc_set_idle zero`),
+			name:     "request user info with too many params",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_dir_search ::::::::::::::::::::"searchkw"`),
 			wantMsg:  cmdInternalSvcErr,
 		},
+		{
+			name:     "request user info, get cookie issue error",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_dir_search them`),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:      []byte("me"),
+							returnErr: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_set_idle`),
+			givenCmd: []byte(`toc_dir_search`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -3444,30 +4433,28 @@ func TestOSCARProxy_SetIdle(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			oServiceSvc := newMockOServiceService(t)
-			for _, params := range tc.mockParams.oServiceBOSParams.idleNotificationParams {
-				oServiceSvc.EXPECT().
-					IdleNotification(ctx, matchSession(params.me), params.bodyIn).
-					Return(params.err)
+			cookieBaker := newMockCookieBaker(t)
+			for _, params := range tc.mockParams.issueParams {
+				cookieBaker.EXPECT().
+					Issue(params.data).
+					Return(params.returnData, params.returnErr)
 			}
 
 			svc := OSCARProxy{
-				Logger:             slog.Default(),
-				OServiceServiceBOS: oServiceSvc,
+				Logger:      slog.Default(),
+				CookieBaker: cookieBaker,
 			}
-			msg := svc.SetIdle(ctx, tc.me, tc.givenCmd)
+			msg := svc.GetDirSearchURL(ctx, tc.me, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_SetInfo(t *testing.T) {
+func TestOSCARProxy_GetDirKeywords(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
-		// me is the TOC user session
-		me *state.Session
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// wantMsg is the expected TOC response
@@ -3477,18 +4464,20 @@ func TestOSCARProxy_SetInfo(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:     "successfully set profile",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_info "my profile!"`),
+			name:     "get keyword list",
+			givenCmd: []byte(`toc_get_dir_keywords`),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordListQueryParams{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
-								TLVRestBlock: wire.TLVRestBlock{
-									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, "my profile!"),
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x0F_0x04_KeywordListReply{
+									Status: 0x01,
+									Interests: []wire.ODirKeywordListItem{
+										{Type: wire.ODirKeywordCategory, ID: 1, Name: "Sports"},
+										{Type: wire.ODirKeyword, ID: 1, Name: "Baseball"},
+										{Type: wire.ODirKeyword, ID: 1, Name: "Hockey"},
+										{Type: wire.ODirKeyword, ID: 0, Name: "Cooking"},
 									},
 								},
 							},
@@ -3496,23 +4485,33 @@ func TestOSCARProxy_SetInfo(t *testing.T) {
 					},
 				},
 			},
+			wantMsg: "DIR_KEYWORDS:C:Sports:K:Baseball:K:Hockey:K:Cooking",
 		},
 		{
-			name:     "set profile, receive error from locate svc",
-			me:       newTestSession("me"),
-			givenCmd: []byte(`toc_set_info "my profile!"`),
+			name:     "get empty keyword list",
+			givenCmd: []byte(`toc_get_dir_keywords`),
 			mockParams: mockParams{
-				locateParams: locateParams{
-					setInfoParams: setInfoParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordListQueryParams{
 						{
-							me: state.NewIdentScreenName("me"),
-							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
-								TLVRestBlock: wire.TLVRestBlock{
-									TLVList: wire.TLVList{
-										wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, "my profile!"),
-									},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x0F_0x04_KeywordListReply{
+									Status: 0x01,
 								},
 							},
+						},
+					},
+				},
+			},
+			wantMsg: "DIR_KEYWORDS",
+		},
+		{
+			name:     "get keyword list, receive error from dir search service",
+			givenCmd: []byte(`toc_get_dir_keywords`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordListQueryParams{
+						{
 							err: io.EOF,
 						},
 					},
@@ -3520,9 +4519,25 @@ func TestOSCARProxy_SetInfo(t *testing.T) {
 			},
 			wantMsg: cmdInternalSvcErr,
 		},
+		{
+			name:     "get keyword list, receive unexpected response from dir search service",
+			givenCmd: []byte(`toc_get_dir_keywords`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordListQueryParams{
+						{
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
 		{
 			name:     "bad command",
-			givenCmd: []byte(`toc_set_info`),
+			givenCmd: []byte(`toc_get_dir_keywords_bad`),
 			wantMsg:  cmdInternalSvcErr,
 		},
 	}
@@ -3531,27 +4546,25 @@ func TestOSCARProxy_SetInfo(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			locateSvc := newMockLocateService(t)
-			for _, params := range tc.mockParams.setInfoParams {
-				locateSvc.EXPECT().
-					SetInfo(ctx, matchSession(params.me), params.inBody).
-					Return(params.err)
+			dirSearchSvc := newMockDirSearchService(t)
+			for _, params := range tc.mockParams.keywordListQueryParams {
+				dirSearchSvc.EXPECT().
+					KeywordListQuery(ctx, wire.SNACFrame{}).
+					Return(params.msg, params.err)
 			}
 
 			svc := OSCARProxy{
-				Logger:        slog.Default(),
-				LocateService: locateSvc,
+				DirSearchService: dirSearchSvc,
+				Logger:           slog.Default(),
 			}
-			msg := svc.SetInfo(ctx, tc.me, tc.givenCmd)
+			msg := svc.GetDirKeywords(ctx, tc.givenCmd)
 
 			assert.Equal(t, tc.wantMsg, msg)
 		})
 	}
 }
 
-func TestOSCARProxy_Signon(t *testing.T) {
-	roastedPass := wire.RoastTOCPassword([]byte("thepass"))
-
+func TestOSCARProxy_GetDirURL(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
 		name string
@@ -3560,17 +4573,5492 @@ func TestOSCARProxy_Signon(t *testing.T) {
 		// givenCmd is the TOC command
 		givenCmd []byte
 		// wantMsg is the expected TOC response
-		wantMsg []string
+		wantMsg string
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
 	}{
 		{
-			name: "successfully login",
+			name:     "successfully request user dir info",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_dir them`),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:       []byte("me"),
+							returnData: []byte("monster"),
+						},
+					},
+				},
+			},
+			wantMsg: "GOTO_URL:directory info:dir_info?cookie=6d6f6e73746572&user=them",
+		},
+		{
+			name:     "request user info, get cookie issue error",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_dir them`),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:      []byte("me"),
+							returnErr: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_get_dir`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			cookieBaker := newMockCookieBaker(t)
+			for _, params := range tc.mockParams.issueParams {
+				cookieBaker.EXPECT().
+					Issue(params.data).
+					Return(params.returnData, params.returnErr)
+			}
+
+			svc := OSCARProxy{
+				Logger:      slog.Default(),
+				CookieBaker: cookieBaker,
+			}
+			msg := svc.GetDirURL(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_GetInfoURL(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// throttle is the profile and directory lookup rate limiter
+		throttle *IMThrottle
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully request user info",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_info them`),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:       []byte("me"),
+							returnData: []byte("monster"),
+						},
+					},
+				},
+			},
+			wantMsg: "GOTO_URL:profile:info?cookie=6d6f6e73746572&from=me&user=them",
+		},
+		{
+			name:     "request user info, get cookie issue error",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_info them`),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:      []byte("me"),
+							returnErr: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "request user info within lookup rate limit",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_info them`),
+			throttle: func() *IMThrottle {
+				throttle := NewIMThrottle(2, time.Minute)
+				throttle.Allow(state.NewIdentScreenName("me"), state.IdentScreenName{})
+				return throttle
+			}(),
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					issueParams: issueParams{
+						{
+							data:       []byte("me"),
+							returnData: []byte("monster"),
+						},
+					},
+				},
+			},
+			wantMsg: "GOTO_URL:profile:info?cookie=6d6f6e73746572&from=me&user=them",
+		},
+		{
+			name:     "request user info, exceed lookup rate limit",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_info them`),
+			throttle: func() *IMThrottle {
+				throttle := NewIMThrottle(1, time.Minute)
+				throttle.Allow(state.NewIdentScreenName("me"), state.IdentScreenName{})
+				return throttle
+			}(),
+			wantMsg: "ERROR:903",
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_get_info`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			cookieBaker := newMockCookieBaker(t)
+			for _, params := range tc.mockParams.issueParams {
+				cookieBaker.EXPECT().
+					Issue(params.data).
+					Return(params.returnData, params.returnErr)
+			}
+
+			svc := OSCARProxy{
+				Logger:         slog.Default(),
+				CookieBaker:    cookieBaker,
+				LookupThrottle: tc.throttle,
+			}
+			msg := svc.GetInfoURL(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_GetBuddyComment(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "get buddy comment",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_buddy_comment "Them"`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					buddyCommentParams: buddyCommentParams{
+						{
+							me:      state.NewIdentScreenName("me"),
+							them:    state.NewIdentScreenName("them"),
+							comment: "met at the LAN party",
+						},
+					},
+				},
+			},
+			wantMsg: "BUDDY_COMMENT:Them:met at the LAN party",
+		},
+		{
+			name:     "get buddy comment, no comment set",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_buddy_comment them`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					buddyCommentParams: buddyCommentParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							them: state.NewIdentScreenName("them"),
+						},
+					},
+				},
+			},
+			wantMsg: "BUDDY_COMMENT:them:",
+		},
+		{
+			name:     "get buddy comment, receive error from buddy comment store",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_buddy_comment them`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					buddyCommentParams: buddyCommentParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							them: state.NewIdentScreenName("them"),
+							err:  io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_buddy_comment_bad them`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			commentStore := newMockBuddyCommentStore(t)
+			for _, params := range tc.mockParams.buddyCommentParams {
+				commentStore.EXPECT().
+					BuddyComment(params.me, params.them).
+					Return(params.comment, params.err)
+			}
+
+			svc := OSCARProxy{
+				BuddyCommentStore: commentStore,
+				Logger:            slog.Default(),
+			}
+			msg := svc.GetBuddyComment(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_GetStatus(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully request status",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName:   "them",
+										WarningLevel: 0,
+										TLVBlock: wire.TLVBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+												wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "UPDATE_BUDDY:them:T:0:1234:5678: O ",
+		},
+		{
+			name:     "successfully request status of differently-formatted screen name",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_get_status "The M"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "them",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "UPDATE_BUDDY:them:T:0:0:0: O ",
+		},
+		{
+			name:     "request status, receive err from locate svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "request status, user not online",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("them"),
+							user: &state.User{
+								DisplayScreenName: "Them",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "UPDATE_BUDDY:Them:F:0:0:0:   ",
+		},
+		{
+			name:     "request status, user not online, user not found",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("them"),
+						},
+					},
+				},
+			},
+			wantMsg: "UPDATE_BUDDY:them:F:0:0:0:   ",
+		},
+		{
+			name:     "request status, receive unexpected error code",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeInvalidSnac,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "request status, unexpected response from locate svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_status them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_get_status`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.userInfoQueryParams {
+				locateSvc.EXPECT().
+					UserInfoQuery(mock.Anything, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userLookupParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.user, params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+				UserManager:   userManager,
+			}
+			msg := svc.GetStatus(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_GetPresence(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "probe online user",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "Them",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "PRESENCE:Them:T:F",
+		},
+		{
+			name:     "probe away user",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "Them",
+										TLVBlock: wire.TLVBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagUnavailable),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "PRESENCE:Them:T:T",
+		},
+		{
+			name:     "probe offline user",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "PRESENCE:them:F:F",
+		},
+		{
+			name:     "probe user who blocks me, user appears offline",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence blocker"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "blocker",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "PRESENCE:blocker:F:F",
+		},
+		{
+			name:     "probe user, receive unexpected error code",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							msg: wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeInvalidSnac,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "probe user, receive error from locate service",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_presence them"),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					userInfoQueryParams: userInfoQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+								ScreenName: "them",
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte("toc_get_presence"),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.userInfoQueryParams {
+				locateSvc.EXPECT().
+					UserInfoQuery(mock.Anything, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+
+			svc := OSCARProxy{
+				LocateService: locateSvc,
+				Logger:        slog.Default(),
+			}
+			msg := svc.GetPresence(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_GetBuddies(t *testing.T) {
+	fnUserInfoQuery := func(buddy string, msg wire.SNACMessage, err error) userInfoQueryParams {
+		return userInfoQueryParams{
+			{
+				me: state.NewIdentScreenName("me"),
+				inBody: wire.SNAC_0x02_0x05_LocateUserInfoQuery{
+					ScreenName: buddy,
+				},
+				msg: msg,
+				err: err,
+			},
+		}
+	}
+	offline := wire.SNACMessage{
+		Body: wire.SNACError{
+			Code: wire.ErrorCodeNotLoggedOn,
+		},
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsgs are the expected TOC responses
+		wantMsgs []string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "refresh mixed online and offline buddy list",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_buddies"),
+			mockParams: mockParams{
+				buddyListerParams: buddyListerParams{
+					buddiesParams: buddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							buddies: []state.IdentScreenName{
+								state.NewIdentScreenName("alice"),
+								state.NewIdentScreenName("bob"),
+								state.NewIdentScreenName("carol"),
+							},
+						},
+					},
+				},
+				locateParams: locateParams{
+					userInfoQueryParams: append(append(
+						fnUserInfoQuery("alice", wire.SNACMessage{
+							Body: wire.SNAC_0x02_0x06_LocateUserInfoReply{
+								TLVUserInfo: wire.TLVUserInfo{
+									ScreenName:   "Alice",
+									WarningLevel: 100,
+									TLVBlock: wire.TLVBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+										},
+									},
+								},
+							},
+						}, nil),
+						// bob is offline
+						fnUserInfoQuery("bob", offline, nil)...),
+						// carol blocks me, so she appears offline
+						fnUserInfoQuery("carol", offline, nil)...),
+				},
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("bob"),
+							user: &state.User{
+								DisplayScreenName: "Bob",
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("carol"),
+							user: &state.User{
+								DisplayScreenName: "Carol",
+							},
+						},
+					},
+				},
+			},
+			wantMsgs: []string{
+				"UPDATE_BUDDY:Alice:T:10:1234:0: O ",
+				"UPDATE_BUDDY:Bob:F:0:0:0:   ",
+				"UPDATE_BUDDY:Carol:F:0:0:0:   ",
+			},
+		},
+		{
+			name:     "refresh empty buddy list",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_buddies"),
+			mockParams: mockParams{
+				buddyListerParams: buddyListerParams{
+					buddiesParams: buddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+			wantMsgs: []string{},
+		},
+		{
+			name:     "refresh buddy list, receive error from buddy lister",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_buddies"),
+			mockParams: mockParams{
+				buddyListerParams: buddyListerParams{
+					buddiesParams: buddiesParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsgs: []string{cmdInternalSvcErr},
+		},
+		{
+			name:     "refresh buddy list, receive error from locate service",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_get_buddies"),
+			mockParams: mockParams{
+				buddyListerParams: buddyListerParams{
+					buddiesParams: buddiesParams{
+						{
+							me:      state.NewIdentScreenName("me"),
+							buddies: []state.IdentScreenName{state.NewIdentScreenName("alice")},
+						},
+					},
+				},
+				locateParams: locateParams{
+					userInfoQueryParams: fnUserInfoQuery("alice", wire.SNACMessage{}, io.EOF),
+				},
+			},
+			wantMsgs: []string{cmdInternalSvcErr},
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte("toc_get_buddies_bad"),
+			wantMsgs: []string{cmdInternalSvcErr},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			buddyLister := newMockBuddyLister(t)
+			for _, params := range tc.mockParams.buddiesParams {
+				buddyLister.EXPECT().
+					Buddies(params.me).
+					Return(params.buddies, params.err)
+			}
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.userInfoQueryParams {
+				locateSvc.EXPECT().
+					UserInfoQuery(mock.Anything, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userLookupParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.user, params.err)
+			}
+
+			svc := OSCARProxy{
+				BuddyLister:   buddyLister,
+				LocateService: locateSvc,
+				Logger:        slog.Default(),
+				UserManager:   userManager,
+			}
+			msgs := svc.GetBuddies(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsgs, msgs)
+		})
+	}
+}
+
+func TestOSCARProxy_InitDone(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully initialize connection",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_init_done`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					clientOnlineParams: clientOnlineParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "initialize connection, receive err from BOS oservice svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_init_done`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					clientOnlineParams: clientOnlineParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+							err:  io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name: "initialize connection more than once",
+			me: func() *state.Session {
+				sess := newTestSession("me")
+				sess.SetSignonComplete()
+				return sess
+			}(),
+			givenCmd: []byte(`toc_init_done`),
+			wantMsg:  cmdInitDoneRepeated,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_init_done_diff`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			oSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.clientOnlineParams {
+				oSvc.EXPECT().
+					ClientOnline(ctx, params.body, matchSession(params.me)).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:             slog.Default(),
+				OServiceServiceBOS: oSvc,
+			}
+			msg := svc.InitDone(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_RecvClientCmd_InitDoneRepeated(t *testing.T) {
+	me := newTestSession("me")
+	ctx := context.Background()
+
+	oSvc := newMockOServiceService(t)
+	oSvc.EXPECT().
+		ClientOnline(ctx, wire.SNAC_0x01_0x02_OServiceClientOnline{}, matchSession(me.IdentScreenName())).
+		Run(func(ctx context.Context, _ wire.SNAC_0x01_0x02_OServiceClientOnline, sess *state.Session) {
+			sess.SetSignonComplete()
+		}).
+		Return(nil).
+		Once()
+	offlineMsgMgr := newMockOfflineMessageManager(t)
+	offlineMsgMgr.EXPECT().
+		RetrieveMessages(me.IdentScreenName()).
+		Return(nil, nil)
+
+	svc := OSCARProxy{
+		Logger:                slog.Default(),
+		OfflineMessageManager: offlineMsgMgr,
+		OServiceServiceBOS:    oSvc,
+	}
+
+	// the first toc_init_done brings the user online
+	msg, ok := svc.RecvClientCmd(ctx, me, NewChatRegistry(), []byte("toc_init_done"), nil, nil)
+	assert.True(t, ok)
+	assert.Empty(t, msg)
+
+	// the second one drops the connection without announcing the user again
+	msg, ok = svc.RecvClientCmd(ctx, me, NewChatRegistry(), []byte("toc_init_done"), nil, nil)
+	assert.False(t, ok)
+	assert.Equal(t, cmdInitDoneRepeated, msg)
+}
+
+func TestOSCARProxy_RecvClientCmd_UnsupportedCommand(t *testing.T) {
+	svc := OSCARProxy{
+		Logger: slog.Default(),
+	}
+
+	// the client is told which command was rejected, and the connection
+	// stays open
+	msg, ok := svc.RecvClientCmd(context.Background(), newTestSession("me"), NewChatRegistry(), []byte("toc_bogus arg1 arg2"), nil, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "ERROR:911:toc_bogus", msg)
+
+	// a colon in the command name can't split the reply into extra fields
+	msg, ok = svc.RecvClientCmd(context.Background(), newTestSession("me"), NewChatRegistry(), []byte("toc_bo:gus arg1"), nil, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "ERROR:911:toc_bo gus", msg)
+}
+
+func TestOSCARProxy_RecvClientCmd_ProtocolViolations(t *testing.T) {
+	me := newTestSession("me")
+	ctx := context.WithValue(context.Background(), "screenName", me.IdentScreenName())
+
+	svc := OSCARProxy{
+		Config: config.Config{
+			TOCMaxProtocolViolations: 3,
+		},
+		Logger:           slog.Default(),
+		ViolationCounter: NewViolationCounter(),
+	}
+
+	send := func(cmd string) (string, bool) {
+		return svc.RecvClientCmd(ctx, me, NewChatRegistry(), []byte(cmd), nil, nil)
+	}
+
+	// a valid command resets the count
+	_, ok := send("toc_garbage")
+	assert.True(t, ok)
+	_, ok = send("toc_get_status")
+	assert.True(t, ok)
+	msg, ok := send("toc_ping")
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(msg, "PONG:"))
+	assert.Zero(t, svc.ViolationCounter.Count(me.IdentScreenName()))
+
+	// repeated garbage eventually disconnects the client
+	for i := 1; i < 3; i++ {
+		_, ok = send("toc_garbage")
+		assert.True(t, ok)
+	}
+	msg, ok = send("toc_get_status")
+	assert.False(t, ok)
+	assert.Equal(t, "ERROR:989:disconnected (too many invalid commands)", msg)
+}
+
+func TestOSCARProxy_RecvClientCmd_ProtocolViolationsDisabled(t *testing.T) {
+	me := newTestSession("me")
+
+	svc := OSCARProxy{
+		Logger:           slog.Default(),
+		ViolationCounter: NewViolationCounter(),
+	}
+
+	for i := 0; i < 100; i++ {
+		_, ok := svc.RecvClientCmd(context.Background(), me, NewChatRegistry(), []byte("toc_garbage"), nil, nil)
+		assert.True(t, ok)
+	}
+}
+
+func TestOSCARProxy_Ping(t *testing.T) {
+	svc := OSCARProxy{
+		Logger: slog.Default(),
+	}
+
+	t.Run("successfully ping", func(t *testing.T) {
+		before := time.Now().Unix()
+		msg := svc.Ping(context.Background(), []byte(`toc_ping`))
+		after := time.Now().Unix()
+
+		ts, found := strings.CutPrefix(msg, "PONG:")
+		if assert.True(t, found) {
+			unix, err := strconv.ParseInt(ts, 10, 64)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, unix, before)
+			assert.LessOrEqual(t, unix, after)
+		}
+	})
+
+	t.Run("bad command", func(t *testing.T) {
+		msg := svc.Ping(context.Background(), []byte(`toc_pong`))
+		assert.Equal(t, cmdInternalSvcErr, msg)
+	})
+}
+
+func TestOSCARProxy_ReportUser(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// cfg is the application config
+		cfg config.Config
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// history is the recent IM history
+		history *IMHistory
+		// throttle is the abuse report rate limiter
+		throttle *IMThrottle
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name: "successfully report user with reason and message context",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCAbuseReports: true,
+			},
+			givenCmd: []byte(`toc_report_user Spammer "unsolicited ads"`),
+			history: func() *IMHistory {
+				history := NewIMHistory()
+				history.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("spammer"), "IM_IN:Spammer:F:hello")
+				history.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("friend"), "IM_IN:friend:F:hi")
+				history.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("spammer"), "IM_IN:Spammer:F:buy now")
+				return history
+			}(),
+			mockParams: mockParams{
+				abuseReportParams: abuseReportParams{
+					addAbuseReportParams: addAbuseReportParams{
+						{
+							report: state.AbuseReport{
+								Reporter: state.NewIdentScreenName("me"),
+								Target:   state.NewIdentScreenName("spammer"),
+								Reason:   "unsolicited ads",
+								Context:  "IM_IN:Spammer:F:hello\nIM_IN:Spammer:F:buy now",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "successfully report user without reason",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCAbuseReports: true,
+			},
+			givenCmd: []byte(`toc_report_user spammer`),
+			mockParams: mockParams{
+				abuseReportParams: abuseReportParams{
+					addAbuseReportParams: addAbuseReportParams{
+						{
+							report: state.AbuseReport{
+								Reporter: state.NewIdentScreenName("me"),
+								Target:   state.NewIdentScreenName("spammer"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "report user, abuse reports disabled",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_report_user spammer`),
+			wantMsg:  "ERROR:981",
+		},
+		{
+			name: "report user, exceed report rate limit",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCAbuseReports: true,
+			},
+			givenCmd: []byte(`toc_report_user spammer`),
+			throttle: func() *IMThrottle {
+				throttle := NewIMThrottle(1, time.Hour)
+				throttle.Allow(state.NewIdentScreenName("me"), state.IdentScreenName{})
+				return throttle
+			}(),
+			wantMsg: "ERROR:903",
+		},
+		{
+			name: "report user, receive error from abuse report store",
+			me:   newTestSession("me"),
+			cfg: config.Config{
+				TOCAbuseReports: true,
+			},
+			givenCmd: []byte(`toc_report_user spammer`),
+			mockParams: mockParams{
+				abuseReportParams: abuseReportParams{
+					addAbuseReportParams: addAbuseReportParams{
+						{
+							report: state.AbuseReport{
+								Reporter: state.NewIdentScreenName("me"),
+								Target:   state.NewIdentScreenName("spammer"),
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_report_user`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			reportStore := newMockAbuseReportStore(t)
+			for _, params := range tc.mockParams.addAbuseReportParams {
+				want := params.report
+				reportStore.EXPECT().
+					AddAbuseReport(mock.MatchedBy(func(report state.AbuseReport) bool {
+						report.Created = time.Time{}
+						return report == want
+					})).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				AbuseReportStore: reportStore,
+				Config:           tc.cfg,
+				IMHistory:        tc.history,
+				Logger:           slog.Default(),
+				ReportThrottle:   tc.throttle,
+			}
+			msg := svc.ReportUser(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_RemoveBuddy(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully remove buddies",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_remove_buddy friend1 friend2 friend3"),
+			mockParams: mockParams{
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					delBuddiesParams: delBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+									{ScreenName: "friend3"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "remove buddies, receive error from buddy service",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_remove_buddy friend1"),
+			mockParams: mockParams{
+				buddyParams: buddyParams{
+					delBuddiesParams: delBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "buddies-only mode removes buddies from the permit list",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_remove_buddy friend1"),
+			mockParams: mockParams{
+				buddyParams: buddyParams{
+					delBuddiesParams: delBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				permitDenyParams: permitDenyParams{
+					delPermListEntriesParams: delPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x06_PermitDenyDelPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "m 5\ng Buddies\nb friend1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "buddies-only mode replaced by a later mode",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_remove_buddy friend1"),
+			mockParams: mockParams{
+				buddyParams: buddyParams{
+					delBuddiesParams: delBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x05_BuddyDelBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "m 5\nm 1\ng Buddies\nb friend1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_remove_buddy_bad`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			buddySvc := newMockBuddyService(t)
+			for _, params := range tc.mockParams.delBuddiesParams {
+				buddySvc.EXPECT().
+					DelBuddies(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+
+			pdSvc := newMockPermitDenyService(t)
+			for _, params := range tc.mockParams.delPermListEntriesParams {
+				pdSvc.EXPECT().
+					DelPermListEntries(ctx, matchSession(params.me), params.body).
+					Return(params.err)
+			}
+
+			tocCfg := newMockTOCConfigStore(t)
+			for _, params := range tc.mockParams.userParams {
+				tocCfg.EXPECT().
+					User(params.screenName).
+					Return(params.returnedUser, params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:            slog.Default(),
+				BuddyService:      buddySvc,
+				PermitDenyService: pdSvc,
+				TOCConfigStore:    tocCfg,
+			}
+			msg := svc.RemoveBuddy(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_RvousAccept(t *testing.T) {
+	fileTransfer := uuid.MustParse("09461343-4C7F-11D1-8222-444553540000")
+	proposal := RvousProposal{
+		Proposer:   state.NewIdentScreenName("chattingChuck"),
+		Cookie:     0x0102030405060708,
+		Capability: fileTransfer,
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// givenProposals are the pending rendezvous proposals
+		givenProposals []RvousProposal
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// wantPending indicates whether the proposal is still pending
+		// afterward
+		wantPending bool
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:           "successfully accept file transfer",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_accept chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000 AAUAAhRG`),
+			givenProposals: []RvousProposal{proposal},
+			wantPending:    true,
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessageAccept,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsPort, uint16(5190)),
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "accept file transfer, proposer signed off",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_accept chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000`),
+			givenProposals: []RvousProposal{proposal},
+			wantMsg:        "ERROR:901:chattingchuck",
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessageAccept,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "accept file transfer, cookie doesn't match proposal",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_accept chattingChuck CAcGBQQDAgE= 09461343-4C7F-11D1-8222-444553540000`),
+			givenProposals: []RvousProposal{proposal},
+			wantMsg:        cmdInternalSvcErr,
+			wantPending:    true,
+		},
+		{
+			name:           "accept file transfer, service doesn't match proposal",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_accept chattingChuck AQIDBAUGBwg= 09461345-4C7F-11D1-8222-444553540000`),
+			givenProposals: []RvousProposal{proposal},
+			wantMsg:        cmdInternalSvcErr,
+			wantPending:    true,
+		},
+		{
+			name:     "accept file transfer, no proposal",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_accept chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:           "accept file transfer, malformed rendezvous data",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_accept chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000 AAUAAhQ=`),
+			givenProposals: []RvousProposal{proposal},
+			wantMsg:        cmdInternalSvcErr,
+			wantPending:    true,
+		},
+		{
+			name:     "accept file transfer, malformed cookie",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_accept chattingChuck not-base64 09461343-4C7F-11D1-8222-444553540000`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
+				icbmSvc.EXPECT().
+					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
+					Return(params.result, params.err)
+			}
+
+			chatRegistry := NewChatRegistry()
+			for _, p := range tc.givenProposals {
+				chatRegistry.AddRvous(p)
+			}
+
+			svc := OSCARProxy{
+				Logger:      slog.Default(),
+				ICBMService: icbmSvc,
+			}
+			msg := svc.RvousAccept(ctx, tc.me, chatRegistry, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+			assert.Equal(t, tc.wantPending, chatRegistry.LookupRvous(proposal))
+		})
+	}
+}
+
+func TestOSCARProxy_RvousCancel(t *testing.T) {
+	fileTransfer := uuid.MustParse("09461343-4C7F-11D1-8222-444553540000")
+	proposal := RvousProposal{
+		Proposer:   state.NewIdentScreenName("chattingChuck"),
+		Cookie:     0x0102030405060708,
+		Capability: fileTransfer,
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// givenProposals are the pending rendezvous proposals
+		givenProposals []RvousProposal
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// wantPending indicates whether the proposal is still pending
+		// afterward
+		wantPending bool
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:           "successfully cancel file transfer",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_cancel chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000`),
+			givenProposals: []RvousProposal{proposal},
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessageCancel,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "cancel file transfer, cookie doesn't match proposal",
+			me:             newTestSession("me"),
+			givenCmd:       []byte(`toc_rvous_cancel chattingChuck CAcGBQQDAgE= 09461343-4C7F-11D1-8222-444553540000`),
+			givenProposals: []RvousProposal{proposal},
+			wantMsg:        cmdInternalSvcErr,
+			wantPending:    true,
+		},
+		{
+			name:     "cancel file transfer, no proposal",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_cancel chattingChuck AQIDBAUGBwg= 09461343-4C7F-11D1-8222-444553540000`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "cancel file transfer, malformed UUID",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_cancel chattingChuck AQIDBAUGBwg= not-a-uuid`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
+				icbmSvc.EXPECT().
+					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
+					Return(params.result, params.err)
+			}
+
+			chatRegistry := NewChatRegistry()
+			for _, p := range tc.givenProposals {
+				chatRegistry.AddRvous(p)
+			}
+
+			svc := OSCARProxy{
+				Logger:      slog.Default(),
+				ICBMService: icbmSvc,
+			}
+			msg := svc.RvousCancel(ctx, tc.me, chatRegistry, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+			assert.Equal(t, tc.wantPending, chatRegistry.LookupRvous(proposal))
+		})
+	}
+}
+
+func TestOSCARProxy_RvousPropose(t *testing.T) {
+	fileTransfer := uuid.MustParse("09461343-4C7F-11D1-8222-444553540000")
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully propose file transfer",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 1 AAUAAhRG`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessagePropose,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsSeqNum, uint16(1)),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsPort, uint16(5190)),
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "propose file transfer, server sets sequence number and verified IP",
+			me: newTestSession("me", func(session *state.Session) {
+				addr := netip.MustParseAddrPort("10.0.0.5:5190")
+				session.SetRemoteAddr(&addr)
+			}),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 2 AAUAAhRGAAQABAECAwQACgACAAk=`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessagePropose,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsSeqNum, uint16(2)),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsPort, uint16(5190)),
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsVerifiedIP, uint32(0x0A000005)),
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "propose file transfer, recipient is offline",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 1`),
+			mockParams: mockParams{
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelRendezvous,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+											Type:       wire.ICBMRdvMessagePropose,
+											Cookie:     0x0102030405060708,
+											Capability: fileTransfer,
+											TLVRestBlock: wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ICBMRdvTLVTagsSeqNum, uint16(1)),
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "propose with malformed UUID",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck not-a-uuid AQIDBAUGBwg= 1`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose chat invitation",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 748F2420-6287-11D1-8222-444553540000 AQIDBAUGBwg= 1`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose with short cookie",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQID 1`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose with zero sequence number",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 0`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose with out of range sequence number",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 65536`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose with malformed rendezvous data",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck 09461343-4C7F-11D1-8222-444553540000 AQIDBAUGBwg= 1 AAUAAhQ=`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "propose with missing arguments",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_rvous_propose chattingChuck`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
+				icbmSvc.EXPECT().
+					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
+					Return(params.result, params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:      slog.Default(),
+				ICBMService: icbmSvc,
+			}
+			msg := svc.RvousPropose(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SendIM(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// cfg is the app configuration
+		cfg config.Config
+		// throttle is the per-recipient IM throttle
+		throttle *IMThrottle
+		// unconfirmedThrottle is the IM throttle for unconfirmed users
+		unconfirmedThrottle *IMThrottle
+		// warnThrottle is the send throttle for warned users
+		warnThrottle *WarnThrottle
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "send instant message, exceed per-recipient throttle",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			throttle: func() *IMThrottle {
+				throttle := NewIMThrottle(1, time.Minute)
+				throttle.Allow(state.NewIdentScreenName("me"), state.NewIdentScreenName("chattingChuck"))
+				return throttle
+			}(),
+			wantMsg: "ERROR:960:chattingChuck",
+		},
+		{
+			name: "send instant message, warned user exceeds send rate",
+			me: newTestSession("me", func(session *state.Session) {
+				session.IncrementWarning(500)
+			}),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			warnThrottle: func() *WarnThrottle {
+				throttle := NewWarnThrottle([]string{"50:1"})
+				throttle.Allow(state.NewIdentScreenName("me"), 500)
+				return throttle
+			}(),
+			wantMsg: "ERROR:903",
+		},
+		{
+			name: "send instant message, unconfirmed user exceeds send rate",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			unconfirmedThrottle: func() *IMThrottle {
+				throttle := NewIMThrottle(1, time.Minute)
+				throttle.Allow(state.NewIdentScreenName("me"), state.IdentScreenName{})
+				return throttle
+			}(),
+			wantMsg: "ERROR:960:chattingChuck",
+		},
+		{
+			name:     "successfully send instant message",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "send instant message while idle, clear idle",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(10 * time.Minute)
+			}),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
+						{
+							me:     state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{IdleTime: 0},
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully auto-reply send instant message",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!" auto`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+										wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "send instant message, receive error from ICBM service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "successfully send instant message to offline user with offline IMs enabled",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			cfg: config.Config{
+				TOCOfflineIMs: true,
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+										wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "send instant message to offline user, offline message queue has room",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			cfg: config.Config{
+				TOCOfflineIMs:    true,
+				TOCMaxOfflineIMs: 2,
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recip:    state.NewIdentScreenName("chattingChuck"),
+							messages: make([]state.OfflineMessage, 1),
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+										wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "send instant message to online user, offline message queue limit set",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			cfg: config.Config{
+				TOCOfflineIMs:    true,
+				TOCMaxOfflineIMs: 2,
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "send instant message to offline user, offline message queue is full",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			cfg: config.Config{
+				TOCOfflineIMs:    true,
+				TOCMaxOfflineIMs: 2,
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recip:    state.NewIdentScreenName("chattingChuck"),
+							messages: make([]state.OfflineMessage, 2),
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "send instant message to offline user",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "send instant message to user who blocks the sender",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeNotLoggedOn,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "send instant message to user the sender blocks",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeInLocalPermitDeny,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "send instant message, ICBM service returns error with no TOC equivalent",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							user:       &state.User{IdentScreenName: state.NewIdentScreenName("chattingChuck")},
+						},
+					},
+				},
+				icbmParams: icbmParams{
+					channelMsgToHostParamsICBM: channelMsgToHostParamsICBM{
+						{
+							sender: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+								ChannelID:  wire.ICBMChannelIM,
+								ScreenName: "chattingchuck",
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+											{
+												ID:      5,
+												Version: 1,
+												Payload: []byte{1, 1, 2},
+											},
+											{
+												ID:      1,
+												Version: 1,
+												Payload: []byte{
+													0x00, 0x00,
+													0x00, 0x00,
+													'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+												},
+											},
+										}),
+									},
+								},
+							},
+							result: &wire.SNACMessage{
+								Body: wire.SNACError{
+									Code: wire.ErrorCodeBustedSnacPayload,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "send instant message to user that doesn't exist",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+						},
+					},
+				},
+			},
+			wantMsg: "ERROR:901:chattingChuck",
+		},
+		{
+			name:     "send instant message, receive error from user manager",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_send_im chattingChuck "hello world!"`),
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					userLookupParams: userLookupParams{
+						{
+							screenName: state.NewIdentScreenName("chattingChuck"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_send_im`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userLookupParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.user, params.err)
+			}
+
+			offlineMessageMgr := newMockOfflineMessageManager(t)
+			for _, params := range tc.mockParams.retrieveMessagesParams {
+				offlineMessageMgr.EXPECT().
+					RetrieveMessages(params.recip).
+					Return(params.messages, params.err)
+			}
+
+			oServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.idleNotificationParams {
+				oServiceSvc.EXPECT().
+					IdleNotification(ctx, matchSession(params.me), params.bodyIn).
+					Return(params.err)
+			}
+
+			icbmSvc := newMockICBMService(t)
+			for _, params := range tc.mockParams.channelMsgToHostParamsICBM {
+				icbmSvc.EXPECT().
+					ChannelMsgToHost(ctx, matchSession(params.sender), params.inFrame, params.inBody).
+					Return(params.result, params.err)
+			}
+
+			svc := OSCARProxy{
+				Config:      tc.cfg,
+				Logger:      slog.Default(),
+				ICBMService: icbmSvc,
+				IMThrottle:  tc.throttle,
+				UserManager: userManager,
+
+				OServiceServiceBOS:    oServiceSvc,
+				OfflineMessageManager: offlineMessageMgr,
+				UnconfirmedIMThrottle: tc.unconfirmedThrottle,
+				WarnThrottle:          tc.warnThrottle,
+			}
+			msg := svc.SendIM(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetAway(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set away with message",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_away "I'm away from my computer right now."`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, "I'm away from my computer right now."),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set away without message",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_away`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, ""),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set away message, receive error from locate service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_away "I'm away from my computer right now."`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, "I'm away from my computer right now."),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_away_bad`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setInfoParams {
+				locateSvc.EXPECT().
+					SetInfo(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+			}
+			msg := svc.SetAway(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_IdleUpdate(t *testing.T) {
+	t.Run("idle time grows with each tick until the user becomes active", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		me := newTestSession("me", func(session *state.Session) {
+			session.SetIdle(2 * time.Minute)
+		})
+		idleSince := me.IdleTime()
+
+		oServiceSvc := newMockOServiceService(t)
+		oServiceSvc.EXPECT().
+			IdleNotification(ctx, matchSession(me.IdentScreenName()), wire.SNAC_0x01_0x11_OServiceIdleNotification{IdleTime: 180}).
+			Return(nil).
+			Once()
+		oServiceSvc.EXPECT().
+			IdleNotification(ctx, matchSession(me.IdentScreenName()), wire.SNAC_0x01_0x11_OServiceIdleNotification{IdleTime: 240}).
+			Run(func(ctx context.Context, sess *state.Session, bodyIn wire.SNAC_0x01_0x11_OServiceIdleNotification) {
+				// the user becomes active again
+				me.UnsetIdle()
+			}).
+			Return(nil).
+			Once()
+
+		svc := OSCARProxy{
+			Logger:             slog.Default(),
+			OServiceServiceBOS: oServiceSvc,
+		}
+
+		tick := make(chan time.Time)
+		done := make(chan error)
+		go func() {
+			done <- svc.IdleUpdate(ctx, me, tick)
+		}()
+
+		tick <- idleSince.Add(3 * time.Minute)
+		tick <- idleSince.Add(4 * time.Minute)
+		// the user is no longer idle, so nothing is announced
+		tick <- idleSince.Add(5 * time.Minute)
+
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("announce idle time, receive error", func(t *testing.T) {
+		ctx := context.Background()
+
+		me := newTestSession("me", func(session *state.Session) {
+			session.SetIdle(2 * time.Minute)
+		})
+
+		oServiceSvc := newMockOServiceService(t)
+		oServiceSvc.EXPECT().
+			IdleNotification(ctx, matchSession(me.IdentScreenName()), mock.Anything).
+			Return(io.EOF)
+
+		svc := OSCARProxy{
+			Logger:             slog.Default(),
+			OServiceServiceBOS: oServiceSvc,
+		}
+
+		tick := make(chan time.Time, 1)
+		tick <- me.IdleTime().Add(3 * time.Minute)
+		assert.ErrorIs(t, svc.IdleUpdate(ctx, me, tick), io.EOF)
+	})
+}
+
+func TestOSCARProxy_AutoAway(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenAutoAway indicates whether the user is already auto-away
+		givenAutoAway bool
+		// wantAutoAway is the expected auto-away state
+		wantAutoAway bool
+		// wantErr is the expected error
+		wantErr error
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name: "idle time crosses threshold, set away message",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(11 * time.Minute)
+			}),
+			wantAutoAway: true,
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, autoAwayMessage),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "idle time below threshold, do nothing",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(9 * time.Minute)
+			}),
+			wantAutoAway: false,
+		},
+		{
+			name: "idle time crosses threshold, user already away, do nothing",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(11 * time.Minute)
+				session.SetAwayMessage("gone fishing")
+			}),
+			wantAutoAway: false,
+		},
+		{
+			name: "auto-away user becomes active, clear away message",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetAwayMessage(autoAwayMessage)
+			}),
+			givenAutoAway: true,
+			wantAutoAway:  false,
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, ""),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "auto-away user replaces away message, stop managing away message",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(11 * time.Minute)
+				session.SetAwayMessage("gone fishing")
+			}),
+			givenAutoAway: true,
+			wantAutoAway:  false,
+		},
+		{
+			name: "idle time crosses threshold, receive error from locate service",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetIdle(11 * time.Minute)
+			}),
+			wantAutoAway: false,
+			wantErr:      io.EOF,
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, autoAwayMessage),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setInfoParams {
+				locateSvc.EXPECT().
+					SetInfo(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Config: config.Config{
+					TOCAutoAwayMins: 10,
+				},
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+			}
+			autoAway, err := svc.updateAutoAway(ctx, tc.me, tc.givenAutoAway)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantAutoAway, autoAway)
+		})
+	}
+}
+
+func TestOSCARProxy_SetBuddyComment(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "set buddy comment",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_buddy_comment "Them" "met at the LAN party"`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					setBuddyCommentParams: setBuddyCommentParams{
+						{
+							me:      state.NewIdentScreenName("me"),
+							them:    state.NewIdentScreenName("them"),
+							comment: "met at the LAN party",
+						},
+					},
+				},
+			},
+			wantMsg: "",
+		},
+		{
+			name:     "clear buddy comment",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_buddy_comment them`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					setBuddyCommentParams: setBuddyCommentParams{
+						{
+							me:   state.NewIdentScreenName("me"),
+							them: state.NewIdentScreenName("them"),
+						},
+					},
+				},
+			},
+			wantMsg: "",
+		},
+		{
+			name:     "set buddy comment that exceeds maximum length",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_buddy_comment them "` + strings.Repeat("a", maxBuddyCommentLen+1) + `"`),
+			wantMsg:  "ERROR:911:them",
+		},
+		{
+			name:     "set buddy comment, receive error from buddy comment store",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_buddy_comment them "met at the LAN party"`),
+			mockParams: mockParams{
+				buddyCommentStoreParams: buddyCommentStoreParams{
+					setBuddyCommentParams: setBuddyCommentParams{
+						{
+							me:      state.NewIdentScreenName("me"),
+							them:    state.NewIdentScreenName("them"),
+							comment: "met at the LAN party",
+							err:     io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_buddy_comment`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			commentStore := newMockBuddyCommentStore(t)
+			for _, params := range tc.mockParams.setBuddyCommentParams {
+				commentStore.EXPECT().
+					SetBuddyComment(params.me, params.them, params.comment).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				BuddyCommentStore: commentStore,
+				Logger:            slog.Default(),
+			}
+			msg := svc.SetBuddyComment(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetCaps(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set capabilities",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000 09460001-4C7F-11D1-8222-444553540000`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
+											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
+											uuid.MustParse("09460001-4C7F-11D1-8222-444553540000"),
+											capChat,
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set capabilities that include chat",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 748F2420-6287-11D1-8222-444553540000 09460000-4C7F-11D1-8222-444553540000`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
+											capChat,
+											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set duplicate capabilities and empty arguments",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000 "" 09460000-4C7F-11D1-8222-444553540000`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
+											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
+											capChat,
+										}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set too many capabilities",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000 09460000-4C7F-11D1-8222-444553540001 09460000-4C7F-11D1-8222-444553540002 09460000-4C7F-11D1-8222-444553540003 09460000-4C7F-11D1-8222-444553540004 09460000-4C7F-11D1-8222-444553540005 09460000-4C7F-11D1-8222-444553540006 09460000-4C7F-11D1-8222-444553540007 09460000-4C7F-11D1-8222-444553540008 09460000-4C7F-11D1-8222-444553540009 09460000-4C7F-11D1-8222-44455354000A 09460000-4C7F-11D1-8222-44455354000B 09460000-4C7F-11D1-8222-44455354000C 09460000-4C7F-11D1-8222-44455354000D 09460000-4C7F-11D1-8222-44455354000E 09460000-4C7F-11D1-8222-44455354000F 09460000-4C7F-11D1-8222-444553540010 09460000-4C7F-11D1-8222-444553540011 09460000-4C7F-11D1-8222-444553540012 09460000-4C7F-11D1-8222-444553540013 09460000-4C7F-11D1-8222-444553540014 09460000-4C7F-11D1-8222-444553540015 09460000-4C7F-11D1-8222-444553540016 09460000-4C7F-11D1-8222-444553540017 09460000-4C7F-11D1-8222-444553540018 09460000-4C7F-11D1-8222-444553540019 09460000-4C7F-11D1-8222-44455354001A 09460000-4C7F-11D1-8222-44455354001B 09460000-4C7F-11D1-8222-44455354001C 09460000-4C7F-11D1-8222-44455354001D 09460000-4C7F-11D1-8222-44455354001E 09460000-4C7F-11D1-8222-44455354001F`),
+			wantMsg:  "ERROR:911",
+		},
+		{
+			name:     "set capability, receive error from locate service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 09460000-4C7F-11D1-8222-444553540000`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, []uuid.UUID{
+											uuid.MustParse("09460000-4C7F-11D1-8222-444553540000"),
+											capChat,
+										}),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set malformed capability UUID",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_caps 09460000-`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_caps_bad`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setInfoParams {
+				locateSvc.EXPECT().
+					SetInfo(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+			}
+			msg := svc.SetCaps(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetConfig(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set permit all config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "permit all" mode requires adding a
+					// deny list entry
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 1\ng Buddies\nb friend1\nb friend2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set permit all config, receive err from config store svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "permit all" mode requires adding a
+					// deny list entry
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 1\ng Buddies\nb friend1",
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set permit all config, receive err from buddy svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "permit all" mode requires adding a
+					// deny list entry
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set permit all config, receive err from permit-deny svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 1\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "permit all" mode requires adding a
+					// deny list entry
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "successfully set deny all config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 2\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "deny all" mode requires adding a
+					// permit list entry
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 2\ng Buddies\nb friend1\nb friend2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set deny all config, receive err from permit-deny svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 2\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					// confusingly, setting "deny all" mode requires adding a
+					// permit list entry
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "successfully set permit some config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend3"},
+									{ScreenName: "friend4"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set permit some config, receive err from permit-deny svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 3\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend3"},
+									{ScreenName: "friend4"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "successfully set buddies-only config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 5\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addPermListEntriesParams: addPermListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x05_PermitDenyAddPermListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 5\np friend3\np friend4\n\ng Buddies\nb friend1\nb friend2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set deny some config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend3"},
+									{ScreenName: "friend4"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "friend2"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set deny some config, receive err from permit-deny svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 4\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend3"},
+									{ScreenName: "friend4"},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "successfully set config containing braces and quotes",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 1\ng \"Best\" {Friends}\nb friend1\nb my friend\n}\n"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "myfriend"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 1\ng \"Best\" {Friends}\nb friend1\nb my friend",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set quoted config containing escaped characters",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config \"m 1\ng Say \\\"hi\\\" \\{now\\}\nb friend1\nb my friend\n\""),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "myfriend"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfigParams: setTOCConfigParams{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m 1\ng Say \"hi\" {now}\nb friend1\nb my friend",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set unknown PD mode",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config {m 6\nd friend3\nd friend4\n\ng Buddies\nb friend1\nb friend2\n}\n"),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_chat_leave`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			pdSvc := newMockPermitDenyService(t)
+			for _, params := range tc.mockParams.addDenyListEntriesParams {
+				pdSvc.EXPECT().
+					AddDenyListEntries(ctx, matchSession(params.me), params.body).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.addPermListEntriesParams {
+				pdSvc.EXPECT().
+					AddPermListEntries(ctx, matchSession(params.me), params.body).
+					Return(params.err)
+			}
+			buddySvc := newMockBuddyService(t)
+			for _, params := range tc.mockParams.addBuddiesParams {
+				buddySvc.EXPECT().
+					AddBuddies(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+			tocConfigSvc := newMockTOCConfigStore(t)
+			for _, params := range tc.mockParams.setTOCConfigParams {
+				tocConfigSvc.EXPECT().
+					SetTOCConfig(params.user, params.config).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				BuddyService:      buddySvc,
+				Logger:            slog.Default(),
+				PermitDenyService: pdSvc,
+				TOCConfigStore:    tocConfigSvc,
+			}
+			msg := svc.SetConfig(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetConfig2(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set deny some config",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config2 {m:4\nd:foe1\ng:Buddies\nb:friend1\nb:my friend\ndone:\n}"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "foe1"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+									{ScreenName: "myfriend"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfig2Params: setTOCConfig2Params{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m:4\nd:foe1\ng:Buddies\nb:friend1\nb:my friend\ndone:",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set config, receive err from config store svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config2 {m:1\ng:Buddies\nb:friend1\n}"),
+			mockParams: mockParams{
+				permitDenyParams: permitDenyParams{
+					addDenyListEntriesParams: addDenyListEntriesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							body: wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+								Users: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "me"},
+								},
+							},
+						},
+					},
+				},
+				buddyParams: buddyParams{
+					addBuddiesParams: addBuddiesParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+								Buddies: []struct {
+									ScreenName string `oscar:"len_prefix=uint8"`
+								}{
+									{ScreenName: "friend1"},
+								},
+							},
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					setTOCConfig2Params: setTOCConfig2Params{
+						{
+							user:   state.NewIdentScreenName("me"),
+							config: "m:1\ng:Buddies\nb:friend1",
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set unknown PD mode",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_config2 {m:6\ng:Buddies\nb:friend1\n}"),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_config`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			pdSvc := newMockPermitDenyService(t)
+			for _, params := range tc.mockParams.addDenyListEntriesParams {
+				pdSvc.EXPECT().
+					AddDenyListEntries(ctx, matchSession(params.me), params.body).
+					Return(params.err)
+			}
+			buddySvc := newMockBuddyService(t)
+			for _, params := range tc.mockParams.addBuddiesParams {
+				buddySvc.EXPECT().
+					AddBuddies(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+			tocConfigSvc := newMockTOCConfigStore(t)
+			for _, params := range tc.mockParams.setTOCConfig2Params {
+				tocConfigSvc.EXPECT().
+					SetTOCConfig2(params.user, params.config).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				BuddyService:      buddySvc,
+				Logger:            slog.Default(),
+				PermitDenyService: pdSvc,
+				TOCConfigStore:    tocConfigSvc,
+			}
+			msg := svc.SetConfig2(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetConfig_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	me := newTestSession("me")
+
+	configs := []string{
+		"m 1\ng Buddies\nb friend1\n",
+		"m 1\ng Buddies\nb friend2\n",
+	}
+
+	// events records the order of the service calls made by each config
+	// write
+	var events []string
+	var eventsMu sync.Mutex
+	record := func(event string) {
+		eventsMu.Lock()
+		defer eventsMu.Unlock()
+		events = append(events, event)
+	}
+
+	pdSvc := newMockPermitDenyService(t)
+	pdSvc.EXPECT().
+		AddDenyListEntries(ctx, matchSession(me.IdentScreenName()), mock.Anything).
+		Return(nil)
+
+	buddySvc := newMockBuddyService(t)
+	buddySvc.EXPECT().
+		AddBuddies(ctx, matchSession(me.IdentScreenName()), mock.Anything).
+		Run(func(_ context.Context, _ *state.Session, inBody wire.SNAC_0x03_0x04_BuddyAddBuddies) {
+			record("buddies:" + inBody.Buddies[0].ScreenName)
+			// widen the window in which another write could interleave
+			time.Sleep(10 * time.Millisecond)
+		}).
+		Return(nil)
+
+	var stored string
+	tocConfigSvc := newMockTOCConfigStore(t)
+	tocConfigSvc.EXPECT().
+		SetTOCConfig(me.IdentScreenName(), mock.Anything).
+		Run(func(_ state.IdentScreenName, config string) {
+			record("config:" + config)
+			eventsMu.Lock()
+			defer eventsMu.Unlock()
+			stored = config
+		}).
+		Return(nil)
+
+	svc := OSCARProxy{
+		BuddyService:      buddySvc,
+		ConfigLocker:      NewConfigLocker(),
+		Logger:            slog.Default(),
+		PermitDenyService: pdSvc,
+		TOCConfigStore:    tocConfigSvc,
+	}
+
+	wg := sync.WaitGroup{}
+	for _, config := range configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := svc.SetConfig(ctx, me, []byte(fmt.Sprintf("toc_set_config {%s}", config)))
+			assert.Empty(t, msg)
+		}()
+	}
+	wg.Wait()
+
+	// each write's buddy list update must be immediately followed by its
+	// own config save
+	assert.Len(t, events, 4)
+	for i := 0; i < len(events); i += 2 {
+		buddy := strings.TrimPrefix(events[i], "buddies:")
+		assert.True(t, strings.HasPrefix(events[i+1], "config:"))
+		assert.Contains(t, events[i+1], "b "+buddy)
+	}
+
+	// the stored config reflects the last applied write
+	lastBuddy := strings.TrimPrefix(events[2], "buddies:")
+	assert.Contains(t, stored, "b "+lastBuddy)
+}
+
+func TestOSCARProxy_SetDir(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// cfg is the server configuration
+		cfg config.Config
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name: "unconfirmed user is not allowed to set directory info",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			cfg: config.Config{
+				TOCUnconfirmedDirListing: false,
+			},
+			givenCmd: []byte(`toc_set_dir "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email":"allow web searches"`),
+			wantMsg:  "ERROR:979:please confirm your account to list yourself in the directory",
+		},
+		{
+			name:     "successfully set directory info with quoted fields",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email":"allow web searches"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setDirInfoParams: setDirInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
+										wire.NewTLVBE(wire.ODirTLVMiddleName, "middle name"),
+										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
+										wire.NewTLVBE(wire.ODirTLVMaidenName, "maiden name"),
+										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
+										wire.NewTLVBE(wire.ODirTLVState, "state"),
+										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set directory info with some blank fields",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country":"email":"allow web searches"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setDirInfoParams: setDirInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
+										wire.NewTLVBE(wire.ODirTLVMiddleName, ""),
+										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
+										wire.NewTLVBE(wire.ODirTLVMaidenName, ""),
+										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
+										wire.NewTLVBE(wire.ODirTLVState, "state"),
+										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set directory info with last two fields absent",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setDirInfoParams: setDirInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
+										wire.NewTLVBE(wire.ODirTLVMiddleName, ""),
+										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
+										wire.NewTLVBE(wire.ODirTLVMaidenName, ""),
+										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
+										wire.NewTLVBE(wire.ODirTLVState, "state"),
+										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set directory info, receive error from locate svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir "first name":"middle name":"last name":"maiden name":"city":"state":"country":"email":"allow web searches"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setDirInfoParams: setDirInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x09_LocateSetDirInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVFirstName, "first name"),
+										wire.NewTLVBE(wire.ODirTLVMiddleName, "middle name"),
+										wire.NewTLVBE(wire.ODirTLVLastName, "last name"),
+										wire.NewTLVBE(wire.ODirTLVMaidenName, "maiden name"),
+										wire.NewTLVBE(wire.ODirTLVCountry, "country"),
+										wire.NewTLVBE(wire.ODirTLVState, "state"),
+										wire.NewTLVBE(wire.ODirTLVCity, "city"),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set directory with too many fields present",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir "first name"::"last name"::"city":"state":"country":"email":"allow web searches":"extra":"extra"`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_dir`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setDirInfoParams {
+				locateSvc.EXPECT().
+					SetDirInfo(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+
+			svc := OSCARProxy{
+				Config:        tc.cfg,
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+			}
+			msg := svc.SetDir(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetDirKeywords(t *testing.T) {
+	keywordList := keywordListQueryParams{
+		{
+			msg: wire.SNACMessage{
+				Body: wire.SNAC_0x0F_0x04_KeywordListReply{
+					Status: 0x01,
+					Interests: []wire.ODirKeywordListItem{
+						{Type: wire.ODirKeywordCategory, ID: 1, Name: "Sports"},
+						{Type: wire.ODirKeyword, ID: 1, Name: "Baseball"},
+						{Type: wire.ODirKeyword, ID: 1, Name: "Hockey"},
+						{Type: wire.ODirKeyword, ID: 0, Name: "Cooking"},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// cfg is the application config
+		cfg config.Config
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "set directory keywords",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords hockey "Cooking"`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordList,
+				},
+				locateParams: locateParams{
+					setKeywordInfoParams: setKeywordInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVInterest, "Hockey"),
+										wire.NewTLVBE(wire.ODirTLVInterest, "Cooking"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantMsg: "",
+		},
+		{
+			name:     "clear directory keywords",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordList,
+				},
+				locateParams: locateParams{
+					setKeywordInfoParams: setKeywordInfoParams{
+						{
+							me:     state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{},
+						},
+					},
+				},
+			},
+			wantMsg: "",
+		},
+		{
+			name:     "set unknown directory keyword",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords hockey curling`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordList,
+				},
+			},
+			wantMsg: "ERROR:911:curling",
+		},
+		{
+			name:     "set keyword category as directory keyword",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords sports`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordList,
+				},
+			},
+			wantMsg: "ERROR:911:sports",
+		},
+		{
+			name:     "set too many directory keywords",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords a b c d e f`),
+			wantMsg:  "ERROR:911",
+		},
+		{
+			name: "unconfirmed user sets directory keywords when not allowed",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}),
+			givenCmd: []byte(`toc_set_dir_keywords hockey`),
+			wantMsg:  "ERROR:979:please confirm your account to list yourself in the directory",
+		},
+		{
+			name:     "set directory keywords, receive error from dir search service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords hockey`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordListQueryParams{
+						{
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set directory keywords, receive error from locate service",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords hockey`),
+			mockParams: mockParams{
+				dirSearchParams: dirSearchParams{
+					keywordListQueryParams: keywordList,
+				},
+				locateParams: locateParams{
+					setKeywordInfoParams: setKeywordInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ODirTLVInterest, "Hockey"),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_dir_keywords_bad hockey`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			dirSearchSvc := newMockDirSearchService(t)
+			for _, params := range tc.mockParams.keywordListQueryParams {
+				dirSearchSvc.EXPECT().
+					KeywordListQuery(ctx, wire.SNACFrame{}).
+					Return(params.msg, params.err)
+			}
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setKeywordInfoParams {
+				locateSvc.EXPECT().
+					SetKeywordInfo(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+
+			svc := OSCARProxy{
+				Config:           tc.cfg,
+				DirSearchService: dirSearchSvc,
+				LocateService:    locateSvc,
+				Logger:           slog.Default(),
+			}
+			msg := svc.SetDirKeywords(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetIcon(t *testing.T) {
+	icon := []byte("GIF89a-buddy-icon")
+	iconHash := md5.Sum(icon)
+	iconB64 := base64.StdEncoding.EncodeToString(icon)
+
+	// iconItem returns a buddy icon feedbag item that references hash
+	iconItem := func(groupID uint16, itemID uint16, hash []byte) wire.FeedbagItem {
+		return wire.FeedbagItem{
+			Name:    "1",
+			GroupID: groupID,
+			ItemID:  itemID,
+			ClassID: wire.FeedbagClassIdBart,
+			TLVLBlock: wire.TLVLBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+						Flags: wire.BARTFlagsCustom,
+						Hash:  hash,
+					}),
+				},
+			},
+		}
+	}
+	// feedbagReply returns a feedbag query reply containing items
+	feedbagReply := func(items ...wire.FeedbagItem) wire.SNACMessage {
+		return wire.SNACMessage{
+			Body: wire.SNAC_0x13_0x06_FeedbagReply{
+				Items: items,
+			},
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "set first buddy icon",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon " + iconB64),
+			mockParams: mockParams{
+				feedbagParams: feedbagParams{
+					feedbagQueryParams: feedbagQueryParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							msg: feedbagReply(
+								wire.FeedbagItem{GroupID: 0, ItemID: 0, ClassID: wire.FeedbagClassIdGroup},
+								wire.FeedbagItem{GroupID: 0, ItemID: 1, ClassID: wire.FeedbagClassIdPdinfo},
+								wire.FeedbagItem{GroupID: 1, ItemID: 2, ClassID: wire.FeedbagClassIdBuddy, Name: "them"},
+							),
+						},
+					},
+					feedbagUpsertItemParams: feedbagUpsertItemParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{iconItem(0, 2, iconHash[:])},
+						},
+					},
+				},
+				bartParams: bartParams{
+					bartUpsertItemParams: bartUpsertItemParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x10_0x02_BARTUploadQuery{
+								Type: wire.BARTTypesBuddyIcon,
+								Data: icon,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "replace existing buddy icon",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon " + iconB64),
+			mockParams: mockParams{
+				feedbagParams: feedbagParams{
+					feedbagQueryParams: feedbagQueryParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							msg: feedbagReply(iconItem(0, 1234, []byte("oldhash"))),
+						},
+					},
+					feedbagUpsertItemParams: feedbagUpsertItemParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{iconItem(0, 1234, iconHash[:])},
+						},
+					},
+				},
+				bartParams: bartParams{
+					bartUpsertItemParams: bartUpsertItemParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x10_0x02_BARTUploadQuery{
+								Type: wire.BARTTypesBuddyIcon,
+								Data: icon,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "clear buddy icon",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon"),
+			mockParams: mockParams{
+				feedbagParams: feedbagParams{
+					feedbagQueryParams: feedbagQueryParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							msg: feedbagReply(iconItem(0, 1234, iconHash[:])),
+						},
+					},
+					feedbagUpsertItemParams: feedbagUpsertItemParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{iconItem(0, 1234, wire.GetClearIconHash())},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "icon exceeds maximum size",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon " + base64.StdEncoding.EncodeToString(make([]byte, maxIconLen+1))),
+			wantMsg:  "ERROR:911",
+		},
+		{
+			name:     "icon is not base64-encoded",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon not-base64!"),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "feedbag query fails",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon " + iconB64),
+			wantMsg:  cmdInternalSvcErr,
+			mockParams: mockParams{
+				feedbagParams: feedbagParams{
+					feedbagQueryParams: feedbagQueryParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "icon upload fails",
+			me:       newTestSession("me"),
+			givenCmd: []byte("toc_set_icon " + iconB64),
+			wantMsg:  cmdInternalSvcErr,
+			mockParams: mockParams{
+				feedbagParams: feedbagParams{
+					feedbagQueryParams: feedbagQueryParams{
+						{
+							me:  state.NewIdentScreenName("me"),
+							msg: feedbagReply(),
+						},
+					},
+					feedbagUpsertItemParams: feedbagUpsertItemParams{
+						{
+							me:    state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{iconItem(0, 1, iconHash[:])},
+						},
+					},
+				},
+				bartParams: bartParams{
+					bartUpsertItemParams: bartUpsertItemParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x10_0x02_BARTUploadQuery{
+								Type: wire.BARTTypesBuddyIcon,
+								Data: icon,
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			feedbagSvc := newMockFeedbagService(t)
+			for _, params := range tc.mockParams.feedbagQueryParams {
+				feedbagSvc.EXPECT().
+					Query(ctx, matchSession(params.me), wire.SNACFrame{}).
+					Return(params.msg, params.err)
+			}
+			for _, params := range tc.mockParams.feedbagUpsertItemParams {
+				feedbagSvc.EXPECT().
+					UpsertItem(ctx, matchSession(params.me), wire.SNACFrame{}, params.items).
+					Return(params.msg, params.err)
+			}
+			bartSvc := newMockBARTService(t)
+			for _, params := range tc.mockParams.bartUpsertItemParams {
+				bartSvc.EXPECT().
+					UpsertItem(ctx, matchSession(params.me), wire.SNACFrame{}, params.inBody).
+					Return(params.msg, params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:         slog.Default(),
+				BARTService:    bartSvc,
+				FeedbagService: feedbagSvc,
+			}
+			msg := svc.SetIcon(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetIdle(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set idle status",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_idle 10`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
+								IdleTime: uint32(10),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "negative idle time is clamped to zero",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_idle -10`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
+								IdleTime: uint32(0),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "huge idle time is clamped to max idle time",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_idle 99999999999`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
+								IdleTime: uint32(maxIdleSecs),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set idle status, receive err from BOS oservice svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_idle 10`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					idleNotificationParams: idleNotificationParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
+								IdleTime: uint32(10),
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "bad secs param",
+			givenCmd: []byte(`toc_set_idle zero`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_idle`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			oServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.idleNotificationParams {
+				oServiceSvc.EXPECT().
+					IdleNotification(ctx, matchSession(params.me), params.bodyIn).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:             slog.Default(),
+				OServiceServiceBOS: oServiceSvc,
+			}
+			msg := svc.SetIdle(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetStatus(t *testing.T) {
+	withUIN := func(session *state.Session) {
+		session.SetUIN(100003)
+	}
+	fnStatusBody := func(status uint32) wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields {
+		return wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.OServiceUserInfoStatus, status),
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set do not disturb",
+			me:       newTestSession("100003", withUIN),
+			givenCmd: []byte(`toc_set_status dnd`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					setUserInfoFieldsParams: setUserInfoFieldsParams{
+						{
+							me: state.NewIdentScreenName("100003"),
+							bodyIn: fnStatusBody(wire.OServiceUserStatusAway |
+								wire.OServiceUserStatusBusy | wire.OServiceUserStatusDND),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set free for chat",
+			me:       newTestSession("100003", withUIN),
+			givenCmd: []byte(`toc_set_status FFC`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					setUserInfoFieldsParams: setUserInfoFieldsParams{
+						{
+							me:     state.NewIdentScreenName("100003"),
+							bodyIn: fnStatusBody(wire.OServiceUserStatusChat),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "successfully set online",
+			me:       newTestSession("100003", withUIN),
+			givenCmd: []byte(`toc_set_status online`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					setUserInfoFieldsParams: setUserInfoFieldsParams{
+						{
+							me:     state.NewIdentScreenName("100003"),
+							bodyIn: fnStatusBody(wire.OServiceUserStatusAvailable),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set status, receive error from oservice service",
+			me:       newTestSession("100003", withUIN),
+			givenCmd: []byte(`toc_set_status na`),
+			mockParams: mockParams{
+				oServiceBOSParams: oServiceParams{
+					setUserInfoFieldsParams: setUserInfoFieldsParams{
+						{
+							me:     state.NewIdentScreenName("100003"),
+							bodyIn: fnStatusBody(wire.OServiceUserStatusAway | wire.OServiceUserStatusOut),
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set status as AIM user",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_status dnd`),
+			wantMsg:  "ERROR:911:dnd",
+		},
+		{
+			name:     "set unknown status",
+			me:       newTestSession("100003", withUIN),
+			givenCmd: []byte(`toc_set_status sleepy`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_status_bad`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			oServiceSvc := newMockOServiceService(t)
+			for _, params := range tc.mockParams.oServiceBOSParams.setUserInfoFieldsParams {
+				oServiceSvc.EXPECT().
+					SetUserInfoFields(ctx, matchSession(params.me), wire.SNACFrame{}, params.bodyIn).
+					Return(wire.SNACMessage{}, params.err)
+			}
+
+			svc := OSCARProxy{
+				Logger:             slog.Default(),
+				OServiceServiceBOS: oServiceSvc,
+			}
+			msg := svc.SetStatus(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_SetInfo(t *testing.T) {
+	withClientVersion := func(version string) func(session *state.Session) {
+		return func(session *state.Session) {
+			session.SetClientID(version)
+		}
+	}
+	tieredCfg := config.Config{
+		TOCMaxProfileLen:    8,
+		TOCProfileLenLimits: []string{"TIC:TiK:10", "TOC2:64"},
+	}
+	profile := strings.Repeat("a", 32)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// cfg is the application config
+		cfg config.Config
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name:     "successfully set profile",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_info "my profile!"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, "my profile!"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set profile, receive error from locate svc",
+			me:       newTestSession("me"),
+			givenCmd: []byte(`toc_set_info "my profile!"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, "my profile!"),
+									},
+								},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+			wantMsg: cmdInternalSvcErr,
+		},
+		{
+			name:     "set profile within newer client's limit",
+			me:       newTestSession("me", withClientVersion("TOC2 Client 1.0")),
+			cfg:      tieredCfg,
+			givenCmd: []byte(`toc_set_info "` + profile + `"`),
+			mockParams: mockParams{
+				locateParams: locateParams{
+					setInfoParams: setInfoParams{
+						{
+							me: state.NewIdentScreenName("me"),
+							inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, profile),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "set profile exceeding older client's limit",
+			me:       newTestSession("me", withClientVersion("TIC:TiK")),
+			cfg:      tieredCfg,
+			givenCmd: []byte(`toc_set_info "` + profile + `"`),
+			wantMsg:  "ERROR:911",
+		},
+		{
+			name:     "set profile exceeding default limit for unknown client",
+			me:       newTestSession("me", withClientVersion("mystery client")),
+			cfg:      tieredCfg,
+			givenCmd: []byte(`toc_set_info "` + profile + `"`),
+			wantMsg:  "ERROR:911",
+		},
+		{
+			name:     "bad command",
+			givenCmd: []byte(`toc_set_info`),
+			wantMsg:  cmdInternalSvcErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			locateSvc := newMockLocateService(t)
+			for _, params := range tc.mockParams.setInfoParams {
+				locateSvc.EXPECT().
+					SetInfo(ctx, matchSession(params.me), params.inBody).
+					Return(params.err)
+			}
+
+			svc := OSCARProxy{
+				Config:        tc.cfg,
+				Logger:        slog.Default(),
+				LocateService: locateSvc,
+			}
+			msg := svc.SetInfo(ctx, tc.me, tc.givenCmd)
+
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestOSCARProxy_Signon(t *testing.T) {
+	roastedPass := wire.RoastTOCPassword([]byte("thepass"), wire.TOCRoastingString)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// cfg is the application config
+		cfg config.Config
+		// givenCmd is the TOC command
+		givenCmd []byte
+		// wantMsg is the expected TOC response
+		wantMsg []string
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name: "successfully login",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+			}),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config"},
+		},
+		{
+			name: "successfully login with toc2_signon",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("TOC2 Client")
+			}),
+			givenCmd: []byte(`toc2_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TOC2 Client" 160 97308224`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC2.0", "CONFIG:my-toc-config"},
+		},
+		{
+			name: "successfully login with toc2_signon, receive TOC2 config",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("TOC2 Client")
+			}),
+			givenCmd: []byte(`toc2_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TOC2 Client" 160 97308224`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig:  "my-toc-config",
+								TOCConfig2: "my-toc2-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC2.0", "CONFIG2:my-toc2-config"},
+		},
+		{
+			name: "successfully login, ignore TOC2 config",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+			}),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig:  "my-toc-config",
+								TOCConfig2: "my-toc2-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config"},
+		},
+		{
+			name:     "login with toc2_signon, bad code",
+			givenCmd: []byte(`toc2_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TOC2 Client" 160 12345`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "login with toc2_signon, missing code",
+			givenCmd: []byte(`toc2_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TOC2 Client"`),
+			wantMsg:  []string{cmdInternalSvcErr},
+		},
+		{
+			name: "successfully login with allowed client version",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("TIC:TiK")
+			}),
+			cfg: config.Config{
+				TOCAllowedClients: []string{"TIC:*"},
+				TOCBlockedClients: []string{"TIC:Old*"},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TIC:TiK"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config"},
+		},
+		{
+			name: "successfully login, receive TiK login banner",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("TIC:TiK 0.90")
+			}),
+			cfg: config.Config{
+				TOCLoginBanner: "Welcome!",
+				TOCLoginBanners: []string{
+					"TIC:TiK*=Welcome TiK user: see the FAQ for setup tips",
+					"*gaim*=https://example.com/gaim-setup",
+				},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TIC:TiK 0.90"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config", "IM_IN:[System]:F:Welcome TiK user: see the FAQ for setup tips"},
+		},
+		{
+			name: "successfully login, receive gaim login banner URL",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("Gaim 0.59")
+			}),
+			cfg: config.Config{
+				TOCLoginBanner: "Welcome!",
+				TOCLoginBanners: []string{
+					"TIC:TiK*=Welcome TiK user: see the FAQ for setup tips",
+					"*gaim*=https://example.com/gaim-setup",
+				},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "Gaim 0.59"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config", "GOTO_URL:banner:https://example.com/gaim-setup"},
+		},
+		{
+			name: "successfully login, receive default login banner",
 			me: newTestSession("me", func(session *state.Session) {
 				session.SetCaps([][16]byte{capChat})
+				session.SetClientID("TOC2 Client")
 			}),
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			cfg: config.Config{
+				TOCLoginBanner: "Welcome!",
+				TOCLoginBanners: []string{
+					"TIC:TiK*=Welcome TiK user: see the FAQ for setup tips",
+					"*gaim*=https://example.com/gaim-setup",
+				},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TOC2 Client"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3616,11 +10104,27 @@ func TestOSCARProxy_Signon(t *testing.T) {
 					},
 				},
 			},
-			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config"},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config", "IM_IN:[System]:F:Welcome!"},
+		},
+		{
+			name: "login with blocked client version",
+			cfg: config.Config{
+				TOCBlockedClients: []string{"TIC:Old*"},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "TIC:OldTiK 1.0"`),
+			wantMsg:  []string{"ERROR:989:this client version is not supported, please upgrade your client"},
+		},
+		{
+			name: "login with client version missing from allow list",
+			cfg: config.Config{
+				TOCAllowedClients: []string{"TIC:TiK"},
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `" english "Gaim/0.59"`),
+			wantMsg:  []string{"ERROR:989:this client version is not supported, please upgrade your client"},
 		},
 		{
 			name:     "login, receive error from auth svc FLAP login",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3643,7 +10147,7 @@ func TestOSCARProxy_Signon(t *testing.T) {
 		},
 		{
 			name:     "login, receive error from auth svc registration",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3676,7 +10180,7 @@ func TestOSCARProxy_Signon(t *testing.T) {
 		},
 		{
 			name:     "login, receive error from buddy list registry",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3703,6 +10207,11 @@ func TestOSCARProxy_Signon(t *testing.T) {
 							sess:       newTestSession("me"),
 						},
 					},
+					signoutParams: signoutParams{
+						{
+							me: state.NewIdentScreenName("me"),
+						},
+					},
 				},
 				buddyListRegistryParams: buddyListRegistryParams{
 					registerBuddyListParams: registerBuddyListParams{
@@ -3715,9 +10224,120 @@ func TestOSCARProxy_Signon(t *testing.T) {
 			},
 			wantMsg: []string{string(cmdInternalSvcErr)},
 		},
+		{
+			name: "login with stale buddy list registration, clear registration",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+			}),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+							err:  state.ErrBuddyListRegistered,
+						},
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+					unregisterBuddyListParams: unregisterBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							returnedUser: &state.User{
+								TOCConfig: "my-toc-config",
+							},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:my-toc-config"},
+		},
+		{
+			name: "login with stale buddy list registration, reject sign on",
+			cfg: config.Config{
+				TOCRejectStaleBuddyList: true,
+			},
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+					signoutParams: signoutParams{
+						{
+							me: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+							err:  state.ErrBuddyListRegistered,
+						},
+					},
+				},
+			},
+			wantMsg: []string{"ERROR:989:this screen name is already signed on, please try again later"},
+		},
 		{
 			name:     "login, receive error from TOC config store",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3764,8 +10384,11 @@ func TestOSCARProxy_Signon(t *testing.T) {
 			wantMsg: []string{string(cmdInternalSvcErr)},
 		},
 		{
-			name:     "login, user not found after login",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			name: "login, user has no TOC config record",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+			}),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3809,11 +10432,62 @@ func TestOSCARProxy_Signon(t *testing.T) {
 					},
 				},
 			},
-			wantMsg: []string{string(cmdInternalSvcErr)},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:"},
+		},
+		{
+			name: "login, brand-new user without saved TOC config",
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetCaps([][16]byte{capChat})
+			}),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
+			mockParams: mockParams{
+				authParams: authParams{
+					flapLoginParams: flapLoginParams{
+						{
+							frame: wire.FLAPSignonFrame{
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.LoginTLVTagsScreenName, "me"),
+										wire.NewTLVBE(wire.LoginTLVTagsRoastedTOCPassword, roastedPass),
+									},
+								},
+							},
+							newUserFn: state.NewStubUser,
+							tlv: wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("thecookie")),
+								},
+							},
+						},
+					},
+					registerBOSSessionParams: registerBOSSessionParams{
+						{
+							authCookie: []byte("thecookie"),
+							sess:       newTestSession("me"),
+						},
+					},
+				},
+				buddyListRegistryParams: buddyListRegistryParams{
+					registerBuddyListParams: registerBuddyListParams{
+						{
+							user: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				tocConfigParams: tocConfigParams{
+					userParams: userParams{
+						{
+							screenName:   state.NewIdentScreenName("me"),
+							returnedUser: &state.User{},
+						},
+					},
+				},
+			},
+			wantMsg: []string{"SIGN_ON:TOC1.0", "CONFIG:"},
 		},
 		{
 			name:     "login with bad credentials",
-			givenCmd: []byte(`toc_signon "" "" me "xx` + hex.EncodeToString(roastedPass) + `"`),
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass) + `"`),
 			mockParams: mockParams{
 				authParams: authParams{
 					flapLoginParams: flapLoginParams{
@@ -3838,6 +10512,36 @@ func TestOSCARProxy_Signon(t *testing.T) {
 			},
 			wantMsg: []string{"ERROR:980"},
 		},
+		{
+			name:     "login with roasted password missing 0x prefix",
+			givenCmd: []byte(`toc_signon "" "" me "` + hex.EncodeToString(roastedPass) + `"`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "login with odd-length roasted password",
+			givenCmd: []byte(`toc_signon "" "" me "0x` + hex.EncodeToString(roastedPass)[1:] + `"`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "login with non-hex roasted password",
+			givenCmd: []byte(`toc_signon "" "" me "0xzz"`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "login with empty roasted password",
+			givenCmd: []byte(`toc_signon "" "" me "0x"`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "login with empty password",
+			givenCmd: []byte(`toc_signon "" "" me ""`),
+			wantMsg:  []string{"ERROR:980"},
+		},
+		{
+			name:     "initialize connection before signing on",
+			givenCmd: []byte(`toc_init_done`),
+			wantMsg:  []string{cmdInternalSvcErr},
+		},
 		{
 			name:     "bad command",
 			givenCmd: []byte(`toc_init_done_diff`),
@@ -3860,10 +10564,20 @@ func TestOSCARProxy_Signon(t *testing.T) {
 					RegisterBOSSession(ctx, params.authCookie).
 					Return(params.sess, params.err)
 			}
+			for _, params := range tc.mockParams.signoutParams {
+				authSvc.EXPECT().
+					Signout(ctx, matchSession(params.me))
+			}
 			buddyRegistry := newMockBuddyListRegistry(t)
 			for _, params := range tc.mockParams.registerBuddyListParams {
 				buddyRegistry.EXPECT().
 					RegisterBuddyList(params.user).
+					Return(params.err).
+					Once()
+			}
+			for _, params := range tc.mockParams.unregisterBuddyListParams {
+				buddyRegistry.EXPECT().
+					UnregisterBuddyList(params.user).
 					Return(params.err)
 			}
 			tocCfg := newMockTOCConfigStore(t)
@@ -3876,6 +10590,7 @@ func TestOSCARProxy_Signon(t *testing.T) {
 			svc := OSCARProxy{
 				AuthService:       authSvc,
 				BuddyListRegistry: buddyRegistry,
+				Config:            tc.cfg,
 				Logger:            slog.Default(),
 				TOCConfigStore:    tocCfg,
 			}
@@ -3887,6 +10602,7 @@ func TestOSCARProxy_Signon(t *testing.T) {
 			} else if assert.NotNil(t, sess) {
 				assert.Equal(t, tc.me.IdentScreenName(), sess.IdentScreenName())
 				assert.Equal(t, tc.me.Caps(), sess.Caps())
+				assert.Equal(t, tc.me.ClientID(), sess.ClientID())
 			}
 		})
 	}
@@ -4021,6 +10737,95 @@ func TestOSCARProxy_Signout(t *testing.T) {
 	}
 }
 
+func TestOSCARProxy_crackHTTPAuthToken(t *testing.T) {
+	cookieBaker, err := state.NewHMACCookieBaker()
+	assert.NoError(t, err)
+
+	svc := OSCARProxy{
+		CookieBaker: cookieBaker,
+	}
+	token, err := svc.newHTTPAuthToken(state.NewIdentScreenName("me"))
+	assert.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		me, err := svc.crackHTTPAuthToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, state.NewIdentScreenName("me"), me)
+	})
+
+	t.Run("restore padding trimmed from signature", func(t *testing.T) {
+		// the cookie's last significant byte is a zero that gets trimmed
+		// along with the padding
+		issued := make([]byte, httpAuthTokenLen)
+		copy(issued, []byte{0x01, 0x02, 0x00})
+
+		cookieBaker := newMockCookieBaker(t)
+		cookieBaker.EXPECT().
+			Issue([]byte("me")).
+			Return(issued, nil)
+		cookieBaker.EXPECT().
+			Crack(issued).
+			Return([]byte("me"), nil)
+
+		svc := OSCARProxy{
+			CookieBaker: cookieBaker,
+		}
+		token, err := svc.newHTTPAuthToken(state.NewIdentScreenName("me"))
+		assert.NoError(t, err)
+		assert.Equal(t, "0102", token)
+
+		me, err := svc.crackHTTPAuthToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, state.NewIdentScreenName("me"), me)
+	})
+
+	corrupted := []byte(token)
+	if corrupted[10] == 'f' {
+		corrupted[10] = '0'
+	} else {
+		corrupted[10] = 'f'
+	}
+
+	rejected := []struct {
+		// name is the unit test name
+		name string
+		// token is the HTTP auth token
+		token string
+	}{
+		{
+			name:  "corrupted token",
+			token: string(corrupted),
+		},
+		{
+			name:  "truncated token",
+			token: token[:len(token)/2],
+		},
+		{
+			name:  "odd-length token",
+			token: token[:len(token)-1],
+		},
+		{
+			name:  "non-hex token",
+			token: "zz" + token[2:],
+		},
+		{
+			name:  "empty token",
+			token: "",
+		},
+		{
+			name:  "oversized token",
+			token: token + strings.Repeat("00", httpAuthTokenLen),
+		},
+	}
+
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.crackHTTPAuthToken(tc.token)
+			assert.Error(t, err)
+		})
+	}
+}
+
 func Test_parseArgs(t *testing.T) {
 	type testCase struct {
 		name         string
@@ -4125,3 +10930,96 @@ func Test_parseArgs(t *testing.T) {
 		})
 	}
 }
+
+func Test_snacErrToTOC(t *testing.T) {
+	tests := []struct {
+		name      string
+		givenCode uint16
+		givenArg  string
+		wantMsg   string
+		wantOK    bool
+	}{
+		{
+			name:      "user not logged on",
+			givenCode: wire.ErrorCodeNotLoggedOn,
+			givenArg:  "them",
+			wantMsg:   "ERROR:901:them",
+			wantOK:    true,
+		},
+		{
+			name:      "rate limited",
+			givenCode: wire.ErrorCodeRateToHost,
+			givenArg:  "them",
+			wantMsg:   "ERROR:903",
+			wantOK:    true,
+		},
+		{
+			name:      "service unavailable",
+			givenCode: wire.ErrorCodeServiceUnavailable,
+			wantMsg:   "ERROR:981",
+			wantOK:    true,
+		},
+		{
+			name:      "no TOC equivalent",
+			givenCode: wire.ErrorCodeBustedSnacPayload,
+			givenArg:  "them",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := snacErrToTOC(tt.givenCode, tt.givenArg)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMsg, msg)
+		})
+	}
+}
+
+func Test_tocError(t *testing.T) {
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// givenCode is the TOC error code
+		givenCode int
+		// givenArgs are the error arguments
+		givenArgs []string
+		// wantMsg is the expected TOC ERROR message
+		wantMsg string
+	}{
+		{
+			name:      "no args",
+			givenCode: 903,
+			wantMsg:   "ERROR:903",
+		},
+		{
+			name:      "single arg",
+			givenCode: 901,
+			givenArgs: []string{"them"},
+			wantMsg:   "ERROR:901:them",
+		},
+		{
+			name:      "multiple args",
+			givenCode: 989,
+			givenArgs: []string{"toc_bogus", "unknown command"},
+			wantMsg:   "ERROR:989:toc_bogus:unknown command",
+		},
+		{
+			name:      "empty arg",
+			givenCode: 950,
+			givenArgs: []string{""},
+			wantMsg:   "ERROR:950:",
+		},
+		{
+			name:      "colon in arg",
+			givenCode: 950,
+			givenArgs: []string{"room:1"},
+			wantMsg:   "ERROR:950:room 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantMsg, tocError(tt.givenCode, tt.givenArgs...))
+		})
+	}
+}
@@ -3,23 +3,52 @@ package toc
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"net/netip"
+	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
+
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
 var (
-	cmdInternalSvcErr = "ERROR:989:internal server error"
-	errDisconnect     = errors.New("got booted by another session")
+	cmdInternalSvcErr   = tocError(989, "internal server error")
+	cmdInitDoneRepeated = tocError(989, "disconnected (toc_init_done already received)")
+	errDisconnect       = disconnectError{
+		reason: tocError(989, "disconnected (signed on from another location)"),
+		cause:  errors.New("got booted by another session"),
+	}
 )
 
+// disconnectError indicates that the server is ending the TOC session. The
+// reason is sent to the client as the final message before the connection
+// closes so that the client can tell the user why they were disconnected.
+type disconnectError struct {
+	reason string // TOC message sent to the client before disconnecting.
+	cause  error  // Why the session ended, for logging.
+}
+
+func (e disconnectError) Error() string {
+	return e.cause.Error()
+}
+
+func (e disconnectError) Unwrap() error {
+	return e.cause
+}
+
 // RecvBOS routes incoming SNAC messages from the BOS server to their
 // corresponding TOC handlers. It ignores any SNAC messages for which there is
 // no TOC response.
 func (s OSCARProxy) RecvBOS(ctx context.Context, me *state.Session, chatRegistry *ChatRegistry, ch chan<- []byte) error {
+	// departure events identify buddies by their ident screen name, so
+	// remember the display screen name each buddy arrived with
+	displayNames := make(map[state.IdentScreenName]string)
 	for {
 		select {
 		case <-ctx.Done():
@@ -29,11 +58,24 @@ func (s OSCARProxy) RecvBOS(ctx context.Context, me *state.Session, chatRegistry
 		case snac := <-me.ReceiveMessage():
 			switch v := snac.Body.(type) {
 			case wire.SNAC_0x03_0x0B_BuddyArrived:
+				displayNames[state.NewIdentScreenName(v.ScreenName)] = v.ScreenName
 				sendOrCancel(ctx, ch, s.UpdateBuddyArrival(v))
 			case wire.SNAC_0x03_0x0C_BuddyDeparted:
-				sendOrCancel(ctx, ch, s.UpdateBuddyDeparted(v))
+				buddy := state.NewIdentScreenName(v.ScreenName)
+				sendOrCancel(ctx, ch, s.UpdateBuddyDeparted(v, displayNames[buddy]))
+				delete(displayNames, buddy)
 			case wire.SNAC_0x04_0x07_ICBMChannelMsgToClient:
-				sendOrCancel(ctx, ch, s.IMIn(ctx, chatRegistry, v))
+				msg := s.IMIn(ctx, chatRegistry, v)
+				if v.ChannelID == wire.ICBMChannelIM {
+					sender := state.NewIdentScreenName(v.ScreenName)
+					// remember the sender so that the user can warn them
+					s.RecentIMSenders.Add(me.IdentScreenName(), sender)
+					// remember the message so that the user can report it
+					s.IMHistory.Add(me.IdentScreenName(), sender, msg)
+				}
+				if msg != "" {
+					sendOrCancel(ctx, ch, msg)
+				}
 			case wire.SNAC_0x01_0x10_OServiceEvilNotification:
 				sendOrCancel(ctx, ch, s.Eviled(v))
 			default:
@@ -59,10 +101,16 @@ func (s OSCARProxy) RecvChat(ctx context.Context, me *state.Session, chatID int,
 			switch v := snac.Body.(type) {
 			case wire.SNAC_0x0E_0x04_ChatUsersLeft:
 				sendOrCancel(ctx, ch, s.ChatUpdateBuddyLeft(v, chatID))
+				sendOrCancel(ctx, ch, s.ChatOccupants(me, chatID))
 			case wire.SNAC_0x0E_0x03_ChatUsersJoined:
 				sendOrCancel(ctx, ch, s.ChatUpdateBuddyArrived(v, chatID))
+				sendOrCancel(ctx, ch, s.ChatOccupants(me, chatID))
 			case wire.SNAC_0x0E_0x06_ChatChannelMsgToClient:
 				sendOrCancel(ctx, ch, s.ChatIn(ctx, v, chatID))
+			case wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate:
+				if msg := s.ChatTopic(v, chatID); msg != "" {
+					sendOrCancel(ctx, ch, msg)
+				}
 			default:
 				s.Logger.DebugContext(ctx, fmt.Sprintf("unsupported snac. foodgroup: %s subgroup: %s",
 					wire.FoodGroupName(snac.Frame.FoodGroup),
@@ -78,6 +126,9 @@ func (s OSCARProxy) RecvChat(ctx context.Context, me *state.Session, chatID int,
 //
 //	A chat message was sent in a chat room.
 //
+// Messages without the public flag were whispered to the user and are marked
+// with whisper flag T.
+//
 // Command syntax: CHAT_IN:<Chat Room Id>:<Source User>:<Whisper? T/F>:<Message>
 func (s OSCARProxy) ChatIn(ctx context.Context, snac wire.SNAC_0x0E_0x06_ChatChannelMsgToClient, chatID int) string {
 	b, ok := snac.Bytes(wire.ChatTLVSenderInformation)
@@ -101,7 +152,25 @@ func (s OSCARProxy) ChatIn(ctx context.Context, snac wire.SNAC_0x0E_0x06_ChatCha
 		return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalChatMessageText: %w", err))
 	}
 
-	return fmt.Sprintf("CHAT_IN:%d:%s:F:%s", chatID, u.ScreenName, text)
+	return chatInMessage(chatID, u.ScreenName, isWhisper(snac.TLVRestBlock), text)
+}
+
+// isWhisper indicates whether a chat message was whispered to the recipient
+// rather than sent to the whole room. Public messages carry the
+// wire.ChatTLVPublicWhisperFlag TLV.
+func isWhisper(block wire.TLVRestBlock) bool {
+	return !block.HasTag(wire.ChatTLVPublicWhisperFlag)
+}
+
+// chatInMessage creates a CHAT_IN server reply for a message sent by sender
+// in the chat room identified by chatID. Param whisper indicates that the
+// message was sent privately to the recipient.
+func chatInMessage(chatID int, sender string, whisper bool, text string) string {
+	whisperFlag := "F"
+	if whisper {
+		whisperFlag = "T"
+	}
+	return fmt.Sprintf("CHAT_IN:%d:%s:%s:%s", chatID, sender, whisperFlag, tocMessageText(text))
 }
 
 // ChatUpdateBuddyArrived handles the CHAT_UPDATE_BUDDY TOC command for chat
@@ -140,12 +209,44 @@ func (s OSCARProxy) ChatUpdateBuddyLeft(snac wire.SNAC_0x0E_0x04_ChatUsersLeft,
 	return fmt.Sprintf("CHAT_UPDATE_BUDDY:%d:F:%s", chatID, strings.Join(users, ":"))
 }
 
+// ChatOccupants handles the CHAT_OCCUPANTS TOC command.
+//
+// This is a non-standard command that indicates how many users are in a chat
+// room. It's sent after each CHAT_UPDATE_BUDDY message so that clients can
+// display the room's current occupant count.
+//
+// Command syntax: CHAT_OCCUPANTS:<Chat Room Id>:<Occupant Count>
+func (s OSCARProxy) ChatOccupants(me *state.Session, chatID int) string {
+	count := s.ChatOccupantCounter.OccupantCount(me.ChatRoomCookie())
+	return fmt.Sprintf("CHAT_OCCUPANTS:%d:%d", chatID, count)
+}
+
+// ChatTopic handles the CHAT_TOPIC TOC command.
+//
+// This is a non-standard command that indicates the chat room topic. It's
+// sent when the user joins a room that has a topic and whenever the room
+// creator changes the topic. It returns an empty string if the room metadata
+// carries no topic.
+//
+// Command syntax: CHAT_TOPIC:<Chat Room Id>:<Topic>
+func (s OSCARProxy) ChatTopic(snac wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate, chatID int) string {
+	topic, hasTopic := snac.String(wire.ChatRoomTLVTopic)
+	if !hasTopic {
+		return ""
+	}
+	return fmt.Sprintf("CHAT_TOPIC:%d:%s", chatID, topic)
+}
+
 // Eviled handles the EVILED TOC command.
 //
 // From the TiK documentation:
 //
 //	The user was just eviled.
 //
+// The ICBM service raises the user's session warning level before it relays
+// the notification, so the new level matches the level reported in the
+// user's status. The level is sent as a percentage.
+//
 // Command syntax: EVILED:<new evil>:<name of eviler, blank if anonymous>
 func (s OSCARProxy) Eviled(snac wire.SNAC_0x01_0x10_OServiceEvilNotification) string {
 	warning := fmt.Sprintf("%d", snac.NewEvil/10)
@@ -156,6 +257,66 @@ func (s OSCARProxy) Eviled(snac wire.SNAC_0x01_0x10_OServiceEvilNotification) st
 	return fmt.Sprintf("EVILED:%s:%s", warning, who)
 }
 
+// rvousPropose formats the RVOUS_PROPOSE TOC command for a rendezvous
+// proposal from sender.
+//
+// From the TiK documentation:
+//
+//	Another user has proposed that we rendezvous with them to perform the
+//	service specified by <uuid>. They want us to connect to them, we have
+//	their rendezvous ip, their proposer_ip, and their verified_ip. The tlv
+//	values are base64 encoded.
+//
+// The cookie is base64 encoded, as toc_rvous_propose expects. Addresses and
+// the port are blank when the proposal doesn't include them. The remaining
+// TLVs follow as tag:value pairs.
+//
+// Command syntax: RVOUS_PROPOSE:<user>:<uuid>:<cookie>:<seq>:<rendezvous_ip>:<proposer_ip>:<verified_ip>:<port>[:tlv tag1:tlv value1[:tlv tag2:tlv value2[:...]]]
+func rvousPropose(sender string, frag wire.ICBMCh2Fragment) string {
+	ip := func(tag uint16) string {
+		v, ok := frag.Uint32BE(tag)
+		if !ok {
+			return ""
+		}
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], v)
+		return netip.AddrFrom4(b).String()
+	}
+
+	port := ""
+	if v, ok := frag.Uint16BE(wire.ICBMRdvTLVTagsPort); ok {
+		port = strconv.Itoa(int(v))
+	}
+	seq, _ := frag.Uint16BE(wire.ICBMRdvTLVTagsSeqNum)
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("RVOUS_PROPOSE:%s:%s:%s:%d:%s:%s:%s:%s",
+		sender,
+		strings.ToUpper(uuid.UUID(frag.Capability).String()),
+		encodeRvousCookie(frag.Cookie),
+		seq,
+		ip(wire.ICBMRdvTLVTagsRdvIP),
+		ip(wire.ICBMRdvTLVTagsRequesterIP),
+		ip(wire.ICBMRdvTLVTagsVerifiedIP),
+		port))
+
+	for _, tlv := range frag.TLVList {
+		switch tlv.Tag {
+		case wire.ICBMRdvTLVTagsSeqNum, wire.ICBMRdvTLVTagsRdvIP, wire.ICBMRdvTLVTagsRequesterIP,
+			wire.ICBMRdvTLVTagsVerifiedIP, wire.ICBMRdvTLVTagsPort:
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(":%d:%s", tlv.Tag, base64.StdEncoding.EncodeToString(tlv.Value)))
+	}
+
+	return sb.String()
+}
+
+// encodeRvousCookie base64-encodes a rendezvous cookie.
+func encodeRvousCookie(cookie uint64) string {
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint64(nil, cookie))
+}
+
 // IMIn handles the IM_IN TOC command.
 //
 // From the TiK documentation:
@@ -163,7 +324,18 @@ func (s OSCARProxy) Eviled(snac wire.SNAC_0x01_0x10_OServiceEvilNotification) st
 //	Receive an IM from someone. Everything after the third colon is the
 //	incoming message, including other colons.
 //
+// Messages queued while the user was offline, other than system notices, are
+// flagged as auto responses. Chat invitations arrive as CHAT_INVITE messages.
+// When someone declines the user's chat invitation, a CHAT_INVITE_DECLINED
+// message is sent, which is not part of the TiK documentation. Rendezvous
+// proposals for anything other than chat, such as file transfers, arrive as
+// RVOUS_PROPOSE messages (see rvousPropose) and can be answered with
+// toc_rvous_accept or toc_rvous_cancel. Other non-chat rendezvous messages
+// yield an empty response, though a cancel from the proposer withdraws the
+// proposal.
+//
 // Command syntax: IM_IN:<Source User>:<Auto Response T/F?>:<Message>
+// Command syntax: CHAT_INVITE_DECLINED:<Chat Room Name>:<Source User>
 func (s OSCARProxy) IMIn(ctx context.Context, chatRegistry *ChatRegistry, snac wire.SNAC_0x04_0x07_ICBMChannelMsgToClient) string {
 	if snac.ChannelID == wire.ICBMChannelRendezvous {
 		rdinfo, has := snac.TLVRestBlock.Bytes(wire.ICBMTLVData)
@@ -174,9 +346,24 @@ func (s OSCARProxy) IMIn(ctx context.Context, chatRegistry *ChatRegistry, snac w
 		if err := wire.UnmarshalBE(&frag, bytes.NewReader(rdinfo)); err != nil {
 			return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
 		}
-		prompt, ok := frag.Bytes(wire.ICBMRdvTLVTagsInvitation)
-		if !ok {
-			return s.runtimeErr(ctx, errors.New("frag.Bytes: missing chat invite prompt"))
+
+		if frag.Capability != capChat {
+			proposal := RvousProposal{
+				Proposer:   state.NewIdentScreenName(snac.ScreenName),
+				Cookie:     frag.Cookie,
+				Capability: frag.Capability,
+			}
+			switch frag.Type {
+			case wire.ICBMRdvMessagePropose:
+				// remember the proposal so that the user can accept or
+				// cancel it
+				chatRegistry.AddRvous(proposal)
+				return rvousPropose(snac.ScreenName, frag)
+			case wire.ICBMRdvMessageCancel:
+				chatRegistry.RemoveRvous(proposal)
+			}
+			s.Logger.DebugContext(ctx, "ignoring non-chat rendezvous", "capability", uuid.UUID(frag.Capability).String(), "type", frag.Type)
+			return ""
 		}
 
 		svcData, ok := frag.Bytes(wire.ICBMRdvTLVTagsSvcData)
@@ -189,15 +376,26 @@ func (s OSCARProxy) IMIn(ctx context.Context, chatRegistry *ChatRegistry, snac w
 			return s.runtimeErr(ctx, fmt.Errorf("wire.UnmarshalBE: %w", err))
 		}
 
-		cookie := strings.Split(roomInfo.Cookie, "-") // make this safe
-		if len(cookie) < 3 {
-			return s.runtimeErr(ctx, errors.New("roomInfo.Cookie: malformed cookie, could not get room name"))
+		roomName, err := roomNameFromCookie(roomInfo.Cookie)
+		if err != nil {
+			return s.runtimeErr(ctx, fmt.Errorf("roomNameFromCookie: %w", err))
 		}
 
-		roomName := cookie[2]
-		chatID := chatRegistry.Add(roomInfo)
+		if frag.Type == wire.ICBMRdvMessageCancel {
+			return fmt.Sprintf("CHAT_INVITE_DECLINED:%s:%s", roomName, snac.ScreenName)
+		}
+
+		prompt, ok := frag.InvitationText()
+		if !ok {
+			return s.runtimeErr(ctx, errors.New("frag.Bytes: missing chat invite prompt"))
+		}
 
-		return fmt.Sprintf("CHAT_INVITE:%s:%d:%s:%s", roomName, chatID, snac.ScreenName, prompt)
+		chatID := chatRegistry.AddInvite(roomInfo, ChatInvite{
+			Inviter: snac.ScreenName,
+			Cookie:  frag.Cookie,
+		})
+
+		return fmt.Sprintf("CHAT_INVITE:%s:%d:%s:%s", roomName, chatID, snac.ScreenName, tocMessageText(prompt))
 	}
 
 	buf, ok := snac.TLVRestBlock.Bytes(wire.ICBMTLVAOLIMData)
@@ -210,11 +408,17 @@ func (s OSCARProxy) IMIn(ctx context.Context, chatRegistry *ChatRegistry, snac w
 	}
 
 	autoResp := "F"
-	if _, isAutoReply := snac.TLVRestBlock.Bytes(wire.ICBMTLVAutoResponse); isAutoReply {
+	_, isAutoReply := snac.TLVRestBlock.Bytes(wire.ICBMTLVAutoResponse)
+	// messages queued while the user was offline carry the time they were
+	// sent. TOC has no way to mark a message as stored, so they're flagged
+	// as auto responses to keep clients from mistaking them for live
+	// messages.
+	_, isStored := snac.TLVRestBlock.Bytes(wire.ICBMTLVSendTime)
+	if isAutoReply || (isStored && snac.ScreenName != state.SystemNoticeSender.String()) {
 		autoResp = "T"
 	}
 
-	return fmt.Sprintf("IM_IN:%s:%s:%s", snac.ScreenName, autoResp, txt)
+	return fmt.Sprintf("IM_IN:%s:%s:%s", snac.ScreenName, autoResp, tocMessageText(txt))
 }
 
 // UpdateBuddyArrival handles the UPDATE_BUDDY TOC command for buddy arrival events.
@@ -260,9 +464,61 @@ func (s OSCARProxy) UpdateBuddyArrival(snac wire.SNAC_0x03_0x0B_BuddyArrived) st
 //			- ' ' - Ignore
 //			- 'U' - The user has set their unavailable flag.
 //
+// Departure events identify the buddy by their ident screen name, so
+// departures name the buddy by displayName, the display screen name the buddy
+// arrived with, the same way as arrivals, IMs, and chat messages do. If
+// displayName is empty, the ident screen name is sent instead.
+//
 // Command syntax: UPDATE_BUDDY:<Buddy User>:<Online? T/F>:<Evil Amount>:<Signon Time>:<IdleTime>:<UC>
-func (s OSCARProxy) UpdateBuddyDeparted(snac wire.SNAC_0x03_0x0C_BuddyDeparted) string {
-	return fmt.Sprintf("UPDATE_BUDDY:%s:F:0:0:0:   ", snac.ScreenName)
+func (s OSCARProxy) UpdateBuddyDeparted(snac wire.SNAC_0x03_0x0C_BuddyDeparted, displayName string) string {
+	if displayName == "" {
+		displayName = snac.ScreenName
+	}
+	return fmt.Sprintf("UPDATE_BUDDY:%s:F:0:0:0:   ", displayName)
+}
+
+// displayScreenName returns the display form of screenName, which may be in
+// ident or display form. It returns screenName unchanged if the user can't be
+// found.
+func (s OSCARProxy) displayScreenName(ctx context.Context, screenName string) string {
+	u, err := s.UserManager.User(state.NewIdentScreenName(screenName))
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "unable to look up display screen name", "err", err.Error())
+		return screenName
+	}
+	if u == nil {
+		return screenName
+	}
+	return u.DisplayScreenName.String()
+}
+
+// roomNameFromCookie extracts the chat room name from a chat room cookie,
+// which has the format <exchange>-<instance>-<room name>.
+func roomNameFromCookie(cookie string) (string, error) {
+	parts := strings.SplitN(cookie, "-", 3)
+	if len(parts) < 3 {
+		return "", errors.New("malformed cookie, could not get room name")
+	}
+	return parts[2], nil
+}
+
+// tocMessageReplacer removes the characters from message text that don't
+// survive the TOC wire format.
+var tocMessageReplacer = strings.NewReplacer(
+	"\x00", "",
+	"\r\n", "<br>",
+	"\r", "<br>",
+	"\n", "<br>",
+)
+
+// tocMessageText prepares message text sent by an OSCAR user for the final
+// field of a TOC server message. HTML and colons are passed through as is,
+// since TiK treats everything after the preceding field as the message and
+// doesn't unescape server messages. NUL bytes, which terminate the message
+// for clients that treat it as a C string, are removed, and line breaks are
+// converted to <br> so that the message stays on one line.
+func tocMessageText(text string) string {
+	return tocMessageReplacer.Replace(text)
 }
 
 func sendOrCancel(ctx context.Context, ch chan<- []byte, msg string) {
@@ -275,11 +531,21 @@ func sendOrCancel(ctx context.Context, ch chan<- []byte, msg string) {
 }
 
 // userInfoToUpdateBuddy creates an UPDATE_BUDDY server reply from a User
-// Info TLV.
+// Info TLV. The user class is derived from the OSCAR user flags.
 func userInfoToUpdateBuddy(snac wire.TLVUserInfo) string {
 	online, _ := snac.Uint32BE(wire.OServiceUserInfoSignonTOD)
 	idle, _ := snac.Uint16BE(wire.OServiceUserInfoIdleTime)
+	flags, _ := snac.Uint16BE(wire.OServiceUserInfoUserFlags)
 	uc := [3]string{" ", "O", " "}
+	if flags&wire.OServiceUserFlagAOL == wire.OServiceUserFlagAOL {
+		uc[0] = "A"
+	}
+	switch {
+	case flags&wire.OServiceUserFlagAdministrator == wire.OServiceUserFlagAdministrator:
+		uc[1] = "A"
+	case flags&wire.OServiceUserFlagUnconfirmed == wire.OServiceUserFlagUnconfirmed:
+		uc[1] = "U"
+	}
 	if snac.IsAway() {
 		uc[2] = "U"
 	}
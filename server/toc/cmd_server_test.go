@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mk6i/retro-aim-server/state"
@@ -36,6 +38,7 @@ func TestOSCARProxy_RecvBOS_ChatIn(t *testing.T) {
 							wire.NewTLVBE(wire.ChatTLVSenderInformation, wire.TLVUserInfo{
 								ScreenName: "them",
 							}),
+							wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
 							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
 								TLVList: wire.TLVList{
 									wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<p>hello world!</p>"),
@@ -47,6 +50,98 @@ func TestOSCARProxy_RecvBOS_ChatIn(t *testing.T) {
 			},
 			wantCmd: []byte("CHAT_IN:0:them:F:<p>hello world!</p>"),
 		},
+		{
+			name:   "send chat message containing colons and line breaks",
+			me:     newTestSession("me"),
+			chatID: 0,
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVSenderInformation, wire.TLVUserInfo{
+								ScreenName: "them",
+							}),
+							wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<a href=\"http://example.com\">time: 12:30</a>\r\nsee you"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("CHAT_IN:0:them:F:<a href=\"http://example.com\">time: 12:30</a><br>see you"),
+		},
+		{
+			name:   "send whispered chat message",
+			me:     newTestSession("me"),
+			chatID: 1,
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVSenderInformation, wire.TLVUserInfo{
+								ScreenName: "them",
+							}),
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText, "psst"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("CHAT_IN:1:them:T:psst"),
+		},
+		{
+			name:   "send chat message from user with display screen name",
+			me:     newTestSession("me"),
+			chatID: 0,
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVSenderInformation, wire.TLVUserInfo{
+								ScreenName: "Chatting Chuck",
+							}),
+							wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<p>hello world!</p>"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("CHAT_IN:0:Chatting Chuck:F:<p>hello world!</p>"),
+		},
+		{
+			name:   "send unicode chat message",
+			me:     newTestSession("me"),
+			chatID: 0,
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVSenderInformation, wire.TLVUserInfo{
+								ScreenName: "them",
+							}),
+							wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "unicode-2-0"),
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText, []byte{0x00, 0x68, 0x00, 0xE9, 0x4E, 0x16}),
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("CHAT_IN:0:them:F:hé世"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -88,12 +183,18 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyArrived(t *testing.T) {
 		chatID int
 		// givenMsg is the incoming SNAC
 		givenMsg wire.SNACMessage
+		// occupantCount is the number of chat room participants
+		occupantCount int
 		// wantCmd is the expected TOC response
 		wantCmd []byte
+		// wantOccupantsCmd is the expected occupant count TOC response
+		wantOccupantsCmd []byte
 	}{
 		{
 			name: "send chat participant arrival",
-			me:   newTestSession("me"),
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetChatRoomCookie("the-cookie")
+			}),
 			givenMsg: wire.SNACMessage{
 				Body: wire.SNAC_0x0E_0x03_ChatUsersJoined{
 					Users: []wire.TLVUserInfo{
@@ -102,7 +203,9 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyArrived(t *testing.T) {
 					},
 				},
 			},
-			wantCmd: []byte("CHAT_UPDATE_BUDDY:0:T:user1:user2"),
+			occupantCount:    3,
+			wantCmd:          []byte("CHAT_UPDATE_BUDDY:0:T:user1:user2"),
+			wantOccupantsCmd: []byte("CHAT_OCCUPANTS:0:3"),
 		},
 	}
 
@@ -110,8 +213,14 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyArrived(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 
+			occupantCounter := newMockChatOccupantCounter(t)
+			occupantCounter.EXPECT().
+				OccupantCount("the-cookie").
+				Return(tc.occupantCount)
+
 			svc := OSCARProxy{
-				Logger: slog.Default(),
+				ChatOccupantCounter: occupantCounter,
+				Logger:              slog.Default(),
 			}
 
 			ch := make(chan []byte)
@@ -129,6 +238,9 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyArrived(t *testing.T) {
 			gotCmd := <-ch
 			assert.Equal(t, string(tc.wantCmd), string(gotCmd))
 
+			gotCmd = <-ch
+			assert.Equal(t, string(tc.wantOccupantsCmd), string(gotCmd))
+
 			cancel()
 			wg.Wait()
 		})
@@ -145,12 +257,18 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyLeft(t *testing.T) {
 		chatID int
 		// givenMsg is the incoming SNAC
 		givenMsg wire.SNACMessage
+		// occupantCount is the number of chat room participants
+		occupantCount int
 		// wantCmd is the expected TOC response
 		wantCmd []byte
+		// wantOccupantsCmd is the expected occupant count TOC response
+		wantOccupantsCmd []byte
 	}{
 		{
 			name: "send chat participant departure",
-			me:   newTestSession("me"),
+			me: newTestSession("me", func(session *state.Session) {
+				session.SetChatRoomCookie("the-cookie")
+			}),
 			givenMsg: wire.SNACMessage{
 				Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{
 					Users: []wire.TLVUserInfo{
@@ -159,7 +277,78 @@ func TestOSCARProxy_RecvBOS_ChatUpdateBuddyLeft(t *testing.T) {
 					},
 				},
 			},
-			wantCmd: []byte("CHAT_UPDATE_BUDDY:0:F:user1:user2"),
+			occupantCount:    1,
+			wantCmd:          []byte("CHAT_UPDATE_BUDDY:0:F:user1:user2"),
+			wantOccupantsCmd: []byte("CHAT_OCCUPANTS:0:1"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			occupantCounter := newMockChatOccupantCounter(t)
+			occupantCounter.EXPECT().
+				OccupantCount("the-cookie").
+				Return(tc.occupantCount)
+
+			svc := OSCARProxy{
+				ChatOccupantCounter: occupantCounter,
+				Logger:              slog.Default(),
+			}
+
+			ch := make(chan []byte)
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				svc.RecvChat(ctx, tc.me, tc.chatID, ch)
+			}()
+
+			status := tc.me.RelayMessage(tc.givenMsg)
+			assert.Equal(t, state.SessSendOK, status)
+
+			gotCmd := <-ch
+			assert.Equal(t, string(tc.wantCmd), string(gotCmd))
+
+			gotCmd = <-ch
+			assert.Equal(t, string(tc.wantOccupantsCmd), string(gotCmd))
+
+			cancel()
+			wg.Wait()
+		})
+	}
+}
+
+func TestOSCARProxy_RecvBOS_ChatTopic(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// me is the TOC user session
+		me *state.Session
+		// chatID is the chat ID
+		chatID int
+		// givenMsg is the incoming SNAC
+		givenMsg wire.SNACMessage
+		// wantCmd is the expected TOC response
+		wantCmd []byte
+	}{
+		{
+			name:   "send chat room topic",
+			me:     newTestSession("me"),
+			chatID: 1,
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, "the room"),
+							wire.NewTLVBE(wire.ChatRoomTLVTopic, "the topic"),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("CHAT_TOPIC:1:the topic"),
 		},
 	}
 
@@ -310,6 +499,66 @@ func TestOSCARProxy_RecvBOS_IMIn(t *testing.T) {
 			},
 			wantCmd: []byte("IM_IN:them:F:hello world!"),
 		},
+		{
+			name: "send IM containing colons, HTML and line breaks",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelIM,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: "them",
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+								{
+									ID:      0x1,
+									Version: 0x1,
+									Payload: append([]byte{
+										0x0, 0x0, // charset
+										0x0, 0x0, // lang
+									}, "<b>note:</b> a:b\nc\x00"...),
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("IM_IN:them:F:<b>note:</b> a:b<br>c"),
+		},
+		{
+			name: "send IM from user with display screen name",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelIM,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: "Chatting Chuck",
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+								{
+									ID:      0x5,
+									Version: 0x1,
+									Payload: []uint8{0x1, 0x1, 0x2},
+								},
+								{
+									ID:      0x1,
+									Version: 0x1,
+									Payload: []uint8{
+										0x0, 0x0, // charset
+										0x0, 0x0, // lang
+										'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("IM_IN:Chatting Chuck:F:hello world!"),
+		},
 		{
 			name: "send IM - auto-response",
 			me:   newTestSession("me"),
@@ -344,6 +593,74 @@ func TestOSCARProxy_RecvBOS_IMIn(t *testing.T) {
 			},
 			wantCmd: []byte("IM_IN:them:T:hello world!"),
 		},
+		{
+			name: "send IM queued while offline",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelIM,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: "them",
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+								{
+									ID:      0x5,
+									Version: 0x1,
+									Payload: []uint8{0x1, 0x1, 0x2},
+								},
+								{
+									ID:      0x1,
+									Version: 0x1,
+									Payload: []uint8{
+										0x0, 0x0, // charset
+										0x0, 0x0, // lang
+										'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+									},
+								},
+							}),
+							wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(1704164645)),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("IM_IN:them:T:hello world!"),
+		},
+		{
+			name: "send system notice queued while offline",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelIM,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: state.SystemNoticeSender.String(),
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, []wire.ICBMCh1Fragment{
+								{
+									ID:      0x5,
+									Version: 0x1,
+									Payload: []uint8{0x1, 0x1, 0x2},
+								},
+								{
+									ID:      0x1,
+									Version: 0x1,
+									Payload: []uint8{
+										0x0, 0x0, // charset
+										0x0, 0x0, // lang
+										'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd', '!',
+									},
+								},
+							}),
+							wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(1704164645)),
+						},
+					},
+				},
+			},
+			wantCmd: []byte("IM_IN:[System]:F:hello world!"),
+		},
 		{
 			name: "send chat invitation",
 			me:   newTestSession("me"),
@@ -357,6 +674,7 @@ func TestOSCARProxy_RecvBOS_IMIn(t *testing.T) {
 						TLVList: wire.TLVList{
 							wire.NewTLVBE(wire.ICBMTLVData, []wire.ICBMCh2Fragment{
 								{
+									Capability: capChat,
 									TLVRestBlock: wire.TLVRestBlock{
 										TLVList: wire.TLVList{
 											wire.NewTLVBE(wire.ICBMRdvTLVTagsInvitation, "join my chat!"),
@@ -374,6 +692,66 @@ func TestOSCARProxy_RecvBOS_IMIn(t *testing.T) {
 			chatRegistry: NewChatRegistry(),
 			wantCmd:      []byte("CHAT_INVITE:the room:0:them:join my chat!"),
 		},
+		{
+			name: "send chat invitation with unicode prompt",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelRendezvous,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: "them",
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Capability: capChat,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsInvitation, []byte{0x00, 'j', 0x00, 'o', 0x00, 'i', 0x00, 'n', 0x00, ' ', 0x00, 0xE9}),
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsInviteMIMECharset, wire.ChatCharsetUnicode),
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRoomInfo{
+											Cookie: "4-0-the room",
+										}),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			chatRegistry: NewChatRegistry(),
+			wantCmd:      []byte("CHAT_INVITE:the room:0:them:join é"),
+		},
+		{
+			name: "receive chat invitation decline",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					ChannelID: wire.ICBMChannelRendezvous,
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName: "them",
+					},
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessageCancel,
+								Capability: capChat,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsUserCancel),
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRoomInfo{
+											Cookie: "4-0-the-room",
+										}),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			chatRegistry: NewChatRegistry(),
+			wantCmd:      []byte("CHAT_INVITE_DECLINED:the-room:them"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -406,6 +784,85 @@ func TestOSCARProxy_RecvBOS_IMIn(t *testing.T) {
 	}
 }
 
+func TestOSCARProxy_IMIn_NonChatRendezvous(t *testing.T) {
+	svc := OSCARProxy{
+		Logger: slog.Default(),
+	}
+
+	chatRegistry := NewChatRegistry()
+	proposal := RvousProposal{
+		Proposer:   state.NewIdentScreenName("them"),
+		Cookie:     0x0102030405060708,
+		Capability: uuid.MustParse("09461343-4C7F-11D1-8222-444553540000"),
+	}
+
+	// a file transfer proposal carries no room info and isn't a chat invite
+	msg := svc.IMIn(context.Background(), chatRegistry, wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+		ChannelID: wire.ICBMChannelRendezvous,
+		TLVUserInfo: wire.TLVUserInfo{
+			ScreenName: "them",
+		},
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       wire.ICBMRdvMessagePropose,
+					Cookie:     0x0102030405060708,
+					Capability: uuid.MustParse("09461343-4C7F-11D1-8222-444553540000"),
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsSeqNum, uint16(1)),
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsRdvIP, uint32(0xC0A80001)),
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsVerifiedIP, uint32(0x0A000005)),
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsPort, uint16(5190)),
+							wire.NewTLVBE(wire.ICBMRdvTLVTagsInvitation, "take this file"),
+						},
+					},
+				}),
+			},
+		},
+	})
+	assert.Equal(t, "RVOUS_PROPOSE:them:09461343-4C7F-11D1-8222-444553540000:AQIDBAUGBwg=:1:192.168.0.1::10.0.0.5:5190:12:dGFrZSB0aGlzIGZpbGU=", msg)
+	assert.True(t, chatRegistry.LookupRvous(proposal))
+
+	// accepting a file transfer has no TOC representation
+	msg = svc.IMIn(context.Background(), chatRegistry, wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+		ChannelID: wire.ICBMChannelRendezvous,
+		TLVUserInfo: wire.TLVUserInfo{
+			ScreenName: "them",
+		},
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       wire.ICBMRdvMessageAccept,
+					Cookie:     0x0102030405060708,
+					Capability: uuid.MustParse("09461343-4C7F-11D1-8222-444553540000"),
+				}),
+			},
+		},
+	})
+	assert.Empty(t, msg)
+	assert.True(t, chatRegistry.LookupRvous(proposal))
+
+	// the proposer withdraws the proposal
+	msg = svc.IMIn(context.Background(), chatRegistry, wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+		ChannelID: wire.ICBMChannelRendezvous,
+		TLVUserInfo: wire.TLVUserInfo{
+			ScreenName: "them",
+		},
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+					Type:       wire.ICBMRdvMessageCancel,
+					Cookie:     0x0102030405060708,
+					Capability: uuid.MustParse("09461343-4C7F-11D1-8222-444553540000"),
+				}),
+			},
+		},
+	})
+	assert.Empty(t, msg)
+	assert.False(t, chatRegistry.LookupRvous(proposal))
+}
+
 func TestOSCARProxy_RecvBOS_UpdateBuddyArrival(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
@@ -436,6 +893,25 @@ func TestOSCARProxy_RecvBOS_UpdateBuddyArrival(t *testing.T) {
 			},
 			wantCmd: []byte("UPDATE_BUDDY:me:T:0:1234:5678: O "),
 		},
+		{
+			name: "send buddy arrival - buddy with display screen name",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName:   "Chatting Chuck",
+						WarningLevel: 0,
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+								wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
+							},
+						},
+					},
+				},
+			},
+			wantCmd: []byte("UPDATE_BUDDY:Chatting Chuck:T:0:1234:5678: O "),
+		},
 		{
 			name: "send buddy arrival - buddy warned 10%",
 			me:   newTestSession("me"),
@@ -475,6 +951,66 @@ func TestOSCARProxy_RecvBOS_UpdateBuddyArrival(t *testing.T) {
 			},
 			wantCmd: []byte("UPDATE_BUDDY:me:T:0:1234:5678: OU"),
 		},
+		{
+			name: "send buddy arrival - unconfirmed buddy",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName:   "me",
+						WarningLevel: 0,
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+								wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
+								wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagOSCARFree|wire.OServiceUserFlagUnconfirmed),
+							},
+						},
+					},
+				},
+			},
+			wantCmd: []byte("UPDATE_BUDDY:me:T:0:1234:5678: U "),
+		},
+		{
+			name: "send buddy arrival - away administrator",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName:   "me",
+						WarningLevel: 0,
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+								wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
+								wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagAdministrator|wire.OServiceUserFlagUnconfirmed|wire.OServiceUserFlagUnavailable),
+							},
+						},
+					},
+				},
+			},
+			wantCmd: []byte("UPDATE_BUDDY:me:T:0:1234:5678: AU"),
+		},
+		{
+			name: "send buddy arrival - AOL buddy",
+			me:   newTestSession("me"),
+			givenMsg: wire.SNACMessage{
+				Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+					TLVUserInfo: wire.TLVUserInfo{
+						ScreenName:   "me",
+						WarningLevel: 0,
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1234)),
+								wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(5678)),
+								wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagAOL),
+							},
+						},
+					},
+				},
+			},
+			wantCmd: []byte("UPDATE_BUDDY:me:T:0:1234:5678:AO "),
+		},
 	}
 
 	for _, tc := range cases {
@@ -508,27 +1044,70 @@ func TestOSCARProxy_RecvBOS_UpdateBuddyArrival(t *testing.T) {
 }
 
 func TestOSCARProxy_RecvBOS_UpdateBuddyDeparted(t *testing.T) {
+	fnArrived := func(screenName string) wire.SNACMessage {
+		return wire.SNACMessage{
+			Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+				TLVUserInfo: wire.TLVUserInfo{
+					ScreenName: screenName,
+				},
+			},
+		}
+	}
+	fnDeparted := func(screenName string) wire.SNACMessage {
+		return wire.SNACMessage{
+			Body: wire.SNAC_0x03_0x0C_BuddyDeparted{
+				TLVUserInfo: wire.TLVUserInfo{
+					ScreenName: screenName,
+				},
+			},
+		}
+	}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
 		// me is the TOC user session
 		me *state.Session
-		// givenMsg is the incoming SNAC
-		givenMsg wire.SNACMessage
-		// wantCmd is the expected TOC response
-		wantCmd []byte
+		// givenMsgs are the incoming SNACs
+		givenMsgs []wire.SNACMessage
+		// wantCmds are the expected TOC responses
+		wantCmds []string
 	}{
 		{
-			name: "send buddy departure",
+			name: "send buddy departure with the display screen name the buddy arrived with",
 			me:   newTestSession("me"),
-			givenMsg: wire.SNACMessage{
-				Body: wire.SNAC_0x03_0x0C_BuddyDeparted{
-					TLVUserInfo: wire.TLVUserInfo{
-						ScreenName: "me",
-					},
-				},
+			givenMsgs: []wire.SNACMessage{
+				fnArrived("Chatting Chuck"),
+				fnDeparted("chattingchuck"),
+			},
+			wantCmds: []string{
+				"UPDATE_BUDDY:Chatting Chuck:T:0:0:0: O ",
+				"UPDATE_BUDDY:Chatting Chuck:F:0:0:0:   ",
+			},
+		},
+		{
+			name: "send buddy departure without prior arrival",
+			me:   newTestSession("me"),
+			givenMsgs: []wire.SNACMessage{
+				fnDeparted("chattingchuck"),
+			},
+			wantCmds: []string{
+				"UPDATE_BUDDY:chattingchuck:F:0:0:0:   ",
+			},
+		},
+		{
+			name: "send buddy departure after buddy already departed",
+			me:   newTestSession("me"),
+			givenMsgs: []wire.SNACMessage{
+				fnArrived("Chatting Chuck"),
+				fnDeparted("chattingchuck"),
+				fnDeparted("chattingchuck"),
+			},
+			wantCmds: []string{
+				"UPDATE_BUDDY:Chatting Chuck:T:0:0:0: O ",
+				"UPDATE_BUDDY:Chatting Chuck:F:0:0:0:   ",
+				"UPDATE_BUDDY:chattingchuck:F:0:0:0:   ",
 			},
-			wantCmd: []byte("UPDATE_BUDDY:me:F:0:0:0:   "),
 		},
 	}
 
@@ -550,11 +1129,13 @@ func TestOSCARProxy_RecvBOS_UpdateBuddyDeparted(t *testing.T) {
 				assert.NoError(t, err)
 			}()
 
-			status := tc.me.RelayMessage(tc.givenMsg)
-			assert.Equal(t, state.SessSendOK, status)
+			for i, msg := range tc.givenMsgs {
+				status := tc.me.RelayMessage(msg)
+				assert.Equal(t, state.SessSendOK, status)
 
-			gotCmd := <-ch
-			assert.Equal(t, string(tc.wantCmd), string(gotCmd))
+				gotCmd := <-ch
+				assert.Equal(t, tc.wantCmds[i], string(gotCmd))
+			}
 
 			cancel()
 			wg.Wait()
@@ -564,3 +1145,88 @@ func TestOSCARProxy_RecvBOS_UpdateBuddyDeparted(t *testing.T) {
 
 func TestOSCARProxy_RecvBOS_Signout(t *testing.T) {
 }
+
+func Test_tocMessageText(t *testing.T) {
+	tests := []struct {
+		name  string
+		given string
+		want  string
+	}{
+		{
+			name:  "colons and HTML are passed through",
+			given: `<font color="#ff0000">re: meeting at 10:30</font>`,
+			want:  `<font color="#ff0000">re: meeting at 10:30</font>`,
+		},
+		{
+			name:  "line breaks are converted to HTML",
+			given: "one\r\ntwo\nthree\rfour",
+			want:  "one<br>two<br>three<br>four",
+		},
+		{
+			name:  "NUL bytes are removed",
+			given: "hello\x00 world",
+			want:  "hello world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tocMessageText(tt.given))
+		})
+	}
+}
+
+func Test_userInfoToUpdateBuddy_IdleAndAway(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   func(sess *state.Session)
+		wantCmd string
+	}{
+		{
+			name:    "active",
+			given:   func(sess *state.Session) {},
+			wantCmd: "UPDATE_BUDDY:me:T:0:1000:0: O ",
+		},
+		{
+			name: "idle only",
+			given: func(sess *state.Session) {
+				sess.SetIdle(10 * time.Minute)
+			},
+			wantCmd: "UPDATE_BUDDY:me:T:0:1000:10: O ",
+		},
+		{
+			name: "away only",
+			given: func(sess *state.Session) {
+				sess.SetAwayMessage("brb")
+			},
+			wantCmd: "UPDATE_BUDDY:me:T:0:1000:0: OU",
+		},
+		{
+			name: "idle and away",
+			given: func(sess *state.Session) {
+				sess.SetIdle(10 * time.Minute)
+				sess.SetAwayMessage("brb")
+			},
+			wantCmd: "UPDATE_BUDDY:me:T:0:1000:10: OU",
+		},
+		{
+			name: "idle cleared while away",
+			given: func(sess *state.Session) {
+				sess.SetIdle(10 * time.Minute)
+				sess.SetAwayMessage("brb")
+				sess.UnsetIdle()
+			},
+			wantCmd: "UPDATE_BUDDY:me:T:0:1000:0: OU",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession("me", func(session *state.Session) {
+				session.SetSignonTime(time.Unix(1000, 0))
+			})
+			tt.given(sess)
+			assert.Equal(t, tt.wantCmd, userInfoToUpdateBuddy(sess.TLVUserInfo()))
+		})
+	}
+}
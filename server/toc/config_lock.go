@@ -0,0 +1,65 @@
+package toc
+
+import (
+	"sync"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// NewConfigLocker creates a new ConfigLocker.
+func NewConfigLocker() *ConfigLocker {
+	return &ConfigLocker{
+		locks: make(map[state.IdentScreenName]*configLock),
+	}
+}
+
+// configLock is a per-user mutex that tracks how many callers hold or wait
+// on it.
+type configLock struct {
+	sync.Mutex
+	refs int // Number of callers holding or waiting on the lock.
+}
+
+// ConfigLocker serializes TOC config mutations per user. Some clients send
+// several toc_set_config commands in quick succession, and without
+// serialization the service calls of one write can interleave with another,
+// leaving the stored config out of sync with the applied buddy and
+// permit/deny lists.
+//
+// ConfigLocker is safe for concurrent use.
+type ConfigLocker struct {
+	locks map[state.IdentScreenName]*configLock // Active locks by user.
+	m     sync.Mutex                            // Synchronization primitive for concurrent access.
+}
+
+// Lock blocks until the config lock for user is acquired and returns a
+// function that releases it. A nil ConfigLocker performs no locking.
+func (c *ConfigLocker) Lock(user state.IdentScreenName) (unlock func()) {
+	if c == nil {
+		return func() {}
+	}
+
+	c.m.Lock()
+	lock, ok := c.locks[user]
+	if !ok {
+		lock = &configLock{}
+		c.locks[user] = lock
+	}
+	lock.refs++
+	c.m.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		c.m.Lock()
+		defer c.m.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			// nobody else is waiting, so drop the lock to keep the map from
+			// growing unbounded
+			delete(c.locks, user)
+		}
+	}
+}
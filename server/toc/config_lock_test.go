@@ -0,0 +1,54 @@
+package toc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestConfigLocker_Lock(t *testing.T) {
+	locker := NewConfigLocker()
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	unlockMe := locker.Lock(me)
+
+	// other users are unaffected
+	unlockThem := locker.Lock(them)
+	unlockThem()
+
+	// the same user blocks until the lock is released
+	acquired := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		unlock := locker.Lock(me)
+		close(acquired)
+		unlock()
+		close(released)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held by another caller")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	unlockMe()
+	<-acquired
+	<-released
+
+	// released locks are purged
+	locker.m.Lock()
+	defer locker.m.Unlock()
+	assert.Empty(t, locker.locks)
+}
+
+func TestConfigLocker_Lock_Nil(t *testing.T) {
+	var locker *ConfigLocker
+	unlock := locker.Lock(state.NewIdentScreenName("me"))
+	unlock()
+}
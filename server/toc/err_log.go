@@ -0,0 +1,123 @@
+package toc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// errLogWindow is how long repeats of an error logged for a user are
+// suppressed.
+const errLogWindow = time.Minute
+
+// NewErrorLogLimiter creates a new ErrorLogLimiter.
+func NewErrorLogLimiter() *ErrorLogLimiter {
+	return &ErrorLogLimiter{
+		window:  errLogWindow,
+		nowFn:   time.Now,
+		entries: make(map[errLogKey]*errLogEntry),
+	}
+}
+
+// errLogKey identifies a distinct error logged for a user.
+type errLogKey struct {
+	user state.IdentScreenName
+	msg  string
+}
+
+// errLogEntry tracks the repeats of a logged error.
+type errLogEntry struct {
+	msg        string    // The log message.
+	args       []any     // The log attributes.
+	loggedAt   time.Time // When the error was last logged.
+	suppressed int       // Number of repeats since the error was last logged.
+}
+
+// ErrorLogLimiter bounds the volume of logs produced by a client that
+// repeatedly sends the same bad command. The first occurrence of an error is
+// logged and repeats within the window are counted instead. The next
+// occurrence after the window ends is logged along with the number of
+// repeats that were suppressed.
+//
+// ErrorLogLimiter is safe for concurrent use.
+type ErrorLogLimiter struct {
+	window    time.Duration              // How long repeats are suppressed.
+	nowFn     func() time.Time           // Returns the current time.
+	entries   map[errLogKey]*errLogEntry // Logged errors by user and message.
+	lastSweep time.Time                  // When idle entries were last purged.
+	m         sync.Mutex                 // Synchronization primitive for concurrent access.
+}
+
+// Log logs an error for user at the error level unless the same error was
+// logged for user within the window.
+func (l *ErrorLogLimiter) Log(ctx context.Context, logger *slog.Logger, user state.IdentScreenName, msg string, args ...any) {
+	if l == nil {
+		logger.ErrorContext(ctx, msg, args...)
+		return
+	}
+
+	l.m.Lock()
+
+	now := l.nowFn()
+
+	if now.Sub(l.lastSweep) >= l.window {
+		// purge entries with nothing left to report so that the map doesn't
+		// grow unbounded
+		for key, entry := range l.entries {
+			if entry.suppressed == 0 && now.Sub(entry.loggedAt) >= l.window {
+				delete(l.entries, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := errLogKey{user: user, msg: fmt.Sprint(append([]any{msg}, args...)...)}
+	entry, found := l.entries[key]
+	if found && now.Sub(entry.loggedAt) < l.window {
+		entry.suppressed++
+		l.m.Unlock()
+		return
+	}
+
+	var suppressed int
+	if found {
+		suppressed = entry.suppressed
+	}
+	l.entries[key] = &errLogEntry{msg: msg, args: args, loggedAt: now}
+
+	l.m.Unlock()
+
+	if suppressed > 0 {
+		args = append(args, "suppressed_repeats", suppressed)
+	}
+	logger.ErrorContext(ctx, msg, args...)
+}
+
+// Clear discards user's logged errors, logging a summary of any repeats that
+// were suppressed.
+func (l *ErrorLogLimiter) Clear(ctx context.Context, logger *slog.Logger, user state.IdentScreenName) {
+	if l == nil {
+		return
+	}
+
+	l.m.Lock()
+	var summaries []*errLogEntry
+	for key, entry := range l.entries {
+		if key.user != user {
+			continue
+		}
+		if entry.suppressed > 0 {
+			summaries = append(summaries, entry)
+		}
+		delete(l.entries, key)
+	}
+	l.m.Unlock()
+
+	for _, entry := range summaries {
+		logger.ErrorContext(ctx, entry.msg, append(entry.args, "suppressed_repeats", entry.suppressed)...)
+	}
+}
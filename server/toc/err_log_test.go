@@ -0,0 +1,110 @@
+package toc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// logLines returns the lines written to a text log.
+func logLines(buf *bytes.Buffer) []string {
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestErrorLogLimiter_Log(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	ctx := context.Background()
+
+	now := time.Now()
+	limiter := NewErrorLogLimiter()
+	limiter.nowFn = func() time.Time { return now }
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	for i := 0; i < 100; i++ {
+		limiter.Log(ctx, logger, me, "unsupported TOC command toc_bad")
+	}
+	// different errors and other users' errors are logged separately
+	limiter.Log(ctx, logger, me, "unsupported TOC command toc_worse")
+	limiter.Log(ctx, logger, them, "unsupported TOC command toc_bad")
+
+	lines := logLines(buf)
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], `msg="unsupported TOC command toc_bad"`)
+		assert.NotContains(t, lines[0], "suppressed_repeats")
+		assert.Contains(t, lines[1], `msg="unsupported TOC command toc_worse"`)
+		assert.Contains(t, lines[2], `msg="unsupported TOC command toc_bad"`)
+	}
+
+	// the first repeat after the window ends is logged with the number of
+	// repeats that were suppressed
+	buf.Reset()
+	now = now.Add(errLogWindow)
+	limiter.Log(ctx, logger, me, "unsupported TOC command toc_bad")
+	limiter.Log(ctx, logger, me, "unsupported TOC command toc_bad")
+
+	lines = logLines(buf)
+	if assert.Len(t, lines, 1) {
+		assert.Contains(t, lines[0], `msg="unsupported TOC command toc_bad"`)
+		assert.Contains(t, lines[0], "suppressed_repeats=99")
+	}
+}
+
+func TestErrorLogLimiter_Log_PurgesIdleEntries(t *testing.T) {
+	now := time.Now()
+	limiter := NewErrorLogLimiter()
+	limiter.nowFn = func() time.Time { return now }
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	limiter.Log(context.Background(), logger, state.NewIdentScreenName("me"), "unsupported TOC command toc_bad")
+	assert.Len(t, limiter.entries, 1)
+
+	now = now.Add(errLogWindow)
+	limiter.Log(context.Background(), logger, state.NewIdentScreenName("them"), "unsupported TOC command toc_bad")
+	assert.Len(t, limiter.entries, 1)
+}
+
+func TestErrorLogLimiter_Clear(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	ctx := context.Background()
+
+	limiter := NewErrorLogLimiter()
+
+	me := state.NewIdentScreenName("me")
+	for i := 0; i < 3; i++ {
+		limiter.Log(ctx, logger, me, "internal service error", "err", "parseArgs: malformed command")
+	}
+	limiter.Log(ctx, logger, me, "unsupported TOC command toc_bad")
+
+	buf.Reset()
+	limiter.Clear(ctx, logger, me)
+
+	// only errors with suppressed repeats are summarized
+	lines := logLines(buf)
+	if assert.Len(t, lines, 1) {
+		assert.Contains(t, lines[0], `msg="internal service error" err="parseArgs: malformed command" suppressed_repeats=2`)
+	}
+	assert.Empty(t, limiter.entries)
+}
+
+func TestErrorLogLimiter_Nil(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	var limiter *ErrorLogLimiter
+	limiter.Log(context.Background(), logger, state.NewIdentScreenName("me"), "unsupported TOC command toc_bad")
+	limiter.Log(context.Background(), logger, state.NewIdentScreenName("me"), "unsupported TOC command toc_bad")
+	limiter.Clear(context.Background(), logger, state.NewIdentScreenName("me"))
+
+	assert.Len(t, logLines(buf), 2)
+}
@@ -35,6 +35,66 @@ type delBuddiesParams []struct {
 	err    error
 }
 
+type buddyCountParams []struct {
+	me    state.IdentScreenName
+	count int
+	err   error
+}
+
+type buddyCounterParams struct {
+	buddyCountParams
+}
+
+// buddiesParams holds multiple scenarios for the Buddies method.
+type buddiesParams []struct {
+	me      state.IdentScreenName
+	buddies []state.IdentScreenName
+	err     error
+}
+
+// buddyCommentParams holds multiple scenarios for the BuddyComment method.
+type buddyCommentParams []struct {
+	me      state.IdentScreenName
+	them    state.IdentScreenName
+	comment string
+	err     error
+}
+
+// setBuddyCommentParams holds multiple scenarios for the SetBuddyComment
+// method.
+type setBuddyCommentParams []struct {
+	me      state.IdentScreenName
+	them    state.IdentScreenName
+	comment string
+	err     error
+}
+
+// buddyCommentStoreParams groups the method scenarios for a
+// BuddyCommentStore.
+type buddyCommentStoreParams struct {
+	buddyCommentParams
+	setBuddyCommentParams
+}
+
+// buddyListerParams groups the method scenarios for a BuddyLister.
+type buddyListerParams struct {
+	buddiesParams
+}
+
+// bartUpsertItemParams holds multiple scenarios for the BARTService
+// UpsertItem method.
+type bartUpsertItemParams []struct {
+	me     state.IdentScreenName
+	inBody wire.SNAC_0x10_0x02_BARTUploadQuery
+	msg    wire.SNACMessage
+	err    error
+}
+
+// bartParams groups the method scenarios for a BARTService.
+type bartParams struct {
+	bartUpsertItemParams
+}
+
 type buddyParams struct {
 	addBuddiesParams
 	broadcastBuddyDepartedParams
@@ -63,6 +123,42 @@ type chatNavParams struct {
 	requestRoomInfoParams
 }
 
+type occupantCountParams []struct {
+	cookie string
+	count  int
+}
+
+type chatOccupantCounterParams struct {
+	occupantCountParams
+}
+
+type chatRoomByCookieParams []struct {
+	cookie string
+	room   state.ChatRoom
+	err    error
+}
+
+type setChatRoomTopicParams []struct {
+	cookie string
+	topic  string
+	err    error
+}
+
+type chatRoomManagerParams struct {
+	chatRoomByCookieParams
+	setChatRoomTopicParams
+}
+
+type relayToAllExceptParams []struct {
+	cookie string
+	except state.IdentScreenName
+	msg    wire.SNACMessage
+}
+
+type chatMessageRelayerParams struct {
+	relayToAllExceptParams
+}
+
 type channelMsgToHostParamsChat []struct {
 	sender state.IdentScreenName
 	inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost
@@ -85,6 +181,29 @@ type evilRequestParams []struct {
 	err    error
 }
 
+// feedbagQueryParams holds multiple scenarios for the FeedbagService Query
+// method.
+type feedbagQueryParams []struct {
+	me  state.IdentScreenName
+	msg wire.SNACMessage
+	err error
+}
+
+// feedbagUpsertItemParams holds multiple scenarios for the FeedbagService
+// UpsertItem method.
+type feedbagUpsertItemParams []struct {
+	me    state.IdentScreenName
+	items []wire.FeedbagItem
+	msg   wire.SNACMessage
+	err   error
+}
+
+// feedbagParams groups the method scenarios for a FeedbagService.
+type feedbagParams struct {
+	feedbagQueryParams
+	feedbagUpsertItemParams
+}
+
 type icbmParams struct {
 	channelMsgToHostParamsICBM
 	evilRequestParams
@@ -109,10 +228,17 @@ type serviceRequestParams []struct {
 	err    error
 }
 
+type setUserInfoFieldsParams []struct {
+	me     state.IdentScreenName
+	bodyIn wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields
+	err    error
+}
+
 type oServiceParams struct {
 	clientOnlineParams
 	idleNotificationParams
 	serviceRequestParams
+	setUserInfoFieldsParams
 }
 
 type flapLoginParams []struct {
@@ -176,9 +302,19 @@ type dirInfoParams []struct {
 	err  error
 }
 
+// setKeywordInfoParams holds multiple scenarios for the SetKeywordInfo
+// method.
+type setKeywordInfoParams []struct {
+	me     state.IdentScreenName
+	inBody wire.SNAC_0x02_0x0F_LocateSetKeywordInfo
+	msg    wire.SNACMessage
+	err    error
+}
+
 type locateParams struct {
 	setDirInfoParams
 	setInfoParams
+	setKeywordInfoParams
 	userInfoQueryParams
 	dirInfoParams
 }
@@ -189,8 +325,16 @@ type infoQueryParams []struct {
 	err    error
 }
 
+// keywordListQueryParams holds multiple scenarios for the KeywordListQuery
+// method.
+type keywordListQueryParams []struct {
+	msg wire.SNACMessage
+	err error
+}
+
 type dirSearchParams struct {
 	infoQueryParams
+	keywordListQueryParams
 }
 
 type addDenyListEntriesParams []struct {
@@ -205,9 +349,16 @@ type addPermListEntriesParams []struct {
 	err  error
 }
 
+type delPermListEntriesParams []struct {
+	me   state.IdentScreenName
+	body wire.SNAC_0x09_0x06_PermitDenyDelPermListEntries
+	err  error
+}
+
 type permitDenyParams struct {
 	addDenyListEntriesParams
 	addPermListEntriesParams
+	delPermListEntriesParams
 }
 
 type registerBuddyListParams []struct {
@@ -231,6 +382,12 @@ type setTOCConfigParams []struct {
 	err    error
 }
 
+type setTOCConfig2Params []struct {
+	user   state.IdentScreenName
+	config string
+	err    error
+}
+
 type userParams []struct {
 	screenName   state.IdentScreenName
 	returnedUser *state.User
@@ -239,24 +396,80 @@ type userParams []struct {
 
 type tocConfigParams struct {
 	setTOCConfigParams
+	setTOCConfig2Params
 	userParams
 }
 
+type userLookupParams []struct {
+	screenName state.IdentScreenName
+	user       *state.User
+	err        error
+}
+
+type userManagerParams struct {
+	userLookupParams
+}
+
 type mockParams struct {
+	abuseReportParams
 	adminParams
 	authParams
+	bartParams
+	buddyCommentStoreParams
+	buddyCounterParams
+	buddyListerParams
 	buddyListRegistryParams
 	buddyParams
+	chatMessageRelayerParams
 	chatNavParams
+	chatOccupantCounterParams
 	chatParams
+	chatRoomManagerParams
 	cookieBakerParams
 	dirSearchParams
+	feedbagParams
 	icbmParams
 	locateParams
+	offlineMessageManagerParams
 	oServiceBOSParams  oServiceParams
 	oServiceChatParams oServiceParams
 	permitDenyParams
 	tocConfigParams
+	userManagerParams
+}
+
+// addAbuseReportParams holds multiple scenarios for the AddAbuseReport
+// method. The report creation time is not compared.
+type addAbuseReportParams []struct {
+	report state.AbuseReport
+	err    error
+}
+
+// abuseReportParams groups the method scenarios for an AbuseReportStore.
+type abuseReportParams struct {
+	addAbuseReportParams
+}
+
+// retrieveMessagesParams holds multiple scenarios for the RetrieveMessages
+// method.
+type retrieveMessagesParams []struct {
+	recip    state.IdentScreenName
+	messages []state.OfflineMessage
+	err      error
+}
+
+// deleteMessageParams holds multiple scenarios for the DeleteMessage
+// method.
+type deleteMessageParams []struct {
+	id  int64
+	err error
+}
+
+// offlineMessageManagerParams groups the method scenarios for an
+// OfflineMessageManager.
+type offlineMessageManagerParams struct {
+	retrieveMessagesParams
+	deleteMessageParams
 }
 
 // issueParams holds multiple scenarios for the Issue method.
@@ -3,7 +3,6 @@ package toc
 import (
 	"bytes"
 	"context"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
@@ -95,14 +94,7 @@ func (s OSCARProxy) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		data, err := hex.DecodeString(cookie)
-		if err != nil {
-			s.Logger.DebugContext(ctx, "error decoding string", "err", err.Error())
-			http.Error(w, "invalid auth cookie", http.StatusForbidden)
-			return
-		}
-
-		if _, err = s.CookieBaker.Crack(data); err != nil {
+		if _, err := s.crackHTTPAuthToken(cookie); err != nil {
 			s.Logger.DebugContext(ctx, "error cracking auth cookie", "err", err.Error())
 			http.Error(w, "invalid auth cookie", http.StatusForbidden)
 			return
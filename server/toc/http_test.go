@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -131,6 +132,18 @@ func TestOSCARProxy_NewServeMux(t *testing.T) {
 			expectedStatus: http.StatusForbidden,
 			expectedBody:   "invalid auth cookie",
 		},
+		{
+			name:           "Retrieve profile with truncated auth cookie",
+			path:           "/info?from=me&user=them&cookie=" + cookie[:len(cookie)-1],
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "invalid auth cookie",
+		},
+		{
+			name:           "Retrieve profile with oversized auth cookie",
+			path:           "/info?from=me&user=them&cookie=" + strings.Repeat("00", 257),
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "invalid auth cookie",
+		},
 		{
 			name:           "Retrieve profile, receive error from locate svc",
 			path:           "/info?from=me&user=them&cookie=" + cookie,
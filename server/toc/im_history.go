@@ -0,0 +1,80 @@
+package toc
+
+import (
+	"sync"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// imHistorySize is the number of recently received instant messages kept per
+// recipient.
+const imHistorySize = 10
+
+// NewIMHistory creates a new IMHistory.
+func NewIMHistory() *IMHistory {
+	return &IMHistory{
+		history: make(map[state.IdentScreenName][]imHistoryEntry),
+	}
+}
+
+// imHistoryEntry is an instant message received by a user.
+type imHistoryEntry struct {
+	sender state.IdentScreenName
+	msg    string
+}
+
+// IMHistory keeps the last few instant messages each signed-on user has
+// received. It provides the message context attached to abuse reports.
+//
+// IMHistory is safe for concurrent use.
+type IMHistory struct {
+	history map[state.IdentScreenName][]imHistoryEntry // Recent messages by recipient, oldest first.
+	m       sync.Mutex                                 // Synchronization primitive for concurrent access.
+}
+
+// Add records msg from sender to recip, discarding recip's oldest message if
+// the history is full.
+func (h *IMHistory) Add(recip, sender state.IdentScreenName, msg string) {
+	if h == nil {
+		return
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	entries := append(h.history[recip], imHistoryEntry{sender: sender, msg: msg})
+	if len(entries) > imHistorySize {
+		entries = entries[len(entries)-imHistorySize:]
+	}
+	h.history[recip] = entries
+}
+
+// From returns the recent messages recip received from sender, oldest first.
+func (h *IMHistory) From(recip, sender state.IdentScreenName) []string {
+	if h == nil {
+		return nil
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	var msgs []string
+	for _, entry := range h.history[recip] {
+		if entry.sender == sender {
+			msgs = append(msgs, entry.msg)
+		}
+	}
+	return msgs
+}
+
+// Clear discards recip's message history.
+func (h *IMHistory) Clear(recip state.IdentScreenName) {
+	if h == nil {
+		return
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	delete(h.history, recip)
+}
@@ -0,0 +1,56 @@
+package toc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestIMHistory_From(t *testing.T) {
+	history := NewIMHistory()
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+	other := state.NewIdentScreenName("other")
+
+	history.Add(me, them, "IM_IN:them:F:hello")
+	history.Add(me, other, "IM_IN:other:F:hi")
+	history.Add(me, them, "IM_IN:them:F:buy now")
+
+	// only messages from the sender are returned, oldest first
+	assert.Equal(t, []string{"IM_IN:them:F:hello", "IM_IN:them:F:buy now"}, history.From(me, them))
+	// the history isn't symmetric
+	assert.Empty(t, history.From(them, me))
+
+	history.Clear(me)
+	assert.Empty(t, history.From(me, them))
+}
+
+func TestIMHistory_Add_DiscardsOldestMessages(t *testing.T) {
+	history := NewIMHistory()
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	for i := 0; i < imHistorySize+2; i++ {
+		history.Add(me, them, fmt.Sprintf("IM_IN:them:F:%d", i))
+	}
+
+	msgs := history.From(me, them)
+	assert.Len(t, msgs, imHistorySize)
+	assert.Equal(t, "IM_IN:them:F:2", msgs[0])
+}
+
+func TestIMHistory_Nil(t *testing.T) {
+	var history *IMHistory
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	history.Add(me, them, "IM_IN:them:F:hello")
+	history.Clear(me)
+	assert.Empty(t, history.From(me, them))
+}
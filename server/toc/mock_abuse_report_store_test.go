@@ -0,0 +1,81 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockAbuseReportStore is an autogenerated mock type for the AbuseReportStore type
+type mockAbuseReportStore struct {
+	mock.Mock
+}
+
+type mockAbuseReportStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockAbuseReportStore) EXPECT() *mockAbuseReportStore_Expecter {
+	return &mockAbuseReportStore_Expecter{mock: &_m.Mock}
+}
+
+// AddAbuseReport provides a mock function with given fields: report
+func (_m *mockAbuseReportStore) AddAbuseReport(report state.AbuseReport) error {
+	ret := _m.Called(report)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddAbuseReport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.AbuseReport) error); ok {
+		r0 = rf(report)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockAbuseReportStore_AddAbuseReport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddAbuseReport'
+type mockAbuseReportStore_AddAbuseReport_Call struct {
+	*mock.Call
+}
+
+// AddAbuseReport is a helper method to define mock.On call
+//   - report state.AbuseReport
+func (_e *mockAbuseReportStore_Expecter) AddAbuseReport(report interface{}) *mockAbuseReportStore_AddAbuseReport_Call {
+	return &mockAbuseReportStore_AddAbuseReport_Call{Call: _e.mock.On("AddAbuseReport", report)}
+}
+
+func (_c *mockAbuseReportStore_AddAbuseReport_Call) Run(run func(report state.AbuseReport)) *mockAbuseReportStore_AddAbuseReport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.AbuseReport))
+	})
+	return _c
+}
+
+func (_c *mockAbuseReportStore_AddAbuseReport_Call) Return(_a0 error) *mockAbuseReportStore_AddAbuseReport_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockAbuseReportStore_AddAbuseReport_Call) RunAndReturn(run func(state.AbuseReport) error) *mockAbuseReportStore_AddAbuseReport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockAbuseReportStore creates a new instance of mockAbuseReportStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockAbuseReportStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockAbuseReportStore {
+	mock := &mockAbuseReportStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
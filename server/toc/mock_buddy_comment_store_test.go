@@ -0,0 +1,140 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockBuddyCommentStore is an autogenerated mock type for the BuddyCommentStore type
+type mockBuddyCommentStore struct {
+	mock.Mock
+}
+
+type mockBuddyCommentStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockBuddyCommentStore) EXPECT() *mockBuddyCommentStore_Expecter {
+	return &mockBuddyCommentStore_Expecter{mock: &_m.Mock}
+}
+
+// BuddyComment provides a mock function with given fields: me, them
+func (_m *mockBuddyCommentStore) BuddyComment(me state.IdentScreenName, them state.IdentScreenName) (string, error) {
+	ret := _m.Called(me, them)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuddyComment")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.IdentScreenName) (string, error)); ok {
+		return rf(me, them)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.IdentScreenName) string); ok {
+		r0 = rf(me, them)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName, state.IdentScreenName) error); ok {
+		r1 = rf(me, them)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockBuddyCommentStore_BuddyComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BuddyComment'
+type mockBuddyCommentStore_BuddyComment_Call struct {
+	*mock.Call
+}
+
+// BuddyComment is a helper method to define mock.On call
+//   - me state.IdentScreenName
+//   - them state.IdentScreenName
+func (_e *mockBuddyCommentStore_Expecter) BuddyComment(me interface{}, them interface{}) *mockBuddyCommentStore_BuddyComment_Call {
+	return &mockBuddyCommentStore_BuddyComment_Call{Call: _e.mock.On("BuddyComment", me, them)}
+}
+
+func (_c *mockBuddyCommentStore_BuddyComment_Call) Run(run func(me state.IdentScreenName, them state.IdentScreenName)) *mockBuddyCommentStore_BuddyComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockBuddyCommentStore_BuddyComment_Call) Return(_a0 string, _a1 error) *mockBuddyCommentStore_BuddyComment_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockBuddyCommentStore_BuddyComment_Call) RunAndReturn(run func(state.IdentScreenName, state.IdentScreenName) (string, error)) *mockBuddyCommentStore_BuddyComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetBuddyComment provides a mock function with given fields: me, them, comment
+func (_m *mockBuddyCommentStore) SetBuddyComment(me state.IdentScreenName, them state.IdentScreenName, comment string) error {
+	ret := _m.Called(me, them, comment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBuddyComment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.IdentScreenName, string) error); ok {
+		r0 = rf(me, them, comment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockBuddyCommentStore_SetBuddyComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetBuddyComment'
+type mockBuddyCommentStore_SetBuddyComment_Call struct {
+	*mock.Call
+}
+
+// SetBuddyComment is a helper method to define mock.On call
+//   - me state.IdentScreenName
+//   - them state.IdentScreenName
+//   - comment string
+func (_e *mockBuddyCommentStore_Expecter) SetBuddyComment(me interface{}, them interface{}, comment interface{}) *mockBuddyCommentStore_SetBuddyComment_Call {
+	return &mockBuddyCommentStore_SetBuddyComment_Call{Call: _e.mock.On("SetBuddyComment", me, them, comment)}
+}
+
+func (_c *mockBuddyCommentStore_SetBuddyComment_Call) Run(run func(me state.IdentScreenName, them state.IdentScreenName, comment string)) *mockBuddyCommentStore_SetBuddyComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(state.IdentScreenName), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *mockBuddyCommentStore_SetBuddyComment_Call) Return(_a0 error) *mockBuddyCommentStore_SetBuddyComment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockBuddyCommentStore_SetBuddyComment_Call) RunAndReturn(run func(state.IdentScreenName, state.IdentScreenName, string) error) *mockBuddyCommentStore_SetBuddyComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockBuddyCommentStore creates a new instance of mockBuddyCommentStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockBuddyCommentStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockBuddyCommentStore {
+	mock := &mockBuddyCommentStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,91 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockBuddyCounter is an autogenerated mock type for the BuddyCounter type
+type mockBuddyCounter struct {
+	mock.Mock
+}
+
+type mockBuddyCounter_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockBuddyCounter) EXPECT() *mockBuddyCounter_Expecter {
+	return &mockBuddyCounter_Expecter{mock: &_m.Mock}
+}
+
+// BuddyCount provides a mock function with given fields: me
+func (_m *mockBuddyCounter) BuddyCount(me state.IdentScreenName) (int, error) {
+	ret := _m.Called(me)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuddyCount")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) (int, error)); ok {
+		return rf(me)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) int); ok {
+		r0 = rf(me)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(me)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockBuddyCounter_BuddyCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BuddyCount'
+type mockBuddyCounter_BuddyCount_Call struct {
+	*mock.Call
+}
+
+// BuddyCount is a helper method to define mock.On call
+//   - me state.IdentScreenName
+func (_e *mockBuddyCounter_Expecter) BuddyCount(me interface{}) *mockBuddyCounter_BuddyCount_Call {
+	return &mockBuddyCounter_BuddyCount_Call{Call: _e.mock.On("BuddyCount", me)}
+}
+
+func (_c *mockBuddyCounter_BuddyCount_Call) Run(run func(me state.IdentScreenName)) *mockBuddyCounter_BuddyCount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockBuddyCounter_BuddyCount_Call) Return(_a0 int, _a1 error) *mockBuddyCounter_BuddyCount_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockBuddyCounter_BuddyCount_Call) RunAndReturn(run func(state.IdentScreenName) (int, error)) *mockBuddyCounter_BuddyCount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockBuddyCounter creates a new instance of mockBuddyCounter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockBuddyCounter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockBuddyCounter {
+	mock := &mockBuddyCounter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,93 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockBuddyLister is an autogenerated mock type for the BuddyLister type
+type mockBuddyLister struct {
+	mock.Mock
+}
+
+type mockBuddyLister_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockBuddyLister) EXPECT() *mockBuddyLister_Expecter {
+	return &mockBuddyLister_Expecter{mock: &_m.Mock}
+}
+
+// Buddies provides a mock function with given fields: me
+func (_m *mockBuddyLister) Buddies(me state.IdentScreenName) ([]state.IdentScreenName, error) {
+	ret := _m.Called(me)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Buddies")
+	}
+
+	var r0 []state.IdentScreenName
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) ([]state.IdentScreenName, error)); ok {
+		return rf(me)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) []state.IdentScreenName); ok {
+		r0 = rf(me)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]state.IdentScreenName)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(me)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockBuddyLister_Buddies_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Buddies'
+type mockBuddyLister_Buddies_Call struct {
+	*mock.Call
+}
+
+// Buddies is a helper method to define mock.On call
+//   - me state.IdentScreenName
+func (_e *mockBuddyLister_Expecter) Buddies(me interface{}) *mockBuddyLister_Buddies_Call {
+	return &mockBuddyLister_Buddies_Call{Call: _e.mock.On("Buddies", me)}
+}
+
+func (_c *mockBuddyLister_Buddies_Call) Run(run func(me state.IdentScreenName)) *mockBuddyLister_Buddies_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockBuddyLister_Buddies_Call) Return(_a0 []state.IdentScreenName, _a1 error) *mockBuddyLister_Buddies_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockBuddyLister_Buddies_Call) RunAndReturn(run func(state.IdentScreenName) ([]state.IdentScreenName, error)) *mockBuddyLister_Buddies_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockBuddyLister creates a new instance of mockBuddyLister. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockBuddyLister(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockBuddyLister {
+	mock := &mockBuddyLister{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,75 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	context "context"
+
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+
+	wire "github.com/mk6i/retro-aim-server/wire"
+)
+
+// mockChatMessageRelayer is an autogenerated mock type for the ChatMessageRelayer type
+type mockChatMessageRelayer struct {
+	mock.Mock
+}
+
+type mockChatMessageRelayer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockChatMessageRelayer) EXPECT() *mockChatMessageRelayer_Expecter {
+	return &mockChatMessageRelayer_Expecter{mock: &_m.Mock}
+}
+
+// RelayToAllExcept provides a mock function with given fields: ctx, chatCookie, except, msg
+func (_m *mockChatMessageRelayer) RelayToAllExcept(ctx context.Context, chatCookie string, except state.IdentScreenName, msg wire.SNACMessage) {
+	_m.Called(ctx, chatCookie, except, msg)
+}
+
+// mockChatMessageRelayer_RelayToAllExcept_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RelayToAllExcept'
+type mockChatMessageRelayer_RelayToAllExcept_Call struct {
+	*mock.Call
+}
+
+// RelayToAllExcept is a helper method to define mock.On call
+//   - ctx context.Context
+//   - chatCookie string
+//   - except state.IdentScreenName
+//   - msg wire.SNACMessage
+func (_e *mockChatMessageRelayer_Expecter) RelayToAllExcept(ctx interface{}, chatCookie interface{}, except interface{}, msg interface{}) *mockChatMessageRelayer_RelayToAllExcept_Call {
+	return &mockChatMessageRelayer_RelayToAllExcept_Call{Call: _e.mock.On("RelayToAllExcept", ctx, chatCookie, except, msg)}
+}
+
+func (_c *mockChatMessageRelayer_RelayToAllExcept_Call) Run(run func(ctx context.Context, chatCookie string, except state.IdentScreenName, msg wire.SNACMessage)) *mockChatMessageRelayer_RelayToAllExcept_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(state.IdentScreenName), args[3].(wire.SNACMessage))
+	})
+	return _c
+}
+
+func (_c *mockChatMessageRelayer_RelayToAllExcept_Call) Return() *mockChatMessageRelayer_RelayToAllExcept_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *mockChatMessageRelayer_RelayToAllExcept_Call) RunAndReturn(run func(context.Context, string, state.IdentScreenName, wire.SNACMessage)) *mockChatMessageRelayer_RelayToAllExcept_Call {
+	_c.Run(run)
+	return _c
+}
+
+// newMockChatMessageRelayer creates a new instance of mockChatMessageRelayer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockChatMessageRelayer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockChatMessageRelayer {
+	mock := &mockChatMessageRelayer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
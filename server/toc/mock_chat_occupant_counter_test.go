@@ -0,0 +1,78 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import mock "github.com/stretchr/testify/mock"
+
+// mockChatOccupantCounter is an autogenerated mock type for the ChatOccupantCounter type
+type mockChatOccupantCounter struct {
+	mock.Mock
+}
+
+type mockChatOccupantCounter_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockChatOccupantCounter) EXPECT() *mockChatOccupantCounter_Expecter {
+	return &mockChatOccupantCounter_Expecter{mock: &_m.Mock}
+}
+
+// OccupantCount provides a mock function with given fields: chatCookie
+func (_m *mockChatOccupantCounter) OccupantCount(chatCookie string) int {
+	ret := _m.Called(chatCookie)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OccupantCount")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(chatCookie)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// mockChatOccupantCounter_OccupantCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OccupantCount'
+type mockChatOccupantCounter_OccupantCount_Call struct {
+	*mock.Call
+}
+
+// OccupantCount is a helper method to define mock.On call
+//   - chatCookie string
+func (_e *mockChatOccupantCounter_Expecter) OccupantCount(chatCookie interface{}) *mockChatOccupantCounter_OccupantCount_Call {
+	return &mockChatOccupantCounter_OccupantCount_Call{Call: _e.mock.On("OccupantCount", chatCookie)}
+}
+
+func (_c *mockChatOccupantCounter_OccupantCount_Call) Run(run func(chatCookie string)) *mockChatOccupantCounter_OccupantCount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *mockChatOccupantCounter_OccupantCount_Call) Return(_a0 int) *mockChatOccupantCounter_OccupantCount_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockChatOccupantCounter_OccupantCount_Call) RunAndReturn(run func(string) int) *mockChatOccupantCounter_OccupantCount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockChatOccupantCounter creates a new instance of mockChatOccupantCounter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockChatOccupantCounter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockChatOccupantCounter {
+	mock := &mockChatOccupantCounter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,138 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockChatRoomManager is an autogenerated mock type for the ChatRoomManager type
+type mockChatRoomManager struct {
+	mock.Mock
+}
+
+type mockChatRoomManager_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockChatRoomManager) EXPECT() *mockChatRoomManager_Expecter {
+	return &mockChatRoomManager_Expecter{mock: &_m.Mock}
+}
+
+// ChatRoomByCookie provides a mock function with given fields: chatCookie
+func (_m *mockChatRoomManager) ChatRoomByCookie(chatCookie string) (state.ChatRoom, error) {
+	ret := _m.Called(chatCookie)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChatRoomByCookie")
+	}
+
+	var r0 state.ChatRoom
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (state.ChatRoom, error)); ok {
+		return rf(chatCookie)
+	}
+	if rf, ok := ret.Get(0).(func(string) state.ChatRoom); ok {
+		r0 = rf(chatCookie)
+	} else {
+		r0 = ret.Get(0).(state.ChatRoom)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(chatCookie)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockChatRoomManager_ChatRoomByCookie_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ChatRoomByCookie'
+type mockChatRoomManager_ChatRoomByCookie_Call struct {
+	*mock.Call
+}
+
+// ChatRoomByCookie is a helper method to define mock.On call
+//   - chatCookie string
+func (_e *mockChatRoomManager_Expecter) ChatRoomByCookie(chatCookie interface{}) *mockChatRoomManager_ChatRoomByCookie_Call {
+	return &mockChatRoomManager_ChatRoomByCookie_Call{Call: _e.mock.On("ChatRoomByCookie", chatCookie)}
+}
+
+func (_c *mockChatRoomManager_ChatRoomByCookie_Call) Run(run func(chatCookie string)) *mockChatRoomManager_ChatRoomByCookie_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *mockChatRoomManager_ChatRoomByCookie_Call) Return(_a0 state.ChatRoom, _a1 error) *mockChatRoomManager_ChatRoomByCookie_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockChatRoomManager_ChatRoomByCookie_Call) RunAndReturn(run func(string) (state.ChatRoom, error)) *mockChatRoomManager_ChatRoomByCookie_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetChatRoomTopic provides a mock function with given fields: chatCookie, topic
+func (_m *mockChatRoomManager) SetChatRoomTopic(chatCookie string, topic string) error {
+	ret := _m.Called(chatCookie, topic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetChatRoomTopic")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(chatCookie, topic)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockChatRoomManager_SetChatRoomTopic_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetChatRoomTopic'
+type mockChatRoomManager_SetChatRoomTopic_Call struct {
+	*mock.Call
+}
+
+// SetChatRoomTopic is a helper method to define mock.On call
+//   - chatCookie string
+//   - topic string
+func (_e *mockChatRoomManager_Expecter) SetChatRoomTopic(chatCookie interface{}, topic interface{}) *mockChatRoomManager_SetChatRoomTopic_Call {
+	return &mockChatRoomManager_SetChatRoomTopic_Call{Call: _e.mock.On("SetChatRoomTopic", chatCookie, topic)}
+}
+
+func (_c *mockChatRoomManager_SetChatRoomTopic_Call) Run(run func(chatCookie string, topic string)) *mockChatRoomManager_SetChatRoomTopic_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *mockChatRoomManager_SetChatRoomTopic_Call) Return(_a0 error) *mockChatRoomManager_SetChatRoomTopic_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockChatRoomManager_SetChatRoomTopic_Call) RunAndReturn(run func(string, string) error) *mockChatRoomManager_SetChatRoomTopic_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockChatRoomManager creates a new instance of mockChatRoomManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockChatRoomManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockChatRoomManager {
+	mock := &mockChatRoomManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
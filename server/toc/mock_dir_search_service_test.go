@@ -80,6 +80,63 @@ func (_c *mockDirSearchService_InfoQuery_Call) RunAndReturn(run func(context.Con
 	return _c
 }
 
+// KeywordListQuery provides a mock function with given fields: _a0, inFrame
+func (_m *mockDirSearchService) KeywordListQuery(_a0 context.Context, inFrame wire.SNACFrame) (wire.SNACMessage, error) {
+	ret := _m.Called(_a0, inFrame)
+
+	if len(ret) == 0 {
+		panic("no return value specified for KeywordListQuery")
+	}
+
+	var r0 wire.SNACMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame) (wire.SNACMessage, error)); ok {
+		return rf(_a0, inFrame)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame) wire.SNACMessage); ok {
+		r0 = rf(_a0, inFrame)
+	} else {
+		r0 = ret.Get(0).(wire.SNACMessage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, wire.SNACFrame) error); ok {
+		r1 = rf(_a0, inFrame)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockDirSearchService_KeywordListQuery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'KeywordListQuery'
+type mockDirSearchService_KeywordListQuery_Call struct {
+	*mock.Call
+}
+
+// KeywordListQuery is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - inFrame wire.SNACFrame
+func (_e *mockDirSearchService_Expecter) KeywordListQuery(_a0 interface{}, inFrame interface{}) *mockDirSearchService_KeywordListQuery_Call {
+	return &mockDirSearchService_KeywordListQuery_Call{Call: _e.mock.On("KeywordListQuery", _a0, inFrame)}
+}
+
+func (_c *mockDirSearchService_KeywordListQuery_Call) Run(run func(_a0 context.Context, inFrame wire.SNACFrame)) *mockDirSearchService_KeywordListQuery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(wire.SNACFrame))
+	})
+	return _c
+}
+
+func (_c *mockDirSearchService_KeywordListQuery_Call) Return(_a0 wire.SNACMessage, _a1 error) *mockDirSearchService_KeywordListQuery_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockDirSearchService_KeywordListQuery_Call) RunAndReturn(run func(context.Context, wire.SNACFrame) (wire.SNACMessage, error)) *mockDirSearchService_KeywordListQuery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // newMockDirSearchService creates a new instance of mockDirSearchService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func newMockDirSearchService(t interface {
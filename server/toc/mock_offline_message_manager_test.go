@@ -0,0 +1,139 @@
+// Code generated by mockery v2.52.1. DO NOT EDIT.
+
+package toc
+
+import (
+	state "github.com/mk6i/retro-aim-server/state"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockOfflineMessageManager is an autogenerated mock type for the OfflineMessageManager type
+type mockOfflineMessageManager struct {
+	mock.Mock
+}
+
+type mockOfflineMessageManager_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *mockOfflineMessageManager) EXPECT() *mockOfflineMessageManager_Expecter {
+	return &mockOfflineMessageManager_Expecter{mock: &_m.Mock}
+}
+
+// DeleteMessage provides a mock function with given fields: id
+func (_m *mockOfflineMessageManager) DeleteMessage(id int64) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMessage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockOfflineMessageManager_DeleteMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMessage'
+type mockOfflineMessageManager_DeleteMessage_Call struct {
+	*mock.Call
+}
+
+// DeleteMessage is a helper method to define mock.On call
+//   - id int64
+func (_e *mockOfflineMessageManager_Expecter) DeleteMessage(id interface{}) *mockOfflineMessageManager_DeleteMessage_Call {
+	return &mockOfflineMessageManager_DeleteMessage_Call{Call: _e.mock.On("DeleteMessage", id)}
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) Run(run func(id int64)) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) Return(_a0 error) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_DeleteMessage_Call) RunAndReturn(run func(int64) error) *mockOfflineMessageManager_DeleteMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RetrieveMessages provides a mock function with given fields: recip
+func (_m *mockOfflineMessageManager) RetrieveMessages(recip state.IdentScreenName) ([]state.OfflineMessage, error) {
+	ret := _m.Called(recip)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetrieveMessages")
+	}
+
+	var r0 []state.OfflineMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) ([]state.OfflineMessage, error)); ok {
+		return rf(recip)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) []state.OfflineMessage); ok {
+		r0 = rf(recip)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]state.OfflineMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(recip)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockOfflineMessageManager_RetrieveMessages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RetrieveMessages'
+type mockOfflineMessageManager_RetrieveMessages_Call struct {
+	*mock.Call
+}
+
+// RetrieveMessages is a helper method to define mock.On call
+//   - recip state.IdentScreenName
+func (_e *mockOfflineMessageManager_Expecter) RetrieveMessages(recip interface{}) *mockOfflineMessageManager_RetrieveMessages_Call {
+	return &mockOfflineMessageManager_RetrieveMessages_Call{Call: _e.mock.On("RetrieveMessages", recip)}
+}
+
+func (_c *mockOfflineMessageManager_RetrieveMessages_Call) Run(run func(recip state.IdentScreenName)) *mockOfflineMessageManager_RetrieveMessages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_RetrieveMessages_Call) Return(_a0 []state.OfflineMessage, _a1 error) *mockOfflineMessageManager_RetrieveMessages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockOfflineMessageManager_RetrieveMessages_Call) RunAndReturn(run func(state.IdentScreenName) ([]state.OfflineMessage, error)) *mockOfflineMessageManager_RetrieveMessages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// newMockOfflineMessageManager creates a new instance of mockOfflineMessageManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockOfflineMessageManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockOfflineMessageManager {
+	mock := &mockOfflineMessageManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -67,6 +67,53 @@ func (_c *mockTOCConfigStore_SetTOCConfig_Call) RunAndReturn(run func(state.Iden
 	return _c
 }
 
+// SetTOCConfig2 provides a mock function with given fields: user, config
+func (_m *mockTOCConfigStore) SetTOCConfig2(user state.IdentScreenName, config string) error {
+	ret := _m.Called(user, config)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTOCConfig2")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, string) error); ok {
+		r0 = rf(user, config)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockTOCConfigStore_SetTOCConfig2_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetTOCConfig2'
+type mockTOCConfigStore_SetTOCConfig2_Call struct {
+	*mock.Call
+}
+
+// SetTOCConfig2 is a helper method to define mock.On call
+//   - user state.IdentScreenName
+//   - config string
+func (_e *mockTOCConfigStore_Expecter) SetTOCConfig2(user interface{}, config interface{}) *mockTOCConfigStore_SetTOCConfig2_Call {
+	return &mockTOCConfigStore_SetTOCConfig2_Call{Call: _e.mock.On("SetTOCConfig2", user, config)}
+}
+
+func (_c *mockTOCConfigStore_SetTOCConfig2_Call) Run(run func(user state.IdentScreenName, config string)) *mockTOCConfigStore_SetTOCConfig2_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *mockTOCConfigStore_SetTOCConfig2_Call) Return(_a0 error) *mockTOCConfigStore_SetTOCConfig2_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockTOCConfigStore_SetTOCConfig2_Call) RunAndReturn(run func(state.IdentScreenName, string) error) *mockTOCConfigStore_SetTOCConfig2_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // User provides a mock function with given fields: screenName
 func (_m *mockTOCConfigStore) User(screenName state.IdentScreenName) (*state.User, error) {
 	ret := _m.Called(screenName)
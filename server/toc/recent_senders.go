@@ -0,0 +1,75 @@
+package toc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// NewRecentIMSenders creates a new RecentIMSenders that remembers a sender
+// for ttl after their last instant message. A ttl of 0 disables tracking.
+func NewRecentIMSenders(ttl time.Duration) *RecentIMSenders {
+	return &RecentIMSenders{
+		ttl:     ttl,
+		nowFn:   time.Now,
+		senders: make(map[recentIMSenderKey]time.Time),
+	}
+}
+
+// recentIMSenderKey identifies a recipient/sender pair.
+type recentIMSenderKey struct {
+	recip  state.IdentScreenName
+	sender state.IdentScreenName
+}
+
+// RecentIMSenders tracks which users have recently sent instant messages to
+// each recipient. It determines who a user is eligible to warn, since users
+// can only warn people who have recently sent them IMs.
+//
+// RecentIMSenders is safe for concurrent use.
+type RecentIMSenders struct {
+	ttl       time.Duration                   // How long a sender stays recent after their last IM.
+	nowFn     func() time.Time                // Returns the current time.
+	senders   map[recentIMSenderKey]time.Time // Time of the last IM per pair.
+	lastSweep time.Time                       // When expired pairs were last purged.
+	m         sync.Mutex                      // Synchronization primitive for concurrent access.
+}
+
+// Add records an instant message from sender to recip.
+func (r *RecentIMSenders) Add(recip, sender state.IdentScreenName) {
+	if r == nil || r.ttl <= 0 {
+		return
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	now := r.nowFn()
+
+	if now.Sub(r.lastSweep) >= r.ttl {
+		// purge expired pairs so that the map doesn't grow unbounded
+		for key, last := range r.senders {
+			if now.Sub(last) >= r.ttl {
+				delete(r.senders, key)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	r.senders[recentIMSenderKey{recip: recip, sender: sender}] = now
+}
+
+// IsRecent reports whether sender sent recip an instant message within the
+// TTL. If tracking is disabled, every sender is considered recent.
+func (r *RecentIMSenders) IsRecent(recip, sender state.IdentScreenName) bool {
+	if r == nil || r.ttl <= 0 {
+		return true
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	last, ok := r.senders[recentIMSenderKey{recip: recip, sender: sender}]
+	return ok && r.nowFn().Sub(last) < r.ttl
+}
@@ -0,0 +1,68 @@
+package toc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestRecentIMSenders_IsRecent(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	senders := NewRecentIMSenders(3 * time.Minute)
+	senders.nowFn = func() time.Time { return now }
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+	other := state.NewIdentScreenName("other")
+
+	senders.Add(me, them)
+
+	// the sender is recent within the window
+	now = now.Add(2 * time.Minute)
+	assert.True(t, senders.IsRecent(me, them))
+	// users who haven't sent an IM aren't recent
+	assert.False(t, senders.IsRecent(me, other))
+	// the relationship isn't symmetric
+	assert.False(t, senders.IsRecent(them, me))
+
+	// the sender is no longer recent after the window
+	now = now.Add(time.Minute)
+	assert.False(t, senders.IsRecent(me, them))
+
+	// another IM restarts the window
+	senders.Add(me, them)
+	now = now.Add(2 * time.Minute)
+	assert.True(t, senders.IsRecent(me, them))
+}
+
+func TestRecentIMSenders_IsRecent_Disabled(t *testing.T) {
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	senders := NewRecentIMSenders(0)
+	senders.Add(me, them)
+	assert.True(t, senders.IsRecent(me, state.NewIdentScreenName("other")))
+	assert.Empty(t, senders.senders)
+
+	var nilSenders *RecentIMSenders
+	nilSenders.Add(me, them)
+	assert.True(t, nilSenders.IsRecent(me, them))
+}
+
+func TestRecentIMSenders_Add_PurgesExpiredPairs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	senders := NewRecentIMSenders(time.Minute)
+	senders.nowFn = func() time.Time { return now }
+
+	senders.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("them"))
+	assert.Len(t, senders.senders, 1)
+
+	now = now.Add(time.Minute)
+	senders.Add(state.NewIdentScreenName("me"), state.NewIdentScreenName("other"))
+	assert.Len(t, senders.senders, 1)
+}
@@ -3,6 +3,7 @@ package toc
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/netip"
+	"os"
 	"sync"
 	"time"
 
@@ -47,6 +49,66 @@ func (b bufferedConn) Read(p []byte) (int, error) {
 	return b.r.Read(p)
 }
 
+// timeoutConn is a wrapper around net.Conn that sets a fresh write deadline
+// before each write, so that a client that stops reading can't block the
+// server indefinitely. A zero writeTimeout disables the deadline.
+type timeoutConn struct {
+	net.Conn
+	writeTimeout time.Duration
+}
+
+// Write writes p to the connection, failing if the write doesn't complete
+// within writeTimeout.
+func (c timeoutConn) Write(p []byte) (int, error) {
+	if c.writeTimeout > 0 {
+		if err := c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(p)
+}
+
+// compressStream is an io.ReadWriter over a client connection that can switch
+// to a DEFLATE-compressed stream in both directions. Each write is flushed
+// so that a FLAP frame is never held back in the compressor. Enabling
+// compression is not safe for concurrent use with Read or Write.
+type compressStream struct {
+	rw io.ReadWriter
+	r  io.Reader     // Decompressing reader, nil until compression is enabled.
+	w  *flate.Writer // Compressing writer, nil until compression is enabled.
+}
+
+// enableCompression compresses all subsequent reads and writes.
+func (c *compressStream) enableCompression() error {
+	w, err := flate.NewWriter(c.rw, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	c.r = flate.NewReader(c.rw)
+	c.w = w
+	return nil
+}
+
+// Read reads data into p, decompressing it if compression is enabled.
+func (c *compressStream) Read(p []byte) (int, error) {
+	if c.r == nil {
+		return c.rw.Read(p)
+	}
+	return c.r.Read(p)
+}
+
+// Write writes p, compressing it if compression is enabled.
+func (c *compressStream) Write(p []byte) (int, error) {
+	if c.w == nil {
+		return c.rw.Write(p)
+	}
+	n, err := c.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, c.w.Flush()
+}
+
 // channelListener is an implementation of net.Listener that accepts connections
 // from a channel instead of a network socket. It is useful for attaching an
 // HTTP service to a connection on the fly.
@@ -83,8 +145,23 @@ type Server struct {
 	BOSProxy   OSCARProxy
 	ListenAddr string
 	Logger     *slog.Logger
+	// ReadTimeout is how long to wait for the next frame from a client before
+	// closing the connection. Zero means no timeout.
+	ReadTimeout time.Duration
+	// WriteTimeout is how long to wait for a write to a client to complete
+	// before closing the connection. Zero means no timeout.
+	WriteTimeout time.Duration
+	// Compression allows clients to negotiate a DEFLATE-compressed
+	// connection with toc_compress before signing on.
+	Compression bool
+	// InitDoneTimeout is how long a client has to send toc_init_done after
+	// signing on before the connection is closed. Zero means no timeout.
+	InitDoneTimeout time.Duration
 }
 
+// errClientClosed indicates that the client connection closed or timed out.
+var errClientClosed = errors.New("client connection closed")
+
 func (rt Server) Start(ctx context.Context) error {
 	listener, err := net.Listen("tcp", rt.ListenAddr)
 	if err != nil {
@@ -99,7 +176,8 @@ func (rt Server) Start(ctx context.Context) error {
 	}()
 
 	httpServer := &http.Server{
-		Handler: rt.BOSProxy.NewServeMux(),
+		Handler:           rt.BOSProxy.NewServeMux(),
+		ReadHeaderTimeout: rt.ReadTimeout,
 		BaseContext: func(net.Listener) context.Context {
 			return ctx
 		},
@@ -130,6 +208,8 @@ func (rt Server) Start(ctx context.Context) error {
 		}()
 	}
 
+	// give connections a chance to send PAUSE to their clients before
+	// returning
 	if !waitForShutdown(&wg) {
 		rt.Logger.ErrorContext(ctx, "shutdown complete, but connections didn't close cleanly")
 	} else {
@@ -143,11 +223,13 @@ func (rt Server) Start(ctx context.Context) error {
 // starts with "FLAP", handle as TOC/FLAP; otherwise, dispatch for HTTP
 // processing.
 func (rt Server) dispatchConn(conn net.Conn, ctx context.Context, httpCh chan net.Conn) error {
-	bufCon := newBufferedConn(conn)
+	bufCon := newBufferedConn(timeoutConn{Conn: conn, writeTimeout: rt.WriteTimeout})
 
+	rt.resetReadDeadline(conn)
 	doFlap := "FLAP"
 	buf, err := bufCon.Peek(len(doFlap))
 	if err != nil {
+		_ = conn.Close()
 		return fmt.Errorf("bufCon.Peek: %w", err)
 	}
 
@@ -172,12 +254,14 @@ func (rt Server) dispatchFLAP(ctx context.Context, conn net.Conn) error {
 	}()
 	ctx = context.WithValue(ctx, "ip", conn.RemoteAddr().String())
 
-	clientFlap, err := rt.initFLAP(conn)
+	stream := &compressStream{rw: conn}
+	clientFlap, err := rt.initFLAP(stream)
 	if err != nil {
 		return err
 	}
 
-	sessBOS, err := rt.login(ctx, clientFlap)
+	rt.resetReadDeadline(conn)
+	sessBOS, err := rt.login(ctx, clientFlap, stream)
 	if err != nil {
 		return fmt.Errorf("rt.login: %w", err)
 	}
@@ -200,15 +284,19 @@ func (rt Server) dispatchFLAP(ctx context.Context, conn net.Conn) error {
 
 	// messages from TOC client
 	fromCh := make(chan wire.FLAPFrame, 1)
-	// messages to TOC client
+	// messages to TOC client. sendToClient is the only goroutine that writes
+	// to the client connection until the session ends.
 	toCh := make(chan []byte, 2)
 
 	// read in messages from client. when client disconnects, it closes fromCh.
-	go rt.readFromClient(ctx, fromCh, clientFlap)
+	go rt.readFromClient(ctx, conn, fromCh, clientFlap)
 
 	g, gCtx := errgroup.WithContext(ctx)
 
 	chatRegistry := NewChatRegistry()
+	// leave chat rooms the client didn't leave before disconnecting. this
+	// runs before the BOS session signs out.
+	defer chatRegistry.CloseAll(ctx, rt.BOSProxy.AuthService)
 
 	g.Go(func() error {
 		return rt.BOSProxy.RecvBOS(gCtx, sessBOS, chatRegistry, toCh)
@@ -219,14 +307,48 @@ func (rt Server) dispatchFLAP(ctx context.Context, conn net.Conn) error {
 	g.Go(func() error {
 		return rt.processCommands(gCtx, g.Go, sessBOS, chatRegistry, fromCh, toCh)
 	})
+	g.Go(func() error {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		return rt.BOSProxy.AutoAway(gCtx, sessBOS, ticker.C)
+	})
+	if secs := rt.BOSProxy.Config.TOCIdleUpdateSecs; secs > 0 {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Duration(secs) * time.Second)
+			defer ticker.Stop()
+			return rt.BOSProxy.IdleUpdate(gCtx, sessBOS, ticker.C)
+		})
+	}
 
 	err = g.Wait()
-	if errors.Is(err, errDisconnect) {
+	rt.sendDisconnectReason(ctx, clientFlap, err)
+	if errors.Is(err, errDisconnect) || errors.Is(err, errClientClosed) {
 		err = nil
 	}
 	return err
 }
 
+// sendDisconnectReason sends the client the reason the server is ending the
+// session. If the server is shutting down, as signaled by ctx, it sends
+// PAUSE, which tells the client to stop sending commands and reconnect.
+// Otherwise, it sends the reason if err is a disconnectError. It must be
+// called before the connection closes.
+func (rt Server) sendDisconnectReason(ctx context.Context, clientFlap *wire.FlapClient, err error) {
+	var reason string
+	var discErr disconnectError
+	switch {
+	case ctx.Err() != nil:
+		reason = "PAUSE"
+	case errors.As(err, &discErr):
+		reason = discErr.reason
+	default:
+		return
+	}
+	if err := clientFlap.SendDataFrame([]byte(reason)); err != nil {
+		rt.Logger.DebugContext(ctx, "unable to send disconnect reason", "err", err.Error())
+	}
+}
+
 func (rt Server) processCommands(
 	ctx context.Context,
 	doAsync func(f func() error),
@@ -235,26 +357,55 @@ func (rt Server) processCommands(
 	fromCh <-chan wire.FLAPFrame,
 	toCh chan<- []byte,
 ) error {
+	// initDeadline fires if the client doesn't finish signing on in time. It's
+	// nil, and therefore never fires, once sign-on completes.
+	var initDeadline <-chan time.Time
+	if rt.InitDoneTimeout > 0 && !sessBOS.SignonComplete() {
+		timer := time.NewTimer(rt.InitDoneTimeout)
+		defer timer.Stop()
+		initDeadline = timer.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-initDeadline:
+			// the deferred signout tells buddies that the user departed
+			return disconnectError{
+				reason: tocError(989, "disconnected (toc_init_done not received in time)"),
+				cause:  fmt.Errorf("toc_init_done not received within %s of sign on", rt.InitDoneTimeout),
+			}
 		case clientFrame, ok := <-fromCh:
 			if !ok {
-				return nil
+				// end the session so that it's torn down along with the
+				// connection
+				return errClientClosed
 			}
 			clientFrame.Payload = bytes.TrimRight(clientFrame.Payload, "\x00") // trim null terminator
 
 			if len(clientFrame.Payload) == 0 {
-				return errors.New("TOC command is empty")
+				return disconnectError{
+					reason: tocError(989, "disconnected (invalid command)"),
+					cause:  errors.New("TOC command is empty"),
+				}
 			}
 			if len(clientFrame.Payload) > 2048 {
-				return errors.New("TOC command exceeds maximum length (2048)")
+				return disconnectError{
+					reason: tocError(989, "disconnected (invalid command)"),
+					cause:  errors.New("TOC command exceeds maximum length (2048)"),
+				}
 			}
 
 			msg, ok := rt.BOSProxy.RecvClientCmd(ctx, sessBOS, chatRegistry, clientFrame.Payload, toCh, doAsync)
 			if !ok {
-				return nil
+				return disconnectError{
+					reason: msg,
+					cause:  errors.New("unable to continue processing TOC commands"),
+				}
+			}
+			if initDeadline != nil && sessBOS.SignonComplete() {
+				initDeadline = nil
 			}
 			if len(msg) > 0 {
 				select {
@@ -267,6 +418,10 @@ func (rt Server) processCommands(
 	}
 }
 
+// sendToClient serializes outbound messages for a session. Command replies
+// and async notifications from any number of producers are written in the
+// order they're received from toClient, one complete TOC message per FLAP
+// frame.
 func (rt Server) sendToClient(ctx context.Context, toClient <-chan []byte, clientFlap *wire.FlapClient) error {
 	for {
 		select {
@@ -290,7 +445,9 @@ func (rt Server) sendToClient(ctx context.Context, toClient <-chan []byte, clien
 	}
 }
 
-func (rt Server) login(ctx context.Context, clientFlap *wire.FlapClient) (*state.Session, error) {
+// login signs on the client. The client may negotiate compression with
+// toc_compress before sending toc_signon.
+func (rt Server) login(ctx context.Context, clientFlap *wire.FlapClient, stream *compressStream) (*state.Session, error) {
 	clientFrame, err := clientFlap.ReceiveFLAP()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -299,6 +456,18 @@ func (rt Server) login(ctx context.Context, clientFlap *wire.FlapClient) (*state
 		return nil, fmt.Errorf("clientFlap.ReceiveFLAP: %w", err)
 	}
 
+	if payload := bytes.TrimRight(clientFrame.Payload, "\x00"); bytes.HasPrefix(payload, []byte("toc_compress")) {
+		if err := rt.negotiateCompression(ctx, clientFlap, stream, payload); err != nil {
+			return nil, err
+		}
+		if clientFrame, err = clientFlap.ReceiveFLAP(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("clientFlap.ReceiveFLAP: %w", err)
+		}
+	}
+
 	sessBOS, reply := rt.BOSProxy.Signon(ctx, clientFrame.Payload)
 	for _, m := range reply {
 		if err := clientFlap.SendDataFrame([]byte(m)); err != nil {
@@ -309,13 +478,57 @@ func (rt Server) login(ctx context.Context, clientFlap *wire.FlapClient) (*state
 	return sessBOS, nil
 }
 
-func (rt Server) readFromClient(ctx context.Context, msgCh chan<- wire.FLAPFrame, clientFlap *wire.FlapClient) {
+// negotiateCompression handles the toc_compress TOC command.
+//
+// This is a non-standard command that a client can send before toc_signon to
+// compress the rest of the connection, which saves bandwidth on metered
+// links. The only supported method is deflate. If the server agrees, it
+// replies with COMPRESS:deflate and both sides compress everything that
+// follows the reply as a single DEFLATE stream, flushed after every FLAP
+// frame. Otherwise, it replies with ERROR:989 and the connection continues
+// uncompressed.
+//
+// Command syntax: toc_compress <method>
+//
+// Response syntax: COMPRESS:<method>
+func (rt Server) negotiateCompression(ctx context.Context, clientFlap *wire.FlapClient, stream *compressStream, payload []byte) error {
+	var method string
+	if _, err := parseArgs(payload, "toc_compress", &method); err != nil {
+		rt.Logger.DebugContext(ctx, "invalid compression request", "err", err.Error())
+	}
+
+	if !rt.Compression || method != "deflate" {
+		if err := clientFlap.SendDataFrame([]byte(tocError(989, "compression is not available"))); err != nil {
+			return fmt.Errorf("clientFlap.SendDataFrame: %w", err)
+		}
+		return nil
+	}
+
+	if err := clientFlap.SendDataFrame([]byte("COMPRESS:deflate")); err != nil {
+		return fmt.Errorf("clientFlap.SendDataFrame: %w", err)
+	}
+	if err := stream.enableCompression(); err != nil {
+		return fmt.Errorf("stream.enableCompression: %w", err)
+	}
+	rt.Logger.DebugContext(ctx, "enabled connection compression")
+
+	return nil
+}
+
+// readFromClient reads frames from the client and sends TOC commands to
+// msgCh until the client disconnects or the read deadline passes. The read
+// deadline is reset before each frame.
+func (rt Server) readFromClient(ctx context.Context, conn net.Conn, msgCh chan<- wire.FLAPFrame, clientFlap *wire.FlapClient) {
 	defer close(msgCh)
 
 	for {
+		rt.resetReadDeadline(conn)
 		clientFrame, err := clientFlap.ReceiveFLAP()
 		if err != nil {
-			if !(errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)) {
+			switch {
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				rt.Logger.InfoContext(ctx, "client connection timed out")
+			case !(errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)):
 				rt.Logger.ErrorContext(ctx, "ReceiveFLAP error", "err", err.Error())
 			}
 			break
@@ -335,6 +548,15 @@ func (rt Server) readFromClient(ctx context.Context, msgCh chan<- wire.FLAPFrame
 	}
 }
 
+// resetReadDeadline gives the client ReadTimeout to send its next frame. It's
+// a no-op if ReadTimeout is not set.
+func (rt Server) resetReadDeadline(conn net.Conn) {
+	if rt.ReadTimeout == 0 {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(rt.ReadTimeout))
+}
+
 // initFLAP sets up a new FLAP connection. It returns a flap client if the
 // connection successfully initialized.
 func (rt Server) initFLAP(rw io.ReadWriter) (*wire.FlapClient, error) {
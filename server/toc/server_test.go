@@ -1 +1,443 @@
 package toc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestServer_Disconnect(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// disconnect produces the error that ends the session
+		disconnect func(t *testing.T, rt Server) error
+		// wantReason is the final message sent to the client
+		wantReason string
+	}{
+		{
+			name: "signed on from another location",
+			disconnect: func(t *testing.T, rt Server) error {
+				me := newTestSession("me")
+				me.Close()
+				return rt.BOSProxy.RecvBOS(context.Background(), me, NewChatRegistry(), make(chan []byte))
+			},
+			wantReason: "ERROR:989:disconnected (signed on from another location)",
+		},
+		{
+			name: "empty command",
+			disconnect: func(t *testing.T, rt Server) error {
+				return processCommand(rt, []byte("\x00"))
+			},
+			wantReason: "ERROR:989:disconnected (invalid command)",
+		},
+		{
+			name: "command exceeds max length",
+			disconnect: func(t *testing.T, rt Server) error {
+				return processCommand(rt, []byte("toc_send_im them "+strings.Repeat("a", 2048)))
+			},
+			wantReason: "ERROR:989:disconnected (invalid command)",
+		},
+		{
+			name: "unable to join chat room",
+			disconnect: func(t *testing.T, rt Server) error {
+				chatNavSvc := newMockChatNavService(t)
+				chatNavSvc.EXPECT().
+					CreateRoom(context.Background(), matchSession(state.NewIdentScreenName("me")), wire.SNACFrame{}, wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+						Exchange: 4,
+						Cookie:   "create",
+						TLVBlock: wire.TLVBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.ChatRoomTLVRoomName, "cool room"),
+							},
+						},
+					}).
+					Return(wire.SNACMessage{}, io.EOF)
+				rt.BOSProxy.ChatNavService = chatNavSvc
+				return processCommand(rt, []byte(`toc_chat_join 4 "cool room"`))
+			},
+			wantReason: "ERROR:989:disconnected (unable to join chat room)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := Server{
+				BOSProxy: OSCARProxy{
+					Logger: slog.Default(),
+				},
+				Logger: slog.Default(),
+			}
+
+			err := tc.disconnect(t, rt)
+			var discErr disconnectError
+			assert.ErrorAs(t, err, &discErr)
+
+			buf := &bytes.Buffer{}
+			rt.sendDisconnectReason(context.Background(), wire.NewFlapClient(0, nil, buf), err)
+
+			// the reason is the last message the client receives
+			frame, err := wire.NewFlapClient(0, buf, nil).ReceiveFLAP()
+			assert.NoError(t, err)
+			assert.Equal(t, wire.FLAPFrameData, frame.FrameType)
+			assert.Equal(t, tc.wantReason, string(frame.Payload))
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+func TestServer_sendDisconnectReason_OtherErrors(t *testing.T) {
+	rt := Server{
+		Logger: slog.Default(),
+	}
+
+	for _, err := range []error{nil, errors.New("connection reset")} {
+		buf := &bytes.Buffer{}
+		rt.sendDisconnectReason(context.Background(), wire.NewFlapClient(0, nil, buf), err)
+		assert.Zero(t, buf.Len())
+	}
+}
+
+func TestServer_sendDisconnectReason_Shutdown(t *testing.T) {
+	rt := Server{
+		Logger: slog.Default(),
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// run the server side of a connected session until shutdown
+	go func() {
+		defer server.Close()
+		serverFlap := wire.NewFlapClient(0, nil, server)
+		err := rt.sendToClient(ctx, make(chan []byte), serverFlap)
+		rt.sendDisconnectReason(ctx, serverFlap, err)
+	}()
+
+	cancel()
+
+	// the client is told to pause before the connection closes
+	clientFlap := wire.NewFlapClient(0, client, nil)
+	frame, err := clientFlap.ReceiveFLAP()
+	assert.NoError(t, err)
+	assert.Equal(t, wire.FLAPFrameData, frame.FrameType)
+	assert.Equal(t, "PAUSE", string(frame.Payload))
+
+	_, err = clientFlap.ReceiveFLAP()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServer_sendToClient_ConcurrentProducers(t *testing.T) {
+	const producers = 4
+	const msgsPerProducer = 50
+	const total = producers * msgsPerProducer
+
+	rt := Server{
+		Logger: slog.Default(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	toCh := make(chan []byte)
+	w := &writeRecorder{writes: make(chan []byte, total)}
+
+	done := make(chan error)
+	go func() {
+		done <- rt.sendToClient(ctx, toCh, wire.NewFlapClient(0, nil, w))
+	}()
+
+	wg := sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < msgsPerProducer; i++ {
+				toCh <- []byte(fmt.Sprintf("IM_IN:producer%d:F:message %d", p, i))
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	next := make(map[int]int) // next expected message per producer
+	for seq := 0; seq < total; seq++ {
+		write := <-w.writes
+
+		// each write contains exactly one complete frame
+		buf := bytes.NewBuffer(write)
+		frame, err := wire.NewFlapClient(0, buf, nil).ReceiveFLAP()
+		assert.NoError(t, err)
+		assert.Zero(t, buf.Len())
+		assert.Equal(t, uint16(seq), frame.Sequence)
+
+		// each producer's messages arrive intact and in order
+		var producer, i int
+		_, err = fmt.Sscanf(string(frame.Payload), "IM_IN:producer%d:F:message %d", &producer, &i)
+		assert.NoError(t, err)
+		assert.Equal(t, next[producer], i)
+		next[producer]++
+	}
+	assert.Len(t, next, producers)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestServer_dispatchConn_SilentClient(t *testing.T) {
+	rt := Server{
+		Logger:      slog.Default(),
+		ReadTimeout: 50 * time.Millisecond,
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- rt.dispatchConn(server, context.Background(), make(chan net.Conn))
+	}()
+
+	// the client never sends anything
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for silent client to be disconnected")
+	}
+
+	// the server closed its end of the connection
+	_, err := client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServer_readFromClient_Timeout(t *testing.T) {
+	rt := Server{
+		Logger:      slog.Default(),
+		ReadTimeout: 50 * time.Millisecond,
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	msgCh := make(chan wire.FLAPFrame)
+	go rt.readFromClient(context.Background(), server, msgCh, wire.NewFlapClient(0, server, server))
+
+	// the client sends a command, then goes silent
+	go func() {
+		_ = wire.NewFlapClient(0, nil, client).SendDataFrame([]byte("toc_init_done"))
+	}()
+
+	frame, ok := <-msgCh
+	assert.True(t, ok)
+	assert.Equal(t, "toc_init_done", string(frame.Payload))
+
+	select {
+	case _, ok = <-msgCh:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for read deadline")
+	}
+
+	// command processing ends once the connection times out
+	err := rt.processCommands(context.Background(), func(f func() error) {}, newTestSession("me"),
+		NewChatRegistry(), msgCh, make(chan []byte))
+	assert.ErrorIs(t, err, errClientClosed)
+}
+
+func TestServer_processCommands_InitDoneTimeout(t *testing.T) {
+	rt := Server{
+		Logger:          slog.Default(),
+		InitDoneTimeout: 50 * time.Millisecond,
+	}
+
+	// the client signs on but never sends toc_init_done
+	err := rt.processCommands(context.Background(), func(f func() error) {}, newTestSession("me"),
+		NewChatRegistry(), make(chan wire.FLAPFrame), make(chan []byte))
+
+	var discErr disconnectError
+	if assert.ErrorAs(t, err, &discErr) {
+		assert.Equal(t, "ERROR:989:disconnected (toc_init_done not received in time)", discErr.reason)
+	}
+}
+
+func TestServer_processCommands_InitDoneTimeout_SignonComplete(t *testing.T) {
+	rt := Server{
+		Logger:          slog.Default(),
+		InitDoneTimeout: 50 * time.Millisecond,
+	}
+
+	sess := newTestSession("me")
+	sess.SetSignonComplete()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// the deadline doesn't apply to a session that finished signing on
+	err := rt.processCommands(ctx, func(f func() error) {}, sess, NewChatRegistry(),
+		make(chan wire.FLAPFrame), make(chan []byte))
+	assert.NoError(t, err)
+}
+
+// writeRecorder records each call to Write.
+type writeRecorder struct {
+	writes chan []byte
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes <- bytes.Clone(p)
+	return len(p), nil
+}
+
+// processCommand sends a single client command to processCommands and returns
+// the error that ended command processing.
+func processCommand(rt Server, payload []byte) error {
+	fromCh := make(chan wire.FLAPFrame, 1)
+	fromCh <- wire.FLAPFrame{
+		FrameType: wire.FLAPFrameData,
+		Payload:   payload,
+	}
+	close(fromCh)
+
+	doAsync := func(f func() error) {}
+	return rt.processCommands(context.Background(), doAsync, newTestSession("me"), NewChatRegistry(), fromCh, make(chan []byte, 1))
+}
+
+func TestServer_login_Compression(t *testing.T) {
+	rt := Server{
+		BOSProxy: OSCARProxy{
+			Logger: slog.Default(),
+		},
+		Compression: true,
+		Logger:      slog.Default(),
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fromCh := make(chan wire.FLAPFrame)
+	toCh := make(chan []byte)
+	go func() {
+		stream := &compressStream{rw: server}
+		serverFlap := wire.NewFlapClient(0, stream, stream)
+		sess, err := rt.login(ctx, serverFlap, stream)
+		assert.NoError(t, err)
+		assert.Nil(t, sess)
+		go rt.readFromClient(ctx, server, fromCh, serverFlap)
+		_ = rt.sendToClient(ctx, toCh, serverFlap)
+	}()
+
+	// record the raw bytes the client receives
+	raw := &bytes.Buffer{}
+	stream := &compressStream{rw: struct {
+		io.Reader
+		io.Writer
+	}{io.TeeReader(client, raw), client}}
+	clientFlap := wire.NewFlapClient(0, stream, stream)
+
+	receive := func() string {
+		frame, err := clientFlap.ReceiveFLAP()
+		assert.NoError(t, err)
+		return string(frame.Payload)
+	}
+
+	assert.NoError(t, clientFlap.SendDataFrame([]byte("toc_compress deflate")))
+	assert.Equal(t, "COMPRESS:deflate", receive())
+	assert.NoError(t, stream.enableCompression())
+	raw.Reset()
+
+	// sign on over the compressed connection. the sign on fails because
+	// the command is incomplete, but the error reply is still compressed.
+	assert.NoError(t, clientFlap.SendDataFrame([]byte("toc_signon")))
+	assert.Equal(t, cmdInternalSvcErr, receive())
+
+	// exchange commands, each in its own frame
+	cmds := []string{"toc_init_done", "toc_set_away", `toc_send_im them "hello there"`}
+	go func() {
+		for _, cmd := range cmds {
+			assert.NoError(t, clientFlap.SendDataFrame([]byte(cmd)))
+		}
+	}()
+	for _, cmd := range cmds {
+		assert.Equal(t, cmd, string((<-fromCh).Payload))
+	}
+
+	msgs := []string{"IM_IN:them:F:hello there", "UPDATE_BUDDY:them:T:0:0:0: O "}
+	go func() {
+		for _, msg := range msgs {
+			toCh <- []byte(msg)
+		}
+	}()
+	for _, msg := range msgs {
+		assert.Equal(t, msg, receive())
+	}
+
+	// the server's frames are sent as a DEFLATE stream rather than as plain
+	// FLAP frames, and each frame ends with a sync flush marker
+	if assert.NotZero(t, raw.Len()) {
+		assert.NotEqual(t, byte(42), raw.Bytes()[0])
+		assert.True(t, bytes.HasSuffix(raw.Bytes(), []byte{0x00, 0x00, 0xff, 0xff}))
+	}
+
+	// repetitive messages, such as busy chat rooms, shrink on the wire
+	raw.Reset()
+	msg := "CHAT_IN:0:them:F:" + strings.Repeat("lol ", 256)
+	go func() {
+		toCh <- []byte(msg)
+	}()
+	assert.Equal(t, msg, receive())
+	assert.Less(t, raw.Len(), len(msg)/4)
+}
+
+func TestServer_login_CompressionDisabled(t *testing.T) {
+	rt := Server{
+		BOSProxy: OSCARProxy{
+			Logger: slog.Default(),
+		},
+		Logger: slog.Default(),
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		stream := &compressStream{rw: server}
+		sess, err := rt.login(context.Background(), wire.NewFlapClient(0, stream, stream), stream)
+		assert.NoError(t, err)
+		assert.Nil(t, sess)
+	}()
+
+	clientFlap := wire.NewFlapClient(0, client, client)
+
+	assert.NoError(t, clientFlap.SendDataFrame([]byte("toc_compress deflate")))
+	frame, err := clientFlap.ReceiveFLAP()
+	assert.NoError(t, err)
+	assert.Equal(t, "ERROR:989:compression is not available", string(frame.Payload))
+
+	// the connection continues uncompressed
+	assert.NoError(t, clientFlap.SendDataFrame([]byte("toc_signon")))
+	frame, err = clientFlap.ReceiveFLAP()
+	assert.NoError(t, err)
+	assert.Equal(t, cmdInternalSvcErr, string(frame.Payload))
+}
@@ -0,0 +1,83 @@
+package toc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// NewIMThrottle creates a new IMThrottle that allows up to limit messages
+// from a sender to a single recipient within a sliding window. A limit of 0
+// disables throttling.
+func NewIMThrottle(limit int, window time.Duration) *IMThrottle {
+	return &IMThrottle{
+		limit:  limit,
+		window: window,
+		nowFn:  time.Now,
+		sends:  make(map[imThrottleKey][]time.Time),
+	}
+}
+
+// imThrottleKey identifies a sender/recipient pair.
+type imThrottleKey struct {
+	sender state.IdentScreenName
+	recip  state.IdentScreenName
+}
+
+// IMThrottle limits how many instant messages a sender can send to a single
+// recipient over a sliding window of time. It complements sender-wide rate
+// limits by preventing a user from flooding one target with messages.
+//
+// IMThrottle is safe for concurrent use.
+type IMThrottle struct {
+	limit     int                           // Max messages per pair within window.
+	window    time.Duration                 // Length of the sliding window.
+	nowFn     func() time.Time              // Returns the current time.
+	sends     map[imThrottleKey][]time.Time // Recent send times per pair.
+	lastSweep time.Time                     // When expired pairs were last purged.
+	m         sync.Mutex                    // Synchronization primitive for concurrent access.
+}
+
+// Allow records a message from sender to recip and reports whether it is
+// within the throttle limit. Messages that exceed the limit are not recorded.
+func (t *IMThrottle) Allow(sender, recip state.IdentScreenName) bool {
+	if t == nil || t.limit <= 0 {
+		return true
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	now := t.nowFn()
+	cutoff := now.Add(-t.window)
+
+	if now.Sub(t.lastSweep) >= t.window {
+		// purge pairs that haven't been active during the window so that
+		// the map doesn't grow unbounded
+		for key, times := range t.sends {
+			if !times[len(times)-1].After(cutoff) {
+				delete(t.sends, key)
+			}
+		}
+		t.lastSweep = now
+	}
+
+	key := imThrottleKey{sender: sender, recip: recip}
+	times := t.sends[key]
+
+	// drop send times that fell out of the window
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	times = times[i:]
+
+	if len(times) >= t.limit {
+		t.sends[key] = times
+		return false
+	}
+
+	t.sends[key] = append(times, now)
+	return true
+}
@@ -0,0 +1,67 @@
+package toc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestIMThrottle_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	throttle := NewIMThrottle(3, 10*time.Second)
+	throttle.nowFn = func() time.Time { return now }
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+	other := state.NewIdentScreenName("other")
+
+	// rapidly send up to the limit
+	for i := 0; i < 3; i++ {
+		assert.True(t, throttle.Allow(me, them))
+	}
+	// the next message to the same recipient is throttled
+	assert.False(t, throttle.Allow(me, them))
+	// other recipients are unaffected
+	assert.True(t, throttle.Allow(me, other))
+	// other senders are unaffected
+	assert.True(t, throttle.Allow(other, them))
+
+	// still throttled before the window slides past the first sends
+	now = now.Add(9 * time.Second)
+	assert.False(t, throttle.Allow(me, them))
+
+	// allowed again once earlier sends fall out of the window
+	now = now.Add(2 * time.Second)
+	assert.True(t, throttle.Allow(me, them))
+}
+
+func TestIMThrottle_Allow_Disabled(t *testing.T) {
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	throttle := NewIMThrottle(0, 10*time.Second)
+	for i := 0; i < 100; i++ {
+		assert.True(t, throttle.Allow(me, them))
+	}
+
+	var nilThrottle *IMThrottle
+	assert.True(t, nilThrottle.Allow(me, them))
+}
+
+func TestIMThrottle_Allow_PurgesInactivePairs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	throttle := NewIMThrottle(3, 10*time.Second)
+	throttle.nowFn = func() time.Time { return now }
+
+	assert.True(t, throttle.Allow(state.NewIdentScreenName("me"), state.NewIdentScreenName("them")))
+	assert.Len(t, throttle.sends, 1)
+
+	now = now.Add(time.Minute)
+	assert.True(t, throttle.Allow(state.NewIdentScreenName("me"), state.NewIdentScreenName("other")))
+	assert.Len(t, throttle.sends, 1)
+}
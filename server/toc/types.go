@@ -9,6 +9,11 @@ import (
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
+// BARTService stores buddy art, such as buddy icons.
+type BARTService interface {
+	UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x10_0x02_BARTUploadQuery) (wire.SNACMessage, error)
+}
+
 type BuddyService interface {
 	AddBuddies(ctx context.Context, sess *state.Session, inBody wire.SNAC_0x03_0x04_BuddyAddBuddies) error
 	BroadcastBuddyDeparted(ctx context.Context, sess *state.Session) error
@@ -16,6 +21,16 @@ type BuddyService interface {
 	RightsQuery(ctx context.Context, inFrame wire.SNACFrame) wire.SNACMessage
 }
 
+// BuddyCounter counts the buddies on a user's buddy list.
+type BuddyCounter interface {
+	BuddyCount(me state.IdentScreenName) (int, error)
+}
+
+// BuddyLister lists the buddies on a user's buddy list.
+type BuddyLister interface {
+	Buddies(me state.IdentScreenName) ([]state.IdentScreenName, error)
+}
+
 type ChatService interface {
 	ChannelMsgToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) (*wire.SNACMessage, error)
 }
@@ -27,6 +42,12 @@ type ChatNavService interface {
 	RequestRoomInfo(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0D_0x04_ChatNavRequestRoomInfo) (wire.SNACMessage, error)
 }
 
+// FeedbagService manages a user's server-side buddy list (feedbag).
+type FeedbagService interface {
+	Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) (wire.SNACMessage, error)
+	UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, items []wire.FeedbagItem) (wire.SNACMessage, error)
+}
+
 type ICBMService interface {
 	ChannelMsgToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) (*wire.SNACMessage, error)
 	ClientEvent(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x14_ICBMClientEvent) error
@@ -39,6 +60,7 @@ type OServiceService interface {
 	ClientOnline(ctx context.Context, _ wire.SNAC_0x01_0x02_OServiceClientOnline, sess *state.Session) error
 	IdleNotification(ctx context.Context, sess *state.Session, bodyIn wire.SNAC_0x01_0x11_OServiceIdleNotification) error
 	ServiceRequest(ctx context.Context, sess *state.Session, frame wire.SNACFrame, bodyIn wire.SNAC_0x01_0x04_OServiceServiceRequest) (wire.SNACMessage, error)
+	SetUserInfoFields(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields) (wire.SNACMessage, error)
 }
 
 type AuthService interface {
@@ -55,12 +77,14 @@ type AuthService interface {
 type LocateService interface {
 	SetDirInfo(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x02_0x09_LocateSetDirInfo) (wire.SNACMessage, error)
 	SetInfo(ctx context.Context, sess *state.Session, inBody wire.SNAC_0x02_0x04_LocateSetInfo) error
+	SetKeywordInfo(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, body wire.SNAC_0x02_0x0F_LocateSetKeywordInfo) (wire.SNACMessage, error)
 	UserInfoQuery(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x02_0x05_LocateUserInfoQuery) (wire.SNACMessage, error)
 	DirInfo(ctx context.Context, inFrame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error)
 }
 
 type DirSearchService interface {
 	InfoQuery(_ context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0F_0x02_InfoQuery) (wire.SNACMessage, error)
+	KeywordListQuery(_ context.Context, inFrame wire.SNACFrame) (wire.SNACMessage, error)
 }
 
 type PermitDenyService interface {
@@ -79,16 +103,56 @@ type BuddyListRegistry interface {
 	UnregisterBuddyList(user state.IdentScreenName) error
 }
 
+// BuddyCommentStore stores the private comments users attach to buddies.
+type BuddyCommentStore interface {
+	BuddyComment(me, them state.IdentScreenName) (string, error)
+	SetBuddyComment(me, them state.IdentScreenName, comment string) error
+}
+
+// AbuseReportStore records abuse reports for operator review.
+type AbuseReportStore interface {
+	AddAbuseReport(report state.AbuseReport) error
+}
+
+// OfflineMessageManager retrieves and deletes messages queued in the offline
+// message store.
+type OfflineMessageManager interface {
+	DeleteMessage(id int64) error
+	RetrieveMessages(recip state.IdentScreenName) ([]state.OfflineMessage, error)
+}
+
 type TOCConfigStore interface {
 	SetTOCConfig(user state.IdentScreenName, config string) error
+	SetTOCConfig2(user state.IdentScreenName, config string) error
 	User(screenName state.IdentScreenName) (*state.User, error)
 }
 
+// ChatRoomManager looks up and updates persisted chat room metadata.
+type ChatRoomManager interface {
+	ChatRoomByCookie(chatCookie string) (state.ChatRoom, error)
+	SetChatRoomTopic(chatCookie string, topic string) error
+}
+
+// ChatMessageRelayer sends messages to chat room participants.
+type ChatMessageRelayer interface {
+	RelayToAllExcept(ctx context.Context, chatCookie string, except state.IdentScreenName, msg wire.SNACMessage)
+}
+
+// ChatOccupantCounter counts the participants of a chat room.
+type ChatOccupantCounter interface {
+	OccupantCount(chatCookie string) int
+}
+
 type CookieBaker interface {
 	Crack(data []byte) ([]byte, error)
 	Issue(data []byte) ([]byte, error)
 }
 
+// UserManager looks up user accounts.
+type UserManager interface {
+	User(screenName state.IdentScreenName) (*state.User, error)
+}
+
 type AdminService interface {
 	InfoChangeRequest(ctx context.Context, sess *state.Session, frame wire.SNACFrame, body wire.SNAC_0x07_0x04_AdminInfoChangeRequest) (wire.SNACMessage, error)
 }
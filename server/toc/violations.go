@@ -0,0 +1,60 @@
+package toc
+
+import (
+	"sync"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// NewViolationCounter creates a new ViolationCounter.
+func NewViolationCounter() *ViolationCounter {
+	return &ViolationCounter{
+		counts: make(map[state.IdentScreenName]int),
+	}
+}
+
+// ViolationCounter counts the consecutive protocol violations, such as
+// malformed or unsupported commands, sent by each signed-on user. It lets the
+// server disconnect clients that are stuck in an error loop.
+//
+// ViolationCounter is safe for concurrent use.
+type ViolationCounter struct {
+	counts map[state.IdentScreenName]int // Consecutive violations by user.
+	m      sync.Mutex                    // Synchronization primitive for concurrent access.
+}
+
+// Add records a protocol violation by user.
+func (c *ViolationCounter) Add(user state.IdentScreenName) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.counts[user]++
+}
+
+// Count returns the number of consecutive protocol violations by user.
+func (c *ViolationCounter) Count(user state.IdentScreenName) int {
+	if c == nil {
+		return 0
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.counts[user]
+}
+
+// Clear discards user's protocol violations.
+func (c *ViolationCounter) Clear(user state.IdentScreenName) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	delete(c.counts, user)
+}
@@ -0,0 +1,34 @@
+package toc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestViolationCounter(t *testing.T) {
+	counter := NewViolationCounter()
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	counter.Add(me)
+	counter.Add(me)
+	counter.Add(them)
+
+	assert.Equal(t, 2, counter.Count(me))
+	assert.Equal(t, 1, counter.Count(them))
+
+	counter.Clear(me)
+	assert.Zero(t, counter.Count(me))
+	assert.Equal(t, 1, counter.Count(them))
+}
+
+func TestViolationCounter_Nil(t *testing.T) {
+	var counter *ViolationCounter
+	counter.Add(state.NewIdentScreenName("me"))
+	assert.Zero(t, counter.Count(state.NewIdentScreenName("me")))
+	counter.Clear(state.NewIdentScreenName("me"))
+}
@@ -0,0 +1,134 @@
+package toc
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// warnRateLimit is the send rate allowed to users whose warning level has
+// reached a threshold.
+type warnRateLimit struct {
+	warning   int // Warning level percentage at which the limit applies.
+	perMinute int // Messages per minute allowed at this warning level.
+}
+
+// warnBucket is the token bucket that tracks a sender's message allowance.
+type warnBucket struct {
+	tokens float64   // Messages the sender can send right now.
+	filled time.Time // When tokens was last replenished.
+}
+
+// NewWarnThrottle creates a new WarnThrottle from a list of warning
+// percent:messages per minute pairs (e.g. "50:10"). Malformed entries are
+// ignored. An empty list disables throttling.
+func NewWarnThrottle(entries []string) *WarnThrottle {
+	var limits []warnRateLimit
+	for _, entry := range entries {
+		warningStr, perMinuteStr, found := strings.Cut(entry, ":")
+		if !found {
+			continue
+		}
+		warning, err := strconv.Atoi(strings.TrimSpace(warningStr))
+		if err != nil || warning < 0 {
+			continue
+		}
+		perMinute, err := strconv.Atoi(strings.TrimSpace(perMinuteStr))
+		if err != nil || perMinute < 0 {
+			continue
+		}
+		limits = append(limits, warnRateLimit{warning: warning, perMinute: perMinute})
+	}
+	// highest threshold first, so that the first limit a warning level
+	// reaches is the one that applies
+	slices.SortFunc(limits, func(a, b warnRateLimit) int {
+		return b.warning - a.warning
+	})
+
+	return &WarnThrottle{
+		limits:  limits,
+		nowFn:   time.Now,
+		buckets: make(map[state.IdentScreenName]*warnBucket),
+	}
+}
+
+// WarnThrottle slows down how fast warned users can send messages, the way
+// the TOC documentation describes: the higher someone's warning level, the
+// slower they can send messages. Each sender gets a token bucket that holds
+// up to a minute's worth of messages and refills at the rate allowed for
+// the sender's current warning level. Users whose warning level is below the
+// lowest threshold aren't throttled.
+//
+// WarnThrottle is safe for concurrent use.
+type WarnThrottle struct {
+	limits  []warnRateLimit                       // Rate limits, highest warning threshold first.
+	nowFn   func() time.Time                      // Returns the current time.
+	buckets map[state.IdentScreenName]*warnBucket // Token buckets of throttled senders.
+	m       sync.Mutex                            // Synchronization primitive for concurrent access.
+}
+
+// Allow records a message from sender, whose warning level is warning in
+// tenths of a percent, and reports whether it is within the rate allowed
+// for that warning level. Messages that exceed the rate are not recorded.
+func (t *WarnThrottle) Allow(sender state.IdentScreenName, warning uint16) bool {
+	if t == nil {
+		return true
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	limit, found := t.limit(int(warning / 10))
+	if !found {
+		// the sender's warning level is below every threshold
+		delete(t.buckets, sender)
+		return true
+	}
+
+	now := t.nowFn()
+	capacity := float64(limit.perMinute)
+
+	bucket, found := t.buckets[sender]
+	if !found {
+		bucket = &warnBucket{tokens: capacity, filled: now}
+		t.buckets[sender] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.filled).Minutes() * capacity
+	// a rising warning level shrinks the bucket
+	bucket.tokens = min(bucket.tokens, capacity)
+	bucket.filled = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// Clear discards sender's token bucket.
+func (t *WarnThrottle) Clear(sender state.IdentScreenName) {
+	if t == nil {
+		return
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	delete(t.buckets, sender)
+}
+
+// limit returns the rate limit that applies to a warning level percentage.
+// It returns false if the warning level is below every threshold.
+func (t *WarnThrottle) limit(warning int) (warnRateLimit, bool) {
+	for _, limit := range t.limits {
+		if warning >= limit.warning {
+			return limit, true
+		}
+	}
+	return warnRateLimit{}, false
+}
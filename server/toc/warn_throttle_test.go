@@ -0,0 +1,74 @@
+package toc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+func TestWarnThrottle_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	throttle := NewWarnThrottle([]string{"90:1", "50:2"})
+	throttle.nowFn = func() time.Time { return now }
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	// below the lowest threshold, messages aren't throttled
+	for i := 0; i < 100; i++ {
+		assert.True(t, throttle.Allow(me, 490))
+	}
+
+	// crossing the threshold limits the sender to 2 messages per minute
+	assert.True(t, throttle.Allow(me, 500))
+	assert.True(t, throttle.Allow(me, 500))
+	assert.False(t, throttle.Allow(me, 500))
+	// other senders are unaffected
+	assert.True(t, throttle.Allow(them, 500))
+
+	// the allowance refills at the rate for the warning level
+	now = now.Add(30 * time.Second)
+	assert.True(t, throttle.Allow(me, 500))
+	assert.False(t, throttle.Allow(me, 500))
+
+	// a higher warning level lowers the rate
+	now = now.Add(time.Minute)
+	assert.True(t, throttle.Allow(me, 900))
+	assert.False(t, throttle.Allow(me, 900))
+
+	// dropping below the lowest threshold lifts the limit
+	assert.True(t, throttle.Allow(me, 100))
+	assert.True(t, throttle.Allow(me, 100))
+}
+
+func TestWarnThrottle_Allow_Disabled(t *testing.T) {
+	me := state.NewIdentScreenName("me")
+
+	// malformed entries are ignored
+	throttle := NewWarnThrottle([]string{"", "50", "fifty:2", "50:two", "-1:2"})
+	for i := 0; i < 100; i++ {
+		assert.True(t, throttle.Allow(me, 1000))
+	}
+
+	var nilThrottle *WarnThrottle
+	assert.True(t, nilThrottle.Allow(me, 1000))
+	nilThrottle.Clear(me)
+}
+
+func TestWarnThrottle_Clear(t *testing.T) {
+	throttle := NewWarnThrottle([]string{"50:1"})
+	throttle.nowFn = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	me := state.NewIdentScreenName("me")
+
+	assert.True(t, throttle.Allow(me, 500))
+	assert.False(t, throttle.Allow(me, 500))
+
+	throttle.Clear(me)
+	assert.Empty(t, throttle.buckets)
+	assert.True(t, throttle.Allow(me, 500))
+}
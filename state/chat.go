@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
@@ -18,6 +21,84 @@ const (
 	PublicExchange uint16 = 5
 )
 
+// ExchangeSettings holds the default properties of the chat rooms that belong
+// to an exchange.
+type ExchangeSettings struct {
+	Charset      string // Character set of room messages.
+	Lang         string // Language of room messages.
+	MaxMsgLen    uint16 // Maximum chat message length.
+	MaxOccupancy uint16 // Maximum number of room occupants.
+}
+
+// defaultChatExchanges holds the room settings that apply to each exchange
+// when the configuration leaves them unset.
+var defaultChatExchanges = ChatExchanges{
+	PrivateExchange: {
+		Charset:      "us-ascii",
+		Lang:         "en",
+		MaxMsgLen:    1024,
+		MaxOccupancy: 100,
+	},
+	// public rooms are operator-run community spaces, so they accommodate
+	// more occupants than user-created rooms.
+	PublicExchange: {
+		Charset:      "us-ascii",
+		Lang:         "en",
+		MaxMsgLen:    1024,
+		MaxOccupancy: 250,
+	},
+}
+
+// ChatExchanges maps each exchange to the default settings of its rooms.
+type ChatExchanges map[uint16]ExchangeSettings
+
+// NewChatExchanges returns the room settings of the private and public
+// exchanges as set in cfg. Settings that cfg leaves unset get built-in
+// defaults.
+func NewChatExchanges(cfg config.Config) ChatExchanges {
+	return ChatExchanges{
+		PrivateExchange: withDefaults(ExchangeSettings{
+			Charset:      cfg.ChatPrivateCharset,
+			Lang:         cfg.ChatPrivateLang,
+			MaxMsgLen:    cfg.ChatPrivateMaxMsgLen,
+			MaxOccupancy: cfg.ChatPrivateMaxOccupancy,
+		}, defaultChatExchanges[PrivateExchange]),
+		PublicExchange: withDefaults(ExchangeSettings{
+			Charset:      cfg.ChatPublicCharset,
+			Lang:         cfg.ChatPublicLang,
+			MaxMsgLen:    cfg.ChatPublicMaxMsgLen,
+			MaxOccupancy: cfg.ChatPublicMaxOccupancy,
+		}, defaultChatExchanges[PublicExchange]),
+	}
+}
+
+// withDefaults returns settings with its zero-valued fields replaced by the
+// corresponding fields of defaults.
+func withDefaults(settings ExchangeSettings, defaults ExchangeSettings) ExchangeSettings {
+	if settings.Charset == "" {
+		settings.Charset = defaults.Charset
+	}
+	if settings.Lang == "" {
+		settings.Lang = defaults.Lang
+	}
+	if settings.MaxMsgLen == 0 {
+		settings.MaxMsgLen = defaults.MaxMsgLen
+	}
+	if settings.MaxOccupancy == 0 {
+		settings.MaxOccupancy = defaults.MaxOccupancy
+	}
+	return settings
+}
+
+// Settings returns the default room settings for an exchange. Unknown
+// exchanges get the private exchange settings.
+func (e ChatExchanges) Settings(exchange uint16) ExchangeSettings {
+	if settings, ok := e[exchange]; ok {
+		return settings
+	}
+	return e[PrivateExchange]
+}
+
 // ErrChatRoomNotFound indicates that a chat room lookup failed.
 var (
 	ErrChatRoomNotFound = errors.New("chat room not found")
@@ -38,7 +119,9 @@ type ChatRoom struct {
 	createTime time.Time
 	creator    IdentScreenName
 	exchange   uint16
+	instance   uint16
 	name       string
+	topic      string
 }
 
 // Creator returns the screen name of the user who created the chat room.
@@ -56,10 +139,28 @@ func (c ChatRoom) Name() string {
 	return c.name
 }
 
-// InstanceNumber returns which instance chatroom exists in. Overflow chat
-// rooms do not exist yet, so all chats happen in the same instance.
+// Topic returns the chat room topic set by the room creator. It's empty if
+// no topic has been set.
+func (c ChatRoom) Topic() string {
+	return c.topic
+}
+
+// SetTopic sets the chat room topic.
+func (c *ChatRoom) SetTopic(topic string) {
+	c.topic = topic
+}
+
+// InstanceNumber returns which instance chatroom exists in. Instances are
+// replicas of a room that share its name, which allows a full room to
+// overflow into another instance. Rooms start out in instance 0.
 func (c ChatRoom) InstanceNumber() uint16 {
-	return 0
+	return c.instance
+}
+
+// WithInstance returns a copy of the chat room that belongs to instance.
+func (c ChatRoom) WithInstance(instance uint16) ChatRoom {
+	c.instance = instance
+	return c
 }
 
 // CreateTime returns when the chat room was inserted in the database.
@@ -82,6 +183,21 @@ func (c ChatRoom) Cookie() string {
 	return fmt.Sprintf("%d-%d-%s", c.exchange, c.InstanceNumber(), c.name)
 }
 
+// splitChatCookie splits a chat room cookie into the cookie of the room's
+// first instance and the instance number. Cookies that are not in the
+// exchange-instance-name format are returned as-is with instance 0.
+func splitChatCookie(cookie string) (string, uint16) {
+	parts := strings.SplitN(cookie, "-", 3)
+	if len(parts) != 3 {
+		return cookie, 0
+	}
+	instance, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return cookie, 0
+	}
+	return fmt.Sprintf("%s-0-%s", parts[0], parts[2]), uint16(instance)
+}
+
 // URL creates a URL that can be used to join a chat room.
 func (c ChatRoom) URL() *url.URL {
 	// macOS client v4.0.9 requires the `roomname` param to precede `exchange`
@@ -94,9 +210,10 @@ func (c ChatRoom) URL() *url.URL {
 	}
 }
 
-// TLVList returns a TLV list of chat room metadata.
-func (c ChatRoom) TLVList() []wire.TLV {
-	return []wire.TLV{
+// TLVList returns a TLV list of chat room metadata. Param settings are the
+// settings of the room's exchange.
+func (c ChatRoom) TLVList(settings ExchangeSettings) []wire.TLV {
+	tlvs := []wire.TLV{
 		// From protocols/oscar/family_chatnav.c in lib purple, these are the
 		// room creation flags:
 		// - 1 Evilable
@@ -106,8 +223,8 @@ func (c ChatRoom) TLVList() []wire.TLV {
 		// It's unclear what effect they actually have.
 		wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
 		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, uint32(c.createTime.Unix())),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, uint16(1024)),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, uint16(100)),
+		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, settings.MaxMsgLen),
+		wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, settings.MaxOccupancy),
 		// From protocols/oscar/family_chatnav.c in lib purple, these are the
 		// room creation permission values:
 		// - 0  creation not allowed
@@ -117,6 +234,10 @@ func (c ChatRoom) TLVList() []wire.TLV {
 		wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
 		wire.NewTLVBE(wire.ChatRoomTLVFullyQualifiedName, c.name),
 		wire.NewTLVBE(wire.ChatRoomTLVRoomName, c.name),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgVisLen, uint16(1024)),
+		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgVisLen, settings.MaxMsgLen),
+	}
+	if c.topic != "" {
+		tlvs = append(tlvs, wire.NewTLVBE(wire.ChatRoomTLVTopic, c.topic))
 	}
+	return tlvs
 }
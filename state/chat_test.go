@@ -3,25 +3,125 @@ package state
 import (
 	"testing"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/wire"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestChatRoom_TLVList(t *testing.T) {
-	room := NewChatRoom("chat-room-name", NewIdentScreenName(""), PublicExchange)
-
-	have := room.TLVList()
-	want := []wire.TLV{
-		wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
-		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, uint32(room.createTime.Unix())),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, uint16(1024)),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, uint16(100)),
-		wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
-		wire.NewTLVBE(wire.ChatRoomTLVFullyQualifiedName, room.name),
-		wire.NewTLVBE(wire.ChatRoomTLVRoomName, room.name),
-		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgVisLen, uint16(1024)),
+	tests := []struct {
+		name     string
+		exchange uint16
+		topic    string
+		wantLen  uint16
+		wantOcc  uint16
+	}{
+		{
+			name:     "private exchange room",
+			exchange: PrivateExchange,
+			wantLen:  1024,
+			wantOcc:  100,
+		},
+		{
+			name:     "public exchange room",
+			exchange: PublicExchange,
+			wantLen:  1024,
+			wantOcc:  250,
+		},
+		{
+			name:     "room with topic",
+			exchange: PrivateExchange,
+			topic:    "the topic",
+			wantLen:  1024,
+			wantOcc:  100,
+		},
 	}
 
-	assert.Equal(t, want, have)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewChatRoom("chat-room-name", NewIdentScreenName(""), tt.exchange)
+			room.topic = tt.topic
+
+			have := room.TLVList(NewChatExchanges(config.Config{}).Settings(tt.exchange))
+			want := []wire.TLV{
+				wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
+				wire.NewTLVBE(wire.ChatRoomTLVCreateTime, uint32(room.createTime.Unix())),
+				wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, tt.wantLen),
+				wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, tt.wantOcc),
+				wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
+				wire.NewTLVBE(wire.ChatRoomTLVFullyQualifiedName, room.name),
+				wire.NewTLVBE(wire.ChatRoomTLVRoomName, room.name),
+				wire.NewTLVBE(wire.ChatRoomTLVMaxMsgVisLen, tt.wantLen),
+			}
+			if tt.topic != "" {
+				want = append(want, wire.NewTLVBE(wire.ChatRoomTLVTopic, tt.topic))
+			}
+
+			assert.Equal(t, want, have)
+		})
+	}
+}
+
+func TestNewChatExchanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		wantPrivate ExchangeSettings
+		wantPublic  ExchangeSettings
+	}{
+		{
+			name: "exchanges configured with different settings",
+			cfg: config.Config{
+				ChatPrivateCharset:      "us-ascii",
+				ChatPrivateLang:         "en",
+				ChatPrivateMaxMsgLen:    512,
+				ChatPrivateMaxOccupancy: 23,
+				ChatPublicCharset:       "iso-8859-1",
+				ChatPublicLang:          "de",
+				ChatPublicMaxMsgLen:     2048,
+				ChatPublicMaxOccupancy:  500,
+			},
+			wantPrivate: ExchangeSettings{
+				Charset:      "us-ascii",
+				Lang:         "en",
+				MaxMsgLen:    512,
+				MaxOccupancy: 23,
+			},
+			wantPublic: ExchangeSettings{
+				Charset:      "iso-8859-1",
+				Lang:         "de",
+				MaxMsgLen:    2048,
+				MaxOccupancy: 500,
+			},
+		},
+		{
+			name: "unset settings fall back to defaults",
+			cfg: config.Config{
+				ChatPublicLang: "fr",
+			},
+			wantPrivate: ExchangeSettings{
+				Charset:      "us-ascii",
+				Lang:         "en",
+				MaxMsgLen:    1024,
+				MaxOccupancy: 100,
+			},
+			wantPublic: ExchangeSettings{
+				Charset:      "us-ascii",
+				Lang:         "fr",
+				MaxMsgLen:    1024,
+				MaxOccupancy: 250,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchanges := NewChatExchanges(tt.cfg)
+			assert.Equal(t, tt.wantPrivate, exchanges.Settings(PrivateExchange))
+			assert.Equal(t, tt.wantPublic, exchanges.Settings(PublicExchange))
+			// unknown exchanges get the private exchange settings
+			assert.Equal(t, tt.wantPrivate, exchanges.Settings(1234))
+		})
+	}
 }
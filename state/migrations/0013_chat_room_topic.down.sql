@@ -0,0 +1,2 @@
+ALTER TABLE chatRoom
+    DROP COLUMN topic;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE chatRoom
+	ADD COLUMN topic TEXT NOT NULL DEFAULT '';
\ No newline at end of file
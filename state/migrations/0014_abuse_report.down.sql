@@ -0,0 +1 @@
+DROP TABLE abuseReport;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE abuseReport
+(
+    reporter VARCHAR(16) NOT NULL,
+    target   VARCHAR(16) NOT NULL,
+    reason   TEXT        NOT NULL DEFAULT '',
+    context  TEXT        NOT NULL DEFAULT '',
+    created  TIMESTAMP   NOT NULL
+);
\ No newline at end of file
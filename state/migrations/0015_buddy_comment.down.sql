@@ -0,0 +1 @@
+DROP TABLE buddyComment;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE buddyComment
+(
+    me      VARCHAR(16) NOT NULL,
+    them    VARCHAR(16) NOT NULL,
+    comment TEXT        NOT NULL,
+    PRIMARY KEY (me, them)
+);
\ No newline at end of file
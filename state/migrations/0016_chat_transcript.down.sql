@@ -0,0 +1 @@
+DROP TABLE chatTranscript;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE chatTranscript
+(
+    room    VARCHAR(50) NOT NULL,
+    sender  VARCHAR(16) NOT NULL,
+    message TEXT        NOT NULL,
+    sent    TIMESTAMP   NOT NULL
+);
+CREATE INDEX idx_chatTranscript_sent ON chatTranscript (sent);
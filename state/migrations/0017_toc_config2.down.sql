@@ -0,0 +1,2 @@
+ALTER TABLE users
+	DROP COLUMN tocConfig2;
\ No newline at end of file
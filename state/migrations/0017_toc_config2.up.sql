@@ -0,0 +1,2 @@
+ALTER TABLE users
+	ADD COLUMN tocConfig2 TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX idx_feedbag_name_classID;
+DROP INDEX idx_feedbag_screenName_classID;
@@ -0,0 +1,2 @@
+CREATE INDEX idx_feedbag_name_classID ON feedbag (name, classID);
+CREATE INDEX idx_feedbag_screenName_classID ON feedbag (screenName, classID);
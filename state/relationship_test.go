@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
@@ -3040,3 +3041,44 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkSQLiteUserStore_AllRelationships(b *testing.B) {
+	defer func() {
+		_ = os.Remove(testFile)
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// 250 users, each with 20 buddies on their server-side buddy list and a
+	// deny list entry, for 5,500 feedbag rows
+	const userCount = 250
+	screenName := func(i int) IdentScreenName {
+		return NewIdentScreenName(fmt.Sprintf("user%d", i%userCount))
+	}
+	for i := 0; i < userCount; i++ {
+		sn := screenName(i)
+		if err := feedbagStore.UseFeedbag(sn); err != nil {
+			b.Fatal(err)
+		}
+		items := []wire.FeedbagItem{
+			pdInfoItem(1, wire.FeedbagPDModeDenySome),
+			newFeedbagItem(wire.FeedbagClassIDDeny, 2, screenName(i+userCount/2).String()),
+		}
+		for j := 1; j <= 20; j++ {
+			items = append(items, newFeedbagItem(wire.FeedbagClassIdBuddy, uint16(j+2), screenName(i+j).String()))
+		}
+		if err := feedbagStore.FeedbagUpsert(sn, items); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := feedbagStore.AllRelationships(screenName(i), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -40,6 +40,8 @@ type Session struct {
 	stopCh            chan struct{}
 	uin               uint32
 	warning           uint16
+	warningDecay      uint16
+	warningUpdated    time.Time
 	userInfoBitmask   uint16
 	userStatusBitmask uint32
 	clientID          string
@@ -109,7 +111,32 @@ func (s *Session) SetUserStatusBitmask(bitmask uint32) {
 func (s *Session) IncrementWarning(incr uint16) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.warning += incr
+	s.warning = s.decayedWarning() + incr
+	s.warningUpdated = s.nowFn()
+}
+
+// SetWarningDecay sets how much the user's warning level drops per minute, in
+// tenths of a percent. A rate of 0 disables decay.
+func (s *Session) SetWarningDecay(perMin uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.warning = s.decayedWarning()
+	s.warningUpdated = s.nowFn()
+	s.warningDecay = perMin
+}
+
+// decayedWarning returns the user's warning level after subtracting the decay
+// accrued since the warning level last changed. The caller must hold the
+// mutex.
+func (s *Session) decayedWarning() uint16 {
+	if s.warningDecay == 0 || s.warning == 0 {
+		return s.warning
+	}
+	decay := s.nowFn().Sub(s.warningUpdated).Minutes() * float64(s.warningDecay)
+	if decay >= float64(s.warning) {
+		return 0
+	}
+	return s.warning - uint16(decay)
 }
 
 // Invisible returns true if the user is idle.
@@ -254,7 +281,7 @@ func (s *Session) TLVUserInfo() wire.TLVUserInfo {
 	defer s.mutex.RUnlock()
 	return wire.TLVUserInfo{
 		ScreenName:   string(s.displayScreenName),
-		WarningLevel: s.warning,
+		WarningLevel: s.decayedWarning(),
 		TLVBlock: wire.TLVBlock{
 			TLVList: s.userInfo(),
 		},
@@ -311,12 +338,12 @@ func (s *Session) Caps() [][16]byte {
 	return s.caps
 }
 
+// Warning returns the user's current warning level in tenths of a percent,
+// less any decay.
 func (s *Session) Warning() uint16 {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	var w uint16
-	w = s.warning
-	return w
+	return s.decayedWarning()
 }
 
 // ReceiveMessage returns a channel of messages relayed via this session. It
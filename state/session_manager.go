@@ -21,7 +21,7 @@ var errSessConflict = errors.New("session conflict: another session was created
 // synchronized message relay between sessions in the session pool. An
 // InMemorySessionManager is safe for concurrent use by multiple goroutines.
 type InMemorySessionManager struct {
-	store    map[IdentScreenName]*sessionSlot
+	store    map[IdentScreenName]*sessionSlot // Sessions keyed by screen name.
 	mapMutex sync.RWMutex
 	logger   *slog.Logger
 }
@@ -118,12 +118,7 @@ func (s *InMemorySessionManager) AddSession(ctx context.Context, screenName Disp
 }
 
 func (s *InMemorySessionManager) findRec(identScreenName IdentScreenName) *sessionSlot {
-	for _, rec := range s.store {
-		if identScreenName == rec.sess.IdentScreenName() {
-			return rec
-		}
-	}
-	return nil
+	return s.store[identScreenName]
 }
 
 // RemoveSession takes a session out of the session pool.
@@ -152,10 +147,8 @@ func (s *InMemorySessionManager) retrieveByScreenNames(screenNames []IdentScreen
 	defer s.mapMutex.RUnlock()
 	var ret []*Session
 	for _, sn := range screenNames {
-		for _, rec := range s.store {
-			if sn == rec.sess.IdentScreenName() {
-				ret = append(ret, rec.sess)
-			}
+		if rec, ok := s.store[sn]; ok {
+			ret = append(ret, rec.sess)
 		}
 	}
 	return ret
@@ -264,6 +257,20 @@ func (s *InMemoryChatSessionManager) AllSessions(cookie string) []*Session {
 	return sessionManager.AllSessions()
 }
 
+// OccupantCount returns the number of chat room participants. The count is
+// computed under the registry lock, so it's consistent with concurrent joins
+// and departures. It returns 0 if the room does not exist.
+func (s *InMemoryChatSessionManager) OccupantCount(cookie string) int {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	sessionManager, ok := s.store[cookie]
+	if !ok {
+		return 0
+	}
+	return len(sessionManager.AllSessions())
+}
+
 // RelayToAllExcept sends a message to all chat room participants except for
 // the participant with a particular screen name. Returns ErrChatRoomNotFound
 // if the room does not exist for cookie.
@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"testing"
@@ -187,6 +188,31 @@ func TestInMemorySessionManager_Retrieve(t *testing.T) {
 	}
 }
 
+func TestInMemorySessionManager_Retrieve_AfterRemove(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	user1, err := sm.AddSession(context.Background(), "user-screen-name-1")
+	assert.NoError(t, err)
+	user2, err := sm.AddSession(context.Background(), "user-screen-name-2")
+	assert.NoError(t, err)
+
+	sm.RemoveSession(user1)
+
+	assert.Nil(t, sm.RetrieveSession(NewIdentScreenName("user-screen-name-1")))
+	assert.Equal(t, user2, sm.RetrieveSession(NewIdentScreenName("user-screen-name-2")))
+
+	recips := []IdentScreenName{
+		NewIdentScreenName("user-screen-name-1"),
+		NewIdentScreenName("user-screen-name-2"),
+	}
+	assert.Equal(t, []*Session{user2}, sm.retrieveByScreenNames(recips))
+
+	// the screen name can sign on again once its session is removed
+	user1Again, err := sm.AddSession(context.Background(), "user-screen-name-1")
+	assert.NoError(t, err)
+	assert.Equal(t, user1Again, sm.RetrieveSession(NewIdentScreenName("user-screen-name-1")))
+}
+
 func TestInMemorySessionManager_RelayToScreenNames(t *testing.T) {
 	sm := NewInMemorySessionManager(slog.Default())
 
@@ -377,6 +403,35 @@ func TestInMemoryChatSessionManager_RelayToAllExcept_HappyPath(t *testing.T) {
 	}
 }
 
+func TestInMemoryChatSessionManager_RelayToAllExcept_SameNameOnEachExchange(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	privateRoom := NewChatRoom("lobby", NewIdentScreenName("creator"), PrivateExchange)
+	publicRoom := NewChatRoom("lobby", NewIdentScreenName("operator"), PublicExchange)
+
+	privateUser, err := sm.AddSession(context.Background(), privateRoom.Cookie(), "user-screen-name-1")
+	assert.NoError(t, err)
+	publicUser, err := sm.AddSession(context.Background(), publicRoom.Cookie(), "user-screen-name-2")
+	assert.NoError(t, err)
+
+	want := wire.SNACMessage{Frame: wire.SNACFrame{FoodGroup: wire.Chat}}
+
+	sm.RelayToAllExcept(context.Background(), privateRoom.Cookie(), NewIdentScreenName("sender"), want)
+
+	select {
+	case have := <-privateUser.ReceiveMessage():
+		assert.Equal(t, want, have)
+	default:
+		assert.Fail(t, "user in the private room should receive the message")
+	}
+
+	select {
+	case <-publicUser.ReceiveMessage():
+		assert.Fail(t, "user in the public room should not receive the message")
+	default:
+	}
+}
+
 func TestInMemoryChatSessionManager_AllSessions_RoomExists(t *testing.T) {
 	sm := NewInMemoryChatSessionManager(slog.Default())
 
@@ -397,6 +452,41 @@ func TestInMemoryChatSessionManager_AllSessions_RoomExists(t *testing.T) {
 	assert.True(t, lookup[user2])
 }
 
+func TestInMemoryChatSessionManager_OccupantCount(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	assert.Equal(t, 0, sm.OccupantCount("the-cookie"))
+
+	// join concurrently
+	wg := sync.WaitGroup{}
+	sessions := make([]*Session, 10)
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sess, err := sm.AddSession(context.Background(), "the-cookie", DisplayScreenName(fmt.Sprintf("user-%d", i)))
+			assert.NoError(t, err)
+			sessions[i] = sess
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, sm.OccupantCount("the-cookie"))
+	assert.Equal(t, 0, sm.OccupantCount("other-cookie"))
+
+	// leave concurrently
+	for _, sess := range sessions[:4] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sm.RemoveSession(sess)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 6, sm.OccupantCount("the-cookie"))
+}
+
 func TestInMemoryChatSessionManager_RelayToScreenName_SessionAndChatRoomExist(t *testing.T) {
 	sm := NewInMemoryChatSessionManager(slog.Default())
 
@@ -481,3 +571,23 @@ func TestInMemoryChatSessionManager_RemoveUserFromAllChats(t *testing.T) {
 	assert.True(t, lookup[user2sess])
 
 }
+
+func BenchmarkInMemorySessionManager_RelayToScreenNames(b *testing.B) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	var buddies []IdentScreenName
+	for i := 0; i < 5000; i++ {
+		screenName := DisplayScreenName(fmt.Sprintf("user-%d", i))
+		if _, err := sm.AddSession(context.Background(), screenName); err != nil {
+			b.Fatal(err)
+		}
+		if i%25 == 0 {
+			buddies = append(buddies, screenName.IdentScreenName())
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sm.retrieveByScreenNames(buddies)
+	}
+}
@@ -28,6 +28,50 @@ func TestSession_IncrementAndGetWarning(t *testing.T) {
 	assert.Equal(t, uint16(3), s.Warning())
 }
 
+func TestSession_IncrementWarning_Concurrent(t *testing.T) {
+	s := NewSession()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.IncrementWarning(1)
+				_ = s.Warning()
+				_ = s.TLVUserInfo()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint16(1000), s.Warning())
+}
+
+func TestSession_WarningDecay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSession()
+	s.nowFn = func() time.Time { return now }
+	s.SetWarningDecay(100)
+
+	s.IncrementWarning(250)
+	assert.Equal(t, uint16(250), s.Warning())
+
+	// the warning level drops by 10% per minute
+	now = now.Add(time.Minute)
+	assert.Equal(t, uint16(150), s.Warning())
+	assert.Equal(t, uint16(150), s.TLVUserInfo().WarningLevel)
+
+	// new warnings add to the decayed level
+	now = now.Add(30 * time.Second)
+	s.IncrementWarning(100)
+	assert.Equal(t, uint16(200), s.Warning())
+
+	// the warning level bottoms out at 0
+	now = now.Add(10 * time.Minute)
+	assert.Zero(t, s.Warning())
+}
+
 func TestSession_SetAndGetInvisible(t *testing.T) {
 	s := NewSession()
 	assert.False(t, s.Invisible())
@@ -203,6 +203,8 @@ type User struct {
 	// TOCConfig is the user's saved server-side info (buddy list, etc) for
 	// on the TOC service.
 	TOCConfig string
+	// TOCConfig2 is the user's saved server-side info for TOC2 clients.
+	TOCConfig2 string
 }
 
 // AIMNameAndAddr holds name and address AIM directory information.
@@ -410,9 +412,10 @@ func (u *User) ValidateRoastedPass(roastedPass []byte) bool {
 
 // ValidateRoastedTOCPass checks if the provided roasted password matches the MD5
 // hash of the user's actual password. A roasted password is a XOR-obfuscated
-// form of the real password, intended to add a simple layer of security. // todo toc description
-func (u *User) ValidateRoastedTOCPass(roastedPass []byte) bool {
-	clearPass := wire.RoastTOCPassword(roastedPass)
+// form of the real password, intended to add a simple layer of security. TOC
+// passwords are roasted with roastingString.
+func (u *User) ValidateRoastedTOCPass(roastedPass []byte, roastingString string) bool {
+	clearPass := wire.RoastTOCPassword(roastedPass, roastingString)
 	md5Hash := wire.WeakMD5PasswordHash(string(clearPass), u.AuthKey) // todo remove string conversion
 	return bytes.Equal(u.WeakMD5Pass, md5Hash)
 }
@@ -455,13 +458,37 @@ func validateICQPassword(pass string) error {
 	return nil
 }
 
+// SystemNoticeSender is the reserved sender of operator notices queued in the
+// offline message store. It can't collide with a registered user because
+// brackets aren't allowed in screen names.
+var SystemNoticeSender = DisplayScreenName("[System]")
+
 type OfflineMessage struct {
+	ID        int64 // Row ID of the stored message, set by RetrieveMessages.
 	Sender    IdentScreenName
 	Recipient IdentScreenName
 	Message   wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
 	Sent      time.Time
 }
 
+// AbuseReport is a user's report of another user's abusive behavior, kept
+// for operator review.
+type AbuseReport struct {
+	Reporter IdentScreenName // The user who filed the report.
+	Target   IdentScreenName // The user being reported.
+	Reason   string          // Optional explanation provided by the reporter.
+	Context  string          // Recent messages the reporter received from the target.
+	Created  time.Time       // When the report was filed.
+}
+
+// ChatTranscriptEntry is a chat message recorded in a chat room transcript.
+type ChatTranscriptEntry struct {
+	Room    string          // The name of the room the message was sent to.
+	Sender  IdentScreenName // The user who sent the message.
+	Message string          // The message text, which may contain HTML.
+	Sent    time.Time       // When the message was sent.
+}
+
 // Category represents an AIM directory category.
 type Category struct {
 	// ID is the category ID
@@ -10,6 +10,8 @@ import (
 	"math"
 	"net/http"
 	"net/mail"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +26,7 @@ import (
 )
 
 var (
+	ErrBuddyListRegistered     = errors.New("buddy list is already registered")
 	ErrKeywordCategoryExists   = errors.New("keyword category already exists")
 	ErrKeywordCategoryNotFound = errors.New("keyword category not found")
 	ErrKeywordExists           = errors.New("keyword already exists")
@@ -44,8 +47,14 @@ type SQLiteUserStore struct {
 
 // NewSQLiteUserStore creates a new instance of SQLiteUserStore. If the
 // database does not already exist, a new one is created with the required
-// schema.
+// schema. It returns an error if the database's directory is missing or not
+// writable, since SQLite needs to create journal files alongside the
+// database.
 func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
+	if err := checkDBDirWritable(dbFilePath); err != nil {
+		return nil, err
+	}
+
 	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys=on", dbFilePath))
 	if err != nil {
 		return nil, err
@@ -67,6 +76,24 @@ func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
 	return store, nil
 }
 
+// checkDBDirWritable verifies that the directory containing dbFilePath exists
+// and that files can be created in it.
+func checkDBDirWritable(dbFilePath string) error {
+	dir := filepath.Dir(dbFilePath)
+	f, err := os.CreateTemp(dir, ".db-write-check-*")
+	if err != nil {
+		return fmt.Errorf("database directory %s is not writable: %w", dir, err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// Close closes the underlying database, releasing its connections. Calling
+// Close more than once is safe. Subsequent queries against the store fail.
+func (f SQLiteUserStore) Close() error {
+	return f.db.Close()
+}
+
 func (f SQLiteUserStore) runMigrations() error {
 	migrationFS, err := fs.Sub(migrations, "migrations")
 	if err != nil {
@@ -424,7 +451,8 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			aim_nickName,
 			aim_zipCode,
 			aim_address,
-			tocConfig
+			tocConfig,
+			tocConfig2
 		FROM users
 		WHERE %s
 	`
@@ -517,6 +545,7 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			&u.AIMDirectoryInfo.ZIPCode,
 			&u.AIMDirectoryInfo.Address,
 			&u.TOCConfig,
+			&u.TOCConfig2,
 		)
 		if err != nil {
 			return nil, err
@@ -705,22 +734,45 @@ func (f SQLiteUserStore) FeedbagLastModified(screenName IdentScreenName) (time.T
 }
 
 // FeedbagDelete deletes an entry from a user's feedbag (buddy list).
+// Either all items are deleted or none are.
 func (f SQLiteUserStore) FeedbagDelete(screenName IdentScreenName, items []wire.FeedbagItem) error {
-	// todo add transaction
+	tx, err := f.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	q := `DELETE FROM feedbag WHERE screenName = ? AND itemID = ?`
 
 	for _, item := range items {
-		if _, err := f.db.Exec(q, screenName.String(), item.ItemID); err != nil {
+		if _, err := tx.Exec(q, screenName.String(), item.ItemID); err != nil {
 			return err
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
 	return nil
 }
 
 // FeedbagUpsert upserts an entry to a user's feedbag (buddy list). An entry is
 // created if it doesn't already exist, or modified if it already exists.
+// Either all items are upserted or none are.
 func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.FeedbagItem) error {
+	tx, err := f.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	q := `
 		INSERT INTO feedbag (screenName, groupID, itemID, classID, name, attributes, pdMode, lastModified)
 		VALUES (?, ?, ?, ?, ?, ?, ?, UNIXEPOCH())
@@ -753,7 +805,7 @@ func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.
 				pdMode = uint8(wire.FeedbagPDModePermitAll)
 			}
 		}
-		_, err := f.db.Exec(q,
+		_, err := tx.Exec(q,
 			screenName.String(),
 			item.GroupID,
 			item.ItemID,
@@ -766,6 +818,10 @@ func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
 	return nil
 }
 
@@ -780,14 +836,27 @@ func (f SQLiteUserStore) ClearBuddyListRegistry() error {
 	return nil
 }
 
-// RegisterBuddyList makes my buddy list visible to other buddy lists.
+// RegisterBuddyList makes my buddy list visible to other buddy lists. It
+// returns ErrBuddyListRegistered if my buddy list is already registered, such
+// as when a previous session was not cleanly signed out. The existing
+// registration is left intact.
 func (f SQLiteUserStore) RegisterBuddyList(user IdentScreenName) error {
 	q := `
 		INSERT INTO buddyListMode (screenName, clientSidePDMode) VALUES(?, ?)
 		ON CONFLICT (screenName) DO NOTHING
 	`
-	_, err := f.db.Exec(q, user.String(), wire.FeedbagPDModePermitAll)
-	return err
+	res, err := f.db.Exec(q, user.String(), wire.FeedbagPDModePermitAll)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBuddyListRegistered
+	}
+	return nil
 }
 
 // UnregisterBuddyList makes my buddy list invisible to other buddy lists.
@@ -920,6 +989,44 @@ func (f SQLiteUserStore) AddBuddy(me IdentScreenName, them IdentScreenName) erro
 	return err
 }
 
+// BuddyCount returns the number of buddies on my client-side buddy list.
+func (f SQLiteUserStore) BuddyCount(me IdentScreenName) (int, error) {
+	q := `
+		SELECT COUNT(*)
+		FROM clientSideBuddyList
+		WHERE me = ? AND isBuddy IS TRUE
+	`
+	var count int
+	err := f.db.QueryRow(q, me.String()).Scan(&count)
+	return count, err
+}
+
+// Buddies returns the screen names on my client-side buddy list in
+// alphabetical order.
+func (f SQLiteUserStore) Buddies(me IdentScreenName) ([]IdentScreenName, error) {
+	q := `
+		SELECT them
+		FROM clientSideBuddyList
+		WHERE me = ? AND isBuddy IS TRUE
+		ORDER BY them
+	`
+	rows, err := f.db.Query(q, me.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buddies []IdentScreenName
+	for rows.Next() {
+		var them string
+		if err := rows.Scan(&them); err != nil {
+			return nil, err
+		}
+		buddies = append(buddies, NewIdentScreenName(them))
+	}
+	return buddies, rows.Err()
+}
+
 // RemoveBuddy removes a buddy from my client-side buddy list.
 func (f SQLiteUserStore) RemoveBuddy(me IdentScreenName, them IdentScreenName) error {
 	q := `
@@ -1074,22 +1181,28 @@ func (f SQLiteUserStore) BARTRetrieve(hash []byte) ([]byte, error) {
 	return body, err
 }
 
-// ChatRoomByCookie looks up a chat room by cookie. Returns
-// ErrChatRoomNotFound if the room does not exist for cookie.
+// ChatRoomByCookie looks up a chat room by cookie. The cookie may refer to
+// any instance of the room. Returns ErrChatRoomNotFound if the room does not
+// exist for cookie.
 func (f SQLiteUserStore) ChatRoomByCookie(cookie string) (ChatRoom, error) {
-	chatRoom := ChatRoom{}
+	// all instances of a room share the record of the first instance
+	baseCookie, instance := splitChatCookie(cookie)
+	chatRoom := ChatRoom{
+		instance: instance,
+	}
 
 	q := `
-		SELECT exchange, name, created, creator
+		SELECT exchange, name, created, creator, topic
 		FROM chatRoom
 		WHERE lower(cookie) = lower(?)
 	`
 	var creator string
-	err := f.db.QueryRow(q, cookie).Scan(
+	err := f.db.QueryRow(q, baseCookie).Scan(
 		&chatRoom.exchange,
 		&chatRoom.name,
 		&chatRoom.createTime,
 		&creator,
+		&chatRoom.topic,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = fmt.Errorf("%w: %s", ErrChatRoomNotFound, cookie)
@@ -1107,7 +1220,7 @@ func (f SQLiteUserStore) ChatRoomByName(exchange uint16, name string) (ChatRoom,
 	}
 
 	q := `
-		SELECT name, created, creator
+		SELECT name, created, creator, topic
 		FROM chatRoom
 		WHERE exchange = ? AND lower(name) = lower(?)
 	`
@@ -1116,6 +1229,7 @@ func (f SQLiteUserStore) ChatRoomByName(exchange uint16, name string) (ChatRoom,
 		&chatRoom.name,
 		&chatRoom.createTime,
 		&creator,
+		&chatRoom.topic,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = ErrChatRoomNotFound
@@ -1125,6 +1239,39 @@ func (f SQLiteUserStore) ChatRoomByName(exchange uint16, name string) (ChatRoom,
 	return chatRoom, err
 }
 
+// ChatRoomsByCreator returns the chat rooms created by creator, oldest
+// first.
+func (f SQLiteUserStore) ChatRoomsByCreator(creator IdentScreenName) ([]ChatRoom, error) {
+	q := `
+		SELECT exchange, created, name, topic
+		FROM chatRoom
+		WHERE creator = ?
+		ORDER BY created ASC
+	`
+	rows, err := f.db.Query(q, creator.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []ChatRoom
+	for rows.Next() {
+		cr := ChatRoom{
+			creator: creator,
+		}
+		if err := rows.Scan(&cr.exchange, &cr.createTime, &cr.name, &cr.topic); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, cr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rooms, nil
+}
+
 // CreateChatRoom creates a new chat room. It sets createTime on chatRoom to
 // the current timestamp.
 func (f SQLiteUserStore) CreateChatRoom(chatRoom *ChatRoom) error {
@@ -1151,9 +1298,36 @@ func (f SQLiteUserStore) CreateChatRoom(chatRoom *ChatRoom) error {
 	return err
 }
 
+// SetChatRoomTopic sets the topic of the chat room identified by cookie. The
+// topic is shared by all instances of the room. Returns ErrChatRoomNotFound if
+// the room does not exist for cookie.
+func (f SQLiteUserStore) SetChatRoomTopic(cookie string, topic string) error {
+	baseCookie, _ := splitChatCookie(cookie)
+
+	q := `
+		UPDATE chatRoom
+		SET topic = ?
+		WHERE lower(cookie) = lower(?)
+	`
+	res, err := f.db.Exec(q, topic, baseCookie)
+	if err != nil {
+		return fmt.Errorf("SetChatRoomTopic: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetChatRoomTopic: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrChatRoomNotFound, cookie)
+	}
+
+	return nil
+}
+
 func (f SQLiteUserStore) AllChatRooms(exchange uint16) ([]ChatRoom, error) {
 	q := `
-		SELECT created, creator, name
+		SELECT created, creator, name, topic
 		FROM chatRoom
 		WHERE exchange = ?
 		ORDER BY created ASC
@@ -1170,7 +1344,7 @@ func (f SQLiteUserStore) AllChatRooms(exchange uint16) ([]ChatRoom, error) {
 			exchange: exchange,
 		}
 		var creator string
-		if err := rows.Scan(&cr.createTime, &creator, &cr.name); err != nil {
+		if err := rows.Scan(&cr.createTime, &creator, &cr.name, &cr.topic); err != nil {
 			return nil, err
 		}
 		cr.creator = NewIdentScreenName(creator)
@@ -1549,15 +1723,147 @@ func (f SQLiteUserStore) SaveMessage(offlineMessage OfflineMessage) error {
 	return err
 }
 
-// RetrieveMessages retrieves all offline messages sent to recipient.
+// AddAbuseReport records an abuse report for operator review.
+func (f SQLiteUserStore) AddAbuseReport(report AbuseReport) error {
+	q := `
+		INSERT INTO abuseReport (reporter, target, reason, context, created)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := f.db.Exec(
+		q,
+		report.Reporter.String(),
+		report.Target.String(),
+		report.Reason,
+		report.Context,
+		report.Created,
+	)
+	return err
+}
+
+// AbuseReports returns all abuse reports, oldest first.
+func (f SQLiteUserStore) AbuseReports() ([]AbuseReport, error) {
+	q := `
+		SELECT reporter, target, reason, context, created
+		FROM abuseReport
+		ORDER BY created ASC
+	`
+	rows, err := f.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []AbuseReport
+	for rows.Next() {
+		var reporter, target string
+		report := AbuseReport{}
+		if err := rows.Scan(&reporter, &target, &report.Reason, &report.Context, &report.Created); err != nil {
+			return nil, err
+		}
+		report.Reporter = NewIdentScreenName(reporter)
+		report.Target = NewIdentScreenName(target)
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// AddChatTranscriptEntry records a chat message in a chat room transcript.
+func (f SQLiteUserStore) AddChatTranscriptEntry(entry ChatTranscriptEntry) error {
+	q := `
+		INSERT INTO chatTranscript (room, sender, message, sent)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := f.db.Exec(
+		q,
+		entry.Room,
+		entry.Sender.String(),
+		entry.Message,
+		entry.Sent,
+	)
+	return err
+}
+
+// DeleteChatTranscriptEntries deletes the chat transcript entries of all
+// rooms that were sent before a given time.
+func (f SQLiteUserStore) DeleteChatTranscriptEntries(before time.Time) error {
+	q := `DELETE FROM chatTranscript WHERE sent < ?`
+	_, err := f.db.Exec(q, before)
+	return err
+}
+
+// ChatTranscript returns the transcript of a chat room, oldest first. The room
+// name is case-insensitive.
+func (f SQLiteUserStore) ChatTranscript(room string) ([]ChatTranscriptEntry, error) {
+	q := `
+		SELECT room, sender, message, sent
+		FROM chatTranscript
+		WHERE lower(room) = lower(?)
+		ORDER BY sent ASC
+	`
+	rows, err := f.db.Query(q, room)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ChatTranscriptEntry
+	for rows.Next() {
+		var sender string
+		entry := ChatTranscriptEntry{}
+		if err := rows.Scan(&entry.Room, &sender, &entry.Message, &entry.Sent); err != nil {
+			return nil, err
+		}
+		entry.Sender = NewIdentScreenName(sender)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// SetBuddyComment sets my private comment about them. An empty comment
+// removes the existing comment.
+func (f SQLiteUserStore) SetBuddyComment(me, them IdentScreenName, comment string) error {
+	if comment == "" {
+		q := `DELETE FROM buddyComment WHERE me = ? AND them = ?`
+		_, err := f.db.Exec(q, me.String(), them.String())
+		return err
+	}
+
+	q := `
+		INSERT INTO buddyComment (me, them, comment)
+		VALUES (?, ?, ?)
+		ON CONFLICT (me, them)
+			DO UPDATE SET comment = excluded.comment
+	`
+	_, err := f.db.Exec(q, me.String(), them.String(), comment)
+	return err
+}
+
+// BuddyComment returns my private comment about them. It returns an empty
+// string if I haven't commented on them.
+func (f SQLiteUserStore) BuddyComment(me, them IdentScreenName) (string, error) {
+	q := `SELECT comment FROM buddyComment WHERE me = ? AND them = ?`
+	var comment string
+	err := f.db.QueryRow(q, me.String(), them.String()).Scan(&comment)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return comment, nil
+}
+
+// RetrieveMessages retrieves all offline messages sent to recipient, oldest
+// first.
 func (f SQLiteUserStore) RetrieveMessages(recip IdentScreenName) ([]OfflineMessage, error) {
 	q := `
 		SELECT 
+		    rowid,
 		    sender, 
 		    message,
 		    sent
 		FROM offlineMessage
 		WHERE recipient = ?
+		ORDER BY sent, rowid
 	`
 	rows, err := f.db.Query(q, recip.String())
 	if err != nil {
@@ -1568,10 +1874,11 @@ func (f SQLiteUserStore) RetrieveMessages(recip IdentScreenName) ([]OfflineMessa
 	var messages []OfflineMessage
 
 	for rows.Next() {
+		var id int64
 		var sender string
 		var buf []byte
 		var sent time.Time
-		if err := rows.Scan(&sender, &buf, &sent); err != nil {
+		if err := rows.Scan(&id, &sender, &buf, &sent); err != nil {
 			return nil, err
 		}
 
@@ -1581,6 +1888,7 @@ func (f SQLiteUserStore) RetrieveMessages(recip IdentScreenName) ([]OfflineMessa
 		}
 
 		messages = append(messages, OfflineMessage{
+			ID:        id,
 			Sender:    NewIdentScreenName(sender),
 			Recipient: recip,
 			Message:   msg,
@@ -1604,6 +1912,15 @@ func (f SQLiteUserStore) DeleteMessages(recip IdentScreenName) error {
 	return err
 }
 
+// DeleteMessage deletes the offline message whose ID is id.
+func (f SQLiteUserStore) DeleteMessage(id int64) error {
+	q := `
+		DELETE FROM offlineMessage WHERE rowid = ?
+	`
+	_, err := f.db.Exec(q, id)
+	return err
+}
+
 // BuddyIconRefByName retrieves the buddy icon reference for a given user
 func (f SQLiteUserStore) BuddyIconRefByName(screenName IdentScreenName) (*wire.BARTID, error) {
 	q := `
@@ -1985,3 +2302,29 @@ func (f SQLiteUserStore) SetTOCConfig(user IdentScreenName, config string) error
 	}
 	return nil
 }
+
+// SetTOCConfig2 sets the user's TOC2 config. It's stored separately from the
+// TOC config set by SetTOCConfig so that TOC and TOC2 clients signing on to
+// the same account don't overwrite each other's config.
+func (f SQLiteUserStore) SetTOCConfig2(user IdentScreenName, config string) error {
+	q := `
+		UPDATE users
+		SET tocConfig2 = ?
+		WHERE identScreenName = ?
+	`
+	res, err := f.db.Exec(q,
+		config,
+		user.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if c == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"net/mail"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -16,6 +17,29 @@ import (
 
 const testFile string = "aim_test.db"
 
+func TestNewSQLiteUserStore(t *testing.T) {
+	t.Run("schema is created at the given path", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "oscar.sqlite")
+
+		f, err := NewSQLiteUserStore(dbPath)
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, f.Close())
+		}()
+
+		assert.FileExists(t, dbPath)
+		users, err := f.AllUsers()
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+	})
+	t.Run("missing directory fails at startup", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "missing", "oscar.sqlite")
+
+		_, err := NewSQLiteUserStore(dbPath)
+		assert.ErrorContains(t, err, "database directory "+filepath.Dir(dbPath)+" is not writable")
+	})
+}
+
 func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
 	t.Run("buddy screen name is converted to ident screen name", func(t *testing.T) {
 		defer func() {
@@ -123,6 +147,53 @@ func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, wire.FeedbagPDMode(pdMode), wire.FeedbagPDModePermitAll)
 	})
+	t.Run("failed upsert commits none of the items", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		// fail the insert of the third item
+		_, err = f.db.Exec(`
+			CREATE TRIGGER failThirdItem BEFORE INSERT ON feedbag
+			WHEN NEW.itemID = 3
+			BEGIN
+				SELECT RAISE(ABORT, 'injected failure');
+			END`)
+		assert.NoError(t, err)
+
+		given := []wire.FeedbagItem{
+			{ItemID: 1, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy1"},
+			{ItemID: 2, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy2"},
+			{ItemID: 3, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy3"},
+			{ItemID: 4, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy4"},
+		}
+
+		me := NewIdentScreenName("me")
+		assert.ErrorContains(t, f.FeedbagUpsert(me, given), "injected failure")
+
+		items, err := f.Feedbag(me)
+		assert.NoError(t, err)
+		assert.Empty(t, items)
+	})
+}
+
+func TestSQLiteUserStore_Close(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Close())
+	// closing again is a no-op
+	assert.NoError(t, f.Close())
+
+	_, err = f.User(NewIdentScreenName("me"))
+	assert.ErrorContains(t, err, "database is closed")
 }
 
 func TestFeedbagDelete(t *testing.T) {
@@ -182,6 +253,39 @@ func TestFeedbagDelete(t *testing.T) {
 	}
 }
 
+func TestFeedbagDelete_FailureCommitsNothing(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	items := []wire.FeedbagItem{
+		{ItemID: 1, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy1"},
+		{ItemID: 2, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy2"},
+		{ItemID: 3, ClassID: wire.FeedbagClassIdBuddy, Name: "buddy3"},
+	}
+
+	me := NewIdentScreenName("me")
+	assert.NoError(t, f.FeedbagUpsert(me, items))
+
+	// fail the deletion of the third item
+	_, err = f.db.Exec(`
+		CREATE TRIGGER failThirdItem BEFORE DELETE ON feedbag
+		WHEN OLD.itemID = 3
+		BEGIN
+			SELECT RAISE(ABORT, 'injected failure');
+		END`)
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, f.FeedbagDelete(me, items), "injected failure")
+
+	have, err := f.Feedbag(me)
+	assert.NoError(t, err)
+	assert.Len(t, have, len(items))
+}
+
 func TestLastModifiedEmpty(t *testing.T) {
 
 	screenName := NewIdentScreenName("sn2day")
@@ -552,10 +656,11 @@ func TestSQLiteUserStore_SetUserPassword_ErrNoUser(t *testing.T) {
 
 func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
 	tests := []struct {
-		name        string
-		givenRoom   ChatRoom
-		lookupRoom  ChatRoom
-		expectedErr error
+		name             string
+		givenRoom        ChatRoom
+		lookupRoom       ChatRoom
+		expectedErr      error
+		expectedInstance uint16
 	}{
 		{
 			name:        "chat room found",
@@ -563,6 +668,13 @@ func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
 			lookupRoom:  NewChatRoom("my chat room", NewIdentScreenName("creator"), PrivateExchange),
 			expectedErr: nil,
 		},
+		{
+			name:             "chat room found - other instance",
+			givenRoom:        NewChatRoom("my chat room", NewIdentScreenName("creator"), PrivateExchange),
+			lookupRoom:       NewChatRoom("my chat room", NewIdentScreenName("creator"), PrivateExchange).WithInstance(2),
+			expectedErr:      nil,
+			expectedInstance: 2,
+		},
 		{
 			name:        "chat room found - different name casing",
 			givenRoom:   NewChatRoom("my chat room", NewIdentScreenName("creator"), PrivateExchange),
@@ -592,12 +704,44 @@ func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
 			gotRoom, err := userStore.ChatRoomByCookie(tt.lookupRoom.Cookie())
 			assert.ErrorIs(t, err, tt.expectedErr)
 			if tt.expectedErr == nil {
-				assert.Equal(t, tt.givenRoom.Cookie(), gotRoom.Cookie())
+				assert.Equal(t, tt.expectedInstance, gotRoom.InstanceNumber())
+				assert.Equal(t, tt.givenRoom.WithInstance(tt.expectedInstance).Cookie(), gotRoom.Cookie())
 			}
 		})
 	}
 }
 
+func TestSQLiteUserStore_ChatRoomsByCreator(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	creator := NewIdentScreenName("creator")
+	rooms := []ChatRoom{
+		NewChatRoom("room 1", creator, PrivateExchange),
+		NewChatRoom("room 2", creator, PrivateExchange),
+		NewChatRoom("room 3", NewIdentScreenName("someone else"), PrivateExchange),
+	}
+	for i := range rooms {
+		assert.NoError(t, f.CreateChatRoom(&rooms[i]))
+	}
+
+	got, err := f.ChatRoomsByCreator(creator)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, rooms[0].Cookie(), got[0].Cookie())
+		assert.Equal(t, rooms[1].Cookie(), got[1].Cookie())
+		assert.Equal(t, creator, got[0].Creator())
+	}
+
+	got, err = f.ChatRoomsByCreator(NewIdentScreenName("nobody"))
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
 func TestSQLiteUserStore_ChatRoomByName(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -646,6 +790,33 @@ func TestSQLiteUserStore_ChatRoomByName(t *testing.T) {
 	}
 }
 
+func TestSQLiteUserStore_ChatRoomByName_SameNameOnEachExchange(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	privateRoom := NewChatRoom("lobby", NewIdentScreenName("creator"), PrivateExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&privateRoom))
+	publicRoom := NewChatRoom("lobby", NewIdentScreenName("operator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&publicRoom))
+
+	// each lookup finds the room on its own exchange
+	gotRoom, err := userStore.ChatRoomByName(PrivateExchange, "Lobby")
+	assert.NoError(t, err)
+	assert.Equal(t, privateRoom.Cookie(), gotRoom.Cookie())
+	assert.Equal(t, privateRoom.Creator(), gotRoom.Creator())
+
+	gotRoom, err = userStore.ChatRoomByName(PublicExchange, "Lobby")
+	assert.NoError(t, err)
+	assert.Equal(t, publicRoom.Cookie(), gotRoom.Cookie())
+	assert.Equal(t, publicRoom.Creator(), gotRoom.Creator())
+
+	assert.NotEqual(t, privateRoom.Cookie(), publicRoom.Cookie())
+}
+
 func TestSQLiteUserStore_AllChatRooms(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -680,6 +851,144 @@ func TestSQLiteUserStore_AllChatRooms(t *testing.T) {
 	assert.Equal(t, chatRooms[0:2], gotRooms)
 }
 
+func TestSQLiteUserStore_BuddyCount(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+
+	count, err := feedbagStore.BuddyCount(me)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend1")))
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend2")))
+	// adding the same buddy twice doesn't change the count
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend2")))
+	// permitted users that aren't buddies aren't counted
+	assert.NoError(t, feedbagStore.PermitBuddy(me, NewIdentScreenName("friend3")))
+	// other users' buddies aren't counted
+	assert.NoError(t, feedbagStore.AddBuddy(NewIdentScreenName("them"), NewIdentScreenName("friend1")))
+
+	count, err = feedbagStore.BuddyCount(me)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, feedbagStore.RemoveBuddy(me, NewIdentScreenName("friend1")))
+
+	count, err = feedbagStore.BuddyCount(me)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestSQLiteUserStore_Buddies(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+
+	buddies, err := feedbagStore.Buddies(me)
+	assert.NoError(t, err)
+	assert.Empty(t, buddies)
+
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend2")))
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend1")))
+	assert.NoError(t, feedbagStore.AddBuddy(me, NewIdentScreenName("friend3")))
+	assert.NoError(t, feedbagStore.RemoveBuddy(me, NewIdentScreenName("friend3")))
+	// permitted users that aren't buddies aren't listed
+	assert.NoError(t, feedbagStore.PermitBuddy(me, NewIdentScreenName("friend4")))
+	// other users' buddies aren't listed
+	assert.NoError(t, feedbagStore.AddBuddy(NewIdentScreenName("them"), NewIdentScreenName("friend5")))
+
+	buddies, err = feedbagStore.Buddies(me)
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{
+		NewIdentScreenName("friend1"),
+		NewIdentScreenName("friend2"),
+	}, buddies)
+}
+
+func TestSQLiteUserStore_BuddyComment(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	them := NewIdentScreenName("them")
+
+	comment, err := feedbagStore.BuddyComment(me, them)
+	assert.NoError(t, err)
+	assert.Empty(t, comment)
+
+	assert.NoError(t, feedbagStore.SetBuddyComment(me, them, "met at the lan party"))
+	assert.NoError(t, feedbagStore.SetBuddyComment(me, them, "met at the LAN party"))
+
+	// the comment survives reconnecting to the store
+	feedbagStore, err = NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	comment, err = feedbagStore.BuddyComment(me, them)
+	assert.NoError(t, err)
+	assert.Equal(t, "met at the LAN party", comment)
+
+	// the comment is private to its owner
+	comment, err = feedbagStore.BuddyComment(them, me)
+	assert.NoError(t, err)
+	assert.Empty(t, comment)
+
+	assert.NoError(t, feedbagStore.SetBuddyComment(me, them, ""))
+	comment, err = feedbagStore.BuddyComment(me, them)
+	assert.NoError(t, err)
+	assert.Empty(t, comment)
+}
+
+func TestSQLiteUserStore_SetChatRoomTopic(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	chatRoom := NewChatRoom("my chat room", NewIdentScreenName("creator"), PrivateExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&chatRoom))
+
+	gotRoom, err := userStore.ChatRoomByCookie(chatRoom.Cookie())
+	assert.NoError(t, err)
+	assert.Empty(t, gotRoom.Topic())
+
+	assert.NoError(t, userStore.SetChatRoomTopic(chatRoom.Cookie(), "the topic"))
+
+	gotRoom, err = userStore.ChatRoomByCookie(chatRoom.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, "the topic", gotRoom.Topic())
+
+	gotRoom, err = userStore.ChatRoomByName(chatRoom.Exchange(), chatRoom.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "the topic", gotRoom.Topic())
+
+	// the topic is shared by all instances of the room
+	assert.NoError(t, userStore.SetChatRoomTopic(chatRoom.WithInstance(1).Cookie(), "the new topic"))
+
+	gotRoom, err = userStore.ChatRoomByCookie(chatRoom.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, "the new topic", gotRoom.Topic())
+
+	err = userStore.SetChatRoomTopic("4-0-missing room", "the topic")
+	assert.ErrorIs(t, err, ErrChatRoomNotFound)
+}
+
 func TestSQLiteUserStore_CreateChatRoom_ErrChatRoomExists(t *testing.T) {
 
 	tt := []struct {
@@ -1864,19 +2173,30 @@ func TestSQLiteUserStore_RetrieveMessages(t *testing.T) {
 			},
 			Sent: sendTime,
 		},
+		{
+			Sender:    NewIdentScreenName("Jill"),
+			Recipient: NewIdentScreenName("Jack"),
+			Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie: 4,
+			},
+			Sent: sendTime.Add(-time.Hour),
+		},
 	}
 
-	for _, msg := range offlineMessages {
+	for i, msg := range offlineMessages {
 		err = f.SaveMessage(msg)
 		assert.NoError(t, err)
+		// messages get sequential row IDs in the order they're saved
+		offlineMessages[i].ID = int64(i + 1)
 	}
 
-	t.Run("Retrieve Messages", func(t *testing.T) {
+	t.Run("Retrieve Messages Oldest First", func(t *testing.T) {
 		messages, err := f.RetrieveMessages(NewIdentScreenName("Jack"))
 		assert.NoError(t, err)
-		if assert.Len(t, messages, 2) {
-			assert.Equal(t, offlineMessages[0], messages[0])
-			assert.Equal(t, offlineMessages[2], messages[1])
+		if assert.Len(t, messages, 3) {
+			assert.Equal(t, offlineMessages[3], messages[0])
+			assert.Equal(t, offlineMessages[0], messages[1])
+			assert.Equal(t, offlineMessages[2], messages[2])
 		}
 	})
 
@@ -1887,6 +2207,106 @@ func TestSQLiteUserStore_RetrieveMessages(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_AbuseReports(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	reportTime := time.Now().UTC()
+
+	reports := []AbuseReport{
+		{
+			Reporter: NewIdentScreenName("John"),
+			Target:   NewIdentScreenName("Spammer"),
+			Reason:   "unsolicited ads",
+			Context:  "IM_IN:Spammer:F:buy now",
+			Created:  reportTime,
+		},
+		{
+			Reporter: NewIdentScreenName("Anne"),
+			Target:   NewIdentScreenName("Spammer"),
+			Created:  reportTime.Add(time.Minute),
+		},
+	}
+
+	for _, report := range reports {
+		assert.NoError(t, f.AddAbuseReport(report))
+	}
+
+	have, err := f.AbuseReports()
+	assert.NoError(t, err)
+	assert.Len(t, have, len(reports))
+	for i, report := range reports {
+		assert.Equal(t, report.Reporter, have[i].Reporter)
+		assert.Equal(t, report.Target, have[i].Target)
+		assert.Equal(t, report.Reason, have[i].Reason)
+		assert.Equal(t, report.Context, have[i].Context)
+		assert.True(t, report.Created.Equal(have[i].Created))
+	}
+}
+
+func TestSQLiteUserStore_ChatTranscript(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	now := time.Now().UTC()
+
+	entries := []ChatTranscriptEntry{
+		{
+			Room:    "Lobby",
+			Sender:  NewIdentScreenName("John"),
+			Message: "<HTML>old news</HTML>",
+			Sent:    now.Add(-48 * time.Hour),
+		},
+		{
+			Room:    "Lobby",
+			Sender:  NewIdentScreenName("Anne"),
+			Message: "hello",
+			Sent:    now,
+		},
+		{
+			Room:    "Other Room",
+			Sender:  NewIdentScreenName("John"),
+			Message: "hi",
+			Sent:    now,
+		},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, f.AddChatTranscriptEntry(entry))
+	}
+
+	// room names are case-insensitive
+	have, err := f.ChatTranscript("lobby")
+	assert.NoError(t, err)
+	if assert.Len(t, have, 2) {
+		for i, entry := range entries[:2] {
+			assert.Equal(t, entry.Room, have[i].Room)
+			assert.Equal(t, entry.Sender, have[i].Sender)
+			assert.Equal(t, entry.Message, have[i].Message)
+			assert.True(t, entry.Sent.Equal(have[i].Sent))
+		}
+	}
+
+	// expired entries are deleted from every room
+	assert.NoError(t, f.DeleteChatTranscriptEntries(now.Add(-24*time.Hour)))
+
+	have, err = f.ChatTranscript("Lobby")
+	assert.NoError(t, err)
+	if assert.Len(t, have, 1) {
+		assert.Equal(t, NewIdentScreenName("Anne"), have[0].Sender)
+	}
+	have, err = f.ChatTranscript("Other Room")
+	assert.NoError(t, err)
+	assert.Len(t, have, 1)
+}
+
 func TestSQLiteUserStore_DeleteMessages(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -1952,6 +2372,41 @@ func TestSQLiteUserStore_DeleteMessages(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_DeleteMessage(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	sendTime := time.Now().UTC()
+
+	for cookie := uint64(1); cookie <= 2; cookie++ {
+		err = f.SaveMessage(OfflineMessage{
+			Sender:    NewIdentScreenName("John"),
+			Recipient: NewIdentScreenName("Jack"),
+			Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie: cookie,
+			},
+			Sent: sendTime,
+		})
+		assert.NoError(t, err)
+	}
+
+	messages, err := f.RetrieveMessages(NewIdentScreenName("Jack"))
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 2) {
+		assert.NoError(t, f.DeleteMessage(messages[0].ID))
+	}
+
+	messages, err = f.RetrieveMessages(NewIdentScreenName("Jack"))
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, uint64(2), messages[0].Message.Cookie)
+	}
+}
+
 func TestSQLiteUserStore_BuddyIconRefByNameExistingRef(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -2673,6 +3128,25 @@ func TestSQLiteUserStore_KeywordsByCategory(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_RegisterBuddyList(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+
+	assert.NoError(t, f.RegisterBuddyList(me))
+	// simulate a stale registration left by a session that wasn't cleanly
+	// signed out
+	assert.ErrorIs(t, f.RegisterBuddyList(me), ErrBuddyListRegistered)
+
+	assert.NoError(t, f.UnregisterBuddyList(me))
+	assert.NoError(t, f.RegisterBuddyList(me))
+}
+
 func TestSQLiteUserStore_UnregisterBuddyList(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -2992,6 +3466,56 @@ func TestSQLiteUserStore_SetPDMode(t *testing.T) {
 }
 
 // Ensure that transitioning between all the PD modes works.
+func TestSQLiteUserStore_BuddiesOnlyPermitList(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	buddy := NewIdentScreenName("buddy")
+	stranger := NewIdentScreenName("stranger")
+
+	for _, user := range []IdentScreenName{me, buddy, stranger} {
+		assert.NoError(t, f.RegisterBuddyList(user))
+	}
+
+	// the stranger has me on their buddy list and wants to send me IMs
+	assert.NoError(t, f.AddBuddy(stranger, me))
+
+	// allow only buddies by restricting the permit list to the buddy list
+	assert.NoError(t, f.AddBuddy(me, buddy))
+	assert.NoError(t, f.SetPDMode(me, wire.FeedbagPDModePermitSome))
+	assert.NoError(t, f.PermitBuddy(me, buddy))
+
+	// the buddy can send me IMs, the stranger can't
+	rel, err := f.Relationship(buddy, me)
+	assert.NoError(t, err)
+	assert.False(t, rel.BlocksYou)
+
+	rel, err = f.Relationship(stranger, me)
+	assert.NoError(t, err)
+	assert.True(t, rel.BlocksYou)
+
+	// the stranger can send me IMs once added as a buddy
+	assert.NoError(t, f.AddBuddy(me, stranger))
+	assert.NoError(t, f.PermitBuddy(me, stranger))
+
+	rel, err = f.Relationship(stranger, me)
+	assert.NoError(t, err)
+	assert.False(t, rel.BlocksYou)
+
+	// the buddy can't send me IMs once removed as a buddy
+	assert.NoError(t, f.RemoveBuddy(me, buddy))
+	assert.NoError(t, f.RemovePermitBuddy(me, buddy))
+
+	rel, err = f.Relationship(buddy, me)
+	assert.NoError(t, err)
+	assert.True(t, rel.BlocksYou)
+}
+
 func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -3239,3 +3763,31 @@ func TestSQLiteUserStore_UpdateSuspendedStatus(t *testing.T) {
 	assert.Equal(t, user.SuspendedStatus, wire.LoginErrSuspendedAccountAge)
 
 }
+
+func TestSQLiteUserStore_SetTOCConfig2(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	u := User{
+		IdentScreenName:   NewIdentScreenName("theuser"),
+		DisplayScreenName: "theUser",
+	}
+	assert.NoError(t, feedbagStore.InsertUser(u))
+
+	assert.NoError(t, feedbagStore.SetTOCConfig(u.IdentScreenName, "m 1\ng Buddies\nb friend1"))
+	assert.NoError(t, feedbagStore.SetTOCConfig2(u.IdentScreenName, "m:1\ng:Buddies\nb:friend2\ndone:"))
+
+	// each config is stored without overwriting the other
+	gotUser, err := feedbagStore.User(u.IdentScreenName)
+	assert.NoError(t, err)
+	if assert.NotNil(t, gotUser) {
+		assert.Equal(t, "m 1\ng Buddies\nb friend1", gotUser.TOCConfig)
+		assert.Equal(t, "m:1\ng:Buddies\nb:friend2\ndone:", gotUser.TOCConfig2)
+	}
+
+	assert.ErrorIs(t, feedbagStore.SetTOCConfig2(NewIdentScreenName("nobody"), "m:1"), ErrNoUser)
+}
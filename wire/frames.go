@@ -184,6 +184,9 @@ func (f *FlapClient) SendSNAC(frame SNACFrame, body any) error {
 	return nil
 }
 
+// SendDataFrame sends a data FLAP frame containing payload. The frame is
+// written with a single call to the underlying writer so that a complete
+// frame is never split between writes.
 func (f *FlapClient) SendDataFrame(payload []byte) error {
 	flap := FLAPFrame{
 		StartMarker: 42,
@@ -191,6 +194,26 @@ func (f *FlapClient) SendDataFrame(payload []byte) error {
 		Sequence:    uint16(f.sequence),
 		Payload:     payload,
 	}
+	buf := &bytes.Buffer{}
+	if err := MarshalBE(flap, buf); err != nil {
+		return err
+	}
+	if _, err := f.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	f.sequence++
+	return nil
+}
+
+// SendKeepAliveFrame sends an empty keepalive FLAP frame, which lets the peer
+// know that the connection is still alive when there's no other traffic.
+func (f *FlapClient) SendKeepAliveFrame() error {
+	flap := FLAPFrame{
+		StartMarker: 42,
+		FrameType:   FLAPFrameKeepAlive,
+		Sequence:    uint16(f.sequence),
+	}
 	if err := MarshalBE(flap, f.w); err != nil {
 		return err
 	}
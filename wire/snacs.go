@@ -2,8 +2,12 @@ package wire
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 //
@@ -622,6 +626,15 @@ const (
 	ICBMRdvTLVTagsSessID              uint16 = 0x0019 // string	Identifier for session
 	ICBMRdvTLVTagsRolloverID          uint16 = 0x001A // string	Identifier of session to rollover
 	ICBMRdvTLVTagsSvcData             uint16 = 0x2711 //	blob	Service specific data
+
+	ICBMRdvMessagePropose uint16 = 0x0000 // Propose a rendezvous
+	ICBMRdvMessageCancel  uint16 = 0x0001 // Cancel a rendezvous proposal
+	ICBMRdvMessageAccept  uint16 = 0x0002 // Accept a rendezvous proposal
+
+	ICBMRdvCancelReasonsUnknown           uint16 = 0x0000 // Unknown reason
+	ICBMRdvCancelReasonsUserCancel        uint16 = 0x0001 // The recipient declined the proposal
+	ICBMRdvCancelReasonsTimeout           uint16 = 0x0002 // The proposal timed out
+	ICBMRdvCancelReasonsAcceptedElsewhere uint16 = 0x0003 // The proposal was accepted by another client instance
 )
 
 // ICBMCh1Fragment represents an ICBM channel 1 (instant message) message
@@ -639,6 +652,18 @@ type ICBMCh2Fragment struct {
 	TLVRestBlock
 }
 
+// InvitationText returns the rendezvous invitation text converted to UTF-8
+// from the charset declared in TLV ICBMRdvTLVTagsInviteMIMECharset. It returns
+// false if the fragment has no invitation text.
+func (f ICBMCh2Fragment) InvitationText() (string, bool) {
+	b, ok := f.Bytes(ICBMRdvTLVTagsInvitation)
+	if !ok {
+		return "", false
+	}
+	charset, _ := f.String(ICBMRdvTLVTagsInviteMIMECharset)
+	return decodeChatCharset(b, charset), true
+}
+
 type ICBMRoomInfo struct {
 	Exchange uint16
 	Cookie   string `oscar:"len_prefix=uint8"`
@@ -721,25 +746,46 @@ func ICBMFragmentList(text string) ([]ICBMCh1Fragment, error) {
 }
 
 // UnmarshalICBMMessageText extracts message text from an ICBM fragment list.
-// Param b is a slice from TLV wire.ICBMTLVAOLIMData.
+// Param b is a slice from TLV wire.ICBMTLVAOLIMData. Messages split across
+// several text fragments are joined, and each fragment is converted from its
+// charset to UTF-8.
 func UnmarshalICBMMessageText(b []byte) (string, error) {
 	var frags []ICBMCh1Fragment
 	if err := UnmarshalBE(&frags, bytes.NewBuffer(b)); err != nil {
 		return "", fmt.Errorf("unable to unmarshal ICBM fragment: %w", err)
 	}
 
+	sb := strings.Builder{}
+	found := false
 	for _, frag := range frags {
-		if frag.ID == 1 { // 1 = message text
-			msg := ICBMCh1Message{}
-			err := UnmarshalBE(&msg, bytes.NewBuffer(frag.Payload))
-			if err != nil {
-				err = fmt.Errorf("unable to unmarshal ICBM message: %w", err)
-			}
-			return string(msg.Text), err
+		if frag.ID != 1 { // 1 = message text
+			continue
 		}
+		msg := ICBMCh1Message{}
+		if err := UnmarshalBE(&msg, bytes.NewBuffer(frag.Payload)); err != nil {
+			return "", fmt.Errorf("unable to unmarshal ICBM message: %w", err)
+		}
+		sb.WriteString(decodeICBMCharset(msg.Text, msg.Charset))
+		found = true
 	}
 
-	return "", errors.New("unable to find message fragment")
+	if !found {
+		return "", errors.New("unable to find message fragment")
+	}
+	return sb.String(), nil
+}
+
+// decodeICBMCharset converts ICBM message text b encoded in charset to UTF-8.
+// Text in ASCII or an unknown charset is left as is.
+func decodeICBMCharset(b []byte, charset uint16) string {
+	switch charset {
+	case ICBMMessageEncodingUnicode:
+		return decodeChatCharset(b, ChatCharsetUnicode)
+	case ICBMMessageEncodingLatin1:
+		return decodeChatCharset(b, ChatCharsetLatin1)
+	default:
+		return string(b)
+	}
 }
 
 type SNAC_0x04_0x08_ICBMEvilRequest struct {
@@ -1065,6 +1111,7 @@ const (
 	ChatRoomTLVMaxMsgLen          uint16 = 0xD1
 	ChatRoomTLVMaxOccupancy       uint16 = 0xD2
 	ChatRoomTLVRoomName           uint16 = 0xD3
+	ChatRoomTLVTopic              uint16 = 0xD4
 	ChatRoomTLVNavCreatePerms     uint16 = 0xD5
 	ChatRoomTLVCharSet1           uint16 = 0xD6
 	ChatRoomTLVLang1              uint16 = 0xD7
@@ -1101,8 +1148,62 @@ type SNAC_0x0E_0x06_ChatChannelMsgToClient struct {
 	TLVRestBlock
 }
 
+// Chat message charsets found in TLV wire.ChatTLVMessageInfoEncoding.
+const (
+	ChatCharsetASCII   = "us-ascii"
+	ChatCharsetLatin1  = "iso-8859-1"
+	ChatCharsetUnicode = "unicode-2-0" // UCS-2, big-endian
+	ChatCharsetUTF8    = "utf-8"
+)
+
+// ChatMessageCharset returns the charset that chat message text should be
+// sent in. Plain ASCII text is labeled us-ascii for the benefit of older
+// clients, everything else is sent as UTF-8.
+func ChatMessageCharset(text string) string {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return ChatCharsetUTF8
+		}
+	}
+	return ChatCharsetASCII
+}
+
+// EncodeChatCharset converts UTF-8 chat text to charset. It returns false if
+// the text contains characters that can't be represented in charset. Text is
+// left as UTF-8 for an unknown or unspecified charset.
+func EncodeChatCharset(text string, charset string) ([]byte, bool) {
+	switch strings.ToLower(charset) {
+	case ChatCharsetASCII:
+		if ChatMessageCharset(text) != ChatCharsetASCII {
+			return nil, false
+		}
+		return []byte(text), true
+	case ChatCharsetLatin1:
+		b := make([]byte, 0, len(text))
+		for _, r := range text {
+			if r > 0xFF {
+				return nil, false
+			}
+			b = append(b, byte(r))
+		}
+		return b, true
+	case ChatCharsetUnicode:
+		b := make([]byte, 0, len(text)*2)
+		for _, r := range text {
+			if r > 0xFFFF {
+				return nil, false // UCS-2 is limited to the BMP
+			}
+			b = binary.BigEndian.AppendUint16(b, uint16(r))
+		}
+		return b, true
+	default:
+		return []byte(text), true
+	}
+}
+
 // UnmarshalChatMessageText extracts message text from a chat message. Param b
-// is a slice from TLV wire.ChatTLVMessageInfo.
+// is a slice from TLV wire.ChatTLVMessageInfo. The text is converted from the
+// charset declared in TLV wire.ChatTLVMessageInfoEncoding to UTF-8.
 func UnmarshalChatMessageText(b []byte) (string, error) {
 	block := TLVRestBlock{}
 	if err := UnmarshalBE(&block, bytes.NewReader(b)); err != nil {
@@ -1114,7 +1215,30 @@ func UnmarshalChatMessageText(b []byte) (string, error) {
 		return "", errors.New("SNAC(0x0E,0x05) has no chat msg text TLV")
 	}
 
-	return string(b), nil
+	charset, _ := block.String(ChatTLVMessageInfoEncoding)
+	return decodeChatCharset(b, charset), nil
+}
+
+// decodeChatCharset converts chat text b encoded in charset to UTF-8. Text in
+// an unknown or unspecified charset is assumed to be UTF-8, which is a
+// superset of us-ascii.
+func decodeChatCharset(b []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case ChatCharsetUnicode:
+		runes := make([]uint16, len(b)/2)
+		for i := range runes {
+			runes[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(runes))
+	case ChatCharsetLatin1:
+		sb := strings.Builder{}
+		for _, c := range b {
+			sb.WriteRune(rune(c))
+		}
+		return sb.String()
+	default:
+		return string(b)
+	}
 }
 
 //
@@ -101,6 +101,75 @@ func TestSNAC_0x01_0x14_OServiceSetPrivacyFlags_MemberFlag(t *testing.T) {
 	}
 }
 
+func TestChatMessageCharset(t *testing.T) {
+	assert.Equal(t, ChatCharsetASCII, ChatMessageCharset("hello world!"))
+	assert.Equal(t, ChatCharsetUTF8, ChatMessageCharset("Grüße, 世界!"))
+}
+
+func TestEncodeChatCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		charset string
+		want    []byte
+		wantOK  bool
+	}{
+		{
+			name:    "ascii text in us-ascii",
+			text:    "hello",
+			charset: ChatCharsetASCII,
+			want:    []byte("hello"),
+			wantOK:  true,
+		},
+		{
+			name:    "non-ascii text in us-ascii",
+			text:    "café",
+			charset: ChatCharsetASCII,
+			wantOK:  false,
+		},
+		{
+			name:    "latin-1 text in iso-8859-1",
+			text:    "café",
+			charset: ChatCharsetLatin1,
+			want:    []byte{'c', 'a', 'f', 0xE9},
+			wantOK:  true,
+		},
+		{
+			name:    "non-latin-1 text in iso-8859-1",
+			text:    "世界",
+			charset: ChatCharsetLatin1,
+			wantOK:  false,
+		},
+		{
+			name:    "text in unicode-2-0",
+			text:    "hé世",
+			charset: ChatCharsetUnicode,
+			want:    []byte{0x00, 0x68, 0x00, 0xE9, 0x4E, 0x16},
+			wantOK:  true,
+		},
+		{
+			name:    "text outside the BMP in unicode-2-0",
+			text:    "😀",
+			charset: ChatCharsetUnicode,
+			wantOK:  false,
+		},
+		{
+			name:    "text in utf-8",
+			text:    "Grüße, 世界!",
+			charset: ChatCharsetUTF8,
+			want:    []byte("Grüße, 世界!"),
+			wantOK:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EncodeChatCharset(tt.text, tt.charset)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestUnmarshalChatMessageText(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -123,6 +192,54 @@ func TestUnmarshalChatMessageText(t *testing.T) {
 			}(),
 			want: "<p>hello world!</p>",
 		},
+		{
+			name: "utf-8 text",
+			b: func() []byte {
+				tlv := TLVRestBlock{
+					TLVList: TLVList{
+						NewTLVBE(ChatTLVMessageInfoEncoding, "utf-8"),
+						NewTLVBE(ChatTLVMessageInfoText, "Grüße, 世界!"),
+					},
+				}
+				b := &bytes.Buffer{}
+				err := MarshalBE(tlv, b)
+				assert.NoError(t, err)
+				return b.Bytes()
+			}(),
+			want: "Grüße, 世界!",
+		},
+		{
+			name: "unicode-2-0 text",
+			b: func() []byte {
+				tlv := TLVRestBlock{
+					TLVList: TLVList{
+						NewTLVBE(ChatTLVMessageInfoEncoding, "unicode-2-0"),
+						NewTLVBE(ChatTLVMessageInfoText, []byte{0x00, 0x68, 0x00, 0xE9, 0x4E, 0x16}),
+					},
+				}
+				b := &bytes.Buffer{}
+				err := MarshalBE(tlv, b)
+				assert.NoError(t, err)
+				return b.Bytes()
+			}(),
+			want: "hé世",
+		},
+		{
+			name: "iso-8859-1 text",
+			b: func() []byte {
+				tlv := TLVRestBlock{
+					TLVList: TLVList{
+						NewTLVBE(ChatTLVMessageInfoEncoding, "iso-8859-1"),
+						NewTLVBE(ChatTLVMessageInfoText, []byte{'c', 'a', 'f', 0xE9}),
+					},
+				}
+				b := &bytes.Buffer{}
+				err := MarshalBE(tlv, b)
+				assert.NoError(t, err)
+				return b.Bytes()
+			}(),
+			want: "café",
+		},
 		{
 			name: "missing ChatTLVMessageInfoText",
 			b: func() []byte {
@@ -147,3 +264,83 @@ func TestUnmarshalChatMessageText(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalICBMMessageText(t *testing.T) {
+	// fragments marshals an ICBM fragment list with a capabilities fragment
+	// followed by a text fragment for each message
+	fragments := func(msgs ...ICBMCh1Message) []byte {
+		frags := []ICBMCh1Fragment{
+			{ID: 5, Version: 1, Payload: []byte{1, 1, 2}},
+		}
+		for _, msg := range msgs {
+			payload := &bytes.Buffer{}
+			assert.NoError(t, MarshalBE(msg, payload))
+			frags = append(frags, ICBMCh1Fragment{ID: 1, Version: 1, Payload: payload.Bytes()})
+		}
+		b := &bytes.Buffer{}
+		assert.NoError(t, MarshalBE(frags, b))
+		return b.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name: "ascii text",
+			b: fragments(ICBMCh1Message{
+				Charset: ICBMMessageEncodingASCII,
+				Text:    []byte("<p>hello world!</p>"),
+			}),
+			want: "<p>hello world!</p>",
+		},
+		{
+			name: "unicode text",
+			b: fragments(ICBMCh1Message{
+				Charset: ICBMMessageEncodingUnicode,
+				Text:    []byte{0x00, 0x68, 0x00, 0xE9, 0x4E, 0x16},
+			}),
+			want: "hé世",
+		},
+		{
+			name: "latin-1 text",
+			b: fragments(ICBMCh1Message{
+				Charset: ICBMMessageEncodingLatin1,
+				Text:    []byte{'c', 'a', 'f', 0xE9},
+			}),
+			want: "café",
+		},
+		{
+			name: "text split across fragments with different charsets",
+			b: fragments(
+				ICBMCh1Message{
+					Charset: ICBMMessageEncodingASCII,
+					Text:    []byte("hello "),
+				},
+				ICBMCh1Message{
+					Charset: ICBMMessageEncodingUnicode,
+					Text:    []byte{0x4E, 0x16, 0x75, 0x4C},
+				},
+			),
+			want: "hello 世界",
+		},
+		{
+			name:    "missing text fragment",
+			b:       fragments(),
+			wantErr: "unable to find message fragment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalICBMMessageText(tt.b)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
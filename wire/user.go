@@ -44,11 +44,20 @@ func RoastPassword(roastedPass []byte) []byte {
 	return clearPass
 }
 
-// RoastPassword toggles password obfuscation using a roasting algorithm for
-// AIM v1.0-v3.0 auth. The first call obfuscates the password, and the second
-// call de-obfuscates the password, and so on.
-func RoastTOCPassword(roastedPass []byte) []byte {
-	var roastTable = []byte("Tic/Toc")
+// TOCRoastingString is the roasting string that TOC clients use by default to
+// obfuscate passwords.
+const TOCRoastingString = "Tic/Toc"
+
+// RoastTOCPassword toggles password obfuscation using the TOC roasting
+// algorithm, which XORs each byte of the password with the corresponding byte
+// of roastingString. The first call obfuscates the password, and the second
+// call de-obfuscates the password, and so on. If roastingString is empty,
+// TOCRoastingString is used.
+func RoastTOCPassword(roastedPass []byte, roastingString string) []byte {
+	if roastingString == "" {
+		roastingString = TOCRoastingString
+	}
+	var roastTable = []byte(roastingString)
 	clearPass := make([]byte, len(roastedPass))
 	for i := range roastedPass {
 		clearPass[i] = roastedPass[i] ^ roastTable[i%len(roastTable)]